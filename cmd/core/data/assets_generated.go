@@ -25,10 +25,10 @@ var Assets = func() http.FileSystem {
 		},
 		"/default-config.toml": &vfsgen۰CompressedFileInfo{
 			name:             "default-config.toml",
-			modTime:          time.Date(2020, 4, 15, 14, 1, 39, 736241741, time.UTC),
-			uncompressedSize: 2231,
+			modTime:          time.Date(2026, 10, 15, 11, 0, 46, 496743462, time.UTC),
+			uncompressedSize: 15206,
 
-			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x54\x4d\x6f\xdb\x38\x10\xbd\xeb\x57\x0c\xe4\x4b\x02\x04\xb1\x93\x34\x81\xd7\x80\x0f\x59\x34\xd8\xbd\x34\x28\xd6\x7b\x0b\x0a\x81\x26\x47\x16\x63\x89\xc3\xf2\xc3\xae\xfe\xfd\x62\x48\x29\x96\xd3\x1e\xba\x45\x73\x89\x39\x1f\xef\x3d\xbe\x19\xb1\xa5\x5d\xd5\xe2\x01\x5b\x58\x43\xa9\x4d\x4d\x65\xc1\xa1\x9a\x5c\x27\x02\xc7\x02\x7e\x0b\x25\xcc\x80\x62\xb0\x31\x40\x4b\x3b\x18\x92\x17\x3d\x45\x90\xc2\x40\xf4\x08\x5c\x06\xe4\xe0\xd5\x93\xb9\x2c\x8e\xbe\xb2\xe4\xb8\xff\x8f\xc5\x62\x51\xc8\x06\xe5\xbe\x8a\x56\x89\x80\x1e\xd6\x10\x5c\xc4\x42\xc4\x40\x95\xa2\xa3\x69\x49\xa8\x49\xb2\x16\xad\x47\x80\x19\xe8\x3a\x15\x82\x47\x77\xd0\x12\xe1\xa8\xdb\x16\xc6\x06\xc8\x0d\x20\x8c\x02\xfc\xa6\x43\x51\xbc\x48\x72\xf8\xa5\x00\x00\xd0\x8a\x95\xb3\x6a\xad\x80\x6a\x40\xb5\xc3\x94\x70\x56\x56\x41\x77\x48\x31\xdd\xed\xa6\xe3\x9a\x86\x8e\xd0\x92\xd9\x01\x03\x80\x6f\x28\xb6\x0a\x8e\x42\x07\x70\xe8\x2d\x19\x8f\x50\x3b\xea\x40\x92\x31\x28\x03\x39\xd8\x62\xcd\xa5\x0e\x43\x74\x06\x46\x40\x74\x8e\x5c\x91\x78\x92\x96\x6b\xb5\xcd\x72\xac\x08\x0d\xd3\xf9\x40\x4e\xec\x38\x5e\xa6\xb8\x6c\x51\x98\xca\x07\xbe\xc7\x78\xef\xd9\x28\x40\x9b\x80\xce\x88\x16\x72\x7e\x8b\xb9\x1c\x15\x90\xe1\x98\x4b\x76\x1b\x0a\x53\x46\xd9\x52\x54\x99\x34\xba\x34\xd2\x26\x04\xeb\x57\xf3\xb9\xc2\xc3\xb5\xd3\xbb\x26\xa0\x6c\xae\x35\xcd\x85\xd5\xf3\xc3\x4d\xd6\x31\x83\xd4\x07\xaf\xc7\x00\x42\x4a\xf4\x1e\x02\xed\xd1\x0c\xc9\x4e\x1b\xdd\xb1\x10\x49\xf6\xcd\x9f\x6d\x36\x74\x36\xd4\xfc\xf5\xf4\x2f\x74\xa4\xb0\xf5\xf3\x95\x56\x93\x20\x6d\x5f\x51\x86\x53\x34\x01\xa7\xe9\x4c\x75\x77\x5f\x43\xf8\x32\x74\xe9\x1a\x24\xba\x50\xd5\xba\xcd\xe3\xdd\x63\x5f\x25\x0b\xad\xa3\x83\x56\xa8\xf2\xa0\xd2\x3a\x6c\x31\x6f\x5f\xeb\xc7\xf1\x68\x1a\x75\x6b\x03\xa1\xd1\x1e\xa4\xf0\x08\x9d\xd8\x23\xf8\xe8\x10\x7a\x8a\x2e\xb9\x93\x4d\x3c\xea\xd0\x70\xff\x6a\x3e\x9f\xfa\x96\x23\xef\x5c\x5b\x2d\x97\xcb\xbb\x61\x76\x6f\x12\x87\x4d\xe3\x2b\xa4\xa8\xae\xb5\xe4\x89\xa5\x24\xeb\x4e\xf5\x6f\x97\x98\x96\xef\xb1\x9f\x94\x15\x2f\x1d\xa9\x6d\xf4\xd9\x08\x76\x33\x09\x91\x96\xeb\x5d\x88\xc9\x0c\xe1\xa5\xd6\x20\x5a\x4f\xe0\xa3\xe5\x8f\x0c\xb3\xb1\x42\x29\xc7\xf5\x2d\x49\xd1\x36\xe4\xc3\x6a\xb9\x58\x2c\xca\xc1\xd1\x01\x8d\x51\xc8\x0d\x20\xa1\x41\x87\xa0\xfd\x69\xa4\xa3\x0e\xb2\x32\x8a\x2c\x03\x8d\xb2\xa4\x4d\xfa\x5c\xc8\xca\xeb\x20\xed\x6a\x3e\x3f\x91\x7c\x58\x7e\x58\x94\x43\xa5\x74\xbd\x65\xff\xb9\xf6\x4f\xe1\xb5\xbc\xbd\x7f\xd8\x34\xe2\xf6\xfe\xa1\x4c\x03\x71\xf8\x35\x6a\x87\x8a\x1f\x90\xb1\x1c\x55\xfa\xbe\xd1\x79\x20\xd3\xf6\x57\x67\x9d\xe5\xe4\xf8\xf6\xfb\xe6\x76\xf9\x8f\x17\x37\xf7\xe5\x3b\x03\x46\xc3\x36\x7a\x67\x1e\x8d\x7a\xca\xf8\xcc\x9c\xff\x7e\x96\xff\x99\x0c\x32\x19\xe3\x8c\xff\xa7\x78\xe7\xac\xb9\xb9\xe2\xc1\x33\x39\xff\xbf\xb6\xd8\x95\xff\x93\x35\xad\x46\x20\xe0\xde\xe9\x16\x4d\x39\x78\x5b\xd6\x50\xee\xb1\x3f\x63\xf8\x35\x8e\x3d\xf6\x45\xf1\xe2\x4d\x67\xf3\x9c\x79\x98\xe9\xcd\x5e\x4f\x36\xe8\xe6\x61\x78\x21\x24\x75\x5d\x34\x3a\xf4\xeb\xd2\xc6\x6d\xab\xe5\x84\x3d\xbd\x1f\x63\x1e\x7c\x70\xda\xec\xae\xce\x15\x1d\x6e\x65\xd2\x90\xb0\x58\x91\x26\xb3\x2e\x6f\xcf\x51\x46\xac\x21\xcf\xef\xf6\xe6\xf9\xd3\x67\xb8\x48\x85\xe4\xa0\xbc\x2b\x2f\xcf\x26\x2d\x62\x68\x3e\x3b\x7d\x28\xdf\x21\xa4\x3c\x3f\xfb\xa7\x8d\xbc\x38\x15\x5f\xe5\xc6\x67\x1a\x4f\xcf\x34\x39\x5f\xbe\x97\x7e\x77\x52\xce\x65\x95\x75\x14\x48\x52\x7a\x24\x3e\x7d\xbc\x9f\xee\x57\x3e\xf3\x57\x5a\x6e\xfe\x7e\x9c\x6c\xca\x8f\x31\xe1\x42\xd7\x60\x90\xdf\x5b\xe1\xfa\xcb\x13\xc5\x30\xe8\xef\xdd\x99\xfd\x34\x8e\x75\xfa\x70\x26\xf5\xe3\xd3\xe6\x4c\x6a\x3a\x27\xa9\x8f\xfc\xeb\x17\xa4\x26\x8a\xdf\x20\xd5\xa3\x8c\x4e\x87\xbe\x32\xa2\xc3\xef\xc0\x7e\x8c\x53\xfc\x17\x00\x00\xff\xff\xdb\xd6\xd7\x60\xb7\x08\x00\x00"),
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xac\x3b\xdb\x72\x23\xb7\x95\xef\xfc\x8a\x53\xad\x87\x90\x76\x4b\x22\xa9\x91\x32\x99\x2a\x3d\x38\x8e\xbd\xfb\x12\x27\x95\x49\x9e\x54\x13\x16\xd8\x7d\x48\x22\x42\x03\x3d\x00\x5a\x14\xe3\x72\xd5\x7e\xd2\x7e\xc3\xfe\xc0\xfe\xd2\xd6\x39\xb8\x34\x9a\xd4\xd8\x4e\x6a\xfd\x30\x56\xe3\x72\xee\x38\x37\x80\xca\xec\x37\x0a\x5f\x50\xc1\x23\x54\x52\xef\x4c\x35\xa3\xa1\x9d\xb1\x9d\xf0\x34\xe6\xf1\xd5\x57\x70\x05\x66\xf0\xfd\xe0\x41\x99\x3d\xc4\xc9\xf9\xc9\x0c\xd0\x08\x0d\x83\x43\xa0\x65\x60\x2c\xfc\xc3\x19\xbd\x98\x1d\xdd\xa6\x37\x96\xf6\xff\x6e\xb9\x5c\xce\x9a\x03\x36\xcf\x9b\xa1\x6f\x85\x47\x07\x8f\xe0\xed\x80\x33\x31\x78\xb3\x69\xcd\x51\x2b\x23\xda\x62\x72\x27\x94\x43\x80\x2b\x90\x3b\x5e\x08\x0e\xed\x8b\x6c\x10\x8e\x52\x29\x48\x1b\x20\x6c\x00\xa1\x5b\xc0\x57\xe9\x67\xb3\xa7\xc6\x58\xfc\x34\x03\x00\x90\x2d\x51\x4e\x54\xcb\x16\xcc\x0e\xb0\xdd\x23\x4f\xd8\xbe\xd9\x78\xd9\xa1\x19\x98\xb7\x55\x47\x6b\x0e\xe6\x08\xca\xe8\x3d\x10\x00\x70\x07\x33\xa8\x16\x8e\x42\x7a\xb0\xe8\x7a\xa3\x1d\xc2\xce\x9a\x0e\x1a\xa3\x35\x36\xde\x58\xd8\xe2\x8e\x96\x5a\xf4\x83\xd5\x90\x00\xa2\xb5\xc6\xce\x18\x0f\xd3\x72\xd3\x6e\x03\x39\xbd\xf0\x07\x42\xe7\xbc\xb1\x62\x4f\xe3\x15\x8f\x37\x0a\x85\xde\x38\x4f\x7c\x24\xbe\xaf\x12\x01\x52\x7b\xb4\x5a\x28\x08\xf3\x5b\x0c\xcb\xb1\x05\xa3\x69\xcc\xb2\xb8\xb5\xf1\x25\xc6\x46\x99\xa1\x0d\x48\x07\xcb\x2a\x3d\x78\xdf\xbb\x0f\xb7\xb7\x2d\xbe\xdc\x58\xb9\x3f\x78\x6c\x0e\x37\xd2\xdc\x8a\x5e\xde\xbe\xac\x02\x1d\x57\xc0\xfb\xe0\x1f\x47\x0f\xa2\x69\xd0\x39\xf0\xe6\x19\x75\x9c\xec\xa4\x96\x1d\x11\xd2\x98\x3e\xcb\x67\x1b\x04\x7a\x15\xfe\x85\xff\xf8\xee\xaf\xd0\x99\x16\x95\xbb\xfd\x20\xdb\x62\xd0\x6c\xff\x81\x8d\x1f\x47\x19\x30\x6b\xa7\xa4\xbb\xfb\xec\xfd\xa7\xb8\x4b\xee\xa0\x41\xeb\x37\x3b\xa9\x82\x7a\x9f\xf1\xb4\x61\x11\xf6\xd6\xbc\xc8\x16\xdb\xa0\x28\x36\x87\x2d\x06\xeb\x53\x2e\xa9\x47\x9a\x44\xb7\xd4\xe0\x0f\xd2\x41\x23\x1c\x42\x27\x9e\x11\xdc\x60\x11\x4e\x66\xb0\x2c\x9d\x20\xc4\xa3\xf4\x07\xda\xff\xe1\xf6\xb6\x94\x9b\x57\x6f\x48\xed\xc3\xfb\xf7\xef\xef\xa2\xee\x32\x89\xd1\xd2\x88\x05\x1e\x95\x3b\xd9\x90\xc6\x78\x92\xe8\xe6\xf5\x99\x89\x72\xf9\x33\x9e\x8a\x65\xb3\xa7\xce\xb4\xdb\xc1\x05\x41\x90\x34\x99\x90\xa6\xa7\xf5\x43\xdb\xc3\xdc\x37\x3d\xec\xac\xe8\xa4\xde\x83\xd4\xd0\x0a\x2f\xf6\x56\x74\x6e\x51\x83\xf5\x03\x0b\x4b\xb8\x46\x4a\x10\xca\x19\x70\x43\x4f\x87\x10\xdb\x1a\x9c\xec\x40\x3a\x90\xfa\xba\xc3\xce\xd8\x13\x38\x25\x5e\x90\x79\x27\xcb\x3d\x08\xdb\x1e\x85\x45\x98\x3b\x44\x08\x54\xdc\x38\xd9\x0d\x4a\x78\x63\x17\x4c\x8f\x68\x5b\x4b\xf4\x28\xd3\x08\x75\x30\xce\x7f\x78\xbf\x5c\x2e\xab\xa8\xb1\x48\x2d\x51\x61\x6c\x24\xc2\x1f\xd0\x22\x48\x37\x9a\xcc\x28\x8e\xed\xc9\xe3\xc6\xd8\x16\x19\xe6\x56\xee\x19\x50\x8b\x3b\x31\x28\xcf\xb3\x10\x66\xcd\x0e\x2c\xee\xa5\xf3\x68\x1d\xcc\xb7\x72\x4f\xf0\x95\xf4\x5e\x21\x71\x8d\x9f\x07\x74\xbe\x04\x67\x5e\xd0\x5a\xd9\xa2\x03\xe9\x19\xd5\xd1\xd8\xf6\xcb\xa8\x68\x76\x44\x75\xb7\xbe\xde\x4a\x0f\x2f\x42\x0d\xf8\x33\xe8\x0a\x90\x17\xe8\xc8\x1b\x38\x2f\xba\xbe\xf0\xa1\x76\xd7\xdc\xdd\xdd\xfd\x8e\x11\xc7\x51\xb3\x03\x6f\x85\x76\x82\x2d\x16\x1a\xd3\xf5\x0a\xf9\x4f\x02\x00\x52\xc3\x0b\xda\xad\x71\x98\xd9\x07\x8b\xa2\x75\xc1\x5e\xe9\x9f\x4d\xc6\x04\xf3\x88\x00\x8c\x05\xec\x4d\x73\xd8\x74\xae\x20\xf7\x82\xa4\x0b\xa2\x1b\xd1\x1c\x70\xe3\x3d\x9b\xfe\xd2\x05\xad\xb6\xa8\xbd\x6c\x84\x2a\x10\xa7\x23\xc5\x34\x06\xf7\xe7\xc2\xe6\x16\x2c\x3a\x12\xe8\x7c\xe9\xa0\x95\x4e\x6c\x15\xc6\xa9\x45\x0d\x9d\x78\xdd\x88\x3d\x6e\x3a\x07\xbd\xb0\xa2\x0b\x6a\x25\xa8\x4a\x76\xd2\x3b\x10\x7b\xa4\xb1\x08\x6a\x4e\x96\xdc\x1b\xa5\xb0\x5d\x44\x65\x04\x32\x8d\x50\xe8\x1a\xdc\x84\xbd\x65\xac\xc8\xc4\x36\x46\x37\x83\xb5\xa8\x7d\xc4\xe0\x0e\xc2\x22\x18\x8d\x13\x81\x13\x06\x42\x9c\xa8\x3e\x5a\xe9\xd1\x01\x2d\xd5\xf8\x82\x36\xe3\x6a\x83\xf9\x13\x07\x9f\x07\xa1\xbd\xf4\x27\x78\x84\x25\x3b\x46\xf1\x0a\x79\x4c\x6a\xc6\x11\x65\x5e\x83\xf4\xbf\x71\xe0\xbc\x95\x8d\x47\x0b\xfe\x20\x34\xf9\x2f\x6f\x1a\xa3\x02\xd3\x30\x5f\x8e\x82\x92\x7e\x44\x93\xa2\xce\x86\xac\x9a\xb8\x7c\xb8\xbf\xbf\x7b\x00\xb8\x02\x25\xec\x9e\x0d\x21\x2c\x08\xe4\x5a\x24\x0f\x8b\x6d\x8a\x4a\xbd\xb0\x8e\x1c\xc4\x5b\xe0\x9d\x32\xc7\x8d\x3f\x58\x74\x07\xa3\x5a\x52\x47\x64\xa5\x10\x8d\xe3\x60\x98\x68\x96\x9e\x91\x28\xb3\xdf\x63\x0b\xc2\xc1\x51\x58\x2d\xf5\xde\xb1\x04\x1b\x33\x68\x42\x2d\x39\x24\x79\xf7\x26\xd2\x02\xf6\x46\xb6\x9b\x9d\xb4\xce\x27\xbc\xe1\x83\xfc\x5a\xb1\x2a\x46\x6d\xb6\xb4\x18\xfc\xeb\xf4\x47\xd0\x27\xf1\x47\xd2\x1e\x7d\x7e\x72\x32\x83\x43\xd0\x46\x5f\x93\x89\x2b\xd1\xf7\xb4\xd2\x0a\xbd\x8f\x16\x74\x46\x8b\x12\x23\x29\x4a\xfc\x4a\x4a\x24\x1d\x06\x2b\x7a\x10\xd6\x0c\xba\x05\x6f\xde\x66\x51\xec\x3c\x5a\x38\x53\xb4\x3f\x60\xa0\x67\x51\x9f\xed\x4a\x27\xa3\x38\x9b\x30\xaf\xa2\x3d\x55\xc4\x98\x03\x3d\x74\x68\x65\xc3\x59\xd6\xb5\xed\x1b\x90\xed\xe8\x9d\xd1\xb9\xcd\x56\x38\x4c\x0c\xad\x40\xee\xd2\x04\x81\xd3\xc9\x38\x83\xdd\xac\xae\x69\x71\x0b\x73\x12\x24\xf1\x37\x6c\xbd\x15\xa5\x25\x39\xd4\x6d\xe1\x46\x26\x38\x2e\x5c\x08\xc5\x25\xdc\xb4\xa8\xc4\xa9\x70\x22\x4e\x2a\xd4\x3e\x24\x33\x2f\x42\x45\x99\xa0\x68\x0e\x25\xf7\x35\x71\xb7\x1b\x14\x39\x47\xb6\x51\x0e\x24\x1c\xa3\x82\xda\xf0\xd5\xa3\x6e\xb1\xdd\xec\x06\xcd\x3b\x12\x8f\x2f\xa8\x5b\x63\x21\x0f\x37\xa6\xc5\xc2\x91\x47\x92\xa3\x27\x98\x87\xc8\x76\x4d\x5f\xd7\x09\xe4\xa2\x86\x89\xcd\x32\x3e\x8b\xde\x9e\x36\xc2\x7b\xec\x7a\x9f\x0f\x09\x8d\x4a\x74\x04\x7f\x27\xa4\xc2\x76\x7a\x6c\xe6\xfc\xc5\x79\x2f\xa7\x82\xae\x8e\x78\x85\x76\x47\xb4\xd8\xe6\x78\x4b\x81\x9b\xcf\x4f\xc0\x83\xaf\x0d\xf6\x0c\xe3\x67\x88\xd9\x8a\xe6\xd9\xec\x76\x9c\xb6\x2e\x97\x9d\x8b\x51\x8c\xc4\x1d\xd5\x15\xac\x8e\x57\x93\xfb\x81\xd6\x0c\x0c\xc6\xe8\x20\x70\xcd\x29\xba\xc6\x02\xe8\x88\x19\x1e\xe1\xe9\xbe\x86\x87\x4f\x00\x57\x90\x87\x59\x9e\x0e\x8e\x07\xd9\x1c\xa2\xb3\x21\x11\x90\x87\x6e\x9e\xb5\x39\x2a\xca\xac\x99\x13\x56\x16\xb4\x48\x47\x04\xb6\x83\x3b\x05\xbb\xdc\x0a\xdf\x1c\x36\x91\x83\xa1\xdd\xa3\x2f\x9d\xa7\x37\x5e\xa8\x08\xd3\x85\x98\x10\x0d\xd4\xec\x88\x52\xb6\x73\x32\x73\x06\x73\x71\x8e\xd8\x8d\x7e\x09\x0f\x87\xc7\xc2\x12\x19\x1f\x0d\x99\xdd\x14\x6c\x5d\x1c\x8b\x74\x62\x49\xbd\x59\x5b\x53\x25\x97\xe1\x2d\x61\xff\x3c\xe0\x40\xb6\xdf\xfb\xc3\x84\xbd\x72\x23\x15\x14\xe4\x8c\xc8\xc4\x89\xf8\xed\xe0\x6a\x3e\x45\x23\x2b\x23\x5a\x9a\x65\x29\x06\x4b\x7a\xd3\xad\x06\xa4\x04\xf6\x8c\x4b\x1e\x62\x56\x27\xb8\x32\x73\x05\x59\x8c\xd1\x7d\x01\xe5\x1b\x8c\x6e\x07\xb7\xb1\xc2\xe3\x26\xd0\xfb\x08\xcb\x9b\xb7\xb9\xed\xd1\x82\xc3\xc6\x68\x2e\x57\x88\x86\x4e\x48\xcd\x38\x2c\xee\x85\x6d\x15\x3a\xd6\x32\xdb\x4d\x8c\x96\x5c\x26\x62\x0b\x83\x6e\xd1\xf2\x5a\x65\x9a\xe7\x18\x68\xba\xde\x38\x8c\xa4\x16\x24\xcc\x97\x3f\x43\x65\x12\xce\xea\x4b\xc2\x99\xf2\xf3\xaf\xca\x88\x91\xb9\xc3\xe0\xa9\x28\x9d\xd4\x95\x51\x1b\xb9\xb2\x9c\xc8\x46\x72\x26\xb0\x67\xc7\x44\xe5\x73\xcc\xfd\x90\x0b\xbb\x08\x2d\xa6\x3b\x1c\xdd\x4a\xc8\x11\x70\x1a\x31\x3b\x40\xe7\xc5\x56\x49\x77\x20\xe3\xa2\xf0\x55\xc4\x44\xd2\x61\x87\x42\xbb\xb1\x92\x8d\x3b\x17\xf5\x05\xf4\xcb\xf0\x13\x1d\x45\x48\x3f\x37\xa4\x8b\x49\xce\xc5\x6e\xb4\x33\xad\xdc\x9d\xae\x39\x7d\x82\x03\xaa\x1e\xed\xe8\x68\x1d\xfa\xe0\x86\x75\x1b\xab\x8a\xb0\x90\x06\xdd\x22\x68\x37\xc1\xaf\xc1\x99\x32\x79\x6b\x84\x52\x0e\x5a\xa3\x7f\xe3\x41\x19\x87\x90\x3a\x04\x73\x43\x85\x05\x74\xc2\x71\x4d\x20\x2c\xd2\x92\x86\x08\x4f\xc9\x5a\x6f\xa4\xf6\xae\x28\xcf\xe0\x2a\xe3\x81\x4e\xf4\xa1\xe8\x9a\xdf\x90\x1f\x00\x63\xe1\xa6\x71\x2f\x41\xc1\x5a\x74\x58\xa7\x68\x52\xc7\xf0\x51\xa7\x24\xaf\xf6\xa7\x1e\x6b\xd7\x08\x85\xf5\xa0\xa5\xaf\x29\x47\xdd\xa4\xe0\x56\xb3\x96\x29\xc5\x86\xc6\xa8\xa1\x63\x77\x2e\xbd\x8b\xe4\x10\xa5\x14\x90\x90\x33\x86\x58\x64\x85\xa9\x60\x48\xc3\xd6\x35\x56\x06\x77\x3c\xa5\x9d\x2c\xe7\x05\xa7\x2b\x46\x21\x87\xd1\x2d\x2e\x18\x83\x13\x2f\x01\x03\x27\x2d\xb9\x88\xb6\xc8\xe5\x6e\xd1\x3e\x18\x7a\x98\x53\x78\x3b\x5d\x1e\x20\x8b\x0d\x55\x38\x13\x1a\xc8\xf4\xa7\x9e\x90\x8f\xee\x46\xb6\x35\x74\xe8\x0f\xa6\x2d\x32\x05\xdd\x8e\x16\xc7\x89\x81\xab\xa1\x1d\xac\xa0\x9d\x81\x4c\xd1\xf7\x1c\x7e\xcf\x28\x75\xec\x9b\x41\x49\x1d\x23\xbf\xc5\x5e\x89\xd3\xb9\x2a\xcb\xfc\x97\xf2\x32\x6c\x43\x8b\xa6\x24\x9c\xce\x86\xb0\x4a\xb2\x27\x72\x8e\xb3\x39\xed\x3c\x8a\x98\xd2\xe5\x68\x35\x37\xbb\x1d\x21\x24\x5c\xd6\xb4\x03\xf3\xc7\x41\x5e\xa2\x6a\x41\x3a\x37\xa0\x1b\xd3\xf3\xa9\x16\x1e\x61\xb5\x64\x17\xa8\xf1\x78\xa6\xa0\x33\xe7\x3e\xc9\xd5\xcf\xdc\x56\xf2\x3c\x77\x29\xb1\x88\xa5\x4b\x01\x8f\xeb\xa1\xe8\x86\xf6\xd6\x1c\xe9\xb8\x73\xf8\x8f\x1d\x2f\xec\x7a\xe3\x51\x37\xa7\x54\xc6\xad\xba\xa9\x0f\x0a\x95\x0e\x3b\xdd\x58\xec\x30\xac\x72\x27\xf5\x23\x02\x99\x1d\x76\x5b\x3a\x4f\xa4\xd3\x1e\x85\x77\xb1\xda\x23\x7e\xba\x1c\x19\x19\xce\x34\x52\x3c\xe3\x29\xca\xaa\x04\xec\xe4\x3f\x31\x88\x2a\x87\x0b\x2e\x1d\x42\xcc\x4f\xc8\xca\x2d\x0c\x88\xe1\xb4\xb8\x1d\xf6\x9b\xe0\x0e\x0a\xef\x83\x3a\x20\x8c\xa7\x80\x57\x5d\xd3\xaa\x68\x8d\x31\x69\x49\x45\xaa\x43\x9d\xec\xb2\x41\x19\x0c\x86\xec\x92\x28\x10\xfa\x94\x36\xcd\x83\xc3\x09\xc0\x41\xfa\xe8\xac\xa3\x51\x04\xc6\x42\x51\xb7\x49\xe6\x3f\x75\x89\xa4\x5f\x76\xb7\xc1\x2a\xf3\xa2\xf5\xbb\xf7\xd7\xeb\xfb\xfb\x48\x02\x29\x97\x0d\x76\x6b\x8d\x68\x1b\xe1\xfc\xb8\x72\x19\xfa\x3c\xc1\x38\x89\x3e\x8f\xa1\xc5\xba\x04\x63\x61\x7d\x7f\xbf\x88\xfd\xad\x9c\xb6\x14\xc1\x36\xe6\x11\x29\x8d\x66\xa0\xae\xc8\x70\xce\x6c\x92\xa3\xa1\x36\x93\x8a\x8f\x6c\x9c\xc6\x13\x96\x32\xdc\x3f\xfd\x08\x05\xdb\xab\x9a\x67\xe1\x11\xee\x6f\x96\x75\xde\x48\xc6\xb7\x76\x15\xfc\x94\x3a\x7a\x7f\xfb\xe1\xe3\x37\xdf\x7f\xf7\xa1\x68\x0b\xd8\xe6\x56\xd9\x06\x5e\xd0\x86\x6e\x59\x3c\x70\xe3\xc1\x66\xe1\xf8\x03\x3a\x8c\x3c\xc0\x7c\xda\xe1\x32\x5a\x9d\x92\x20\x1a\x63\xed\xd0\x7b\x6c\x0b\x00\xa9\x3b\x48\xfd\xcc\x9e\x7b\x60\x24\x42\xe9\x79\x63\x14\x10\xc3\x0d\x66\x42\xa5\x0e\x1c\x2d\x77\x81\x29\x0b\x71\x43\x17\x81\x0f\xda\x89\x1d\x6e\xdc\xb3\xec\x37\x69\x8a\x24\x71\x77\xce\xdd\xc4\x39\x9a\xdd\x94\xfa\xed\xa9\x17\xce\x4d\x73\x9a\x7d\x19\xef\xd4\x29\xe1\x3b\x23\x93\x6c\x21\x91\x4a\xe7\xd5\x1c\x75\x11\xe2\xeb\x6c\xc6\x3a\x34\x3a\xda\x69\x0f\x8e\xfd\x5a\x63\x94\x92\x2d\x4e\x19\xa2\x70\xaf\x14\xf7\xfd\x9f\xee\x13\x2f\xd4\x38\x50\x98\xfc\xc3\x39\x13\xc1\xdb\xd2\x39\x72\xd0\x0d\xca\xcb\x7e\x5c\xcb\xb4\xa5\x38\x09\x2b\x98\x13\xed\x7b\xe1\xf1\x28\x4e\x2e\x3b\x8c\xef\xbf\x5d\xde\xdf\x7e\xff\xed\xf2\x21\xa9\xee\x87\x3f\xfd\xf5\xbb\x0f\x20\x3d\x34\x07\x2e\xd2\xcf\x2b\xb9\x90\x3b\x1e\xa5\xc5\x3a\x60\xba\xce\x71\x7c\x6f\x88\x24\x07\xdf\x7f\xbb\x7a\x60\x79\x86\xf9\xc6\x48\x15\x87\xef\x23\x92\x9d\xb1\x0d\x6e\x12\xc5\x1b\x5e\x47\x6c\xbf\x4b\x6c\x9f\x33\x33\xa7\xcd\xb7\x04\x78\x71\x21\x04\x37\x34\x0d\x62\x0b\x72\x34\x57\x38\x88\x50\x90\x38\xd1\xe1\x94\x83\x3a\x62\xc0\xe6\x60\xd8\xd5\xc4\x82\x94\xe8\xcd\xd2\x92\x2e\x64\x30\x5d\xcf\x0a\x3c\x13\x1d\xdb\x27\xef\x4f\x9b\x43\x2a\xca\x55\xf8\x6e\xe7\xd0\x27\x61\x2a\xd4\x12\xb5\xdf\xf0\xe2\x47\x78\x7a\xf8\x94\x3d\x45\x94\x59\xa9\x2c\x3e\x7c\xa7\x0b\xfe\xf6\x06\xb6\xa7\xd8\xca\x12\x6d\x4c\xec\xb2\x80\x12\xa4\x49\x2e\x40\xf2\x5a\xdf\x25\x2a\x02\xc1\x11\xd3\xb8\x7e\x74\x7c\x1c\x42\xa8\x9c\x85\xf9\xd0\x83\x37\xb0\x5a\xaf\xc6\x85\x35\x34\x87\x41\x53\x19\x10\x41\xd0\x81\xe0\x3f\x33\x88\xd1\x01\x8a\x36\x68\x73\x13\x99\x79\x84\xa7\xdf\x26\xa6\x53\x87\x97\xf3\x5a\x36\xe6\xe0\xe3\x1d\xcc\xf1\x66\x7f\xf3\xb6\x2a\x38\xea\xbf\x62\xcb\xed\x2c\x4a\xf6\xe9\xb4\x16\x3d\x90\x04\x2c\x66\xc9\xcc\x4b\xf2\x42\x14\x7b\x92\xa1\xc7\x75\x81\xe1\x48\x09\x73\xae\xa7\xd4\x39\x78\x84\x1f\xa1\xec\x4b\x50\x63\x8e\x62\x3b\x8d\x4f\x7d\x6d\x22\xf8\x11\x96\x35\x14\xbd\xc8\x77\xf5\x59\x8f\x3b\xf4\xab\x2b\xf8\x09\x7e\x9a\xcd\xae\xd8\x63\xa4\xbd\x73\x63\xc1\xa1\x95\x42\x01\x35\x2a\x16\xb9\x04\x2b\x8b\x7c\x6d\xfc\x79\xd5\x56\xd3\x97\xb4\x63\x20\xa1\xc2\xe5\xdc\x81\x5d\xc1\x53\xba\x3b\x60\xc2\x09\xe9\xa7\xd9\x15\xd0\x7f\xd5\x7d\xc5\x49\xc9\xef\xd6\x37\xab\x87\xf7\x37\xab\x9b\xfb\x0f\xf7\xcb\x75\x95\xe8\x1b\x5b\x27\x66\x97\xaf\x38\x02\x45\xad\xdc\xed\xd0\x8e\x21\x81\x6d\xd3\xc4\x2b\x87\xa0\xca\x82\x23\x9a\xe1\xe6\x11\xee\xbb\xd0\x79\x62\x0f\x4a\x8b\x17\xf5\xac\x08\x9a\xe1\xde\xe7\x80\x19\xdb\x7c\x1b\xaf\x45\x36\x69\xc4\xd8\x3c\xc9\xfa\x5c\x10\xc7\xde\x80\xf4\x05\xab\x71\xc5\x84\x59\x22\xe0\x11\x2a\xba\x3e\xba\xf5\xfe\xf4\xb7\x8f\xbf\x5f\x32\xa7\x19\x95\x6f\xfa\x7a\xe2\xa8\x4b\x45\xc8\x1d\x48\x3f\x65\x9b\xc8\x1f\x8d\x30\xd3\x57\xd6\x6a\x23\xf4\xf1\xba\xe5\x42\x5a\x74\x8b\xc4\xdd\x1a\x19\x60\x86\xd3\xe5\x9b\x7e\x01\xc6\xc2\x41\xbc\x60\xb6\x14\xa9\xe1\x0d\x0e\x2f\x74\x1c\x27\xb3\x9a\xd7\xac\x66\xeb\x07\x66\x78\x52\x74\xa5\x32\xe8\x45\x48\xc5\xe9\xd5\xf6\xc4\xf5\x16\xcc\xb3\xcb\x94\x0e\xc8\x7f\xd7\xd0\x4a\xd7\x58\xf4\x58\x83\xd4\xfd\xe0\x99\xba\x70\x30\x16\xb3\xab\xc9\x79\xa1\x53\x17\xdb\x6c\x4a\x25\x1c\x73\x8d\xc2\x06\x37\xe6\x52\x67\xbe\x70\x58\x8b\x3a\xe5\xd9\x71\x3d\x73\x1e\xfa\x1e\x45\x8d\xc0\x37\x0f\xc4\xf1\xd3\xa4\x5a\xfb\x94\x98\x65\xe2\xf9\x8a\xbc\xeb\xd1\x0a\x3f\x58\xac\xe2\x54\xd1\xa7\xac\x22\xe1\x69\xaa\x3c\xd4\x71\x68\x3c\xd9\xab\xe5\x32\x8e\xa1\x6e\x4c\x74\x04\xd5\x4e\x19\xe1\xef\xd6\x19\x02\x15\xa0\xdc\x7c\x49\x00\xae\xc0\xd8\x30\xbc\xe9\x2d\x3a\x8c\x37\xf7\xda\x1f\x5c\x05\xf3\xc3\xa0\x5b\x8b\xad\x3f\xf0\x31\x36\x83\x13\x9a\x3e\x68\x4f\x8f\xb6\x93\x8a\x2f\xb7\xa4\xa7\x43\xfd\x1b\x1f\xef\x54\x5b\xf0\x66\x8f\x5c\x6a\xf3\x51\x61\xe8\x11\x5d\x08\x3b\x63\xfb\x27\x57\xb5\x56\x1c\x83\xd4\x72\x0b\x99\x6b\xe5\x38\xf6\x08\x73\x5a\xf0\x75\x0a\x5b\xf0\x55\x9a\x0f\xa1\x9b\xc5\x4b\x95\xa1\x92\xac\xb6\x17\xb4\x0e\x61\x1e\x36\xdf\x86\xb5\x70\x9d\x83\x5e\xa0\x85\xea\x70\xe2\xf6\x7f\xfe\xfb\xdb\x2a\x4b\x43\x89\x2d\x2a\xf6\xf9\x52\x7b\xdc\xa3\xcd\x57\x7a\xda\xc4\x2b\xdf\xad\xf4\x2e\x4b\x6d\x11\x5a\xb5\x91\x82\x54\x33\x30\x94\x0c\x73\x1e\x5b\x4f\x89\x43\xb9\x4b\x57\x74\x7c\x78\xc6\x09\x5e\x37\x68\xea\x8f\xea\xf8\xd8\x21\x9e\xe9\x83\x70\x9c\x6d\x4f\xe0\xa2\xe6\x84\xf2\x47\xa8\x96\x7c\x76\x64\xab\xb0\xaa\xa1\x5a\xf1\x97\x1d\x74\x55\xa7\x63\xc5\x21\xa3\x82\x9f\xf2\x5e\x9d\x4a\x98\x18\xae\x28\x0e\x04\xce\xe6\x83\xd4\x7e\xf5\x00\xc6\x02\xfd\x75\xb7\x2e\x92\x80\x18\xa3\xbe\xcc\xf9\x68\x55\x7c\x7b\x4f\x08\xb6\xd2\x33\x12\xce\x65\x43\x9b\x04\x06\xcd\x9c\x60\x44\xb9\x3d\x81\xd4\x2d\xbe\x46\xa7\xfc\x23\x13\x4f\x77\x45\x55\x94\x42\x9d\x39\x88\x25\x53\x62\x2c\x7e\xdc\xdc\xdc\xc0\x4f\x8b\x8c\x7c\x2b\xfd\x26\x2a\xb2\x10\x4f\x82\x99\x25\xf4\x65\xa1\x84\xfb\x33\xb3\x0b\xa7\x3c\xe8\xa5\x3c\x56\x3c\x5f\xc1\x3c\x4b\x46\x3a\x70\xbd\x92\x1e\xbc\x81\x83\xdc\x1f\xd8\x57\x52\x1d\x45\x2b\xa3\x09\xd5\x23\x7d\x93\x3b\xec\x14\x74\xfb\xc1\xbb\x71\x0f\xf7\xe4\xe9\xaa\xe7\x68\x22\x5d\x3d\xda\xa2\xe7\x75\x29\xfb\x52\xe6\xa7\x42\xdc\x53\xb4\x59\x2e\x4f\x55\xba\xbc\x27\x89\xec\xa4\xed\xe8\xef\x4d\x27\xb5\xb1\xd5\xa7\xbc\x29\xfa\x39\x16\xc1\xa4\x69\x15\xeb\x7d\xd1\x02\x05\x7a\xd1\x3c\xef\xc3\xa5\xd6\x2f\x7a\xd0\x0c\xba\x74\xc6\xe1\xce\xb6\x38\x40\x6e\xbc\x4c\xcf\x7d\xaa\x10\x42\xb9\xb8\xd1\xc6\xe7\x02\xd0\x2d\x0a\x6a\x4b\x0a\x43\x03\x37\x4f\xe2\x6b\x6f\x63\x3f\xc7\xec\x8a\x63\x17\xe0\x69\xaa\x78\x84\x05\x87\xda\x19\x4b\x07\x7e\xa0\xe6\x82\xa3\x52\xf5\x58\xc3\xd7\x70\x0d\x5f\xc1\x2d\xfc\x9d\x55\x4b\xf9\xb6\xe6\xec\xd7\x15\x0c\x5d\x38\xc2\xd1\xff\xd5\xc9\xf5\x19\x1b\xce\x2d\x41\xa1\xa7\x25\xb1\xc9\x17\x24\x49\xb5\x5b\xbe\xfb\xe1\xfa\x13\xc6\xd6\x60\x68\xb3\x7a\x63\x32\xbe\x71\x8e\x1a\xbc\x37\xcb\x15\x7c\x45\xc4\xfe\x7d\x0d\xd7\xb0\xbc\xb9\x0f\x5f\xf0\x35\xbc\x1b\x65\xc0\xb5\x82\x97\x5b\xa9\x38\x69\xed\x53\x01\x9d\x9a\x06\xb1\x68\x78\xed\xc9\x92\x1a\xd3\x75\x5c\x1f\xb1\x73\x48\x97\xc4\x27\x7e\x45\xc5\x4d\xb2\x94\xae\xc3\x3c\x25\x9f\x5c\x5d\x8f\x12\x99\xb8\x67\xc5\x65\x09\x15\xcc\x0e\xa4\x27\xf3\xfc\x62\x8d\x30\x2f\x6e\xc7\x1b\x35\xb4\xa9\xd9\x35\x5e\xb8\x04\xe9\x44\x0a\x37\x4c\xe1\xa5\x80\x26\xd3\x8f\xb0\x7c\x5d\x2e\x57\xcb\x3c\x9b\xd0\x05\xfe\x1b\x7e\xaa\x84\xaf\xbd\xd1\x18\xfa\x4b\xc1\x3a\xe6\x29\x04\x91\x2c\xbf\x82\xd5\xf2\xef\x69\x4d\x0d\xae\x13\xd6\x43\x87\x9e\xaf\xfd\xf5\x0b\xea\x60\xe2\xe1\x56\x82\xf4\x38\xda\x86\x1e\x0b\xbd\xc9\x3d\xf1\x2e\x2c\x26\xdb\xab\x47\x27\x33\xa6\x62\xc1\x15\xc7\x6b\xc9\x18\x94\xea\x68\x34\x5b\x6c\x4c\x87\x6e\x34\x9e\xd2\xd6\x03\x1f\xd7\x77\xeb\xdf\x3e\xbc\x8f\xcd\x7c\x6d\x3c\x48\xba\x33\xa0\x0c\x17\xdb\xc4\x9b\x74\xa0\x07\xa5\x4a\xf9\x0e\x0e\x27\x1e\x2f\xa4\x08\x49\x62\x55\x74\x89\x11\xc9\x26\xa6\x21\x17\xd8\x37\x65\x7e\xf2\xee\x72\xba\xc4\xc0\x51\xa7\x8a\x19\x09\x7d\xbd\xaf\x60\xee\xe4\x5e\xe3\xe8\x49\x17\xb3\x59\x30\x61\xe3\xa4\xc7\x28\x04\xe1\x1c\x76\x5b\x95\x3a\xb9\x74\x87\xdf\x18\xed\xe5\x7e\x30\x83\xbb\xc8\x02\x4b\x23\xcb\x62\x23\x07\xd2\x0b\x1b\x5b\xed\xee\x20\x77\x24\x1d\x85\x3b\xb6\x52\xfe\x0e\x91\x8a\x4e\xc3\x9f\xfe\x82\x6d\xa1\x29\xe9\x52\x9c\x9c\xc7\xd2\x8c\xa3\x3a\x0f\x65\xb0\xa1\xf9\x2a\x7a\x07\xa1\x70\x7d\x57\x90\xb1\x45\x7f\x44\x8c\x0d\xd2\xec\x54\x63\x29\x5d\x98\xca\xaf\xc8\x27\x51\xa3\xdd\x9f\x7e\x45\x2a\x59\x0a\x3e\x50\x9f\x66\x02\xbd\xdc\xb0\x9b\x24\x97\x75\x14\xc3\x23\x2c\xe1\xa7\x1a\xca\xd9\x75\x39\xbb\x7a\xa0\xf6\x1d\x67\xf0\x0d\x1f\x4a\xa2\xb4\x0e\x1d\x71\x0e\xa9\xa1\x3c\x41\xed\x81\xee\x44\x1c\x08\x1f\x5d\xa3\x1b\xe3\x69\x2c\x5e\x22\x8a\xd0\xf7\x6f\x91\xda\x22\x2d\x97\x2b\xa6\x1b\xcb\xd8\x71\xcf\x17\xe4\x96\x91\x07\xdf\x1c\xdf\x77\x84\x6a\x26\x9c\xb2\x9d\xf4\xe9\x91\x52\x02\x3b\xbb\xfa\xf9\x08\xcb\x20\x53\x84\xca\xe5\x08\x9f\x12\xee\xf0\x84\xf9\x83\x70\x45\xb2\x34\xca\x03\xe4\xf4\xcc\xfe\x92\x5e\x5b\x2b\x5f\xde\xac\x10\xd8\xba\xab\x8b\x62\xe0\x2e\x17\x03\x45\xb5\xff\x90\xf6\x07\x69\x3c\xc2\x53\x1c\xa0\xff\x7e\xcc\xb8\x42\x42\x58\xd5\x45\xae\x4e\x0a\x87\x2b\x88\x89\xe1\xf6\x94\x7a\x10\x6f\xef\xef\x11\xdb\x72\xfb\xaa\x66\x4d\x97\xe5\xc8\x17\xfb\x0e\xf5\x9b\x20\x63\xa3\xa0\x04\x7a\x37\x02\x0d\x8e\xa3\x2e\x8a\x9b\xe5\xea\x4b\x90\x0e\x66\xb0\x13\xde\xde\x8d\x70\xe2\x39\x18\xb7\xb2\x19\xa7\xc0\xb8\xc5\x83\xcc\x97\x91\xdc\x19\x88\x8d\xb6\x98\xb4\x1a\x0d\xd2\xbb\xd4\x50\x70\xc3\xf6\x7a\x3b\x70\x76\xc0\x75\x06\x55\x6d\xf3\xd5\xf5\xfa\xdd\x6f\x17\xe0\x50\x71\xab\x3f\x40\x9e\x5d\xb1\x25\x4a\x47\xeb\x3e\x0f\x98\x00\x1d\x8c\xf3\xf5\x18\x96\x53\xcf\x81\xe3\x07\xcd\xc5\xd7\x69\xb4\xb4\xbc\xb7\x0d\xf7\x72\xbc\x20\xb8\x7c\xee\xc3\x8c\x2b\x38\x36\xc7\xfd\x6c\xfb\x1d\x3f\x90\xb8\xe8\xbe\xa4\x9b\xae\x44\x7c\x0a\x53\x0e\x3d\xf0\xf3\xcc\x59\x4e\x0d\x73\xf6\x30\x3a\xaf\xd4\x54\xf5\xa8\x03\xe9\x44\x66\x02\xb5\x3d\xa5\xf5\xf9\x45\x1f\x43\x4a\x5d\xb6\xd9\x55\x14\xe9\x34\x3f\xcc\x89\x78\xdc\xfc\x08\x55\x3f\x74\xfd\xaa\x0a\x09\x19\xcf\x07\x13\x3b\x56\x0b\xee\x3a\x9d\xe2\x89\xe7\x1a\x3d\x17\xcf\xc6\x4e\xb3\xc4\xb1\x2b\xc0\x4b\xae\x83\xfd\x83\x45\x6a\x0a\x39\x38\xa0\x50\xfe\x90\xd3\x9d\x88\x3c\x9f\xf8\xf8\xcd\x1f\x39\xf7\x13\x10\x72\xc7\x45\x79\xb4\xa3\x2a\x2f\xce\x76\x60\xa2\xa8\x49\x43\x85\x7f\x17\x47\x58\x57\x93\xfe\xd7\x7a\x19\x1a\x60\x61\xfe\x1c\xfe\xb9\x0b\x29\x51\xb1\x6c\x8a\xf1\x2f\x7a\x92\xf3\xd6\x42\x31\xfc\x66\x77\x61\x76\x05\x52\x4b\x4f\x96\x1f\x5d\xa2\xd9\x41\x7e\x06\xcc\x17\xce\xc1\x37\xc8\x8e\x74\x23\x94\x0a\xfa\x00\x99\x9f\x59\x4a\xef\xc0\xf3\x25\x4f\x0d\xc5\x93\x4a\x52\xbe\xe0\x96\x8f\x68\x9e\xcb\xbe\x50\x28\x48\xd0\x4a\xd3\xc2\xce\x28\x65\x8e\x0e\x9c\xd4\x08\x47\x82\xcb\x54\xc0\xd7\x20\x3a\xaa\xca\x86\x16\xa9\x63\x20\xf5\x7c\xfd\xbf\xff\x05\x1e\x6e\xe3\xc6\x45\x46\x15\x0a\x0b\x42\x18\x5b\x63\x2d\x4e\xe2\x6d\xe6\x25\xcb\xf5\x67\x82\x6b\xd1\x6e\x8d\x23\x29\x85\x5c\x87\x5b\x4c\xbe\x8f\x5b\x71\xdd\x41\x4d\x2b\x57\x83\xc6\xbd\xe0\x9b\xfa\x51\x7c\x45\x03\xdc\x22\xb0\x93\x4b\xf0\x33\x53\x8f\x70\xbf\xbc\x49\x48\xa2\x30\xe2\x2b\x12\xd2\x49\x7c\x92\x6f\x07\x35\xb9\xca\x4b\xf7\x9b\x82\x0f\x43\x4c\xe0\x5b\xd4\xfc\xfc\x8e\x86\xa9\x20\xe4\xe1\x8a\x16\x54\x42\xa9\x6a\x51\xbc\x07\xa4\xf8\x7b\xed\x0d\xcc\x97\xe1\x21\x20\x99\x39\xb9\x0b\xd6\xd3\xfc\x97\xda\x70\x35\x04\x1f\x15\xdc\x93\x50\x6a\x31\xbd\xe0\x66\xc5\x46\xca\x5b\xd4\x12\xdb\xf8\x66\xe6\x0a\x3a\xe9\xf8\x81\x6a\x3a\xcb\x6e\x04\x92\xde\xfc\x15\x3a\x0b\x30\xb2\xc2\xc6\x4d\x8f\xf0\xb4\xaa\x61\xfd\x96\x26\x89\xf8\x1c\x67\xad\xe9\x4a\xeb\xf7\xa6\xfc\x4a\xf2\x0a\x72\x9a\xcd\x42\xed\xc9\x25\x50\xbe\x26\xcf\xcf\xf7\xb6\x27\x28\x9f\xbd\x8d\xaf\xe4\xe6\xcb\xfb\x3a\x97\x59\xe9\xc6\x11\xb6\x83\xe7\x54\x3d\xbd\xef\x69\xe1\x14\xda\x56\xe7\x39\xec\xd8\x73\x75\xf1\xd5\x33\x0c\xda\x4b\x05\xd2\x03\x7e\x1e\x44\x78\x07\x83\x1b\xb6\xaa\x50\x58\x16\xc8\xa3\xa7\xcb\x7b\x67\x57\x71\x77\x38\x9b\x81\x7a\x07\xd2\xe7\xc6\x4f\x78\xc1\x94\x01\x8c\xaf\x44\x41\x86\xe2\xc2\xa1\x8f\x67\x2a\xbd\x8f\x96\xe9\x01\x00\xb6\xf9\x91\xd4\xec\x2a\xfa\x7a\x9a\xe5\xcb\x82\xf2\xe2\x3d\x14\x88\x34\x1c\x4d\xb3\x0c\x06\xd3\xab\xbc\x45\x9d\x6d\x62\x6c\x4b\xe8\x36\xbf\x72\x22\xf3\x00\x7e\xf4\xc8\xc3\xab\xe5\x99\x81\x3c\x6f\x88\xf3\x6c\x22\x91\x8c\x47\xa8\x26\xd8\x52\x7d\x9a\xd1\xba\xcb\x93\x7e\x9f\xed\x22\xcb\xbb\x38\xff\x65\x47\x62\x4d\xe4\x24\x00\xc5\x03\xad\xbb\x78\x68\x8b\x0b\x8d\xdc\xa5\x2f\xee\x5a\x38\x02\x8e\x29\xb4\x0c\xd7\x02\xff\x44\x6b\xc0\xd8\x2c\x8d\xe8\x46\x98\xff\xbd\x32\x5b\xa1\xc0\xa1\xa7\x67\x84\xdc\x2c\x39\x7f\xc1\x25\xdd\xf8\x9b\x8f\xf2\xe2\x23\x37\x24\xce\x1e\xb5\x5e\xaf\xc6\x2b\xf9\xf8\x08\x73\xe2\x2d\xf9\xa8\x65\x46\x2e\x8e\x20\xc9\xeb\x52\x00\xec\xb5\xc2\xe8\x1b\xef\xd7\xd6\x6e\x3c\x97\x93\xf7\xc2\xf7\x85\x38\x2f\x08\xbd\x9b\x4c\x94\x2f\x61\x49\xd8\x4f\xa6\x6f\x06\x11\xae\xfd\x50\xb7\x39\x87\x30\x7d\x73\xe3\x9b\xfe\xc3\xed\xed\xf8\x9b\x95\x77\xef\xdf\x2d\xab\xb8\xb2\xb1\xa7\x3e\x79\x8c\xdf\x0b\x27\x9b\xf5\xfd\xc3\xc7\x83\x58\xdf\x3f\x54\xb1\xb1\xf0\x79\x90\x16\x5b\xf6\xf0\x71\x39\xb6\xe1\xbd\x91\x75\x51\xa8\xe5\xce\xaa\xf8\xcc\x7f\xaf\xd6\xef\xff\xe2\xc4\xea\xbe\x1a\x75\x33\xf9\x7d\xcf\x47\xb9\xd7\xdf\xe8\xf6\xbb\x00\xbf\xca\x11\xfa\xd7\xe2\xff\xc1\x68\x6e\xfd\x11\x9c\xaa\xbe\x84\x37\xc5\x1a\x36\x6f\x1a\xb4\x2c\x22\xfa\xff\x4d\x8f\x5d\xf5\x2f\x62\xe5\x5f\x32\x79\x03\xb4\xb7\xfc\xd1\x53\x89\x83\x1e\x13\x3d\x42\xf5\x8c\xa7\x09\x86\x7f\x0f\xc7\x33\x9e\x66\xb3\x27\xa7\xbb\x3e\xe8\x99\x94\xc9\x3f\x31\x7c\x2c\x7e\x90\xb4\x7a\x88\x3f\x68\x23\x57\x4c\x39\xd8\xe9\xb1\xea\x87\xad\x92\x4d\x81\x3d\x35\x94\x78\x1e\x9c\xb7\x1c\xcc\x26\x14\xbd\xac\x9b\x50\xd2\x01\x00\x10\x45\xd2\xe8\xc7\x6a\x3d\x85\x92\x60\xc5\x79\x30\x3b\xf8\xf8\xc3\x1f\xff\x0c\x73\x5e\x48\x01\xf7\xae\x5a\x4c\x34\x2d\x06\x7f\xf8\xb3\x95\x2f\xd5\x19\x84\x2e\xbe\x59\x2f\x2c\x72\x3e\x2e\xae\xc3\xc6\x1f\x4c\xfa\xfa\xc1\x14\xdf\x8b\x73\xd2\xef\x46\xca\x69\xd9\x26\xff\xe6\xe4\x11\xaa\x3f\xfe\xe1\xbe\xb4\xaf\xf0\x4d\x1e\xb5\xfa\xf8\x9f\xdf\x54\xe5\xef\xc5\xde\x82\x09\x73\xb9\x03\x8d\x14\x8d\x85\x3d\x2d\x46\x14\x51\xd1\xd5\x1b\xc2\xf9\xb5\x70\x7a\x2b\x5f\x26\xa4\xfe\xe1\xbb\x8f\x13\x52\xf9\x9b\x49\xfd\xe6\xbb\x8f\xff\x16\xa9\x8c\xe2\xff\x81\x54\x87\xcd\x60\xa5\x3f\x6d\x52\x3a\x5e\xfd\x32\x9c\xd9\xff\x0d\x00\xe9\x6a\x17\x5c\x66\x3b\x00\x00"),
 		},
 		"/min-config.toml": &vfsgen۰CompressedFileInfo{
 			name:             "min-config.toml",
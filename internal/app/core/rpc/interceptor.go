@@ -41,6 +41,7 @@ type stater interface {
 
 type rpcCli interface {
 	Call(name, id string, p []byte) <-chan []byte
+	CallBatch(name string, ids []string, p []byte) []<-chan []byte
 }
 
 type api interface {
@@ -125,9 +126,10 @@ func (s Service) undoAll() {
 }
 
 var (
-	errTimeout   = jsonrpc.ErrServer.AddData("msg", "timeout")
-	errUnmarshal = jsonrpc.ErrParse.AddData("msg", "json unmarshal error")
-	errBadIDType = jsonrpc.ErrInternal.AddData("msg", "id should be string or null")
+	errTimeout    = jsonrpc.ErrServer.AddData("msg", "timeout")
+	errUnmarshal  = jsonrpc.ErrParse.AddData("msg", "json unmarshal error")
+	errBadIDType  = jsonrpc.ErrInternal.AddData("msg", "id should be string or null")
+	errEmptyBatch = jsonrpc.ErrInvalidRequest.AddData("msg", "empty batch")
 )
 
 func (s Service) sendState(parent string, value interface{}) {
@@ -303,25 +305,48 @@ func (s Service) prepareRequest(payload []byte) ([]byte, objx.Map, *jsonrpc.Erro
 		return nil, nil, &e
 	}
 
+	data, dataChanged, e := s.prepareData(payload)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	if changed || dataChanged {
+		payload, err = jsoniter.ConfigFastest.Marshal(data)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return payload, data, nil
+}
+
+// prepareData decodes request (with filled template) and sets its id and value to write
+// it returns true if request was changed
+func (s Service) prepareData(payload []byte) (objx.Map, bool, *jsonrpc.Error) {
 	var data objx.Map
 
-	err = jsoniter.ConfigFastest.Unmarshal(payload, &data)
+	err := jsoniter.ConfigFastest.Unmarshal(payload, &data)
 	if err != nil {
 		e := errUnmarshal.AddData("err", err.Error())
-		return nil, nil, &e
+		return nil, false, &e
 	}
 
-	id := data.Get("id")
+	changed := false
 
-	if !id.IsNil() && !id.IsStr() {
-		e := errBadIDType.AddData("current_id", id.Data())
-		return nil, nil, &e
-	}
+	// notification is forwarded without id (null id is request)
+	if !isNotification(data) {
+		id := data.Get("id")
 
-	if id.IsNil() {
-		data.Set("id", nanoid.New())
+		if !id.IsNil() && !id.IsStr() {
+			e := errBadIDType.AddData("current_id", id.Data())
+			return nil, false, &e
+		}
 
-		changed = true
+		if id.IsNil() {
+			data.Set("id", nanoid.New())
+
+			changed = true
+		}
 	}
 
 	if data.Get("params._type").Str() == "write" {
@@ -336,22 +361,31 @@ func (s Service) prepareRequest(payload []byte) ([]byte, objx.Map, *jsonrpc.Erro
 		}
 	}
 
-	if changed {
-		payload, err = jsoniter.ConfigFastest.Marshal(data)
-		if err != nil {
-			panic(err)
-		}
-	}
+	return data, changed, nil
+}
 
-	return payload, data, nil
+// isNotification reports whether request has no id
+// connector doesn't respond to notification
+func isNotification(data objx.Map) bool {
+	_, ok := data["id"]
+	return !ok
 }
 
 func (s Service) Call(name string, payload []byte) []byte {
+	if jsonrpc.IsBatch(payload) {
+		return s.callBatch(name, payload)
+	}
+
 	payload, data, err := s.prepareRequest(payload)
 	if err != nil {
 		return jsonrpc.BuildErrResp("", *err)
 	}
 
+	if isNotification(data) {
+		s.rpc.Call(name, "", payload)
+		return nil
+	}
+
 	resultC := s.rpc.Call(name, data.Get("id").Str(), payload)
 	timer := time.NewTimer(s.timeout)
 	select {
@@ -370,6 +404,113 @@ func (s Service) Call(name string, payload []byte) []byte {
 	}
 }
 
+// callBatch sends all requests of batch to connector in one message
+// and returns array of their responses in order of requests
+// (requests which can't be prepared are answered by core, notifications are skipped)
+func (s Service) callBatch(name string, payload []byte) []byte {
+	var items []jsoniter.RawMessage
+
+	err := jsoniter.ConfigFastest.Unmarshal(payload, &items)
+	if err != nil {
+		return jsonrpc.BuildErrResp("", errUnmarshal.AddData("err", err.Error()))
+	}
+
+	if len(items) == 0 {
+		return jsonrpc.BuildErrResp("", errEmptyBatch)
+	}
+
+	res := make([][]byte, len(items))
+	payloads := make([][]byte, 0, len(items))
+	reqs := make([]objx.Map, 0, len(items))
+	ids := make([]string, 0, len(items))
+	idx := make([]int, 0, len(items))
+
+	for i, item := range items {
+		p, data, e := s.prepareRequest(item)
+		if e != nil {
+			res[i] = jsonrpc.BuildErrResp("", *e)
+			continue
+		}
+
+		payloads = append(payloads, p)
+
+		if isNotification(data) {
+			continue
+		}
+
+		reqs = append(reqs, data)
+		ids = append(ids, data.Get("id").Str())
+		idx = append(idx, i)
+	}
+
+	if len(payloads) != 0 {
+		s.waitBatch(name, joinBatch(payloads), reqs, ids, idx, res)
+	}
+
+	resps := res[:0]
+
+	for _, r := range res {
+		if r != nil {
+			resps = append(resps, r)
+		}
+	}
+
+	if len(resps) == 0 {
+		// nothing to respond (batch of notifications)
+		return nil
+	}
+
+	return joinBatch(resps)
+}
+
+func joinBatch(items [][]byte) []byte {
+	return append(append([]byte{'['}, bytes.Join(items, []byte{','})...), ']')
+}
+
+// waitBatch sends batch to connector and puts responses of reqs to res by their indexes idx
+func (s Service) waitBatch(name string, payload []byte, reqs []objx.Map, ids []string, idx []int, res [][]byte) {
+	resultC := s.rpc.CallBatch(name, ids, payload)
+
+	// timeout is shared by all requests of batch
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	expired := false
+
+	for j, i := range idx {
+		data, id, ch := reqs[j], ids[j], resultC[j]
+
+		var (
+			msg []byte
+			ok  bool
+		)
+
+		if expired {
+			select {
+			case msg, ok = <-ch:
+			default:
+			}
+		} else {
+			select {
+			case msg, ok = <-ch:
+			case <-timer.C:
+				expired = true
+			}
+		}
+
+		if !ok {
+			res[i] = jsonrpc.BuildErrResp(id, errTimeout)
+			continue
+		}
+
+		if data.Get("params._type").Str() == "read" {
+			msg = s.prepareResponse(data, msg)
+		}
+
+		res[i] = msg
+	}
+}
+
 func (s Service) prepareResponse(req objx.Map, resp []byte) []byte { // nolint: funlen
 	parent := req.Get("params._parent").Str()
 	if parent == "" {
@@ -0,0 +1,143 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// connectorCli answers each request by its method as result
+type connectorCli struct {
+	messages [][]byte
+}
+
+func (c *connectorCli) answer(id string, req objx.Map) chan []byte {
+	ch := make(chan []byte, 1)
+	ch <- []byte(`{"jsonrpc":"2.0","id":"` + id + `","result":"` + req.Get("method").Str() + `"}`)
+
+	return ch
+}
+
+func (c *connectorCli) Call(name, id string, p []byte) <-chan []byte {
+	c.messages = append(c.messages, p)
+
+	var req objx.Map
+	if err := jsoniter.ConfigFastest.Unmarshal(p, &req); err != nil {
+		panic(err)
+	}
+
+	return c.answer(id, req)
+}
+
+func (c *connectorCli) CallBatch(name string, ids []string, p []byte) []<-chan []byte {
+	c.messages = append(c.messages, p)
+
+	var reqs []objx.Map
+	if err := jsoniter.ConfigFastest.Unmarshal(p, &reqs); err != nil {
+		panic(err)
+	}
+
+	byID := make(map[string]objx.Map, len(reqs))
+	for _, req := range reqs {
+		byID[req.Get("id").Str()] = req
+	}
+
+	res := make([]<-chan []byte, len(ids))
+	for i, id := range ids {
+		res[i] = c.answer(id, byID[id])
+	}
+
+	return res
+}
+
+func TestCallBatch(t *testing.T) {
+	cli := &connectorCli{}
+	s := Service{rpc: cli, timeout: 50 * time.Millisecond}
+
+	resp := s.Call("modbus", []byte(` [
+		{"jsonrpc":"2.0","id":"1","method":"a"},
+		{"jsonrpc":"2.0","id":"2","method":"b"},
+		{"jsonrpc":"2.0","id":"3","method":"c"}
+	]`))
+
+	// all requests go to connector in one message
+	if len(cli.messages) != 1 || !jsonrpc.IsBatch(cli.messages[0]) {
+		t.Fatalf("expected one batch message but got %q", cli.messages)
+	}
+
+	var sent []objx.Map
+	if err := jsoniter.ConfigFastest.Unmarshal(cli.messages[0], &sent); err != nil || len(sent) != 3 {
+		t.Fatalf("unexpected batch message %s (%v)", cli.messages[0], err)
+	}
+
+	var res []objx.Map
+	if err := jsoniter.ConfigFastest.Unmarshal(resp, &res); err != nil {
+		t.Fatalf("unexpected response %s (%v)", resp, err)
+	}
+
+	if len(res) != 3 {
+		t.Fatalf("expected 3 responses but got %s", resp)
+	}
+
+	// responses are in order of requests
+	for i, method := range []string{"a", "b", "c"} {
+		if res[i].Get("result").Str() != method || res[i].Get("id").Str() != sent[i].Get("id").Str() {
+			t.Errorf("unexpected response %d: %v", i, res[i])
+		}
+	}
+}
+
+func TestCallNotification(t *testing.T) {
+	cli := &connectorCli{}
+	s := Service{rpc: cli, timeout: 50 * time.Millisecond}
+
+	if resp := s.Call("modbus", []byte(`{"jsonrpc":"2.0","method":"a"}`)); resp != nil {
+		t.Errorf("notification should have no response but got %s", resp)
+	}
+
+	if resp := s.Call("modbus", []byte(`[{"jsonrpc":"2.0","method":"a"},{"jsonrpc":"2.0","method":"b"}]`)); resp != nil {
+		t.Errorf("batch of notifications should have no response but got %s", resp)
+	}
+
+	resp := s.Call("modbus", []byte(`[{"jsonrpc":"2.0","method":"a"},{"jsonrpc":"2.0","id":"1","method":"b"}]`))
+
+	var res []objx.Map
+	if err := jsoniter.ConfigFastest.Unmarshal(resp, &res); err != nil || len(res) != 1 ||
+		res[0].Get("id").Str() != "1" || res[0].Get("result").Str() != "b" {
+		t.Errorf("unexpected response of mixed batch %s (%v)", resp, err)
+	}
+
+	// notifications are forwarded to connector without id
+	if len(cli.messages) != 3 {
+		t.Fatalf("expected 3 messages but got %q", cli.messages)
+	}
+
+	var sent []objx.Map
+	if err := jsoniter.ConfigFastest.Unmarshal(cli.messages[2], &sent); err != nil || len(sent) != 2 {
+		t.Fatalf("unexpected batch message %s (%v)", cli.messages[2], err)
+	}
+
+	if isNotification(sent[1]) || !isNotification(sent[0]) {
+		t.Errorf("unexpected ids of batch message %s", cli.messages[2])
+	}
+}
@@ -27,8 +27,42 @@ import (
 func Setup(version ...string) {
 	config.Init(version)
 
-	viper.SetDefault("modbus.mode", "tcp") // rtu also supported
+	viper.SetDefault("modbus.mode", "tcp") // udp, rtu and ascii also supported
 	viper.SetDefault("modbus.addr", "localhost:8000")
+	viper.SetDefault("modbus.byte_order", "big")
+	viper.SetDefault("modbus.word_order", "big")
+	viper.SetDefault("modbus.timestamp_format", "rfc3339")
+	viper.SetDefault("modbus.cache_ttl", "0s")
+	viper.SetDefault("modbus.coalesce_reads", false)
+	viper.SetDefault("modbus.max_quantity", 0)
+	viper.SetDefault("modbus.max_response_bytes", 65536)
+	viper.SetDefault("modbus.slow_threshold_ms", 0)
+	viper.SetDefault("modbus.transaction_id_first", 0)
+	viper.SetDefault("modbus.transaction_id_last", 0)
+	viper.SetDefault("modbus.address_base", 0)
+	viper.SetDefault("modbus.frame_delay", "0s")
+	viper.SetDefault("modbus.extended_function", 0)
+	viper.SetDefault("modbus.retry_attempts", 0)
+	viper.SetDefault("modbus.retry_backoff", "100ms")
+	viper.SetDefault("modbus.retry_exceptions", []int{5, 6})
+	viper.SetDefault("modbus.batch_retry_budget", 0)
+	viper.SetDefault("modbus.batch_retry_budget_time", "0s")
+	viper.SetDefault("modbus.queue_depth", 0)
+	viper.SetDefault("modbus.queue_wait", "0s")
+	viper.SetDefault("modbus.bus_rate_limit", 0.0)
+	viper.SetDefault("modbus.bus_rate_wait", "1s")
+	viper.SetDefault("modbus.shutdown_timeout", "10s")
+	viper.SetDefault("modbus.connect_timeout", "0s")
+	viper.SetDefault("modbus.register_locks", false)
+	viper.SetDefault("modbus.points_file", "")
+	viper.SetDefault("modbus.subscriptions_file", "")
+	viper.SetDefault("modbus.record_file", "")
+	viper.SetDefault("modbus.replay_file", "")
+	viper.SetDefault("modbus.max_subscriptions", 100)
+	viper.SetDefault("modbus.idempotency_ttl", "1m")
+	viper.SetDefault("modbus.idempotency_size", 1000)
+	viper.SetDefault("modbus.debug_calls", false)
+	viper.SetDefault("modbus.strict_slave_id", false)
 
 	viper.Set("modbus.ws_path", "/modbus")
 }
@@ -18,11 +18,17 @@ package entrypoint
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/stretchr/objx"
 
 	"github.com/Rightech/ric-edge/internal/app/modbus/handler"
 	"github.com/Rightech/ric-edge/internal/pkg/ws"
@@ -31,33 +37,413 @@ import (
 	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
 )
 
-func Start(done <-chan os.Signal) error {
-	var (
-		transport  modbus.Transporter
-		packagerFn handler.PackagerFn
-	)
+// rateLimit is a per slave requests limit from config
+type rateLimit struct {
+	SlaveID byte          `mapstructure:"slave_id"`
+	Rate    float64       `mapstructure:"rate"`
+	MaxWait time.Duration `mapstructure:"max_wait"`
+}
 
-	mode := viper.GetString("modbus.mode")
+// slaveTransport is a per slave transport overrides from config
+type slaveTransport struct {
+	SlaveID                      byte `mapstructure:"slave_id"`
+	handler.SlaveTransportConfig `mapstructure:",squash"`
+}
+
+// toParams converts config numbers to json.Number as in requests
+func toParams(m map[string]interface{}) objx.Map {
+	params := make(objx.Map, len(m))
+
+	for k, v := range m {
+		switch v.(type) {
+		case int, int64, float64:
+			params[k] = json.Number(fmt.Sprint(v))
+		default:
+			params[k] = v
+		}
+	}
+
+	return params
+}
+
+// newTransport creates transport of mode (tcp, udp, rtu or ascii) connected to addr
+// sim mode creates in-memory simulator with initial values of sim registers instead
+func newTransport(mode, addr string, sim []handler.SimRegister) (modbus.Transporter, handler.PackagerFn, error) {
 	switch mode {
+	case "sim":
+		return handler.NewSimulator(sim...), func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, nil
 	case "tcp":
-		hndlr := modbus.NewTCPTransporter(viper.GetString("modbus.addr"))
+		hndlr := modbus.NewTCPTransporter(addr)
 		hndlr.Logger = logger.New("debug", log.DebugLevel)
-		transport = hndlr
-		packagerFn = func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }
+
+		return hndlr, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, nil
+	case "udp":
+		// tcp framing in datagrams
+		hndlr := modbus.NewUDPTransporter(addr)
+		hndlr.Logger = logger.New("debug", log.DebugLevel)
+
+		return hndlr, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, nil
 	case "rtu":
-		hndlr := modbus.NewRTUTransporter(viper.GetString("modbus.addr"))
+		hndlr := modbus.NewRTUTransporter(addr)
 		hndlr.Logger = logger.New("debug", log.DebugLevel)
-		transport = hndlr
-		packagerFn = func(s byte) modbus.Packager { return modbus.NewRTUPackager(s) }
+
+		return hndlr, func(s byte) modbus.Packager { return modbus.NewRTUPackager(s) }, nil
 	case "ascii":
-		hndlr := modbus.NewASCIITransporter(viper.GetString("modbus.addr"))
+		hndlr := modbus.NewASCIITransporter(addr)
 		hndlr.Logger = logger.New("debug", log.DebugLevel)
-		transport = hndlr
-		packagerFn = func(s byte) modbus.Packager { return modbus.NewASCIIPackager(s) }
+
+		return hndlr, func(s byte) modbus.Packager { return modbus.NewASCIIPackager(s) }, nil
+	default:
+		return nil, nil, errors.New("modbus.mode should be tcp, udp, rtu, ascii or sim but " + mode + " given")
+	}
+}
+
+// modeFraming returns framing which transport of mode carries
+// (tcp, udp and sim transports read responses by mbap header, serial ones by their frames)
+func modeFraming(mode string) string {
+	switch mode {
+	case "rtu", "ascii":
+		return mode
 	default:
-		return errors.New("modbus.mode should be tcp, rtu or ascii but " + mode + " given")
+		return "tcp"
+	}
+}
+
+func Start(done <-chan os.Signal) error {
+	mode := viper.GetString("modbus.mode")
+
+	var sim []handler.SimRegister
+	if err := viper.UnmarshalKey("modbus.simulator", &sim); err != nil {
+		return err
+	}
+
+	if err := handler.ValidateSimRegisters(sim); err != nil {
+		return errors.New("modbus.simulator: " + err.Error())
+	}
+
+	if mode == "sim" {
+		log.Warn("modbus simulator is used instead of the bus")
+	}
+
+	transport, packagerFn, err := newTransport(mode, viper.GetString("modbus.addr"), sim)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range []string{"modbus.byte_order", "modbus.word_order"} {
+		order := viper.GetString(k)
+		if !handler.IsValidOrder(order) {
+			return errors.New(k + " should be big or little but " + order + " given")
+		}
+	}
+
+	if format := viper.GetString("modbus.timestamp_format"); !handler.IsValidTimestampFormat(format) {
+		return errors.New("modbus.timestamp_format should be rfc3339 or epoch_ms but " + format + " given")
+	}
+
+	if base := viper.GetInt64("modbus.address_base"); base != 0 && base != 1 {
+		return errors.New("modbus.address_base should be 0 or 1")
+	}
+
+	extendedFunction := viper.GetUint("modbus.extended_function")
+	if extendedFunction > 127 {
+		return errors.New("modbus.extended_function should be function code (0-127)")
+	}
+
+	maxQuantity := viper.GetUint("modbus.max_quantity")
+	if maxQuantity > math.MaxUint16 {
+		return errors.New("modbus.max_quantity should be less than 65536")
+	}
+
+	opts := []handler.Option{
+		handler.DefaultByteOrder(viper.GetString("modbus.byte_order")),
+		handler.DefaultWordOrder(viper.GetString("modbus.word_order")),
+		handler.TimestampFormat(viper.GetString("modbus.timestamp_format")),
+		handler.ReadCache(viper.GetDuration("modbus.cache_ttl")),
+		handler.CoalesceReads(viper.GetBool("modbus.coalesce_reads")),
+		handler.MaxQuantity(uint16(maxQuantity)),
+		handler.AddressBase(viper.GetInt64("modbus.address_base")),
+		handler.FrameDelay(viper.GetDuration("modbus.frame_delay")),
+		handler.ExtendedAddressing(byte(extendedFunction)),
+		handler.Retry(viper.GetInt("modbus.retry_attempts"), viper.GetDuration("modbus.retry_backoff")),
+		handler.BatchRetryBudget(viper.GetInt("modbus.batch_retry_budget"), viper.GetDuration("modbus.batch_retry_budget_time")),
+		handler.BusQueue(viper.GetInt("modbus.queue_depth"), viper.GetDuration("modbus.queue_wait")),
+		handler.BusRateLimit(viper.GetFloat64("modbus.bus_rate_limit"), viper.GetDuration("modbus.bus_rate_wait")),
+		handler.MaxResponseBytes(viper.GetInt("modbus.max_response_bytes")),
+		handler.SlowThreshold(time.Duration(viper.GetInt("modbus.slow_threshold_ms")) * time.Millisecond),
+		handler.IdempotencyKeys(viper.GetDuration("modbus.idempotency_ttl"), viper.GetInt("modbus.idempotency_size")),
+		handler.DebugCalls(viper.GetBool("modbus.debug_calls")),
+		handler.StrictSlaveIDs(viper.GetBool("modbus.strict_slave_id")),
+		handler.ConnectTimeout(viper.GetDuration("modbus.connect_timeout")),
+		handler.RegisterLocks(viper.GetBool("modbus.register_locks")),
+	}
+
+	firstID, lastID := viper.GetUint("modbus.transaction_id_first"), viper.GetUint("modbus.transaction_id_last")
+	if lastID > 0 {
+		if lastID > math.MaxUint16 || firstID > lastID {
+			return errors.New("modbus.transaction_id_first and modbus.transaction_id_last should be range of 0-65535")
+		}
+
+		opts = append(opts, handler.TransactionIDs(modbus.NewTransactionIdRange(uint16(firstID), uint16(lastID))))
+	}
+
+	// other framings than the one of mode need own transport
+	framings := map[string]bool{modeFraming(mode): true}
+	opts = append(opts, handler.Framing(modeFraming(mode), packagerFn))
+
+	for name, addr := range viper.GetStringMapString("modbus.framing_addr") {
+		if name != "tcp" && name != "rtu" && name != "ascii" {
+			return errors.New("modbus.framing_addr keys should be tcp, rtu or ascii but " + name + " given")
+		}
+
+		if framings[name] {
+			return errors.New("modbus.framing_addr: " + name + " framing is carried by transport of mode " + mode)
+		}
+
+		t, pGetter, err := newTransport(name, addr, nil)
+		if err != nil {
+			return err
+		}
+
+		framings[name] = true
+		opts = append(opts, handler.Framing(name, pGetter), handler.FramingConnection(name, t))
+	}
+
+	// slaves with the same address share connection
+	connections := make(map[string]modbus.Transporter)
+	slaveAddrs := make(map[int]string)
+
+	for k, v := range viper.GetStringMapString("modbus.slave_addr") {
+		slaveID, err := strconv.ParseUint(k, 10, 8)
+		if err != nil {
+			return errors.New("modbus.slave_addr keys should be slave ids but " + k + " given")
+		}
+
+		slaveAddrs[int(slaveID)] = v
+
+		t, ok := connections[v]
+		if !ok {
+			t, _, err = newTransport(mode, v, sim)
+			if err != nil {
+				return err
+			}
+
+			connections[v] = t
+		}
+
+		opts = append(opts, handler.SlaveConnection(byte(slaveID), t))
+	}
+
+	for k, v := range viper.GetStringMapString("modbus.slave_framing") {
+		slaveID, err := strconv.ParseUint(k, 10, 8)
+		if err != nil {
+			return errors.New("modbus.slave_framing keys should be slave ids but " + k + " given")
+		}
+
+		if !framings[v] {
+			return errors.New("modbus.slave_framing: framing " + v + " should be " + modeFraming(mode) +
+				" or have address in modbus.framing_addr")
+		}
+
+		opts = append(opts, handler.SlaveFraming(byte(slaveID), v))
+	}
+
+	defaults := make(map[string]objx.Map)
+	for method := range viper.GetStringMap("modbus.defaults") {
+		defaults[method] = toParams(viper.GetStringMap("modbus.defaults." + method))
+	}
+
+	opts = append(opts, handler.MethodDefaults(defaults))
+
+	var points []handler.Point
+	if err := viper.UnmarshalKey("modbus.points", &points); err != nil {
+		return err
+	}
+
+	if path := viper.GetString("modbus.points_file"); path != "" {
+		filePoints, err := handler.LoadPoints(path)
+		if err != nil {
+			return errors.New("modbus.points_file: " + err.Error())
+		}
+
+		points = append(points, filePoints...)
+	}
+
+	if err := handler.ValidatePoints(points); err != nil {
+		return errors.New("modbus.points: " + err.Error())
 	}
 
+	opts = append(opts, handler.Profile(points...))
+
+	var devices []handler.Device
+	if err := viper.UnmarshalKey("modbus.devices", &devices); err != nil {
+		return err
+	}
+
+	if err := handler.ValidateDevices(devices); err != nil {
+		return errors.New("modbus.devices: " + err.Error())
+	}
+
+	for _, d := range devices {
+		// device without host is on the main connection
+		host := d.Host
+		if host == "" {
+			host = viper.GetString("modbus.addr")
+		}
+
+		if addr, ok := slaveAddrs[d.UnitID]; ok && addr != host {
+			return errors.New("modbus.devices: host of device " + d.Name + " conflicts with modbus.slave_addr of its unit_id")
+		}
+
+		if d.Host == "" {
+			continue
+		}
+
+		t, ok := connections[d.Host]
+		if !ok {
+			var err error
+
+			t, _, err = newTransport(mode, d.Host, sim)
+			if err != nil {
+				return err
+			}
+
+			connections[d.Host] = t
+		}
+
+		opts = append(opts, handler.DeviceConnection(d.Name, t))
+	}
+
+	opts = append(opts, handler.Devices(devices...))
+
+	if path := viper.GetString("modbus.subscriptions_file"); path != "" {
+		subs, err := handler.LoadSubscriptions(path)
+		if err != nil {
+			return errors.New("modbus.subscriptions_file: " + err.Error())
+		}
+
+		opts = append(opts, handler.SubscriptionsFile(path), handler.Subscriptions(subs...))
+	}
+
+	opts = append(opts, handler.MaxSubscriptions(viper.GetInt("modbus.max_subscriptions")))
+
+	if path := viper.GetString("modbus.record_file"); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.New("modbus.record_file: " + err.Error())
+		}
+		defer f.Close()
+
+		opts = append(opts, handler.TraceFunc(handler.RecordTrace(f)))
+	}
+
+	if path := viper.GetString("modbus.replay_file"); path != "" {
+		records, err := handler.LoadTrace(path)
+		if err != nil {
+			return errors.New("modbus.replay_file: " + err.Error())
+		}
+
+		log.WithField("records", len(records)).Warn("device is replaced by recorded transactions")
+
+		opts = append(opts, handler.Replay(records...))
+	}
+
+	var exceptions []byte
+
+	for _, code := range viper.GetIntSlice("modbus.retry_exceptions") {
+		if !(0 < code && code <= math.MaxUint8) {
+			return errors.New("modbus.retry_exceptions should contain exception codes")
+		}
+
+		exceptions = append(exceptions, byte(code))
+	}
+
+	opts = append(opts, handler.RetryExceptions(exceptions...))
+
+	for _, slaveID := range viper.GetIntSlice("modbus.unsafe_skip_checksum") {
+		if !(0 <= slaveID && slaveID <= math.MaxUint8) {
+			return errors.New("modbus.unsafe_skip_checksum should contain slave ids")
+		}
+
+		log.WithField("slave_id", slaveID).Warn("response checksum verification disabled (unsafe)")
+
+		opts = append(opts, handler.UnsafeSkipChecksum(byte(slaveID)))
+	}
+
+	for _, slaveID := range viper.GetIntSlice("modbus.unsafe_parallel") {
+		if !(0 <= slaveID && slaveID <= math.MaxUint8) {
+			return errors.New("modbus.unsafe_parallel should contain slave ids")
+		}
+
+		log.WithField("slave_id", slaveID).Warn("bus lock disabled (unsafe)")
+
+		opts = append(opts, handler.UnsafeParallel(byte(slaveID)))
+	}
+
+	for _, slaveID := range viper.GetIntSlice("modbus.force_multiple_write") {
+		if !(0 <= slaveID && slaveID <= math.MaxUint8) {
+			return errors.New("modbus.force_multiple_write should contain slave ids")
+		}
+
+		opts = append(opts, handler.ForceMultipleWrite(byte(slaveID)))
+	}
+
+	for _, slaveID := range viper.GetIntSlice("modbus.lenient_echo") {
+		if !(0 <= slaveID && slaveID <= math.MaxUint8) {
+			return errors.New("modbus.lenient_echo should contain slave ids")
+		}
+
+		opts = append(opts, handler.LenientEcho(byte(slaveID)))
+	}
+
+	for _, slaveID := range viper.GetIntSlice("modbus.read_write_verify") {
+		if !(0 <= slaveID && slaveID <= math.MaxUint8) {
+			return errors.New("modbus.read_write_verify should contain slave ids")
+		}
+
+		opts = append(opts, handler.ReadWriteVerify(byte(slaveID)))
+	}
+
+	var transports []slaveTransport
+	if err := viper.UnmarshalKey("modbus.slave_transport", &transports); err != nil {
+		return err
+	}
+
+	for _, t := range transports {
+		opts = append(opts, handler.SlaveTransport(t.SlaveID, t.SlaveTransportConfig))
+	}
+
+	var limits []rateLimit
+	if err := viper.UnmarshalKey("modbus.rate_limit", &limits); err != nil {
+		return err
+	}
+
+	for _, l := range limits {
+		opts = append(opts, handler.SlaveRateLimit(l.SlaveID, l.Rate, l.MaxWait))
+	}
+
+	var access []handler.AccessRule
+	if err := viper.UnmarshalKey("modbus.access", &access); err != nil {
+		return err
+	}
+
+	if err := handler.ValidateAccessRules(access); err != nil {
+		return err
+	}
+
+	opts = append(opts, handler.AccessPolicy(access...))
+
+	var ackPolls []handler.AckPollConfig
+	if err := viper.UnmarshalKey("modbus.ack_poll", &ackPolls); err != nil {
+		return err
+	}
+
+	if err := handler.ValidateAckPolling(ackPolls); err != nil {
+		return err
+	}
+
+	opts = append(opts, handler.AckPolling(ackPolls...))
+
 	cli, err := ws.New(viper.GetInt("ws_port"), viper.GetString("version"),
 		viper.GetString("modbus.ws_path"))
 	if err != nil {
@@ -66,10 +452,22 @@ func Start(done <-chan os.Signal) error {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	go jsonrpc.ServeWithReconnect(ctx, cli, handler.New(transport, packagerFn),
-		jsonrpc.CatchPanic(viper.GetBool("catch_panic")))
+	opts = append(opts, handler.Context(ctx))
+
+	srv := handler.New(transport, packagerFn, opts...)
+
+	go jsonrpc.ServeWithReconnect(ctx, cli, srv, jsonrpc.CatchPanic(viper.GetBool("catch_panic")))
 
 	<-done
+
+	// let transactions in progress complete so slaves don't get cut frames
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(),
+		viper.GetDuration("modbus.shutdown_timeout"))
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.WithError(err).Warn("modbus shutdown")
+	}
+	shutdownCancel()
+
 	cancel()
 	cli.Close()
 
@@ -0,0 +1,203 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// kinds of access denied by rule
+const (
+	accessRead  = "read"
+	accessWrite = "write"
+	accessAll   = "all"
+)
+
+// AccessRule denies reads, writes or both in address range of table
+// addresses are wire addresses (0-based)
+type AccessRule struct {
+	// slaves of rule (empty means all slaves)
+	SlaveIDs []byte `mapstructure:"slave_ids"`
+	// coil, discrete, input or holding (empty means all tables)
+	Function string `mapstructure:"function"`
+	// inclusive address range
+	From uint16 `mapstructure:"from"`
+	To   uint16 `mapstructure:"to"`
+	// read, write or all
+	Deny string `mapstructure:"deny"`
+}
+
+func (r AccessRule) validate() error {
+	switch r.Function {
+	case "", pointCoil, pointDiscrete, pointInput, pointHolding:
+	default:
+		return errors.New("function should be coil, discrete, input or holding")
+	}
+
+	switch r.Deny {
+	case accessRead, accessWrite, accessAll:
+	default:
+		return errors.New("deny should be read, write or all")
+	}
+
+	if r.From > r.To {
+		return errors.New("from should not be greater than to")
+	}
+
+	return nil
+}
+
+// ValidateAccessRules checks access rules definitions
+func ValidateAccessRules(rules []AccessRule) error {
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			return errors.New("access rule " + strconv.Itoa(i) + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// AccessPolicy denies requests matching any of rules
+// it's checked for each frame before send (including nested and raw requests)
+func AccessPolicy(rules ...AccessRule) Option {
+	return func(s *Service) {
+		s.access = append(s.access, rules...)
+	}
+}
+
+// tableAccess is a read or write of address range
+type tableAccess struct {
+	function string
+	write    bool
+	address  uint16
+	quantity uint16
+}
+
+// pduAccesses returns table accesses of request pdu
+// functions without table addresses (diagnostics, file records, etc.) have no accesses
+func pduAccesses(pdu *modbus.ProtocolDataUnit) []tableAccess {
+	d := pdu.Data
+	if len(d) < 4 {
+		return nil
+	}
+
+	addr, quantity := binary.BigEndian.Uint16(d), binary.BigEndian.Uint16(d[2:])
+
+	switch pdu.FunctionCode {
+	case modbus.FuncCodeReadCoils:
+		return []tableAccess{{pointCoil, false, addr, quantity}}
+	case modbus.FuncCodeReadDiscreteInputs:
+		return []tableAccess{{pointDiscrete, false, addr, quantity}}
+	case modbus.FuncCodeReadHoldingRegisters:
+		return []tableAccess{{pointHolding, false, addr, quantity}}
+	case modbus.FuncCodeReadInputRegisters:
+		return []tableAccess{{pointInput, false, addr, quantity}}
+	case modbus.FuncCodeWriteSingleCoil:
+		return []tableAccess{{pointCoil, true, addr, 1}}
+	case modbus.FuncCodeWriteMultipleCoils:
+		return []tableAccess{{pointCoil, true, addr, quantity}}
+	case modbus.FuncCodeWriteSingleRegister, modbus.FuncCodeMaskWriteRegister:
+		return []tableAccess{{pointHolding, true, addr, 1}}
+	case modbus.FuncCodeWriteMultipleRegisters:
+		return []tableAccess{{pointHolding, true, addr, quantity}}
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		if len(d) < 8 {
+			return nil
+		}
+
+		return []tableAccess{
+			{pointHolding, false, addr, quantity},
+			{pointHolding, true, binary.BigEndian.Uint16(d[4:]), binary.BigEndian.Uint16(d[6:])},
+		}
+	default:
+		return nil
+	}
+}
+
+func (r AccessRule) denies(slaveID byte, a tableAccess) bool {
+	if len(r.SlaveIDs) > 0 && !containsByte(r.SlaveIDs, slaveID) {
+		return false
+	}
+
+	if r.Function != "" && r.Function != a.function {
+		return false
+	}
+
+	if r.Deny != accessAll && (r.Deny == accessWrite) != a.write {
+		return false
+	}
+
+	// zero quantity touches nothing but it's checked as one address
+	last := uint32(a.address)
+	if a.quantity > 0 {
+		last += uint32(a.quantity) - 1
+	}
+
+	return uint32(r.From) <= last && uint32(a.address) <= uint32(r.To)
+}
+
+func containsByte(values []byte, v byte) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func accessDeniedErr(slaveID byte, a tableAccess) error {
+	kind := accessRead
+	if a.write {
+		kind = accessWrite
+	}
+
+	return jsonrpc.ErrServer.AddData("msg", "access denied").AddData("slave_id", slaveID).
+		AddData("function", a.function).AddData("access", kind).
+		AddData("address", a.address).AddData("quantity", a.quantity).SetCode(-32098)
+}
+
+// accessTransporter rejects frames denied by access rules without sending
+type accessTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+	rules    []AccessRule
+	slaveID  byte
+}
+
+func (t accessTransporter) Send(adu []byte) ([]byte, error) {
+	pdu, err := t.packager.Decode(adu)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range pduAccesses(pdu) {
+		for _, r := range t.rules {
+			if r.denies(t.slaveID, a) {
+				return nil, accessDeniedErr(t.slaveID, a)
+			}
+		}
+	}
+
+	return t.Transporter.Send(adu)
+}
@@ -0,0 +1,208 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+const (
+	funcCodeReadExceptionStatus = 0x07
+
+	ackPollInterval = 100 * time.Millisecond
+	ackPollTimeout  = 10 * time.Second
+)
+
+// AckPollConfig sets polling of slave which answered write of method
+// by acknowledge exception (05, command accepted but not completed yet)
+type AckPollConfig struct {
+	Method string `mapstructure:"method"`
+	// holding register polled until it equals done_value
+	// (read exception status is polled until slave answers it if not set)
+	Address   *uint16 `mapstructure:"address"`
+	DoneValue uint16  `mapstructure:"done_value"`
+	// zero values mean defaults (100ms and 10s)
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// AckPolling enables polling of acknowledged writes of methods
+// write result is returned after slave completes command
+func AckPolling(configs ...AckPollConfig) Option {
+	return func(s *Service) {
+		if s.ackPolls == nil {
+			s.ackPolls = make(map[string]AckPollConfig, len(configs))
+		}
+
+		for _, c := range configs {
+			if c.Interval <= 0 {
+				c.Interval = ackPollInterval
+			}
+
+			if c.Timeout <= 0 {
+				c.Timeout = ackPollTimeout
+			}
+
+			s.ackPolls[c.Method] = c
+		}
+	}
+}
+
+// ValidateAckPolling checks that poll configs have method
+func ValidateAckPolling(configs []AckPollConfig) error {
+	for _, c := range configs {
+		if c.Method == "" {
+			return errors.New("ack_poll method required")
+		}
+	}
+
+	return nil
+}
+
+// ackPollTransporter polls slave after acknowledge exception of write
+// and returns write response when command is completed
+type ackPollTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+	config   AckPollConfig
+	ctx      context.Context
+}
+
+// ackResponse returns response pdu of completed write (nil if it can't be built)
+func ackResponse(req *modbus.ProtocolDataUnit) *modbus.ProtocolDataUnit {
+	switch req.FunctionCode {
+	case modbus.FuncCodeWriteSingleCoil, modbus.FuncCodeWriteSingleRegister, modbus.FuncCodeMaskWriteRegister:
+		return req
+	case modbus.FuncCodeWriteMultipleCoils, modbus.FuncCodeWriteMultipleRegisters:
+		if len(req.Data) < 4 {
+			return nil
+		}
+
+		return &modbus.ProtocolDataUnit{FunctionCode: req.FunctionCode, Data: req.Data[:4]}
+	default:
+		return nil
+	}
+}
+
+func ackTimeoutErr(c AckPollConfig) error {
+	return jsonrpc.ErrServer.AddData("msg", "acknowledged write not completed within timeout").
+		AddData("timeout_ms", c.Timeout.Nanoseconds()/1e6).SetCode(-32098)
+}
+
+func (t ackPollTransporter) Send(adu []byte) ([]byte, error) {
+	res, err := t.Transporter.Send(adu)
+	if err != nil || exceptionCode(t.packager, adu, res) != modbus.ExceptionCodeAcknowledge {
+		return res, err
+	}
+
+	req, err := t.packager.Decode(adu)
+	if err != nil {
+		return res, nil
+	}
+
+	done := ackResponse(req)
+	if done == nil {
+		return res, nil
+	}
+
+	exception, err := t.poll()
+	if err != nil {
+		return nil, err
+	}
+
+	if exception != 0 {
+		done = &modbus.ProtocolDataUnit{FunctionCode: req.FunctionCode | 0x80, Data: []byte{exception}}
+	}
+
+	// response of tcp frame should have transaction id of request
+	if tp, ok := t.packager.(*modbus.TCPPackager); ok && len(adu) >= 2 {
+		tp.SetTransactionId(binary.BigEndian.Uint16(adu))
+	}
+
+	return t.packager.Encode(done)
+}
+
+// poll polls slave until command is completed
+// it returns exception code if slave answered poll by exception other than busy
+func (t ackPollTransporter) poll() (byte, error) {
+	poll := &modbus.ProtocolDataUnit{FunctionCode: funcCodeReadExceptionStatus}
+	if t.config.Address != nil {
+		poll = &modbus.ProtocolDataUnit{
+			FunctionCode: modbus.FuncCodeReadHoldingRegisters,
+			Data:         dataBlock(*t.config.Address, 1),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, t.config.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if t.ctx.Err() != nil {
+				return 0, t.ctx.Err()
+			}
+
+			return 0, ackTimeoutErr(t.config)
+		case <-ticker.C:
+		}
+
+		adu, err := t.packager.Encode(poll)
+		if err != nil {
+			return 0, err
+		}
+
+		res, err := t.Transporter.Send(adu)
+		if err != nil {
+			return 0, err
+		}
+
+		if err = t.packager.Verify(adu, res); err != nil {
+			return 0, err
+		}
+
+		switch code := exceptionCode(t.packager, adu, res); code {
+		case modbus.ExceptionCodeAcknowledge, modbus.ExceptionCodeServerDeviceBusy:
+			continue
+		case 0:
+		default:
+			return code, nil
+		}
+
+		if t.config.Address == nil {
+			return 0, nil
+		}
+
+		pdu, err := t.packager.Decode(res)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(pdu.Data) == 3 && binary.BigEndian.Uint16(pdu.Data[1:]) == t.config.DoneValue {
+			return 0, nil
+		}
+	}
+}
@@ -0,0 +1,152 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+const (
+	benchmarkCount    = 100
+	benchmarkMaxCount = 10000
+	benchmarkTimeout  = time.Minute
+
+	benchmarkLoopback = "loopback"
+	benchmarkRead     = "read"
+)
+
+var errLoopbackMismatch = errors.New("modbus: loopback response doesn't echo request")
+
+type benchmarkResult struct {
+	Mode string `json:"mode"`
+	// count of sent transactions and failed ones (errors and exceptions)
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// total time and latencies of successful transactions in milliseconds
+	Total float64 `json:"total_ms"`
+	Min   float64 `json:"min_ms"`
+	P50   float64 `json:"p50_ms"`
+	P95   float64 `json:"p95_ms"`
+	Max   float64 `json:"max_ms"`
+	// false if benchmark was stopped by timeout or shutdown
+	Complete bool `json:"complete"`
+}
+
+// benchmark sends count loopback (diagnostics return query data) or read (one holding register)
+// transactions one by one and returns latency distribution and error rate
+// each transaction waits for the bus as usual, cache is not used
+func (s Service) benchmark(params objx.Map) (interface{}, error) {
+	mode := params.Get("mode").Str(benchmarkLoopback)
+	if mode != benchmarkLoopback && mode != benchmarkRead {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "mode should be loopback or read").AddData("v", mode)
+	}
+
+	count, err := getInt64(params, "count", benchmarkCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if count < 1 || count > benchmarkMaxCount {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "count should be 1-10000").AddData("v", count)
+	}
+
+	addr, err := getUint16(params, "address", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := getDuration(params, "timeout", benchmarkTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := func(i int) error {
+		_, err := s.getClient(slaveID).ReadHoldingRegisters(addr, 1)
+		return err
+	}
+
+	if mode == benchmarkLoopback {
+		transaction = func(i int) error {
+			data := make([]byte, 4)
+			binary.BigEndian.PutUint16(data, diagReturnQueryData)
+			binary.BigEndian.PutUint16(data[2:], uint16(i))
+
+			res, err := s.send(slaveID, &modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeDiagnostics, Data: data})
+			if err == nil && !bytes.Equal(res.Data, data) {
+				err = errLoopbackMismatch
+			}
+
+			return err
+		}
+	}
+
+	res := benchmarkResult{Mode: mode, Complete: true}
+	latencies := make([]time.Duration, 0, count)
+	deadline := time.Now().Add(timeout)
+	start := time.Now()
+
+	for i := 0; i < int(count); i++ {
+		if s.ctx.Err() != nil || time.Now().After(deadline) {
+			res.Complete = false
+			break
+		}
+
+		t := time.Now()
+		err := transaction(i)
+		latency := time.Since(t)
+
+		res.Count++
+
+		if err != nil {
+			res.Errors++
+			continue
+		}
+
+		latencies = append(latencies, latency)
+	}
+
+	res.Total = ms(time.Since(start))
+	if res.Count > 0 {
+		res.ErrorRate = float64(res.Errors) / float64(res.Count)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	if len(latencies) > 0 {
+		res.Min = ms(latencies[0])
+		res.P50 = percentile(latencies, 50)
+		res.P95 = percentile(latencies, 95)
+		res.Max = ms(latencies[len(latencies)-1])
+	}
+
+	return res, nil
+}
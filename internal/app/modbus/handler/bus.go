@@ -0,0 +1,126 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+var errBusBusy = errors.New("modbus: bus busy")
+
+// busLock is a mutex with bounded queue of waiting transactions
+type busLock struct {
+	lock chan struct{}
+	// nil if queue depth is not limited
+	queue chan struct{}
+	// 0 means wait as long as needed
+	maxWait time.Duration
+}
+
+func newBusLock() *busLock {
+	return &busLock{lock: make(chan struct{}, 1)}
+}
+
+// acquire takes the bus
+// it returns errBusBusy if queue is full or bus is not free within maxWait
+func (l *busLock) acquire() error {
+	select {
+	case l.lock <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if l.queue != nil {
+		select {
+		case l.queue <- struct{}{}:
+			defer func() { <-l.queue }()
+		default:
+			return errBusBusy
+		}
+	}
+
+	if l.maxWait <= 0 {
+		l.lock <- struct{}{}
+		return nil
+	}
+
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+
+	select {
+	case l.lock <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errBusBusy
+	}
+}
+
+func (l *busLock) release() {
+	<-l.lock
+}
+
+// BusQueue limits transactions waiting for the bus to depth (0 means no limit)
+// and their wait time to maxWait (0 means no limit)
+// transactions over the limits fail with bus busy error
+func BusQueue(depth int, maxWait time.Duration) Option {
+	return func(s *Service) {
+		s.bus.queue = nil
+		if depth > 0 {
+			s.bus.queue = make(chan struct{}, depth)
+		}
+
+		s.bus.maxWait = maxWait
+	}
+}
+
+// lockedTransporter allows only one transaction on the bus at a time
+// (not all transporters do it, e.g. rtu)
+// after transaction it keeps the bus silent for delay
+type lockedTransporter struct {
+	modbus.Transporter
+	// nil if transactions bypass the lock
+	bus   *busLock
+	delay time.Duration
+}
+
+func (t lockedTransporter) Send(adu []byte) ([]byte, error) {
+	if t.bus != nil {
+		if err := t.bus.acquire(); err != nil {
+			return nil, err
+		}
+		defer t.bus.release()
+	}
+
+	res, err := t.Transporter.Send(adu)
+
+	if t.delay > 0 {
+		time.Sleep(t.delay)
+	}
+
+	return res, err
+}
+
+// FrameDelay sets silent interval after each transaction
+// (some slow rtu slaves miss next frame without it)
+func FrameDelay(d time.Duration) Option {
+	return func(s *Service) {
+		s.frameDelay = d
+	}
+}
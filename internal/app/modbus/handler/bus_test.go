@@ -0,0 +1,58 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+func TestFrameDelay(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m, FrameDelay(20*time.Millisecond),
+		SlaveTransport(2, SlaveTransportConfig{FrameDelay: 50 * time.Millisecond}))
+
+	read := func(slaveID string) time.Duration {
+		t.Helper()
+
+		start := time.Now()
+
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"address": num("0"), "quantity": num("1"), "slave_id": num(slaveID)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return time.Since(start)
+	}
+
+	// bus is kept silent after each transaction
+	if d := read("1"); d < 20*time.Millisecond {
+		t.Errorf("expected frame delay of 20ms but call took %v", d)
+	}
+
+	// slave transport overrides it
+	if d := read("2"); d < 50*time.Millisecond {
+		t.Errorf("expected frame delay of 50ms but call took %v", d)
+	}
+}
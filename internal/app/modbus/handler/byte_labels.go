@@ -0,0 +1,72 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"strconv"
+)
+
+// validateByteLabels checks that point is input or holding register of bytes encoding
+// and there is unique label of each byte
+func (p Point) validateByteLabels() error {
+	if p.Function != pointInput && p.Function != pointHolding {
+		return errors.New("byte_labels can be used with input or holding registers only")
+	}
+
+	if p.Encoding != encBytes {
+		return errors.New("byte_labels can be used with bytes encoding only")
+	}
+
+	if len(p.Enum) > 0 || len(p.BitLabels) > 0 || len(p.Parts) > 0 {
+		return errors.New("byte_labels can't be used with enum, bit_labels or parts")
+	}
+
+	registers := int(p.Quantity)
+	if registers == 0 {
+		registers = 1
+	}
+
+	if len(p.ByteLabels) != registers*2 {
+		return errors.New("byte_labels should have " + strconv.Itoa(registers*2) + " names (two per register)")
+	}
+
+	names := make(map[string]bool, len(p.ByteLabels))
+
+	for _, name := range p.ByteLabels {
+		if name == "" || names[name] {
+			return errors.New("byte_labels names should be unique and not empty")
+		}
+
+		names[name] = true
+	}
+
+	return nil
+}
+
+// byteFields returns byte values by label
+func (p Point) byteFields(values []interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(p.ByteLabels))
+
+	for i, label := range p.ByteLabels {
+		if i < len(values) {
+			res[label] = values[i]
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,159 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+type cacheKey struct {
+	slaveID  byte
+	function byte
+	address  uint16
+	quantity uint16
+	// device of unit id (devices on different hosts may have the same unit id)
+	device string
+}
+
+type cacheEntry struct {
+	data []byte
+	at   time.Time
+}
+
+// readCache keeps read results for short time
+// so identical polls don't touch the bus
+// all methods are safe for nil cache (cache disabled)
+type readCache struct {
+	ttl   time.Duration
+	mx    sync.Mutex
+	items map[cacheKey]cacheEntry
+}
+
+// getMaxAge returns max_age_ms param as duration
+func getMaxAge(params objx.Map) (time.Duration, error) {
+	ms, err := getInt64(params, "max_age_ms")
+	if err != nil {
+		return 0, err
+	}
+
+	if ms <= 0 {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", "max_age_ms should be positive").AddData("v", ms)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func newReadCache(ttl time.Duration) *readCache {
+	return &readCache{ttl: ttl, items: make(map[cacheKey]cacheEntry)}
+}
+
+// get returns cached data which is not older than ttl
+// and max age (0 means ttl only)
+func (c *readCache) get(k cacheKey, maxAge time.Duration) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	v, ok := c.items[k]
+	if !ok {
+		return nil, false
+	}
+
+	age := time.Since(v.at)
+
+	if age > c.ttl {
+		delete(c.items, k)
+		return nil, false
+	}
+
+	// entry is still good for reads without max age
+	if maxAge > 0 && age > maxAge {
+		return nil, false
+	}
+
+	return v.data, true
+}
+
+// entry returns cached data with its time regardless of ttl
+func (c *readCache) entry(k cacheKey) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	v, ok := c.items[k]
+
+	return v, ok
+}
+
+func (c *readCache) set(k cacheKey, data []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mx.Lock()
+	c.items[k] = cacheEntry{data, time.Now()}
+	c.mx.Unlock()
+}
+
+// swap sets data of key and returns previous entry regardless of ttl
+func (c *readCache) swap(k cacheKey, data []byte) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	v, ok := c.items[k]
+	c.items[k] = cacheEntry{data, time.Now()}
+
+	return v, ok
+}
+
+// invalidate removes entries of given slave and function
+// which overlap with written address range (of all devices with the slave id)
+func (c *readCache) invalidate(slaveID, function byte, address, quantity uint16) {
+	if c == nil {
+		return
+	}
+
+	end := int(address) + int(quantity)
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for k := range c.items {
+		if k.slaveID != slaveID || k.function != function {
+			continue
+		}
+
+		if int(k.address) < end && int(address) < int(k.address)+int(k.quantity) {
+			delete(c.items, k)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+func TestReadCache(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 1
+
+	srv := newMockService(m, ReadCache(50*time.Millisecond))
+	read := func() interface{} {
+		t.Helper()
+
+		res, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("1")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	read()
+	m.holding[0] = 2
+
+	// cached within ttl
+	if res := read(); !reflect.DeepEqual(res, []interface{}{uint16(1)}) || len(m.pdus) != 1 {
+		t.Errorf("expected cached value without transaction but got %v (%d)", res, len(m.pdus))
+	}
+
+	// write to cached register invalidates it
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register", Params: objx.Map{"address": num("0"), "value": num("3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := read(); !reflect.DeepEqual(res, []interface{}{uint16(3)}) {
+		t.Errorf("expected written value but got %v", res)
+	}
+
+	m.holding[0] = 4
+
+	time.Sleep(60 * time.Millisecond)
+
+	// expired value is read again
+	if res := read(); !reflect.DeepEqual(res, []interface{}{uint16(4)}) {
+		t.Errorf("expected value after ttl but got %v", res)
+	}
+}
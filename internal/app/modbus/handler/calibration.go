@@ -0,0 +1,84 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// calibrationParams are raw and engineering values of two calibration points
+var calibrationParams = []string{"cal_raw_low", "cal_raw_high", "cal_eng_low", "cal_eng_high"} // nolint: gochecknoglobals
+
+// calibration is linear transform of raw values to engineering ones
+type calibration struct {
+	rawLow float64
+	engLow float64
+	slope  float64
+}
+
+// getCalibration returns two-point calibration from params (nil if not passed)
+func getCalibration(params objx.Map, encoding string) (*calibration, error) {
+	values := make([]float64, 0, len(calibrationParams))
+
+	for _, k := range calibrationParams {
+		if params.Get(k).IsNil() {
+			continue
+		}
+
+		v, err := getFloat64(params, k, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, v)
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case len(calibrationParams):
+	default:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "cal_raw_low, cal_raw_high, cal_eng_low and cal_eng_high should be used together")
+	}
+
+	if encoding == encRaw || encoding == encTimestamp || encoding == encBytes {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "calibration can't be used with "+encoding+" encoding")
+	}
+
+	rawLow, rawHigh, engLow, engHigh := values[0], values[1], values[2], values[3]
+	if rawLow == rawHigh {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "cal_raw_low and cal_raw_high should differ")
+	}
+
+	return &calibration{
+		rawLow: rawLow,
+		engLow: engLow,
+		slope:  (engHigh - engLow) / (rawHigh - rawLow),
+	}, nil
+}
+
+// apply converts decoded value to engineering one (as float64)
+func (c *calibration) apply(v interface{}) interface{} {
+	f, ok := toFloat64(v)
+	if c == nil || !ok {
+		return v
+	}
+
+	return c.engLow + (f-c.rawLow)*c.slope
+}
@@ -0,0 +1,102 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+const (
+	meiCANopen = 0x0D
+	// max pdu size without function code and mei type
+	meiMaxPayload = 251
+)
+
+// canopenRequest sends CANopen general reference request (MEI type 13)
+// with data payload (base64) and returns payload of response as is
+//
+// Request:
+//
+//	Function code         : 1 byte (0x2B)
+//	MEI type              : 1 byte (0x0D)
+//	Data                  : up to 251 bytes
+//
+// Response: the same layout
+//
+// length of response data (response_length param) is required in rtu mode
+func (s Service) canopenRequest(params objx.Map) (interface{}, error) {
+	mei, err := getInt64(params, "mei_type", meiCANopen)
+	if err != nil {
+		return nil, err
+	}
+
+	if mei != meiCANopen {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "mei_type should be 13").AddData("v", mei)
+	}
+
+	payload, err := getBytes(params, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) > meiMaxPayload {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "data is too long").
+			AddData("v", len(payload)).AddData("max", meiMaxPayload)
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// rtu frame has no length of CANopen response so it should be known in advance
+	length, err := getInt64(params, "response_length", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if length < 0 || length > meiMaxPayload {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "response_length should be between 0 and 251").
+			AddData("v", length)
+	}
+
+	srv := s
+	if length > 0 {
+		srv = s.withResponseLength(2 + int(length))
+	} else if _, rtu := s.getPackager(slaveID).(*modbus.RTUPackager); rtu {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "response_length is required in rtu mode")
+	}
+
+	res, err := srv.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeEncapsulatedInterface,
+		Data:         append([]byte{meiCANopen}, payload...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Data) == 0 || res.Data[0] != meiCANopen {
+		return nil, fmt.Errorf("modbus: wrong CANopen response '% x'", res.Data)
+	}
+
+	return res.Data[1:], nil
+}
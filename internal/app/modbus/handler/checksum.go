@@ -0,0 +1,58 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// UnsafeSkipChecksum disables CRC (rtu) or LRC (ascii) verification
+// of responses from given slave
+//
+// UNSAFE: corrupted responses will be accepted as valid ones,
+// use it only for slaves which send wrong checksum and can't be fixed
+func UnsafeSkipChecksum(slaveID byte) Option {
+	return func(s *Service) {
+		if s.skipChecksum == nil {
+			s.skipChecksum = make(map[byte]bool)
+		}
+
+		s.skipChecksum[slaveID] = true
+	}
+}
+
+// getPackager returns packager for slave
+func (s Service) getPackager(slaveID byte) modbus.Packager {
+	p := s.packagerGetter(slaveID)
+
+	if tp, ok := p.(*modbus.TCPPackager); ok && s.transactionIDs != nil {
+		tp.IdSource = s.transactionIDs
+	}
+
+	if !s.skipChecksum[slaveID] {
+		return p
+	}
+
+	switch v := p.(type) {
+	case *modbus.RTUPackager:
+		v.SkipChecksum = true
+	case *modbus.ASCIIPackager:
+		v.SkipChecksum = true
+	}
+
+	return p
+}
@@ -0,0 +1,70 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// badCRCSlave is rtu slave which sends responses with wrong crc
+type badCRCSlave struct {
+	*rtuSlave
+}
+
+func (m badCRCSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.rtuSlave.Send(adu)
+	if err == nil {
+		res[len(res)-1] ^= 0xFF
+	}
+
+	return res, err
+}
+
+func TestUnsafeSkipChecksum(t *testing.T) {
+	m := badCRCSlave{&rtuSlave{&mockSlave{}}}
+	m.holding[0] = 42
+
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewRTUPackager(s) }, UnsafeSkipChecksum(1))
+
+	read := func(slaveID string) (interface{}, error) {
+		return srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"address": num("0"), "quantity": num("1"), "slave_id": num(slaveID)},
+		})
+	}
+
+	res, err := read("1")
+	if err != nil || !reflect.DeepEqual(res, []interface{}{uint16(42)}) {
+		t.Errorf("expected response with wrong crc accepted but got %v (%v)", res, err)
+	}
+
+	// other slaves are still verified
+	_, err = read("2")
+
+	// data of jsonrpc error is private
+	if desc := fmt.Sprintf("%#v", err); !strings.Contains(desc, "crc") {
+		t.Errorf("expected crc error but got %s", desc)
+	}
+}
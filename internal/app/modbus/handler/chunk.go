@@ -0,0 +1,176 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// protocol limits of registers in one FC03/FC04, FC16 and FC23 (write part) requests
+const (
+	maxReadRegisters      = 125
+	maxWriteRegisters     = 123
+	maxReadWriteRegisters = 121
+)
+
+// chunkSize returns max registers in one transaction
+// which is multiple of value size (so value not splitted between requests)
+func chunkSize(limit, valueRegisters int) int {
+	return limit - limit%valueRegisters
+}
+
+func checkRange(addr uint16, quantity int) error {
+	if int(addr)+quantity > int(maxUint16)+1 {
+		return jsonrpc.ErrInvalidParams.AddData("msg", "address and quantity out of range")
+	}
+
+	return nil
+}
+
+// partialWriteError is write which was committed partially
+// (slave echoed less registers than requested)
+type partialWriteError struct {
+	requested, written uint16
+}
+
+func (e partialWriteError) Error() string {
+	return fmt.Sprintf("modbus: response quantity '%v' does not match request '%v'", e.written, e.requested)
+}
+
+// writtenErr describes failed write with count of written registers
+// and address (in request base) of first register which wasn't written
+func writtenErr(err error, written int, failedAt int64) error {
+	return jsonrpc.ErrServer.AddData("msg", err.Error()).AddData("written_count", written).
+		AddData("failed_at_address", failedAt).SetCode(-32098)
+}
+
+// sendWriteRegisters writes registers by FC16 request and checks echo of slave
+// if slave echoed less registers it returns partialWriteError
+// (echo of slave with lenient echo isn't checked)
+func (s Service) sendWriteRegisters(slaveID byte, addr, quantity uint16, value []byte) ([]byte, error) {
+	if quantity < 1 || quantity > maxWriteRegisters {
+		return nil, fmt.Errorf("modbus: quantity '%v' must be between '%v' and '%v',", quantity, 1, maxWriteRegisters)
+	}
+
+	if s.lenientEcho[slaveID] {
+		return s.sendLenientWrite(slaveID, modbus.FuncCodeWriteMultipleRegisters, addr, quantity, value)
+	}
+
+	data := make([]byte, 5+len(value))
+	binary.BigEndian.PutUint16(data, addr)
+	binary.BigEndian.PutUint16(data[2:], quantity)
+	data[4] = byte(len(value))
+	copy(data[5:], value)
+
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{FunctionCode: modbus.FuncCodeWriteMultipleRegisters, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Data) != 4 {
+		return nil, fmt.Errorf("modbus: response data size '%v' does not match expected '%v'", len(res.Data), 4)
+	}
+
+	if v := binary.BigEndian.Uint16(res.Data); v != addr {
+		return nil, fmt.Errorf("modbus: response address '%v' does not match request '%v'", v, addr)
+	}
+
+	written := binary.BigEndian.Uint16(res.Data[2:])
+
+	switch {
+	case written < quantity:
+		return nil, partialWriteError{quantity, written}
+	case written > quantity:
+		return nil, fmt.Errorf("modbus: response quantity '%v' does not match request '%v'", written, quantity)
+	default:
+		return res.Data[2:], nil
+	}
+}
+
+// writeRegistersChunked writes registers by several sequential FC16 requests
+// on error it returns how many registers was written and first failed address
+// (base is address base of request)
+func (s Service) writeRegistersChunked(slaveID byte, addr, quantity uint16, value []byte,
+	valueRegisters int, base int64) (interface{}, error) {
+	if err := checkRange(addr, int(quantity)); err != nil {
+		return nil, err
+	}
+
+	step := chunkSize(maxWriteRegisters, valueRegisters)
+	written := 0
+
+	for written < int(quantity) {
+		n := int(quantity) - written
+		if n > step {
+			n = step
+		}
+
+		chunkAddr := addr + uint16(written)
+
+		_, err := s.sendWriteRegisters(slaveID, chunkAddr, uint16(n), value[written*2:(written+n)*2])
+
+		// committed part of chunk is written too
+		var perr partialWriteError
+		if errors.As(err, &perr) {
+			s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, chunkAddr, uint16(n))
+			written += int(perr.written)
+		}
+
+		if err != nil {
+			return nil, writtenErr(err, written, int64(addr)+int64(written)+base)
+		}
+
+		s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, chunkAddr, uint16(n))
+
+		written += n
+	}
+
+	return []uint16{quantity}, nil
+}
+
+// readRegistersChunked reads registers by several sequential requests
+// and returns concatenated raw result
+func (s Service) readRegistersChunked(slaveID, function byte, addr, quantity uint16) ([]byte, error) {
+	if err := checkRange(addr, int(quantity)); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, int(quantity)*2)
+	read := 0
+
+	for read < int(quantity) {
+		n := int(quantity) - read
+		if n > maxReadRegisters {
+			n = maxReadRegisters
+		}
+
+		b, err := s.readBlock(slaveID, function, addr+uint16(read), uint16(n))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, b...)
+		read += n
+	}
+
+	return res, nil
+}
@@ -0,0 +1,121 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestWriteChunked(t *testing.T) {
+	for _, tc := range []struct {
+		encoding   string
+		values     int
+		quantities []uint16
+	}{
+		{"uint16", 250, []uint16{123, 123, 4}},
+		// value of 2 registers isn't split between requests
+		{"uint32", 125, []uint16{122, 122, 6}},
+	} {
+		m := &mockSlave{}
+
+		values := make([]interface{}, tc.values)
+		for i := range values {
+			values[i] = num(strconv.Itoa(i + 1))
+		}
+
+		res, err := newMockService(m).Call(jsonrpc.Request{
+			Method: "modbus-write-multiple-registers",
+			Params: objx.Map{"address": num("3"), "value": values, "encoding": tc.encoding, "chunked": true},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.encoding, err)
+		}
+
+		if !reflect.DeepEqual(res, []uint16{250}) {
+			t.Errorf("%s: unexpected result %v", tc.encoding, res)
+		}
+
+		addr := uint16(3)
+		for i, pdu := range m.pdus {
+			if a, q := binary.BigEndian.Uint16(pdu[1:]), binary.BigEndian.Uint16(pdu[3:]); i >= len(tc.quantities) ||
+				a != addr || q != tc.quantities[i] {
+				t.Errorf("%s: unexpected request %d of %d registers at %d", tc.encoding, i, q, a)
+			} else {
+				addr += q
+			}
+		}
+
+		if len(m.pdus) != len(tc.quantities) {
+			t.Errorf("%s: expected %d requests but got %d", tc.encoding, len(tc.quantities), len(m.pdus))
+		}
+
+		// last value is written to the last registers
+		if last := m.holding[252]; last != uint16(tc.values) {
+			t.Errorf("%s: unexpected last register %v", tc.encoding, last)
+		}
+	}
+}
+
+func TestReadChunked(t *testing.T) {
+	m := &mockSlave{}
+	for i := range m.inputs[:mockBankSize] {
+		m.inputs[i] = uint16(i)
+	}
+
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-input", Params: objx.Map{"address": num("2"), "quantity": num("251"), "chunked": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := res.([]interface{})
+	if len(values) != 251 || values[0] != uint16(2) || values[250] != uint16(252) {
+		t.Errorf("unexpected result of %d values", len(values))
+	}
+
+	if len(m.pdus) != 3 {
+		t.Fatalf("expected 3 requests but got %d", len(m.pdus))
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadInputRegisters, 0x00, 0xFC, 0x00, 0x01})
+
+	// failed chunk fails whole read
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-input", Params: objx.Map{"address": num("10"), "quantity": num("250"), "chunked": true},
+	})
+	if err == nil {
+		t.Error("expected error of chunk out of slave range")
+	}
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-input", Params: objx.Map{"address": num("65500"), "quantity": num("200"), "chunked": true},
+	})
+	if err == nil {
+		t.Error("expected error of range over address space")
+	}
+}
@@ -0,0 +1,188 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// layouts of device clock registers
+const (
+	// unix time in seconds (uint32, word order by params)
+	clockEpoch = "epoch"
+	// separate year, month, day, hour, minute and second registers
+	// (year can be 2-digit)
+	clockYMDHMS = "ymdhms"
+)
+
+// clockRegisters contains count of registers of clock layouts
+var clockRegisters = map[string]uint16{ // nolint: gochecknoglobals
+	clockEpoch:  2,
+	clockYMDHMS: 6,
+}
+
+// clockResult is result of modbus-read-clock
+// skew is positive if device clock is ahead of gateway clock
+type clockResult struct {
+	DeviceTime  interface{} `json:"device_time"`
+	GatewayTime interface{} `json:"gateway_time"`
+	SkewMs      int64       `json:"skew_ms"`
+}
+
+// getClockLayout returns layout param (epoch by default)
+func getClockLayout(params objx.Map) (string, error) {
+	layout := params.Get("layout").Str(clockEpoch)
+	if _, ok := clockRegisters[layout]; !ok {
+		return "", jsonrpc.ErrInvalidParams.AddData("msg", "layout should be epoch or ymdhms").AddData("v", layout)
+	}
+
+	return layout, nil
+}
+
+// getClockBlock returns function and params of clock registers,
+// they are set by register map point (point param) or by address and input params
+func (s Service) getClockBlock(params objx.Map) (byte, objx.Map, error) {
+	if params.Get("point").IsNil() {
+		function := byte(modbus.FuncCodeReadHoldingRegisters)
+		if params.Get("input").Bool() {
+			function = modbus.FuncCodeReadInputRegisters
+		}
+
+		return function, params, nil
+	}
+
+	p, pp, err := s.getPoint(params, "point")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if p.Function != pointInput && p.Function != pointHolding {
+		return 0, nil, jsonrpc.ErrInvalidParams.AddData("msg", "clock point should be input or holding register").
+			AddData("v", p.Name)
+	}
+
+	return pointFunctions[p.Function], pp, nil
+}
+
+// decodeClock returns time of device clock registers,
+// device clock is set to time zone with offset from UTC
+func decodeClock(c codec, layout string, res []byte, offset time.Duration) (time.Time, error) {
+	zone := time.FixedZone("", int(offset/time.Second))
+
+	if layout == clockEpoch {
+		values, err := c.decode(res)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Unix(int64(values[0].(uint32)), 0).Add(-offset).In(zone), nil
+	}
+
+	var v [6]int
+	for i := range v {
+		v[i] = int(binary.BigEndian.Uint16(res[i*2:]))
+	}
+
+	if v[0] < 100 {
+		v[0] += 2000
+	}
+
+	t := time.Date(v[0], time.Month(v[1]), v[2], v[3], v[4], v[5], 0, zone)
+
+	// time.Date normalizes out of range values (e.g. month 13)
+	if t.Month() != time.Month(v[1]) || t.Day() != v[2] || t.Hour() != v[3] || t.Minute() != v[4] || t.Second() != v[5] {
+		return time.Time{}, jsonrpc.ErrServer.AddData("msg", "clock registers hold invalid date").
+			AddData("v", v).SetCode(-32098)
+	}
+
+	return t, nil
+}
+
+// readClock reads device clock registers and returns device time
+// with its difference from gateway clock
+func (s Service) readClock(params objx.Map) (interface{}, error) {
+	layout, err := getClockLayout(params)
+	if err != nil {
+		return nil, err
+	}
+
+	function, pp, err := s.getClockBlock(params)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := getUint16(pp, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(pp)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.getCodec(objx.Map{
+		"encoding":   encUint32,
+		"byte_order": pp.Get("byte_order").Str(s.byteOrder),
+		"word_order": pp.Get("word_order").Str(s.wordOrder),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// tz_offset param is checked for timestamp encoding only
+	format, offset, err := s.getTimestampEncoding(pp, codec{encoding: encTimestamp})
+	if err != nil {
+		return nil, err
+	}
+
+	// clock should be fresh
+	s.noCache = true
+
+	start := time.Now()
+
+	res, err := s.readBlock(slaveID, function, addr, clockRegisters[layout])
+	if err != nil {
+		return nil, err
+	}
+
+	// registers are read somewhere between request and response
+	gateway := start.Add(time.Since(start) / 2)
+
+	device, err := decodeClock(c, layout, res, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	result := clockResult{SkewMs: int64(device.Sub(gateway) / time.Millisecond)}
+
+	if format == timestampEpochMs {
+		result.DeviceTime = device.UnixNano() / int64(time.Millisecond)
+		result.GatewayTime = gateway.UnixNano() / int64(time.Millisecond)
+	} else {
+		result.DeviceTime = device.Format(time.RFC3339)
+		result.GatewayTime = gateway.UTC().Format(time.RFC3339Nano)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,72 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import "sync"
+
+type readFlight struct {
+	done chan struct{}
+	res  []byte
+	err  error
+	// count of reads waiting result
+	waiters int
+}
+
+// readFlights coalesces identical concurrent reads
+// so they share one bus transaction and its result
+// all methods are safe for nil flights (coalescing disabled)
+type readFlights struct {
+	mx    sync.Mutex
+	items map[cacheKey]*readFlight
+}
+
+func newReadFlights() *readFlights {
+	return &readFlights{items: make(map[cacheKey]*readFlight)}
+}
+
+// do calls read or waits result of the same read in progress
+func (f *readFlights) do(k cacheKey, read func() ([]byte, error)) ([]byte, error) {
+	if f == nil {
+		return read()
+	}
+
+	f.mx.Lock()
+
+	if fl, ok := f.items[k]; ok {
+		fl.waiters++
+		f.mx.Unlock()
+		<-fl.done
+
+		return fl.res, fl.err
+	}
+
+	fl := &readFlight{done: make(chan struct{})}
+	f.items[k] = fl
+	f.mx.Unlock()
+
+	defer func() {
+		f.mx.Lock()
+		delete(f.items, k)
+		f.mx.Unlock()
+
+		close(fl.done)
+	}()
+
+	fl.res, fl.err = read()
+
+	return fl.res, fl.err
+}
@@ -0,0 +1,121 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// framingError is internally inconsistent response
+// (usually garbled by bus collision or noise)
+type framingError struct {
+	reason   string
+	request  []byte
+	response []byte
+}
+
+func (e *framingError) Error() string {
+	return "modbus: response framing error (possible bus collision): " + e.reason
+}
+
+func (e *framingError) rpcError() jsonrpc.Error {
+	return jsonrpc.ErrServer.AddData("msg", "response framing error (possible bus collision)").
+		AddData("reason", e.reason).AddData("request", e.request).AddData("response", e.response).
+		SetCode(-32098)
+}
+
+// byteCountFunctions contains functions which response data starts with byte count
+var byteCountFunctions = map[byte]bool{ // nolint: gochecknoglobals
+	modbus.FuncCodeReadCoils:                  true,
+	modbus.FuncCodeReadDiscreteInputs:         true,
+	modbus.FuncCodeReadInputRegisters:         true,
+	modbus.FuncCodeReadHoldingRegisters:       true,
+	modbus.FuncCodeReadWriteMultipleRegisters: true,
+}
+
+// isEmptyResponse reports whether response of read has zero byte count
+// (request quantity is never 0 so it's a glitch of gateway)
+// response should be checked by checkFraming before
+func isEmptyResponse(packager modbus.Packager, res []byte) bool {
+	pdu, err := packager.Decode(res)
+	if err != nil {
+		return false
+	}
+
+	return byteCountFunctions[pdu.FunctionCode] && len(pdu.Data) == 1 && pdu.Data[0] == 0
+}
+
+// checkFraming returns error if response doesn't match request framing
+func checkFraming(packager modbus.Packager, adu, res []byte) *framingError {
+	fail := func(reason string) *framingError {
+		return &framingError{reason: reason, request: adu, response: res}
+	}
+
+	if err := packager.Verify(adu, res); err != nil {
+		return fail(err.Error())
+	}
+
+	resPDU, err := packager.Decode(res)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	reqPDU, err := packager.Decode(adu)
+	if err != nil {
+		return nil
+	}
+
+	switch resPDU.FunctionCode {
+	case reqPDU.FunctionCode:
+	case reqPDU.FunctionCode | 0x80:
+		return nil
+	default:
+		return fail("function code mismatch")
+	}
+
+	if byteCountFunctions[resPDU.FunctionCode] &&
+		(len(resPDU.Data) == 0 || int(resPDU.Data[0]) != len(resPDU.Data)-1) {
+		return fail("byte count mismatch")
+	}
+
+	return nil
+}
+
+// framingTransporter returns framingError on inconsistent responses
+// and errEmptyResponse on reads without data
+type framingTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+}
+
+func (t framingTransporter) Send(adu []byte) ([]byte, error) {
+	res, err := t.Transporter.Send(adu)
+	if err != nil {
+		return res, err
+	}
+
+	if ferr := checkFraming(t.packager, adu, res); ferr != nil {
+		return nil, ferr
+	}
+
+	if isEmptyResponse(t.packager, res) {
+		return nil, errEmptyResponse
+	}
+
+	return res, nil
+}
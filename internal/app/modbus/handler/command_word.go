@@ -0,0 +1,58 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+
+	"github.com/stretchr/objx"
+)
+
+// getCommandWord returns word of command_word param (nil if it's not passed)
+// some devices (e.g. motion controllers) expect each multiple registers write
+// to start with command word, it's written before encoded value
+func getCommandWord(params objx.Map) (*uint16, error) {
+	if params.Get("command_word").IsNil() {
+		return nil, nil
+	}
+
+	// command isn't a register value, so it can't be split or read back
+	for _, k := range []string{"chunked", "verify", "enron"} {
+		if params.Get(k).Bool() {
+			return nil, conflictErr("command_word", k)
+		}
+	}
+
+	word, err := getUint16(params, "command_word")
+	if err != nil {
+		return nil, err
+	}
+
+	return &word, nil
+}
+
+// withCommandWord prepends command word to encoded value
+func withCommandWord(word *uint16, b []byte) []byte {
+	if word == nil {
+		return b
+	}
+
+	res := make([]byte, 2, 2+len(b))
+	binary.BigEndian.PutUint16(res, *word)
+
+	return append(res, b...)
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// compareWriteResult is result of modbus-compare-and-write
+// current is value of register after call (written value or actual one on mismatch)
+type compareWriteResult struct {
+	Written bool        `json:"written"`
+	Current interface{} `json:"current"`
+}
+
+// encodeSingle returns registers of one value of k param
+func encodeSingle(c codec, params objx.Map, k string) ([]byte, error) {
+	if params.Get(k).IsInterSlice() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be number")
+	}
+
+	values, err := getValues(params, k)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.encode(k, values)
+}
+
+// compareAndWrite writes value to holding register (or several registers of 32-bit encoding)
+// only if it still holds expected value, current value is returned on mismatch
+//
+// read and write go under the same bus lock (and register lock if enabled),
+// so it's atomic for calls of this service but NOT for other masters on the bus
+func (s Service) compareAndWrite(params objx.Map) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.getCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := encodeSingle(c, params, "expected")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := encodeSingle(c, params, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := uint16(c.registers())
+	if err := checkRange(addr, int(quantity)); err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// register lock is taken before bus lock, so waiting for it doesn't block the bus
+	unlock := s.registerLocks.lock(slaveID, addr)
+	defer unlock()
+
+	if _, bus := s.connection(slaveID); bus != nil {
+		if err := bus.acquire(); err != nil {
+			return nil, err
+		}
+		defer bus.release()
+
+		s = s.withBusHeld(slaveID)
+	}
+
+	cli := s.getClient(slaveID)
+
+	// cache is bypassed, register should be fresh
+	res, err := cli.ReadHoldingRegisters(addr, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) != len(expected) {
+		return nil, truncatedErr(len(expected), len(res))
+	}
+
+	if !bytes.Equal(res, expected) {
+		current, err := c.decode(res)
+		if err != nil {
+			return nil, err
+		}
+
+		return compareWriteResult{Current: current[0]}, nil
+	}
+
+	if quantity == 1 {
+		_, err = cli.WriteSingleRegister(addr, binary.BigEndian.Uint16(value))
+	} else {
+		_, err = cli.WriteMultipleRegisters(addr, quantity, value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, quantity)
+
+	current, err := c.decode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareWriteResult{Written: true, Current: current[0]}, nil
+}
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// maxReadGap is max count of unused registers (or bits) between values
+// read by one transaction (composite point parts and polled points)
+const maxReadGap = 4
+
+// PointPart is a source register of composite point
+// its value is shifted left by shift bits and ORed with other parts
+type PointPart struct {
+	Address uint16 `mapstructure:"address" json:"address"`
+	Shift   uint   `mapstructure:"shift" json:"shift"`
+}
+
+func (p Point) validateParts() error {
+	if len(p.Parts) < 2 {
+		return errors.New("composite point should have at least 2 parts")
+	}
+
+	if p.Function != pointInput && p.Function != pointHolding {
+		return errors.New("composite point should be input or holding register")
+	}
+
+	if p.Encoding != "" && p.Encoding != encUint32 && p.Encoding != encInt32 {
+		return errors.New("encoding of composite point should be uint32 or int32")
+	}
+
+	if p.Quantity != 0 {
+		return errors.New("composite point can't have quantity")
+	}
+
+	for _, part := range p.Parts {
+		if part.Shift > 16 {
+			return errors.New("shift of composite point part should be 0-16")
+		}
+	}
+
+	return nil
+}
+
+// compositeErr returns error of composite point used by write methods
+func compositeErr(p Point) error {
+	return jsonrpc.ErrInvalidParams.AddData("msg", "composite point can be read only").AddData("v", p.Name)
+}
+
+// compositeBlock is one read transaction of composite point parts
+type compositeBlock struct {
+	address, quantity uint16
+}
+
+// compositeBlocks groups part addresses into as few reads as possible
+// (parts with small gaps between them are read together)
+func compositeBlocks(parts []PointPart) []compositeBlock {
+	addrs := make([]int, len(parts))
+	for i, part := range parts {
+		addrs[i] = int(part.Address)
+	}
+
+	sort.Ints(addrs)
+
+	var blocks []compositeBlock
+
+	for _, addr := range addrs {
+		if n := len(blocks); n > 0 {
+			b := &blocks[n-1]
+			end := int(b.address) + int(b.quantity)
+
+			if addr < end {
+				continue
+			}
+
+			if addr-end <= maxReadGap && addr-int(b.address) < maxReadRegisters {
+				b.quantity = uint16(addr - int(b.address) + 1)
+				continue
+			}
+		}
+
+		blocks = append(blocks, compositeBlock{uint16(addr), 1})
+	}
+
+	return blocks
+}
+
+// readComposite reads parts of composite point and returns assembled value
+// (uint32 or int32 by point encoding)
+func (s Service) readComposite(p Point, params objx.Map) (interface{}, error) {
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var v uint32
+
+	for _, b := range compositeBlocks(p.Parts) {
+		res, err := s.readBlock(slaveID, pointFunctions[p.Function], b.address, b.quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, part := range p.Parts {
+			if part.Address >= b.address && part.Address < b.address+b.quantity {
+				v |= uint32(binary.BigEndian.Uint16(res[(part.Address-b.address)*2:])) << part.Shift
+			}
+		}
+	}
+
+	if p.Encoding == encInt32 {
+		return int32(v), nil
+	}
+
+	return v, nil
+}
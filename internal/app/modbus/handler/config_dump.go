@@ -0,0 +1,216 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/objx"
+)
+
+type retryDump struct {
+	Attempts   int    `json:"attempts"`
+	Backoff    string `json:"backoff"`
+	Exceptions []int  `json:"exceptions"`
+}
+
+type slaveTransportDump struct {
+	Timeout        string `json:"timeout,omitempty"`
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	FrameDelay     string `json:"frame_delay,omitempty"`
+	RetryAttempts  int    `json:"retry_attempts,omitempty"`
+	RetryBackoff   string `json:"retry_backoff,omitempty"`
+}
+
+type accessRuleDump struct {
+	SlaveIDs []int  `json:"slave_ids,omitempty"`
+	Function string `json:"function,omitempty"`
+	From     uint16 `json:"from"`
+	To       uint16 `json:"to"`
+	Deny     string `json:"deny"`
+}
+
+type ackPollDump struct {
+	Method    string  `json:"method"`
+	Address   *uint16 `json:"address,omitempty"`
+	DoneValue uint16  `json:"done_value"`
+	Interval  string  `json:"interval"`
+	Timeout   string  `json:"timeout"`
+}
+
+// configDump is effective configuration of service (modbus-config-dump method)
+// per slave settings are keyed by slave id
+type configDump struct {
+	ByteOrder        string `json:"byte_order"`
+	WordOrder        string `json:"word_order"`
+	AddressBase      int64  `json:"address_base"`
+	MaxQuantity      uint16 `json:"max_quantity,omitempty"`
+	FrameDelay       string `json:"frame_delay,omitempty"`
+	ConnectTimeout   string `json:"connect_timeout,omitempty"`
+	TimestampFormat  string `json:"timestamp_format"`
+	ExtendedFunction byte   `json:"extended_function,omitempty"`
+	StrictSlaveIDs   bool   `json:"strict_slave_id"`
+	RegisterLocks    bool   `json:"register_locks"`
+	DebugCalls       bool   `json:"debug_calls"`
+
+	Defaults map[string]objx.Map `json:"defaults"`
+	// register map points sorted by name
+	Points []Point `json:"points"`
+	// devices behind gateway sorted by name
+	Devices []Device         `json:"devices"`
+	Access  []accessRuleDump `json:"access"`
+	Retry   *retryDump       `json:"retry,omitempty"`
+	AckPoll []ackPollDump    `json:"ack_polling"`
+
+	SlaveTransports map[string]slaveTransportDump `json:"slave_transport"`
+	SlaveFramings   map[string]string             `json:"slave_framing"`
+	// slaves with own connection
+	SlaveConnections   []int `json:"slave_connections"`
+	UnsafeParallel     []int `json:"unsafe_parallel"`
+	SkipChecksum       []int `json:"skip_checksum"`
+	ForceMultipleWrite []int `json:"force_multiple_write"`
+	LenientEcho        []int `json:"lenient_echo"`
+	ReadWriteVerify    []int `json:"read_write_verify"`
+}
+
+// durationString returns duration in config format (empty for zero)
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return d.String()
+}
+
+// sortedKeys returns sorted slave ids (or codes) of set
+func sortedKeys(set map[byte]bool) []int {
+	res := make([]int, 0, len(set))
+
+	for id, ok := range set {
+		if ok {
+			res = append(res, int(id))
+		}
+	}
+
+	sort.Ints(res)
+
+	return res
+}
+
+// configDump returns effective configuration of service
+// (options passed to New with defaults applied), nothing is redacted
+// as configuration has no secrets
+func (s Service) configDump(objx.Map) (interface{}, error) {
+	res := configDump{
+		ByteOrder:        s.byteOrder,
+		WordOrder:        s.wordOrder,
+		AddressBase:      s.addressBase,
+		MaxQuantity:      s.maxQuantity,
+		FrameDelay:       durationString(s.frameDelay),
+		ConnectTimeout:   durationString(s.connectTimeout),
+		TimestampFormat:  s.timestampFormat,
+		ExtendedFunction: s.extendedFunction,
+		StrictSlaveIDs:   s.strictSlaveIDs,
+		RegisterLocks:    s.registerLocks != nil,
+		DebugCalls:       s.debugCalls,
+
+		Defaults: make(map[string]objx.Map, len(s.defaults)),
+		Points:   make([]Point, 0, len(s.points)),
+		Devices:  make([]Device, 0, len(s.devices)),
+		Access:   make([]accessRuleDump, 0, len(s.access)),
+		AckPoll:  make([]ackPollDump, 0, len(s.ackPolls)),
+
+		SlaveTransports: make(map[string]slaveTransportDump, len(s.slaveTransports)),
+		SlaveFramings:   make(map[string]string, len(s.slaveFramings)),
+
+		UnsafeParallel:     sortedKeys(s.parallel),
+		SkipChecksum:       sortedKeys(s.skipChecksum),
+		ForceMultipleWrite: sortedKeys(s.multipleWrite),
+		LenientEcho:        sortedKeys(s.lenientEcho),
+		ReadWriteVerify:    sortedKeys(s.readWriteVerify),
+	}
+
+	for method, params := range s.defaults {
+		res.Defaults[method] = params.Copy()
+	}
+
+	for _, p := range s.points {
+		res.Points = append(res.Points, p)
+	}
+
+	sort.Slice(res.Points, func(i, j int) bool { return res.Points[i].Name < res.Points[j].Name })
+
+	for _, d := range s.devices {
+		res.Devices = append(res.Devices, d)
+	}
+
+	sort.Slice(res.Devices, func(i, j int) bool { return res.Devices[i].Name < res.Devices[j].Name })
+
+	for _, r := range s.access {
+		rule := accessRuleDump{Function: r.Function, From: r.From, To: r.To, Deny: r.Deny}
+		for _, id := range r.SlaveIDs {
+			rule.SlaveIDs = append(rule.SlaveIDs, int(id))
+		}
+
+		res.Access = append(res.Access, rule)
+	}
+
+	if s.retry != nil {
+		res.Retry = &retryDump{
+			Attempts:   s.retry.attempts,
+			Backoff:    s.retry.backoff.String(),
+			Exceptions: sortedKeys(s.retry.exceptions),
+		}
+	}
+
+	for _, c := range s.ackPolls {
+		res.AckPoll = append(res.AckPoll, ackPollDump{
+			Method:    c.Method,
+			Address:   c.Address,
+			DoneValue: c.DoneValue,
+			Interval:  c.Interval.String(),
+			Timeout:   c.Timeout.String(),
+		})
+	}
+
+	sort.Slice(res.AckPoll, func(i, j int) bool { return res.AckPoll[i].Method < res.AckPoll[j].Method })
+
+	for id, c := range s.slaveTransports {
+		res.SlaveTransports[strconv.Itoa(int(id))] = slaveTransportDump{
+			Timeout:        durationString(c.Timeout),
+			ConnectTimeout: durationString(c.ConnectTimeout),
+			FrameDelay:     durationString(c.FrameDelay),
+			RetryAttempts:  c.RetryAttempts,
+			RetryBackoff:   durationString(c.RetryBackoff),
+		}
+	}
+
+	for id, framing := range s.slaveFramings {
+		res.SlaveFramings[strconv.Itoa(int(id))] = framing
+	}
+
+	connected := make(map[byte]bool, len(s.connections))
+	for id := range s.connections {
+		connected[id] = true
+	}
+
+	res.SlaveConnections = sortedKeys(connected)
+
+	return res, nil
+}
@@ -0,0 +1,142 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// slaveConnection is connection of slave which doesn't share the bus
+// (e.g. tcp slave with own address)
+type slaveConnection struct {
+	transport modbus.Transporter
+	bus       *busLock
+}
+
+// SlaveConnection sets independent transport of slaveID
+// transactions on different connections can go concurrently
+// (slaves with the same transport share its lock)
+func SlaveConnection(slaveID byte, t modbus.Transporter) Option {
+	return func(s *Service) {
+		if s.connections == nil {
+			s.connections = make(map[byte]slaveConnection)
+		}
+
+		s.connections[slaveID] = s.newConnection(t)
+	}
+}
+
+// DeviceConnection sets transport of device with host,
+// transactions of device go to it instead of connection of its unit id
+// (devices and slaves with the same transport share its lock)
+func DeviceConnection(name string, t modbus.Transporter) Option {
+	return func(s *Service) {
+		if s.deviceConnections == nil {
+			s.deviceConnections = make(map[string]slaveConnection)
+		}
+
+		s.deviceConnections[name] = s.newConnection(t)
+	}
+}
+
+// newConnection returns connection of transport with lock of the same transport if it's set
+func (s *Service) newConnection(t modbus.Transporter) slaveConnection {
+	c := slaveConnection{transport: t, bus: newBusLock()}
+
+	for _, other := range s.connections {
+		if other.transport == t {
+			c.bus = other.bus
+		}
+	}
+
+	for _, other := range s.framingConnections {
+		if other.transport == t {
+			c.bus = other.bus
+		}
+	}
+
+	for _, other := range s.deviceConnections {
+		if other.transport == t {
+			c.bus = other.bus
+		}
+	}
+
+	return c
+}
+
+// UnsafeParallel disables bus lock of slaveID transactions,
+// so they go concurrently with each other and with other slaves
+// (bus queue limits are not applied to them too)
+//
+// UNSAFE: use it only if transport of slave is safe for concurrent use
+// (e.g. tcp slave with own connection). Concurrent frames on shared
+// rtu or ascii line collide and corrupt each other
+func UnsafeParallel(slaveID byte) Option {
+	return func(s *Service) {
+		if s.parallel == nil {
+			s.parallel = make(map[byte]bool)
+		}
+
+		s.parallel[slaveID] = true
+	}
+}
+
+// connection returns transport and bus lock of slave
+// (or of own transport of framing or device of current call)
+// (lock is nil if slave transactions bypass it)
+func (s Service) connection(slaveID byte) (modbus.Transporter, *busLock) {
+	t, bus := s.transport, s.bus
+
+	if c, ok := s.framingConnections[s.framing]; ok {
+		t, bus = c.transport, c.bus
+	} else if s.device != "" {
+		// devices without host are on the main connection
+		if c, ok := s.deviceConnections[s.device]; ok {
+			t, bus = c.transport, c.bus
+		}
+	} else if c, ok := s.connections[slaveID]; ok {
+		t, bus = c.transport, c.bus
+	}
+
+	if s.parallel[slaveID] {
+		bus = nil
+	}
+
+	return t, bus
+}
+
+// ownConnection reports whether transactions of slave (or device of current call)
+// go to connection other than the main one
+func (s Service) ownConnection(slaveID byte) bool {
+	if s.device != "" {
+		_, ok := s.deviceConnections[s.device]
+		return ok
+	}
+
+	_, ok := s.connections[slaveID]
+
+	return ok
+}
+
+// withLayer returns service which wraps transport of all slaves with layer
+// (it's applied before bus lock, so it sees every transaction)
+func (s Service) withLayer(layer func(modbus.Transporter) modbus.Transporter) Service {
+	layers := make([]func(modbus.Transporter) modbus.Transporter, 0, len(s.layers)+1)
+	s.layers = append(append(layers, s.layers...), layer)
+
+	return s
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// wrapResult wraps read result if detect_wrap param set
+type wrapResult struct {
+	Result interface{} `json:"result"`
+	// true if any counter rolled over since previous read
+	Wrapped bool `json:"wrapped"`
+	// indexes of rolled over values
+	WrappedIndexes []int `json:"wrapped_indexes,omitempty"`
+}
+
+// checkDetectWrap checks that values of codec are 32-bit counters
+func checkDetectWrap(c codec) error {
+	if c.encoding != encInt32 && c.encoding != encUint32 {
+		return jsonrpc.ErrInvalidParams.AddData("msg", "detect_wrap requires int32 or uint32 encoding").
+			AddData("v", c.encoding)
+	}
+
+	if c.cal != nil {
+		return conflictErr("detect_wrap", "cal_raw_low")
+	}
+
+	return nil
+}
+
+// wrapped32 reports whether counter decreased from prev to cur by 32-bit rollover
+// (distance forward modulo 2^32 is less than half of range, bigger decreases are resets)
+func wrapped32(prev, cur interface{}) bool {
+	switch p := prev.(type) {
+	case uint32:
+		c, ok := cur.(uint32)
+		return ok && c < p && c-p < 1<<31
+	case int32:
+		c, ok := cur.(int32)
+		return ok && c < p && uint32(c)-uint32(p) < 1<<31
+	default:
+		return false
+	}
+}
+
+// detectWrap remembers read data of key and returns indexes of values
+// which rolled over since previous read (first read has no previous values)
+func (s Service) detectWrap(k cacheKey, c codec, res []byte) ([]int, error) {
+	prev, ok := s.counters.swap(k, res)
+	if !ok {
+		return nil, nil
+	}
+
+	prevValues, err := c.decode(prev.data)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := c.decode(res)
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapped []int
+
+	for i := 0; i < len(values) && i < len(prevValues); i++ {
+		if wrapped32(prevValues[i], values[i]) {
+			wrapped = append(wrapped, i)
+		}
+	}
+
+	return wrapped, nil
+}
+
+// withWrap wraps result if detect_wrap param set
+func withWrap(params objx.Map, res interface{}, wrapped []int) interface{} {
+	if !params.Get("detect_wrap").Bool() {
+		return res
+	}
+
+	return wrapResult{Result: res, Wrapped: len(wrapped) > 0, WrappedIndexes: wrapped}
+}
+
+// readCounterPoint reads point with rollover detection (detect_wrap param)
+func (s Service) readCounterPoint(p Point, params objx.Map) (interface{}, error) {
+	if p.unplanned() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "detect_wrap isn't supported by composite point "+
+			"or point with exponent_address").AddData("v", p.Name)
+	}
+
+	b, err := s.getPointBlock(p, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.bits {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "detect_wrap requires register point").AddData("v", p.Name)
+	}
+
+	err = checkDetectWrap(b.codec)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.readBlock(b.slaveID, b.function, b.address, b.count)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := s.detectWrap(cacheKey{b.slaveID, b.function, b.address, b.count, s.device}, b.codec, res)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := b.decode(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return withWrap(params, p.value(values, params), wrapped), nil
+}
@@ -0,0 +1,212 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// result formats (format param)
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// csvMethods contains methods which result can be formatted as CSV
+var csvMethods = map[string]bool{ // nolint: gochecknoglobals
+	"modbus-read-coil":     true,
+	"modbus-read-discrete": true,
+	"modbus-read-input":    true,
+	"modbus-read-holding":  true,
+	"modbus-read-point":    true,
+	"modbus-read-points":   true,
+	"modbus-read-all":      true,
+}
+
+var errCSVResult = jsonrpc.ErrInvalidParams.AddData("msg",
+	"format csv requires plain values (summary, bitmask, timestamp and stale flags can't be used)")
+
+// callFormatted calls method and returns its result in format of format param
+// csv result is string of address,value rows for register and bit reads
+// and name,value rows for point reads (sorted, without header)
+func (s Service) callFormatted(req jsonrpc.Request) (interface{}, error) {
+	format := req.Params.Get("format").Str()
+
+	params := req.Params.Copy()
+	delete(params, "format")
+
+	switch {
+	case format == formatJSON:
+		return s.call(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params})
+	case format != formatCSV:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "format should be json or csv").AddData("v", format)
+	case !csvMethods[req.Method]:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "format csv is supported by read methods only").
+			AddData("method", req.Method)
+	case params.Get("verbose").Bool():
+		return nil, conflictErr("verbose", "format")
+	}
+
+	// registers are read by address so values of wide encodings get address of first register
+	registers := req.Method == "modbus-read-input" || req.Method == "modbus-read-holding"
+	if registers {
+		params["keyed"] = true
+	}
+
+	res, err := s.call(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method == "modbus-read-point" {
+		return writeCSV([][]interface{}{{params.Get("point").Str(), res}})
+	}
+
+	var rows [][]interface{}
+
+	switch v := res.(type) {
+	case map[string]interface{}:
+		rows = mapRows(v, registers)
+	case map[string]snapshotValue:
+		values := make(map[string]interface{}, len(v))
+		for name, sv := range v {
+			// failed points have empty value
+			values[name] = sv.Value
+		}
+
+		rows = mapRows(values, false)
+	case []uint16, []interface{}:
+		addr, err := getInt64(params, "address")
+		if err != nil {
+			return nil, err
+		}
+
+		rows = bitRows(addr, v)
+	default:
+		return nil, errCSVResult
+	}
+
+	return writeCSV(rows)
+}
+
+// mapRows returns key,value rows of values sorted by key (numerically for addresses)
+func mapRows(values map[string]interface{}, addresses bool) [][]interface{} {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if addresses {
+			a, _ := strconv.Atoi(keys[i])
+			b, _ := strconv.Atoi(keys[j])
+
+			return a < b
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	rows := make([][]interface{}, len(keys))
+	for i, k := range keys {
+		rows[i] = []interface{}{k, values[k]}
+	}
+
+	return rows
+}
+
+// bitRows returns address,value rows of bits read from addr
+func bitRows(addr int64, bits interface{}) [][]interface{} {
+	var values []interface{}
+
+	switch v := bits.(type) {
+	case []uint16:
+		for _, b := range v {
+			values = append(values, b)
+		}
+	case []interface{}:
+		values = v
+	}
+
+	rows := make([][]interface{}, len(values))
+	for i, v := range values {
+		rows[i] = []interface{}{addr + int64(i), v}
+	}
+
+	return rows
+}
+
+// csvField formats value as CSV field
+// structured values (units, enums, arrays) are written as JSON
+func csvField(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// writeCSV returns rows as CSV string, fields are quoted if needed
+func writeCSV(rows [][]interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	for _, row := range rows {
+		record := make([]string, len(row))
+
+		for i, v := range row {
+			f, err := csvField(v)
+			if err != nil {
+				return "", err
+			}
+
+			record[i] = f
+		}
+
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+
+	return buf.String(), w.Error()
+}
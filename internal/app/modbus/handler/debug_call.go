@@ -0,0 +1,147 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// DebugCalls enables modbus-debug-call method
+// it's disabled by default because it exposes raw frames
+func DebugCalls(enabled bool) Option {
+	return func(s *Service) {
+		s.debugCalls = enabled
+	}
+}
+
+// debugFrame is one transaction on the wire
+type debugFrame struct {
+	RequestADU  []byte `json:"request_adu"`
+	ResponseADU []byte `json:"response_adu"`
+	Request     []byte `json:"request_pdu"`
+	Response    []byte `json:"response_pdu"`
+	// transaction time in milliseconds (bus lock wait excluded)
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+type debugResult struct {
+	Result interface{}    `json:"result"`
+	Error  *jsonrpc.Error `json:"error,omitempty"`
+	Frames []debugFrame   `json:"frames"`
+	// time of whole call in milliseconds
+	Duration float64 `json:"duration_ms"`
+}
+
+// debugRecorder remembers frames sent by transporter
+// (nested methods can send concurrently)
+type debugRecorder struct {
+	mx       sync.Mutex
+	packager modbus.Packager
+	frames   []debugFrame
+}
+
+// pdu returns function code and data of frame (nil if frame can't be decoded)
+func (r *debugRecorder) pdu(adu []byte) []byte {
+	pdu, err := r.packager.Decode(adu)
+	if err != nil {
+		return nil
+	}
+
+	return append([]byte{pdu.FunctionCode}, pdu.Data...)
+}
+
+type debugTransporter struct {
+	modbus.Transporter
+	recorder *debugRecorder
+}
+
+func (t debugTransporter) Send(adu []byte) ([]byte, error) {
+	start := time.Now()
+	res, err := t.Transporter.Send(adu)
+
+	frame := debugFrame{
+		RequestADU:  append([]byte{}, adu...),
+		ResponseADU: append([]byte{}, res...),
+		Request:     t.recorder.pdu(adu),
+		Response:    t.recorder.pdu(res),
+		Duration:    ms(time.Since(start)),
+	}
+
+	if err != nil {
+		frame.Error = err.Error()
+	}
+
+	t.recorder.mx.Lock()
+	t.recorder.frames = append(t.recorder.frames, frame)
+	t.recorder.mx.Unlock()
+
+	return res, err
+}
+
+// debugCall calls method with params and returns its result (or error)
+// with all frames it has sent and received
+// cache isn't used so the call goes to the wire
+func (s Service) debugCall(req jsonrpc.Request) (interface{}, error) {
+	if !s.debugCalls {
+		return nil, jsonrpc.ErrInvalidRequest.AddData("msg", "debug calls disabled")
+	}
+
+	method := req.Params.Get("method").Str()
+	if method == "modbus-debug-call" {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "modbus-debug-call can't be nested")
+	}
+
+	params := objx.Map{}
+
+	switch v := req.Params.Get("params").Data().(type) {
+	case nil:
+	case objx.Map:
+		params = v.Copy()
+	case map[string]interface{}:
+		params = objx.New(v).Copy()
+	default:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "params should be object")
+	}
+
+	recorder := &debugRecorder{packager: s.packagerGetter(0), frames: []debugFrame{}}
+
+	srv := s.withLayer(func(t modbus.Transporter) modbus.Transporter {
+		return debugTransporter{t, recorder}
+	})
+	srv.noCache = true
+
+	start := time.Now()
+	res, err := srv.call(jsonrpc.Request{Method: method, ID: req.ID, Params: params})
+	duration := time.Since(start)
+
+	recorder.mx.Lock()
+	defer recorder.mx.Unlock()
+
+	result := debugResult{Result: res, Frames: recorder.frames, Duration: ms(duration)}
+	if err != nil {
+		result.Error = toRPCError(err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,181 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+const (
+	meiReadDeviceID = 0x0E
+
+	// read device id codes (stream access of object categories)
+	deviceIDBasic    = 0x01
+	deviceIDRegular  = 0x02
+	deviceIDExtended = 0x03
+
+	// meaningful response without objects
+	deviceIDHeaderSize = 6
+)
+
+// first object id of categories
+var deviceIDFirstObject = map[byte]byte{ // nolint: gochecknoglobals
+	deviceIDBasic:    0x00,
+	deviceIDRegular:  0x03,
+	deviceIDExtended: 0x80,
+}
+
+// names of standard objects
+var deviceObjectNames = map[byte]string{ // nolint: gochecknoglobals
+	0x00: "vendor_name",
+	0x01: "product_code",
+	0x02: "major_minor_revision",
+	0x03: "vendor_url",
+	0x04: "product_name",
+	0x05: "model_name",
+	0x06: "user_application_name",
+}
+
+type deviceObject struct {
+	ID   byte   `json:"id"`
+	Name string `json:"name,omitempty"`
+	// value if it's utf-8 string otherwise raw (base64)
+	Value string `json:"value,omitempty"`
+	Raw   []byte `json:"raw,omitempty"`
+}
+
+type deviceIdentification struct {
+	ConformityLevel byte           `json:"conformity_level"`
+	Objects         []deviceObject `json:"objects"`
+}
+
+// readDeviceIDPage reads one response of category stream starting from objectID
+// it returns objects, conformity level and next object id (0 if no more follows)
+//
+// Request:
+//
+//	Function code         : 1 byte (0x2B)
+//	MEI type              : 1 byte (0x0E)
+//	Read device id code   : 1 byte
+//	Object id             : 1 byte
+//
+// Response:
+//
+//	Function code         : 1 byte (0x2B)
+//	MEI type              : 1 byte (0x0E)
+//	Read device id code   : 1 byte
+//	Conformity level      : 1 byte
+//	More follows          : 1 byte (0xFF if more objects)
+//	Next object id        : 1 byte
+//	Number of objects     : 1 byte
+//	Objects               : N x (id 1 byte, length 1 byte, value length bytes)
+func (s Service) readDeviceIDPage(slaveID, code, objectID byte) ([]deviceObject, byte, byte, error) {
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeEncapsulatedInterface,
+		Data:         []byte{meiReadDeviceID, code, objectID},
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	data := res.Data
+	if len(data) < deviceIDHeaderSize || data[0] != meiReadDeviceID || data[1] != code {
+		return nil, 0, 0, fmt.Errorf("modbus: wrong read device id response '% x'", data)
+	}
+
+	level, more, next, count := data[2], data[3], data[4], int(data[5])
+	data = data[deviceIDHeaderSize:]
+
+	objects := make([]deviceObject, 0, count)
+
+	for i := 0; i < count; i++ {
+		if len(data) < 2 || len(data) < 2+int(data[1]) {
+			return nil, 0, 0, fmt.Errorf("modbus: read device id response object %v is truncated", i)
+		}
+
+		obj := deviceObject{ID: data[0], Name: deviceObjectNames[data[0]]}
+		value := data[2 : 2+int(data[1])]
+
+		if utf8.Valid(value) {
+			obj.Value = string(value)
+		} else {
+			obj.Raw = append([]byte{}, value...)
+		}
+
+		objects = append(objects, obj)
+		data = data[2+int(data[1]):]
+	}
+
+	if more != 0xFF {
+		next = 0
+	}
+
+	return objects, level, next, nil
+}
+
+// readDeviceIdentification walks all object categories supported by slave
+// (basic, regular and extended) following continuation of responses
+func (s Service) readDeviceIdentification(params objx.Map) (interface{}, error) {
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := deviceIdentification{Objects: []deviceObject{}}
+	seen := make(map[byte]bool)
+
+	for code := byte(deviceIDBasic); code <= deviceIDExtended; code++ {
+		// conformity level of slave limits categories (0x80 bit means individual access)
+		if code > deviceIDBasic && code > result.ConformityLevel&0x7F {
+			break
+		}
+
+		next := deviceIDFirstObject[code]
+
+		for {
+			objects, level, n, err := s.readDeviceIDPage(slaveID, code, next)
+			if err != nil {
+				return nil, err
+			}
+
+			if code == deviceIDBasic {
+				result.ConformityLevel = level
+			}
+
+			for _, obj := range objects {
+				if !seen[obj.ID] {
+					seen[obj.ID] = true
+					result.Objects = append(result.Objects, obj)
+				}
+			}
+
+			// next object id should move forward otherwise slave loops
+			if n == 0 || n <= next {
+				break
+			}
+
+			next = n
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// deviceIDSlave answers read device id requests with two objects per response
+type deviceIDSlave struct {
+	level   byte
+	objects map[byte]string
+	pdus    [][]byte
+}
+
+func (m *deviceIDSlave) Send(adu []byte) ([]byte, error) {
+	pdu := adu[7:]
+	m.pdus = append(m.pdus, append([]byte{}, pdu...))
+
+	code, id := pdu[2], pdu[3]
+	last := map[byte]int{deviceIDBasic: 0x02, deviceIDRegular: 0x7F, deviceIDExtended: 0xFF}[code]
+
+	var (
+		objects []byte
+		count   byte
+		next    byte
+	)
+
+	for i := int(id); i <= last; i++ {
+		v, ok := m.objects[byte(i)]
+		if !ok {
+			continue
+		}
+
+		if count == 2 {
+			next = byte(i)
+			break
+		}
+
+		objects = append(objects, byte(i), byte(len(v)))
+		objects = append(objects, v...)
+		count++
+	}
+
+	more := byte(0)
+	if next != 0 {
+		more = 0xFF
+	}
+
+	res := append([]byte{pdu[0], meiReadDeviceID, code, m.level, more, next, count}, objects...)
+
+	header := make([]byte, 7)
+	copy(header, adu[:7])
+	binary.BigEndian.PutUint16(header[4:], uint16(len(res)+1))
+
+	return append(header, res...), nil
+}
+
+func TestReadDeviceIdentification(t *testing.T) {
+	m := &deviceIDSlave{
+		level: 0x83,
+		objects: map[byte]string{
+			0x00: "vendor", 0x01: "code", 0x02: "1.0",
+			0x04: "product", 0x80: "hr:0:u16", 0x81: "hr:1:f32", 0x90: "\xff\x01",
+		},
+	}
+
+	res, err := newTestService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-device-identification",
+		Params: objx.Map{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := deviceIdentification{
+		ConformityLevel: 0x83,
+		Objects: []deviceObject{
+			{ID: 0x00, Name: "vendor_name", Value: "vendor"},
+			{ID: 0x01, Name: "product_code", Value: "code"},
+			{ID: 0x02, Name: "major_minor_revision", Value: "1.0"},
+			{ID: 0x04, Name: "product_name", Value: "product"},
+			{ID: 0x80, Value: "hr:0:u16"},
+			{ID: 0x81, Value: "hr:1:f32"},
+			{ID: 0x90, Raw: []byte{0xFF, 0x01}},
+		},
+	}
+
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+
+	// basic (2 pages), regular and extended (2 pages)
+	if len(m.pdus) != 5 {
+		t.Errorf("expected 5 requests but got %d", len(m.pdus))
+	}
+}
@@ -0,0 +1,149 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// unit ids of devices behind gateway (0 is broadcast, 248-255 are reserved)
+const (
+	minUnitID = 1
+	maxUnitID = 247
+)
+
+// Device is a physical device behind gateway (e.g. on serial sub-bus of modbus tcp gateway)
+// selected by unit id, it has own register map available by device param of point methods
+type Device struct {
+	Name string `mapstructure:"name" json:"name"`
+	// unit id (slave_id) of device, it's unique on its host
+	// (requests of device are routed by host and unit id)
+	UnitID int `mapstructure:"unit_id" json:"unit_id"`
+	// address of gateway (empty means the main connection),
+	// devices with the same host share connection (see DeviceConnection)
+	Host string `mapstructure:"host" json:"host,omitempty"`
+	// register map of device, points are read and written with unit id of device
+	Points []Point `mapstructure:"points" json:"points"`
+}
+
+// deviceUnit is unit id on host
+type deviceUnit struct {
+	host   string
+	unitID int
+}
+
+// ValidateDevices checks devices definitions, names and unit ids uniqueness on hosts
+func ValidateDevices(devices []Device) error {
+	names := make(map[string]bool, len(devices))
+	units := make(map[deviceUnit]string, len(devices))
+
+	for i, d := range devices {
+		prefix := "device " + strconv.Itoa(i) + ": "
+
+		if d.Name == "" {
+			return errors.New(prefix + "name required")
+		}
+
+		if names[d.Name] {
+			return errors.New(prefix + "duplicate name " + d.Name)
+		}
+
+		// status of devices is reported by name along with slave ids
+		if _, err := strconv.Atoi(d.Name); err == nil {
+			return errors.New(prefix + "name can't be a number")
+		}
+
+		names[d.Name] = true
+
+		if !(minUnitID <= d.UnitID && d.UnitID <= maxUnitID) {
+			return errors.New(prefix + "unit_id should be in range 1-247")
+		}
+
+		unit := deviceUnit{d.Host, d.UnitID}
+		if other, ok := units[unit]; ok {
+			return errors.New(prefix + "unit_id " + strconv.Itoa(d.UnitID) + " is used by device " + other + " of the same host")
+		}
+
+		units[unit] = d.Name
+
+		for _, p := range d.Points {
+			if p.SlaveID != nil {
+				return errors.New(prefix + "point " + p.Name + " can't have slave_id")
+			}
+
+			if p.PollInterval != 0 {
+				return errors.New(prefix + "point " + p.Name + " can't have poll_interval")
+			}
+		}
+
+		if err := ValidatePoints(d.Points); err != nil {
+			return errors.New(prefix + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Devices sets devices with own register maps
+// devices should be checked by ValidateDevices before
+// (connections of devices with host are set by DeviceConnection)
+func Devices(devices ...Device) Option {
+	return func(s *Service) {
+		if s.devices == nil {
+			s.devices = make(map[string]Device, len(devices))
+		}
+
+		for _, d := range devices {
+			points := make([]Point, len(d.Points))
+
+			for i, p := range d.Points {
+				unitID := byte(d.UnitID)
+				p.SlaveID = &unitID
+
+				points[i] = compilePoint(p)
+			}
+
+			d.Points = points
+			s.devices[d.Name] = d
+		}
+	}
+}
+
+// devicePoint returns point of device from device param
+func (s Service) devicePoint(params objx.Map, name string) (Point, error) {
+	if !params.Get("slave_id").IsNil() {
+		return Point{}, conflictErr("device", "slave_id")
+	}
+
+	d, ok := s.devices[params.Get("device").Str()]
+	if !ok {
+		return Point{}, jsonrpc.ErrInvalidParams.AddData("msg", "unknown device").AddData("v", params.Get("device").Data())
+	}
+
+	for _, p := range d.Points {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+
+	return Point{}, jsonrpc.ErrInvalidParams.AddData("msg", "unknown point of device "+d.Name).AddData("v", name)
+}
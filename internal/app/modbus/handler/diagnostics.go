@@ -0,0 +1,293 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+type commEventCounter struct {
+	Status     uint16 `json:"status"`
+	EventCount uint16 `json:"event_count"`
+}
+
+// Request:
+//
+//	Function code         : 1 byte (0x0B)
+//
+// Response:
+//
+//	Function code         : 1 byte (0x0B)
+//	Status                : 2 bytes (0xFFFF if slave busy)
+//	Event count           : 2 bytes
+func (s Service) commEventCounter(params objx.Map) (interface{}, error) {
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeGetCommEventCounter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Data) != 4 {
+		return nil, fmt.Errorf("modbus: response data size '%v' does not match expected '%v'",
+			len(res.Data), 4)
+	}
+
+	return commEventCounter{
+		Status:     binary.BigEndian.Uint16(res.Data),
+		EventCount: binary.BigEndian.Uint16(res.Data[2:]),
+	}, nil
+}
+
+const (
+	diagReturnQueryData          = 0x0000
+	diagRestartCommunications    = 0x0001
+	diagReturnDiagnosticRegister = 0x0002
+	diagClearCounters            = 0x000A
+	diagReturnBusMessageCount    = 0x000B
+	diagReturnBusCommErrorCount  = 0x000C
+	diagReturnBusExceptionCount  = 0x000D
+	diagReturnSlaveMessageCount  = 0x000E
+	diagReturnSlaveNoRespCount   = 0x000F
+	diagReturnSlaveNAKCount      = 0x0010
+	diagReturnSlaveBusyCount     = 0x0011
+	diagReturnBusOverrunCount    = 0x0012
+)
+
+// diagEcho contains sub-functions which response should echo request data
+// other supported sub-functions return data word (register or counter value)
+var diagEcho = map[uint16]bool{ // nolint: gochecknoglobals
+	diagReturnQueryData:          true,
+	diagRestartCommunications:    true,
+	diagReturnDiagnosticRegister: false,
+	diagClearCounters:            true,
+	diagReturnBusMessageCount:    false,
+	diagReturnBusCommErrorCount:  false,
+	diagReturnBusExceptionCount:  false,
+	diagReturnSlaveMessageCount:  false,
+	diagReturnSlaveNoRespCount:   false,
+	diagReturnSlaveNAKCount:      false,
+	diagReturnSlaveBusyCount:     false,
+	diagReturnBusOverrunCount:    false,
+}
+
+// diagCounters contains names of counters returned by sub-functions
+var diagCounters = map[uint16]string{ // nolint: gochecknoglobals
+	diagReturnBusMessageCount:   "bus_message",
+	diagReturnBusCommErrorCount: "bus_communication_error",
+	diagReturnBusExceptionCount: "bus_exception_error",
+	diagReturnSlaveMessageCount: "slave_message",
+	diagReturnSlaveNoRespCount:  "slave_no_response",
+	diagReturnSlaveNAKCount:     "slave_nak",
+	diagReturnSlaveBusyCount:    "slave_busy",
+	diagReturnBusOverrunCount:   "bus_character_overrun",
+}
+
+type diagnosticsResult struct {
+	SubFunction uint16 `json:"sub_function"`
+	Data        uint16 `json:"data"`
+	// name of counter which value is data (counter sub-functions)
+	Counter string `json:"counter,omitempty"`
+	// set if counters and diagnostic register are cleared (clear counters sub-function)
+	Cleared bool `json:"cleared,omitempty"`
+}
+
+// Request:
+//
+//	Function code         : 1 byte (0x08)
+//	Sub-function          : 2 bytes
+//	Data                  : 2 bytes
+//
+// Response:
+//
+//	Function code         : 1 byte (0x08)
+//	Sub-function          : 2 bytes
+//	Data                  : 2 bytes
+func (s Service) diagnostics(params objx.Map) (interface{}, error) {
+	subFunction, err := getUint16(params, "sub_function")
+	if err != nil {
+		return nil, err
+	}
+
+	echo, ok := diagEcho[subFunction]
+	if !ok {
+		// force listen only mode is not supported because slave doesn't respond on it
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "unsupported sub_function").
+			AddData("v", subFunction)
+	}
+
+	data, err := getUint16(params, "data", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pdu := make([]byte, 4)
+	binary.BigEndian.PutUint16(pdu, subFunction)
+	binary.BigEndian.PutUint16(pdu[2:], data)
+
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeDiagnostics,
+		Data:         pdu,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Data) != 4 {
+		return nil, fmt.Errorf("modbus: response data size '%v' does not match expected '%v'",
+			len(res.Data), 4)
+	}
+
+	respSubFunction := binary.BigEndian.Uint16(res.Data)
+	if respSubFunction != subFunction {
+		return nil, fmt.Errorf("modbus: response sub-function '%v' does not match request '%v'",
+			respSubFunction, subFunction)
+	}
+
+	respData := binary.BigEndian.Uint16(res.Data[2:])
+	if echo && respData != data {
+		return nil, fmt.Errorf("modbus: response data '%v' does not match request '%v'",
+			respData, data)
+	}
+
+	return diagnosticsResult{
+		SubFunction: subFunction,
+		Data:        respData,
+		Counter:     diagCounters[subFunction],
+		Cleared:     subFunction == diagClearCounters,
+	}, nil
+}
+
+type commEvent struct {
+	Raw   uint8    `json:"raw"`
+	Type  string   `json:"type"`
+	Flags []string `json:"flags,omitempty"`
+}
+
+// bits of receive event (bit 7 is set)
+var receiveEventFlags = []string{ // nolint: gochecknoglobals
+	1: "communication_error",
+	4: "character_overrun",
+	5: "listen_only_mode",
+	6: "broadcast_received",
+}
+
+// bits of send event (bit 7 is cleared and bit 6 is set)
+var sendEventFlags = []string{ // nolint: gochecknoglobals
+	0: "read_exception",
+	1: "slave_abort_exception",
+	2: "slave_busy_exception",
+	3: "slave_program_nak_exception",
+	4: "write_timeout",
+	5: "listen_only_mode",
+}
+
+func eventFlags(e byte, names []string) []string {
+	var res []string
+
+	for i, name := range names {
+		if name != "" && e&(1<<uint(i)) != 0 {
+			res = append(res, name)
+		}
+	}
+
+	return res
+}
+
+func decodeCommEvent(e byte) commEvent {
+	switch {
+	case e&0x80 != 0:
+		return commEvent{e, "receive", eventFlags(e, receiveEventFlags)}
+	case e&0x40 != 0:
+		return commEvent{e, "send", eventFlags(e, sendEventFlags)}
+	case e == 0x04:
+		return commEvent{e, "listen_only_mode", nil}
+	case e == 0x00:
+		return commEvent{e, "communication_restart", nil}
+	default:
+		return commEvent{e, "unknown", nil}
+	}
+}
+
+type commEventLog struct {
+	Status       uint16      `json:"status"`
+	EventCount   uint16      `json:"event_count"`
+	MessageCount uint16      `json:"message_count"`
+	Events       []commEvent `json:"events"`
+}
+
+// Request:
+//
+//	Function code         : 1 byte (0x0C)
+//
+// Response:
+//
+//	Function code         : 1 byte (0x0C)
+//	Byte count            : 1 byte
+//	Status                : 2 bytes
+//	Event count           : 2 bytes
+//	Message count         : 2 bytes
+//	Events                : (byte count - 6) bytes
+func (s Service) commEventLog(params objx.Map) (interface{}, error) {
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeGetCommEventLog,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	count := int(res.Data[0])
+	length := len(res.Data) - 1
+
+	if count != length || count < 6 {
+		return nil, fmt.Errorf("modbus: response data size '%v' does not match count '%v'",
+			length, count)
+	}
+
+	events := make([]commEvent, 0, count-6)
+	for _, e := range res.Data[7:] {
+		events = append(events, decodeCommEvent(e))
+	}
+
+	return commEventLog{
+		Status:       binary.BigEndian.Uint16(res.Data[1:]),
+		EventCount:   binary.BigEndian.Uint16(res.Data[3:]),
+		MessageCount: binary.BigEndian.Uint16(res.Data[5:]),
+		Events:       events,
+	}, nil
+}
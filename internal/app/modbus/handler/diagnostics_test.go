@@ -0,0 +1,118 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestCommEventCounter(t *testing.T) {
+	m := &mockSlave{busy: 1}
+	srv := newMockService(m)
+
+	read := jsonrpc.Request{Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("1")}}
+
+	// exception response doesn't count as event
+	if _, err := srv.Call(read); err == nil {
+		t.Fatal("expected busy exception")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := srv.Call(read); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-comm-event-counter", Params: objx.Map{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeGetCommEventCounter})
+
+	if expected := (commEventCounter{EventCount: 2}); res != expected {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+}
+
+func TestDiagnosticsLoopback(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-diagnostics", Params: objx.Map{"sub_function": num("0"), "data": num("42295")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeDiagnostics, 0x00, 0x00, 0xA5, 0x37})
+
+	if expected := (diagnosticsResult{Data: 0xA537}); res != expected {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+
+	// sub-function unsupported by slave is answered by exception
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-diagnostics", Params: objx.Map{"sub_function": num("2")}})
+
+	var mbErr *modbus.ModbusError
+	if !errors.As(err, &mbErr) || mbErr.ExceptionCode != modbus.ExceptionCodeIllegalFunction {
+		t.Errorf("expected illegal function exception but got %v", err)
+	}
+}
+
+func TestCommEventLog(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register", Params: objx.Map{"address": num("0"), "value": num("1")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// diagnostic register isn't supported by slave
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-diagnostics", Params: objx.Map{"sub_function": num("2")},
+	}); err == nil {
+		t.Fatal("expected exception")
+	}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-comm-event-log", Params: objx.Map{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeGetCommEventLog})
+
+	expected := commEventLog{EventCount: 1, MessageCount: 3, Events: []commEvent{
+		{Raw: 0x41, Type: "send", Flags: []string{"read_exception"}},
+		{Raw: 0x80, Type: "receive"},
+		{Raw: 0x40, Type: "send"},
+		{Raw: 0x80, Type: "receive"},
+	}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+}
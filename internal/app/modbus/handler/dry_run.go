@@ -0,0 +1,88 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+var errDryRun = errors.New("modbus: dry run")
+
+// dryRunTransporter remembers request and doesn't send it
+type dryRunTransporter struct {
+	adu []byte
+}
+
+func (t *dryRunTransporter) Send(adu []byte) ([]byte, error) {
+	t.adu = adu
+	return nil, errDryRun
+}
+
+type dryRunResult struct {
+	ADU []byte `json:"adu"`
+	PDU []byte `json:"pdu"`
+}
+
+// dryRun parses request and builds frame as usual but doesn't send it
+// it returns frame (adu) and pdu as base64 strings
+func (s Service) dryRun(req jsonrpc.Request) (interface{}, error) {
+	tr := &dryRunTransporter{}
+
+	srv := s
+	srv.transport = tr
+	srv.connections = nil
+	srv.framingConnections = nil
+	srv.deviceConnections = nil
+	srv.cache = nil
+	srv.flights = nil
+	srv.subs = nil
+	srv.limiters = nil
+	srv.metrics = nil
+	srv.trace = nil
+	// recorded transactions and layers would answer instead of dry run transport
+	srv.replay = nil
+	srv.layers = nil
+	// nothing goes to the bus, so it's not locked, limited or kept silent
+	srv.bus = nil
+	srv.busLimiter = nil
+	srv.frameDelay = 0
+	srv.slaveTransports = nil
+
+	params := req.Params.Copy()
+	delete(params, "dry_run")
+
+	_, err := srv.call(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params})
+	if err == nil {
+		return nil, jsonrpc.ErrInvalidRequest.AddData("msg", "method doesn't send anything")
+	}
+
+	if !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	pdu, err := s.packagerGetter(0).Decode(tr.adu)
+	if err != nil {
+		return nil, err
+	}
+
+	return dryRunResult{
+		ADU: tr.adu,
+		PDU: append([]byte{pdu.FunctionCode}, pdu.Data...),
+	}, nil
+}
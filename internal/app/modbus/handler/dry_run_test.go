@@ -0,0 +1,120 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestDryRun(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("5"), "value": num("7"), "slave_id": num("3"), "dry_run": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, ok := res.(dryRunResult)
+	if !ok {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	if expected := []byte{0x06, 0x00, 0x05, 0x00, 0x07}; !bytes.Equal(r.PDU, expected) {
+		t.Errorf("expected pdu % x but got % x", expected, r.PDU)
+	}
+
+	// tcp frame has header with unit id before pdu
+	if len(r.ADU) != 12 || r.ADU[6] != 3 || !bytes.Equal(r.ADU[7:], r.PDU) {
+		t.Errorf("unexpected adu % x", r.ADU)
+	}
+
+	if len(m.pdus) != 0 || m.holding[5] != 0 {
+		t.Errorf("dry run shouldn't be sent but got %x", m.pdus)
+	}
+
+	// invalid params are still rejected
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register", Params: objx.Map{"address": num("5"), "dry_run": true},
+	})
+	if err == nil {
+		t.Error("expected error of missing value")
+	}
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-stats", Params: objx.Map{"dry_run": true}})
+	if err == nil {
+		t.Error("expected error of method without request")
+	}
+}
+
+func TestDryRunBus(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m, BusQueue(0, 10*time.Millisecond), BusRateLimit(1, 0))
+
+	params := objx.Map{"address": num("1"), "value": num("5"), "dry_run": true}
+
+	// bus is taken by another transaction
+	if err := srv.bus.acquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-write-register", Params: params.Copy()}); err != nil {
+			t.Fatalf("dry run %d: %v", i, err)
+		}
+	}
+
+	srv.bus.release()
+
+	// token of rate limit isn't taken by dry runs
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("1"), "value": num("5")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.pdus) != 1 {
+		t.Errorf("expected one bus transaction but got %d", len(m.pdus))
+	}
+}
+
+func TestDryRunReplay(t *testing.T) {
+	srv := New(nil, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, Replay())
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("1"), "value": num("5"), "dry_run": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pdu := res.(dryRunResult).PDU; !bytes.Equal(pdu, []byte{modbus.FuncCodeWriteSingleRegister, 0, 1, 0, 5}) {
+		t.Errorf("unexpected pdu % x", pdu)
+	}
+}
@@ -0,0 +1,652 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// supported register encodings
+const (
+	encUint16  = "uint16"
+	encInt16   = "int16"
+	encUint32  = "uint32"
+	encInt32   = "int32"
+	encFloat32 = "float32"
+	// binary-coded decimal (4 digits per register)
+	encBCD   = "bcd"
+	encBCD32 = "bcd32"
+	// signed fixed-point divided by 2^fractional_bits (register reads only)
+	encFixed   = "fixed"
+	encFixed32 = "fixed32"
+	// 32-bit unix time in seconds (register reads only)
+	encTimestamp = "timestamp"
+	// high and low bytes of register as separate values (register reads only)
+	encBytes = "bytes"
+
+	// bits packed to integer (coils and discrete inputs only)
+	encBitmask = "bitmask"
+	// registers bytes as is (register reads only)
+	encRaw = "raw"
+)
+
+// encodingRegisters contains count of registers used by one value
+var encodingRegisters = map[string]int{ // nolint: gochecknoglobals
+	encUint16:  1,
+	encInt16:   1,
+	encUint32:  2,
+	encInt32:   2,
+	encFloat32: 2,
+	encBCD:     1,
+	encBCD32:   2,
+	encFixed:   1,
+	encFixed32: 2,
+	encRaw:     1,
+
+	encTimestamp: 2,
+	encBytes:     1,
+}
+
+// encodingAliases contains 32-bit encodings with fixed byte arrangement
+// named as in vendor manuals (a is the most significant byte)
+var encodingAliases = map[string]codec{ // nolint: gochecknoglobals
+	"float32_abcd": {encoding: encFloat32},
+	"float32_cdab": {encoding: encFloat32, wordSwap: true},
+	"float32_badc": {encoding: encFloat32, byteSwap: true},
+	"float32_dcba": {encoding: encFloat32, byteSwap: true, wordSwap: true},
+	"int32_abcd":   {encoding: encInt32},
+	"int32_cdab":   {encoding: encInt32, wordSwap: true},
+	"int32_badc":   {encoding: encInt32, byteSwap: true},
+	"int32_dcba":   {encoding: encInt32, byteSwap: true, wordSwap: true},
+	"uint32_abcd":  {encoding: encUint32},
+	"uint32_cdab":  {encoding: encUint32, wordSwap: true},
+	"uint32_badc":  {encoding: encUint32, byteSwap: true},
+	"uint32_dcba":  {encoding: encUint32, byteSwap: true, wordSwap: true},
+}
+
+// isValidEncoding reports whether encoding is supported by register methods
+func isValidEncoding(encoding string) bool {
+	_, ok := encodingRegisters[encoding]
+	if !ok {
+		_, ok = encodingAliases[encoding]
+	}
+
+	return ok
+}
+
+const (
+	orderBig    = "big"
+	orderLittle = "little"
+)
+
+// decode modes
+const (
+	decodeStrict  = "strict"
+	decodeLenient = "lenient"
+)
+
+// codec converts registers to values and vice versa
+// by default values are big endian and for multi register values
+// high word goes first
+type codec struct {
+	encoding string
+	// swap bytes inside each register
+	byteSwap bool
+	// low word goes first
+	wordSwap bool
+	// saturate out of range values on encode instead of error
+	clamp bool
+	// raw value (register or two registers) decoded as null
+	null *uint32
+	// linear transform of decoded values (nil if not set)
+	cal *calibration
+	// fractional bits of fixed encodings
+	fractionalBits uint
+	// values which can't be decoded are null instead of error
+	lenient bool
+	// format of timestamp encoding values
+	timestampFormat string
+	// offset of device local time from UTC (timestamp encoding)
+	tzOffset time.Duration
+}
+
+// IsValidOrder reports whether order can be used as byte or word order
+func IsValidOrder(order string) bool {
+	return order == orderBig || order == orderLittle
+}
+
+// getOrder returns true if order from params (or default) is little
+func getOrder(params objx.Map, k, def string) (bool, error) {
+	switch params.Get(k).Str(def) {
+	case orderBig:
+		return false, nil
+	case orderLittle:
+		return true, nil
+	default:
+		return false, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be big or little")
+	}
+}
+
+func (s Service) getCodec(params objx.Map) (codec, error) {
+	c := codec{encoding: params.Get("encoding").Str(encUint16)}
+
+	if alias, ok := encodingAliases[c.encoding]; ok {
+		for _, k := range []string{"byte_order", "word_order"} {
+			if !params.Get(k).IsNil() {
+				return codec{}, conflictErr("encoding", k)
+			}
+		}
+
+		alias.clamp = params.Get("clamp").Bool()
+
+		var err error
+
+		alias.lenient, err = getLenient(params)
+		if err != nil {
+			return codec{}, err
+		}
+
+		return alias, nil
+	}
+
+	if _, ok := encodingRegisters[c.encoding]; !ok {
+		return codec{}, jsonrpc.ErrInvalidParams.AddData("msg", "unsupported encoding").
+			AddData("v", c.encoding)
+	}
+
+	var err error
+
+	c.byteSwap, err = getOrder(params, "byte_order", s.byteOrder)
+	if err != nil {
+		return codec{}, err
+	}
+
+	c.wordSwap, err = getOrder(params, "word_order", s.wordOrder)
+	if err != nil {
+		return codec{}, err
+	}
+
+	c.clamp = params.Get("clamp").Bool()
+
+	c.fractionalBits, err = getFractionalBits(params, c)
+	if err != nil {
+		return codec{}, err
+	}
+
+	c.lenient, err = getLenient(params)
+	if err != nil {
+		return codec{}, err
+	}
+
+	c.timestampFormat, c.tzOffset, err = s.getTimestampEncoding(params, c)
+	if err != nil {
+		return codec{}, err
+	}
+
+	return c, nil
+}
+
+// getLenient returns true if decode_mode param is lenient
+func getLenient(params objx.Map) (bool, error) {
+	switch mode := params.Get("decode_mode").Str(decodeStrict); mode {
+	case decodeStrict:
+		return false, nil
+	case decodeLenient:
+		return true, nil
+	default:
+		return false, jsonrpc.ErrInvalidParams.AddData("msg", "decode_mode should be strict or lenient").
+			AddData("v", mode)
+	}
+}
+
+// getFractionalBits returns fractional_bits param of fixed encodings
+// it should be less than width of value
+func getFractionalBits(params objx.Map, c codec) (uint, error) {
+	if c.encoding != encFixed && c.encoding != encFixed32 {
+		if !params.Get("fractional_bits").IsNil() {
+			return 0, jsonrpc.ErrInvalidParams.AddData("msg", "fractional_bits supported by fixed encodings only")
+		}
+
+		return 0, nil
+	}
+
+	v, err := getInt64(params, "fractional_bits", 0)
+	if err != nil {
+		return 0, err
+	}
+
+	max := int64(c.registers()*16 - 1)
+	if !(0 <= v && v <= max) {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", "fractional_bits should be in range 0-"+
+			strconv.FormatInt(max, 10)+" for "+c.encoding).AddData("v", v)
+	}
+
+	return uint(v), nil
+}
+
+// getNullValue returns null_value param (nil if not passed)
+// it's raw unsigned value of one or two registers (depends on encoding)
+func getNullValue(params objx.Map, c codec) (*uint32, error) {
+	if params.Get("null_value").IsNil() {
+		return nil, nil
+	}
+
+	v, err := getInt64(params, "null_value")
+	if err != nil {
+		return nil, err
+	}
+
+	max := maxUint16
+	if c.registers() == 2 {
+		max = math.MaxUint32
+	}
+
+	if !(0 <= v && v <= max) {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "null_value should be raw unsigned value of "+
+			c.encoding).AddData("v", v)
+	}
+
+	null := uint32(v)
+
+	return &null, nil
+}
+
+func orderName(swap bool) string {
+	if swap {
+		return orderLittle
+	}
+
+	return orderBig
+}
+
+// verboseResult describes how register values was decoded
+// so consumer can check it or re-decode raw data
+type verboseResult struct {
+	// method and slave of request (for correlation of responses)
+	Method  string `json:"method"`
+	SlaveID byte   `json:"slave_id"`
+
+	Values    []interface{} `json:"values"`
+	Encoding  string        `json:"encoding"`
+	ByteOrder string        `json:"byte_order"`
+	WordOrder string        `json:"word_order"`
+	Raw       []byte        `json:"raw"`
+	// registers as received (before byte and word order applied)
+	RawRegisters []uint16 `json:"raw_registers"`
+	// byte count reported by slave and count of received data bytes
+	ReportedBytes int `json:"reported_bytes"`
+	ReceivedBytes int `json:"received_bytes"`
+	// function code (request one or exception one with 0x80 bit) and pdu length
+	// of last response as received
+	FunctionCode byte `json:"function_code"`
+	PDULength    int  `json:"pdu_length"`
+	// set if sla_ms param passed
+	WithinSLA *bool `json:"within_sla,omitempty"`
+	// completion time of transaction if with_timestamp param passed
+	Timestamp interface{} `json:"timestamp,omitempty"`
+}
+
+// decodeVerbose decodes registers and wraps values into verboseResult
+func (c codec) decodeVerbose(b []byte, stats responseStats) (verboseResult, error) {
+	values, err := c.decode(b)
+	if err != nil {
+		return verboseResult{}, err
+	}
+
+	return verboseResult{
+		Values:    values,
+		Encoding:  c.encoding,
+		ByteOrder: orderName(c.byteSwap),
+		WordOrder: orderName(c.wordSwap),
+		Raw:       b,
+
+		RawRegisters: rawRegisters(b),
+
+		ReportedBytes: stats.reported,
+		ReceivedBytes: stats.received,
+
+		FunctionCode: stats.functionCode,
+		PDULength:    stats.pduLength,
+	}, nil
+}
+
+// rawRegisters splits bytes to big endian registers
+// (odd last byte is ignored)
+func rawRegisters(b []byte) []uint16 {
+	res := make([]uint16, len(b)/2)
+	for i := range res {
+		res[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+
+	return res
+}
+
+// toFloat64 converts decoded value to float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func (c codec) registers() int {
+	return encodingRegisters[c.encoding]
+}
+
+// order converts value bytes from wire order to big endian and back
+// (this operation is symmetric)
+func (c codec) order(b []byte) {
+	if c.byteSwap {
+		for i := 0; i < len(b)-1; i += 2 {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+
+	if c.wordSwap && len(b) == 4 {
+		b[0], b[1], b[2], b[3] = b[2], b[3], b[0], b[1]
+	}
+}
+
+var (
+	errRawEncoding   = jsonrpc.ErrInvalidParams.AddData("msg", "raw encoding supported by register reads only")
+	errFixedEncoding = jsonrpc.ErrInvalidParams.AddData("msg", "fixed encodings supported by register reads only")
+
+	errTimestampEncoding = jsonrpc.ErrInvalidParams.AddData("msg", "timestamp encoding supported by register reads only")
+	errBytesEncoding     = jsonrpc.ErrInvalidParams.AddData("msg", "bytes encoding supported by register reads only")
+)
+
+func (c codec) decode(b []byte) ([]interface{}, error) {
+	if c.encoding == encRaw {
+		return nil, errRawEncoding
+	}
+
+	size := c.registers() * 2
+	if len(b)%size != 0 && !c.lenient {
+		return nil, fmt.Errorf("modbus: response size '%v' is not multiple of %s size '%v'",
+			len(b), c.encoding, size)
+	}
+
+	res := make([]interface{}, 0, (len(b)+size-1)/size)
+	buf := make([]byte, size)
+
+	for i := 0; i+size <= len(b); i += size {
+		copy(buf, b[i:i+size])
+		c.order(buf)
+
+		if c.null != nil && c.raw(buf) == *c.null {
+			res = append(res, nil)
+			if c.encoding == encBytes {
+				res = append(res, nil)
+			}
+
+			continue
+		}
+
+		switch c.encoding {
+		case encUint16:
+			res = append(res, binary.BigEndian.Uint16(buf))
+		case encInt16:
+			res = append(res, int16(binary.BigEndian.Uint16(buf)))
+		case encUint32:
+			res = append(res, binary.BigEndian.Uint32(buf))
+		case encInt32:
+			res = append(res, int32(binary.BigEndian.Uint32(buf)))
+		case encFloat32:
+			res = append(res, math.Float32frombits(binary.BigEndian.Uint32(buf)))
+		case encFixed:
+			res = append(res, math.Ldexp(float64(int16(binary.BigEndian.Uint16(buf))), -int(c.fractionalBits)))
+		case encFixed32:
+			res = append(res, math.Ldexp(float64(int32(binary.BigEndian.Uint32(buf))), -int(c.fractionalBits)))
+		case encTimestamp:
+			res = append(res, c.timestamp(binary.BigEndian.Uint32(buf)))
+		case encBytes:
+			// byte_order little puts low byte first
+			res = append(res, buf[0], buf[1])
+		case encBCD, encBCD32:
+			v, err := decodeBCD(buf)
+			if err != nil && c.lenient {
+				res = append(res, nil)
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if c.encoding == encBCD {
+				res = append(res, uint16(v))
+			} else {
+				res = append(res, v)
+			}
+		}
+
+		if c.cal != nil {
+			res[len(res)-1] = c.cal.apply(res[len(res)-1])
+		}
+	}
+
+	// incomplete last value
+	if len(b)%size != 0 {
+		res = append(res, nil)
+	}
+
+	return res, nil
+}
+
+// raw returns unsigned value of ordered value bytes
+func (c codec) raw(buf []byte) uint32 {
+	if len(buf) == 2 {
+		return uint32(binary.BigEndian.Uint16(buf))
+	}
+
+	return binary.BigEndian.Uint32(buf)
+}
+
+// decodeBCD converts big endian binary-coded decimal to integer
+func decodeBCD(b []byte) (uint32, error) {
+	var v uint32
+
+	for i := 0; i < len(b)*2; i++ {
+		nibble := b[i/2] >> 4
+		if i%2 == 1 {
+			nibble = b[i/2] & 0x0F
+		}
+
+		if nibble > 9 {
+			return 0, fmt.Errorf("modbus: invalid bcd nibble '%X' at position %v of '% x'", nibble, i, b)
+		}
+
+		v = v*10 + uint32(nibble)
+	}
+
+	return v, nil
+}
+
+// encodeBCD converts integer to big endian binary-coded decimal
+func encodeBCD(v uint32, buf []byte) {
+	for i := len(buf)*2 - 1; i >= 0; i-- {
+		nibble := byte(v % 10)
+		v /= 10
+
+		if i%2 == 1 {
+			buf[i/2] = nibble
+		} else {
+			buf[i/2] |= nibble << 4
+		}
+	}
+}
+
+// limits returns range of integer encoding
+func (c codec) limits() (int64, int64) {
+	switch c.encoding {
+	case encInt16:
+		return minInt16, maxInt16
+	case encUint32:
+		return 0, math.MaxUint32
+	case encInt32:
+		return math.MinInt32, math.MaxInt32
+	case encBCD:
+		return 0, 9999
+	case encBCD32:
+		return 0, 99999999
+	default:
+		return minUint16, maxUint16
+	}
+}
+
+// clampValue saturates value to encoding range
+// it returns true if value was changed
+func (c codec) clampValue(value float64) (float64, bool) {
+	min, max := -math.MaxFloat32, math.MaxFloat32
+
+	if c.encoding != encFloat32 {
+		imin, imax := c.limits()
+		min, max = float64(imin), float64(imax)
+	}
+
+	switch {
+	case value < min:
+		return min, true
+	case value > max:
+		return max, true
+	default:
+		return value, false
+	}
+}
+
+// encodeValue puts value to buf (big endian)
+// it returns true if value was clamped
+func (c codec) encodeValue(k string, v interface{}, buf []byte) (bool, error) {
+	num, err := toNumber(k, v)
+	if err != nil {
+		return false, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of numbers")
+	}
+
+	clamped := false
+
+	if c.clamp {
+		value, err := num.Float64()
+		if err != nil && !math.IsInf(value, 0) {
+			return false, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of numbers")
+		}
+
+		if value, clamped = c.clampValue(value); clamped {
+			num = json.Number(strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+
+	if c.encoding == encFloat32 {
+		value, err := num.Float64()
+		if err != nil || math.IsInf(value, 0) || math.IsNaN(value) ||
+			math.Abs(value) > math.MaxFloat32 {
+			return false, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of float32")
+		}
+
+		binary.BigEndian.PutUint32(buf, math.Float32bits(float32(value)))
+
+		return clamped, nil
+	}
+
+	value, err := toInt64(k, num)
+	if err != nil {
+		return false, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of "+c.encoding)
+	}
+
+	min, max := c.limits()
+
+	if !(min <= value && value <= max) {
+		return false, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of "+c.encoding)
+	}
+
+	switch {
+	case c.encoding == encBCD || c.encoding == encBCD32:
+		encodeBCD(uint32(value), buf)
+	case len(buf) == 2:
+		binary.BigEndian.PutUint16(buf, uint16(value))
+	default:
+		binary.BigEndian.PutUint32(buf, uint32(value))
+	}
+
+	return clamped, nil
+}
+
+// encode converts values to registers bytes in wire order
+func (c codec) encode(k string, values []interface{}) ([]byte, error) {
+	res, _, err := c.encodeClamped(k, values)
+	return res, err
+}
+
+// encodeClamped is like encode but also returns true if any value was clamped
+func (c codec) encodeClamped(k string, values []interface{}) ([]byte, bool, error) {
+	switch c.encoding {
+	case encRaw:
+		return nil, false, errRawEncoding
+	case encFixed, encFixed32:
+		return nil, false, errFixedEncoding
+	case encTimestamp:
+		return nil, false, errTimestampEncoding
+	case encBytes:
+		return nil, false, errBytesEncoding
+	}
+
+	size := c.registers() * 2
+	res := make([]byte, len(values)*size)
+	clamped := false
+
+	for i, v := range values {
+		buf := res[i*size : (i+1)*size]
+
+		ok, err := c.encodeValue(k, v, buf)
+		if err != nil {
+			return nil, false, err
+		}
+
+		clamped = clamped || ok
+
+		c.order(buf)
+	}
+
+	return res, clamped, nil
+}
+
+type clampResult struct {
+	Result  interface{} `json:"result"`
+	Clamped bool        `json:"clamped"`
+}
@@ -0,0 +1,285 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestWriteEncodedRegisters(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	write := func(encoding string, value ...interface{}) error {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-write-multiple-registers",
+			Params: objx.Map{"address": num("0"), "value": value, "encoding": encoding},
+		})
+
+		return err
+	}
+
+	if err := write("float32", num("1.5"), num("-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []uint16{0x3FC0, 0x0000, 0xC000, 0x0000}; !reflect.DeepEqual(m.holding[:4], expected) {
+		t.Errorf("expected float32 registers %x but got %x", expected, m.holding[:4])
+	}
+
+	if err := write("int32", num("-70000")); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []uint16{0xFFFE, 0xEE90}; !reflect.DeepEqual(m.holding[:2], expected) {
+		t.Errorf("expected int32 registers %x but got %x", expected, m.holding[:2])
+	}
+
+	if err := write("uint32", num("4294967295")); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []uint16{0xFFFF, 0xFFFF}; !reflect.DeepEqual(m.holding[:2], expected) {
+		t.Errorf("expected uint32 registers %x but got %x", expected, m.holding[:2])
+	}
+
+	sent := len(m.pdus)
+
+	for _, tc := range []struct {
+		encoding string
+		value    json.Number
+	}{
+		{"uint32", num("-1")},
+		{"uint32", num("4294967296")},
+		{"int32", num("2147483648")},
+		{"int32", num("1.5")},
+	} {
+		if err := write(tc.encoding, tc.value); err == nil {
+			t.Errorf("%s %s: expected out of range error", tc.encoding, tc.value)
+		}
+	}
+
+	if len(m.pdus) != sent {
+		t.Error("values out of range shouldn't be sent")
+	}
+}
+
+func TestDefaultOrder(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[1] = 0x0200, 0x0100
+	srv := newMockService(m, DefaultByteOrder("little"), DefaultWordOrder("little"))
+
+	read := func(params objx.Map) interface{} {
+		t.Helper()
+
+		params["address"], params["quantity"], params["encoding"] = num("0"), num("2"), "uint32"
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	// registers 0x0200 0x0100 with swapped bytes and words are 0x00010002
+	if res := read(objx.Map{}); !reflect.DeepEqual(res, []interface{}{uint32(0x00010002)}) {
+		t.Errorf("expected value of default orders but got %v", res)
+	}
+
+	// request params override defaults
+	res := read(objx.Map{"byte_order": "big", "word_order": "big"})
+	if !reflect.DeepEqual(res, []interface{}{uint32(0x02000100)}) {
+		t.Errorf("expected value of request orders but got %v", res)
+	}
+
+	// and defaults are used for writes too
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": num("4"), "value": num("1"), "encoding": "uint32"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{0x10, 0x00, 0x04, 0x00, 0x02, 0x04, 0x01, 0x00, 0x00, 0x00})
+}
+
+func TestReadVerbose(t *testing.T) {
+	m := &mockSlave{}
+	m.inputs[4], m.inputs[5] = 0xFFFE, 0x0100
+
+	res, err := newMockService(m, DefaultByteOrder("little")).Call(jsonrpc.Request{
+		Method: "modbus-read-input",
+		Params: objx.Map{"address": num("4"), "quantity": num("2"), "encoding": "int16", "verbose": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok := res.(verboseResult)
+	if !ok {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	// values are decoded by orders of service, raw data is as received
+	if !reflect.DeepEqual(v.Values, []interface{}{int16(-257), int16(1)}) || v.Encoding != "int16" ||
+		v.ByteOrder != "little" || v.WordOrder != "big" || !bytes.Equal(v.Raw, []byte{0xFF, 0xFE, 0x01, 0x00}) {
+		t.Errorf("unexpected verbose result %+v", v)
+	}
+
+	// verbose result isn't used without flag
+	res, err = newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-input",
+		Params: objx.Map{"address": num("4"), "quantity": num("1"), "verbose": false},
+	})
+	if err != nil || !reflect.DeepEqual(res, []interface{}{uint16(0xFFFE)}) {
+		t.Errorf("unexpected result %v (%v)", res, err)
+	}
+}
+
+// overCountSlave reports 2 bytes more in byte count of read responses than it sends
+type overCountSlave struct {
+	*mockSlave
+}
+
+func (m overCountSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.mockSlave.Send(adu)
+	if err == nil && res[7]&0x80 == 0 {
+		res[8] += 2
+	}
+
+	return res, err
+}
+
+func TestVerboseByteCounts(t *testing.T) {
+	read := jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("2"), "verbose": true},
+	}
+
+	res, err := newMockService(&mockSlave{}).Call(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := res.(verboseResult); v.ReportedBytes != 4 || v.ReceivedBytes != 4 {
+		t.Errorf("unexpected byte counts %+v", v)
+	}
+
+	srv := New(overCountSlave{&mockSlave{}}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	// both counts are reported with error (data of jsonrpc error is private)
+	_, err = srv.Call(read)
+	if desc := fmt.Sprintf("%#v", err); !strings.Contains(desc, `"reported_bytes":6`) ||
+		!strings.Contains(desc, `"received_bytes":4`) {
+		t.Errorf("expected error with byte counts but got %s", desc)
+	}
+}
+
+// delaySlave answers after delay
+type delaySlave struct {
+	*mockSlave
+	delay time.Duration
+}
+
+func (m delaySlave) Send(adu []byte) ([]byte, error) {
+	time.Sleep(m.delay)
+	return m.mockSlave.Send(adu)
+}
+
+func TestVerboseSLA(t *testing.T) {
+	srv := New(delaySlave{&mockSlave{}, 20 * time.Millisecond}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	for _, tc := range []struct {
+		sla    string
+		within bool
+	}{
+		{"1000", true},
+		{"5", false},
+	} {
+		res, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"address": num("0"), "quantity": num("1"), "verbose": true, "sla_ms": num(tc.sla)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v := res.(verboseResult); v.WithinSLA == nil || *v.WithinSLA != tc.within {
+			t.Errorf("sla %s: expected within_sla %v but got %+v", tc.sla, tc.within, v.WithinSLA)
+		}
+	}
+
+	// within_sla isn't set without sla_ms
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "verbose": true},
+	})
+	if err != nil || res.(verboseResult).WithinSLA != nil {
+		t.Errorf("unexpected result %+v (%v)", res, err)
+	}
+}
+
+func TestReadRaw(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[1] = 0x0102, 0x0304
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "raw"},
+	})
+	if err != nil || !reflect.DeepEqual(res, []byte{1, 2, 3, 4}) {
+		t.Errorf("unexpected raw result %v (%v)", res, err)
+	}
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "raw", "compress": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, ok := res.(compressedResult)
+	if !ok || !c.Compressed {
+		t.Fatalf("unexpected compressed result %+v", res)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(c.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := ioutil.ReadAll(r); err != nil || !bytes.Equal(b, []byte{1, 2, 3, 4}) {
+		t.Errorf("unexpected decompressed data %v (%v)", b, err)
+	}
+}
@@ -0,0 +1,131 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// endiannessCandidate is value decoded with one of byte and word orders
+type endiannessCandidate struct {
+	ByteOrder string      `json:"byte_order"`
+	WordOrder string      `json:"word_order"`
+	Value     interface{} `json:"value"`
+	Match     bool        `json:"match"`
+}
+
+type endiannessResult struct {
+	// orders which reproduce expected value (empty if none)
+	Matches []endiannessCandidate `json:"matches"`
+	// value decoded with each of four orders
+	Candidates []endiannessCandidate `json:"candidates"`
+	// registers as received
+	RawRegisters []uint16 `json:"raw_registers"`
+}
+
+// detectEndianness reads 32-bit value (float32 by default) which is known to operator
+// and reports which byte and word orders decode it as expected value
+func (s Service) detectEndianness(params objx.Map) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Get("value").IsNil() {
+		return nil, emptyErr("value")
+	}
+
+	expected, err := getFloat64(params, "value", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := params.Get("encoding").Str(encFloat32)
+
+	switch encoding {
+	case encFloat32, encInt32, encUint32:
+	default:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "encoding should be float32, int32 or uint32").
+			AddData("v", encoding)
+	}
+
+	function := byte(modbus.FuncCodeReadHoldingRegisters)
+	if params.Get("input").Bool() {
+		function = modbus.FuncCodeReadInputRegisters
+	}
+
+	// value should be fresh
+	s.noCache = true
+
+	res, err := s.readBlock(slaveID, function, addr, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) != 4 {
+		return nil, truncatedErr(4, len(res))
+	}
+
+	result := endiannessResult{Matches: []endiannessCandidate{}, RawRegisters: rawRegisters(res)}
+
+	for _, order := range []codec{
+		{encoding: encoding},
+		{encoding: encoding, wordSwap: true},
+		{encoding: encoding, byteSwap: true},
+		{encoding: encoding, byteSwap: true, wordSwap: true},
+	} {
+		values, err := order.decode(res)
+		if err != nil {
+			return nil, err
+		}
+
+		c := endiannessCandidate{
+			ByteOrder: orderName(order.byteSwap),
+			WordOrder: orderName(order.wordSwap),
+			Value:     values[0],
+			Match:     endiannessMatch(values[0], expected),
+		}
+
+		if c.Match {
+			result.Matches = append(result.Matches, c)
+		}
+
+		result.Candidates = append(result.Candidates, c)
+	}
+
+	return result, nil
+}
+
+// endiannessMatch reports whether decoded value equals expected one
+// (float32 values are compared with precision of float32)
+func endiannessMatch(v interface{}, expected float64) bool {
+	if f, ok := v.(float32); ok {
+		return f == float32(expected)
+	}
+
+	f, _ := toFloat64(v)
+
+	return f == expected
+}
@@ -0,0 +1,167 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// max quantity of 32-bit registers in one enron read (byte count is one byte)
+const maxEnronRegisters = 62
+
+// getEnronCodec returns codec of 32-bit values (uint32 by default)
+func (s Service) getEnronCodec(params objx.Map) (codec, error) {
+	if params.Get("encoding").IsNil() {
+		params = params.Copy()
+		params["encoding"] = encUint32
+	}
+
+	c, err := s.getCodec(params)
+	if err != nil {
+		return codec{}, err
+	}
+
+	if c.registers() != 2 {
+		return codec{}, jsonrpc.ErrInvalidParams.AddData("msg", "enron registers support 32-bit encodings only").
+			AddData("v", c.encoding)
+	}
+
+	return c, nil
+}
+
+// readEnron reads registers of Enron (Daniels) convention
+// where each register address holds 32-bit value
+//
+// Request:
+//
+//	Function code         : 1 byte (0x03 or 0x04)
+//	Starting address      : 2 bytes
+//	Quantity of registers : 2 bytes
+//
+// Response:
+//
+//	Function code         : 1 byte (0x03 or 0x04)
+//	Byte count            : 1 byte
+//	Register value        : N x 4 bytes
+func (s Service) readEnron(params objx.Map, function byte) (interface{}, error) {
+	addr, quantity, err := getAddrAndQuantity(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity < 1 || quantity > maxEnronRegisters {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "quantity should be between 1 and 62 in enron mode")
+	}
+
+	c, err := s.getEnronCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.withResponseLength(2+int(quantity)*4).send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: function,
+		Data:         dataBlock(addr, quantity),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := res.Data[1:]
+	if int(res.Data[0]) != len(values) || len(values) != int(quantity)*4 {
+		return nil, truncatedErr(int(quantity)*4, len(values))
+	}
+
+	return c.decode(values)
+}
+
+// writeEnron writes 32-bit registers of Enron convention
+// single value is written by FC6 and array by FC16 (quantity is count of values)
+func (s Service) writeEnron(params objx.Map, single bool) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.getEnronCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := getValues(params, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	if single && len(values) != 1 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "value should be single number")
+	}
+
+	// request has address, quantity and byte count before values
+	if len(values) > maxEnronRegisters-1 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "too many values for enron write")
+	}
+
+	value, err := c.encode("value", values)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := uint16(len(values))
+
+	pdu := &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeWriteSingleRegister,
+		Data:         append(dataBlock(addr), value...),
+	}
+	// echo of address and value
+	expected := pdu.Data
+
+	if !single {
+		pdu.FunctionCode = modbus.FuncCodeWriteMultipleRegisters
+		pdu.Data = append(append(dataBlock(addr, quantity), byte(len(value))), value...)
+		// echo of address and quantity
+		expected = dataBlock(addr, quantity)
+	}
+
+	res, err := s.withResponseLength(1+len(expected)).send(slaveID, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, quantity)
+
+	if !bytes.Equal(res.Data, expected) {
+		return nil, fmt.Errorf("modbus: response data '% x' does not match request '% x'", res.Data, expected)
+	}
+
+	return []uint16{quantity}, nil
+}
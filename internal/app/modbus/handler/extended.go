@@ -0,0 +1,130 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// ExtendedAddressing enables modbus-read-extended method
+// which reads registers with 32-bit address by vendor function code
+func ExtendedAddressing(functionCode byte) Option {
+	return func(s *Service) {
+		s.extendedFunction = functionCode
+	}
+}
+
+// expectSender is implemented by transporters which need response length
+// to read non-standard responses (e.g. rtu)
+type expectSender interface {
+	SendExpect(adu []byte, length int) ([]byte, error)
+}
+
+// expectTransporter sends with explicit response length if transporter supports it
+type expectTransporter struct {
+	modbus.Transporter
+	// rtu frame is slave id + pdu + crc
+	pduLength int
+}
+
+func (t expectTransporter) Send(adu []byte) ([]byte, error) {
+	if es, ok := t.Transporter.(expectSender); ok {
+		return es.SendExpect(adu, 1+t.pduLength+2)
+	}
+
+	return t.Transporter.Send(adu)
+}
+
+// withResponseLength returns service which expects responses with pdu of length
+func (s Service) withResponseLength(pduLength int) Service {
+	return s.withLayer(func(t modbus.Transporter) modbus.Transporter {
+		return expectTransporter{t, pduLength}
+	})
+}
+
+// readExtended reads registers from extended (32-bit) address space
+//
+// Request:
+//
+//	Function code         : 1 byte (vendor specific)
+//	Starting address      : 4 bytes
+//	Quantity of registers : 2 bytes
+//
+// Response:
+//
+//	Function code         : 1 byte (vendor specific)
+//	Byte count            : 1 byte
+//	Register value        : N* x 2 bytes
+func (s Service) readExtended(params objx.Map) (interface{}, error) {
+	if s.extendedFunction == 0 {
+		return nil, jsonrpc.ErrInvalidRequest.AddData("msg", "extended addressing disabled")
+	}
+
+	addr, err := getInt64(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	if !(0 <= addr && addr <= math.MaxUint32) {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "address should be uint32")
+	}
+
+	quantity, err := getUint16(params, "quantity")
+	if err != nil {
+		return nil, err
+	}
+
+	if quantity < 1 || quantity > maxReadRegisters {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "quantity should be between 1 and 125")
+	}
+
+	c, err := s.getCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 6)
+	binary.BigEndian.PutUint32(data, uint32(addr))
+	binary.BigEndian.PutUint16(data[4:], quantity)
+
+	// rtu transport can't calculate length of vendor function response
+	res, err := s.withResponseLength(2+int(quantity)*2).send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: s.extendedFunction,
+		Data:         data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := res.Data[1:]
+	if int(res.Data[0]) != len(values) || len(values) != int(quantity)*2 {
+		return nil, truncatedErr(int(quantity)*2, len(values))
+	}
+
+	return c.decode(values)
+}
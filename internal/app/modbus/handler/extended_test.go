@@ -0,0 +1,94 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// extendedSlave answers vendor function of 32-bit addresses by holding registers
+// at address - extendedBase and records response length expected from rtu transport
+type extendedSlave struct {
+	*mockSlave
+	expected int
+}
+
+const (
+	extendedFunction = 0x41
+	extendedBase     = 0x10000
+)
+
+func (m *extendedSlave) Send(adu []byte) ([]byte, error) {
+	pdu := adu[7:]
+	if pdu[0] != extendedFunction {
+		return m.mockSlave.Send(adu)
+	}
+
+	m.pdus = append(m.pdus, append([]byte{}, pdu...))
+
+	addr := binary.BigEndian.Uint32(pdu[1:]) - extendedBase
+	quantity := binary.BigEndian.Uint16(pdu[5:])
+
+	return simResponse(adu, append([]byte{extendedFunction},
+		simRegisters(m.holding[addr:addr+uint32(quantity)])...), 0), nil
+}
+
+func (m *extendedSlave) SendExpect(adu []byte, length int) ([]byte, error) {
+	m.expected = length
+	return m.Send(adu)
+}
+
+func TestReadExtended(t *testing.T) {
+	m := &extendedSlave{mockSlave: &mockSlave{}}
+	m.holding[4], m.holding[5] = 0x0001, 0x0002
+
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, ExtendedAddressing(extendedFunction))
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-extended",
+		Params: objx.Map{"address": num("65540"), "quantity": num("2"), "encoding": "uint32"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, []interface{}{uint32(0x00010002)}) {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	m.assertPDU(t, []byte{extendedFunction, 0, 1, 0, 4, 0, 2})
+
+	// slave id, function code, byte count, 2 registers and crc
+	if m.expected != 9 {
+		t.Errorf("expected rtu response of 9 bytes but got %d", m.expected)
+	}
+
+	_, err = newMockService(&mockSlave{}).Call(jsonrpc.Request{
+		Method: "modbus-read-extended",
+		Params: objx.Map{"address": num("65540"), "quantity": num("2")},
+	})
+	if err == nil {
+		t.Error("expected error of disabled extended addressing")
+	}
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// methods which accept slave_ids param
+var fanOutMethods = map[string]bool{ // nolint: gochecknoglobals
+	"modbus-write-coil":     true,
+	"modbus-write-register": true,
+}
+
+// fanOut executes the same write on each slave of slave_ids param sequentially
+// (slave_ids overrides slave_id) and returns per slave results
+// failed write doesn't stop writes of next slaves
+func (s Service) fanOut(req jsonrpc.Request) (interface{}, error) {
+	ids, err := getArray(req.Params, "slave_ids")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, emptyErr("slave_ids")
+	}
+
+	s, err = s.withRetryBudget(req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]multiItemResult, len(ids))
+
+	for i, id := range ids {
+		// nil (not deleted) slave_ids isn't restored from defaults in nested call
+		params := req.Params.Copy()
+		params["slave_ids"] = nil
+		params["slave_id"] = id
+
+		results[i].Method = req.Method
+
+		// invalid slave id is reported by nested call
+		if slaveID, err := getSlaveID(params); err == nil {
+			results[i].SlaveID = slaveID
+		}
+
+		s.callItem(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params}, &results[i])
+	}
+
+	return results, nil
+}
+
+// isFanOut reports whether request should be executed on several slaves
+func isFanOut(method string, params objx.Map) bool {
+	return fanOutMethods[method] && !params.Get("slave_ids").IsNil()
+}
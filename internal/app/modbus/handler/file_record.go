@@ -0,0 +1,257 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+const (
+	fileRecordReferenceType = 6
+	fileRecordMaxNumber     = 0x270F
+	// max byte count of file record request and response
+	fileRecordMaxBytes = 0xF5
+	// reference type + file number + record number + record length
+	fileSubRequestSize = 7
+)
+
+type fileRecord struct {
+	FileNumber   uint16   `json:"file_number"`
+	RecordNumber uint16   `json:"record_number"`
+	Data         []uint16 `json:"data"`
+}
+
+func getFileRecordParams(params objx.Map) (file, record uint16, err error) {
+	file, err = getUint16(params, "file_number")
+	if err != nil {
+		return
+	}
+
+	if file == 0 {
+		err = jsonrpc.ErrInvalidParams.AddData("msg", "file_number should be greater than 0")
+		return
+	}
+
+	record, err = getUint16(params, "record_number")
+	if err != nil {
+		return
+	}
+
+	if record > fileRecordMaxNumber {
+		err = jsonrpc.ErrInvalidParams.AddData("msg", "record_number should be less than 10000")
+		return
+	}
+
+	return
+}
+
+func getFileRecords(params objx.Map) ([]objx.Map, error) {
+	items, err := getArray(params, "records")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "records should not be empty")
+	}
+
+	res := make([]objx.Map, 0, len(items))
+
+	for _, v := range items {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "records should be array of objects")
+		}
+
+		res = append(res, item)
+	}
+
+	return res, nil
+}
+
+// Request:
+//
+//	Function code         : 1 byte (0x14)
+//	Byte count            : 1 byte
+//	Sub-requests          : N x 7 bytes
+//	  Reference type      : 1 byte (0x06)
+//	  File number         : 2 bytes
+//	  Record number       : 2 bytes
+//	  Record length       : 2 bytes
+//
+// Response:
+//
+//	Function code         : 1 byte (0x14)
+//	Byte count            : 1 byte
+//	Sub-responses         : N x (2 + length x 2) bytes
+//	  Response length     : 1 byte
+//	  Reference type      : 1 byte (0x06)
+//	  Record data         : length x 2 bytes
+func (s Service) readFileRecord(params objx.Map) (interface{}, error) {
+	records, err := getFileRecords(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records)*fileSubRequestSize > fileRecordMaxBytes {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "too many records")
+	}
+
+	result := make([]fileRecord, 0, len(records))
+	lengths := make([]uint16, 0, len(records))
+	pdu := make([]byte, 1, 1+len(records)*fileSubRequestSize)
+	pdu[0] = byte(len(records) * fileSubRequestSize)
+	respSize := 0
+
+	for _, r := range records {
+		file, record, err := getFileRecordParams(r)
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := getUint16(r, "record_length")
+		if err != nil {
+			return nil, err
+		}
+
+		respSize += 2 + int(length)*2
+		if length == 0 || respSize > fileRecordMaxBytes {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "wrong record_length").
+				AddData("v", length)
+		}
+
+		pdu = append(pdu, fileRecordReferenceType)
+		pdu = append(pdu, dataBlock(file, record, length)...)
+
+		result = append(result, fileRecord{FileNumber: file, RecordNumber: record})
+		lengths = append(lengths, length)
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeReadFileRecord,
+		Data:         pdu,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	count := int(res.Data[0])
+	if count != len(res.Data)-1 || count != respSize {
+		return nil, fmt.Errorf("modbus: response data size '%v' does not match count '%v'",
+			len(res.Data)-1, count)
+	}
+
+	data := res.Data[1:]
+
+	for i, length := range lengths {
+		respLength := int(data[0])
+		if respLength != 1+int(length)*2 {
+			return nil, fmt.Errorf("modbus: sub-response length '%v' does not match expected '%v'",
+				respLength, 1+int(length)*2)
+		}
+
+		if data[1] != fileRecordReferenceType {
+			return nil, fmt.Errorf("modbus: sub-response reference type '%v' does not match '%v'",
+				data[1], fileRecordReferenceType)
+		}
+
+		result[i].Data = parseResult(data[2 : 1+respLength])
+		data = data[1+respLength:]
+	}
+
+	return result, nil
+}
+
+// Request:
+//
+//	Function code         : 1 byte (0x15)
+//	Byte count            : 1 byte
+//	Reference type        : 1 byte (0x06)
+//	File number           : 2 bytes
+//	Record number         : 2 bytes
+//	Record length         : 2 bytes
+//	Record data           : length x 2 bytes
+//
+// Response: echo of request
+func (s Service) writeFileRecord(params objx.Map) (interface{}, error) {
+	file, record, err := getFileRecordParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := getRegistersBytes(params, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 || fileSubRequestSize+len(value) > fileRecordMaxBytes {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "wrong value length").
+			AddData("v", len(value)/2)
+	}
+
+	length, err := getUint16(params, "record_length", int64(len(value)/2))
+	if err != nil {
+		return nil, err
+	}
+
+	if int(length) != len(value)/2 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "record_length and value length mismatch").
+			AddData("params", []string{"record_length", "value"}).
+			AddData("record_length", length).
+			AddData("expected", len(value)/2)
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pdu := make([]byte, 0, 1+fileSubRequestSize+len(value))
+	pdu = append(pdu, byte(fileSubRequestSize+len(value)), fileRecordReferenceType)
+	pdu = append(pdu, dataBlock(file, record, length)...)
+	pdu = append(pdu, value...)
+
+	res, err := s.send(slaveID, &modbus.ProtocolDataUnit{
+		FunctionCode: modbus.FuncCodeWriteFileRecord,
+		Data:         pdu,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(res.Data, pdu) {
+		return nil, fmt.Errorf("modbus: response data '% x' does not match request '% x'",
+			res.Data, pdu)
+	}
+
+	return fileRecord{
+		FileNumber:   file,
+		RecordNumber: record,
+		Data:         parseResult(res.Data[1+fileSubRequestSize:]),
+	}, nil
+}
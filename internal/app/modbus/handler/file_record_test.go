@@ -0,0 +1,106 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestReadFileRecord(t *testing.T) {
+	m := &mockSlave{}
+	copy(m.file(4)[1:], []uint16{0x0DFE, 0x0020})
+	m.file(3)[9] = 0x33CD
+
+	res, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-file-record",
+		Params: objx.Map{"records": []interface{}{
+			map[string]interface{}{"file_number": num("4"), "record_number": num("1"), "record_length": num("2")},
+			map[string]interface{}{"file_number": num("3"), "record_number": num("9"), "record_length": num("1")},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadFileRecord, 0x0E,
+		0x06, 0x00, 0x04, 0x00, 0x01, 0x00, 0x02,
+		0x06, 0x00, 0x03, 0x00, 0x09, 0x00, 0x01,
+	})
+
+	expected := []fileRecord{
+		{FileNumber: 4, RecordNumber: 1, Data: []uint16{0x0DFE, 0x0020}},
+		{FileNumber: 3, RecordNumber: 9, Data: []uint16{0x33CD}},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+
+	// record beyond file end is answered by exception
+	_, err = newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-file-record",
+		Params: objx.Map{"records": []interface{}{
+			map[string]interface{}{"file_number": num("4"), "record_number": num("9999"), "record_length": num("2")},
+		}},
+	})
+
+	var mbErr *modbus.ModbusError
+	if !errors.As(err, &mbErr) || mbErr.ExceptionCode != modbus.ExceptionCodeIllegalDataAddress {
+		t.Errorf("expected illegal data address exception but got %v", err)
+	}
+}
+
+func TestWriteFileRecord(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-file-record",
+		Params: objx.Map{"file_number": num("4"), "record_number": num("7"), "value": []interface{}{num("1711"), num("1214")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeWriteFileRecord, 0x0B,
+		0x06, 0x00, 0x04, 0x00, 0x07, 0x00, 0x02, 0x06, 0xAF, 0x04, 0xBE,
+	})
+
+	expected := fileRecord{FileNumber: 4, RecordNumber: 7, Data: []uint16{0x06AF, 0x04BE}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+
+	if records := m.file(4)[7:9]; !reflect.DeepEqual(records, []uint16{0x06AF, 0x04BE}) {
+		t.Errorf("unexpected file records %x", records)
+	}
+
+	// record_length should match value
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-file-record",
+		Params: objx.Map{"file_number": num("4"), "record_number": num("7"), "record_length": num("3"), "value": []interface{}{num("1")}},
+	})
+	if err == nil || len(m.pdus) != 1 {
+		t.Errorf("expected error of record_length without request but got %v", err)
+	}
+}
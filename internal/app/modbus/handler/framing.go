@@ -0,0 +1,91 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// Framing registers packager which can be selected by name
+// with framing request param or by SlaveFraming option
+// main transport should be able to carry frames of this packager
+// unless framing has own transport (see FramingConnection)
+func Framing(name string, pGetter PackagerFn) Option {
+	return func(s *Service) {
+		if s.framings == nil {
+			s.framings = make(map[string]PackagerFn)
+		}
+
+		s.framings[name] = pGetter
+	}
+}
+
+// FramingConnection sets transport of framing (e.g. serial port of rtu segment),
+// requests with the framing go to it instead of the main transport
+func FramingConnection(name string, t modbus.Transporter) Option {
+	return func(s *Service) {
+		if s.framingConnections == nil {
+			s.framingConnections = make(map[string]slaveConnection)
+		}
+
+		s.framingConnections[name] = s.newConnection(t)
+	}
+}
+
+// SlaveFraming sets framing used by default for given slave
+// framing should be registered with Framing option
+func SlaveFraming(slaveID byte, name string) Option {
+	return func(s *Service) {
+		if s.slaveFramings == nil {
+			s.slaveFramings = make(map[byte]string)
+		}
+
+		s.slaveFramings[slaveID] = name
+	}
+}
+
+// withFraming returns service which uses packager selected by framing param
+// or by slave framing (if it's configured)
+func (s Service) withFraming(params objx.Map) (Service, error) {
+	name := params.Get("framing").Str()
+
+	if name == "" {
+		slaveID, err := getSlaveID(params)
+		if err != nil {
+			return s, err
+		}
+
+		name = s.slaveFramings[slaveID]
+	}
+
+	if name == "" {
+		return s, nil
+	}
+
+	pGetter, ok := s.framings[name]
+	if !ok {
+		return s, jsonrpc.ErrInvalidParams.AddData("msg", "unknown framing").AddData("v", name)
+	}
+
+	s.packagerGetter = pGetter
+	s.framing = name
+
+	return s, nil
+}
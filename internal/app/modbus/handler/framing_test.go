@@ -0,0 +1,98 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// rtuSlave is mockSlave on rtu line
+type rtuSlave struct {
+	*mockSlave
+}
+
+func (m *rtuSlave) Send(adu []byte) ([]byte, error) {
+	p := modbus.NewRTUPackager(adu[0])
+
+	pdu, err := p.Decode(adu)
+	if err != nil {
+		return nil, err
+	}
+
+	tcp, err := modbus.NewTCPPackager(adu[0]).Encode(pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.mockSlave.Send(tcp)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Encode(&modbus.ProtocolDataUnit{FunctionCode: res[7], Data: res[8:]})
+}
+
+func TestFramingConnection(t *testing.T) {
+	main := &mockSlave{}
+	main.holding[0] = 1
+
+	segment := &rtuSlave{&mockSlave{}}
+	segment.holding[0] = 2
+
+	srv := newMockService(main,
+		Framing("tcp", func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }),
+		Framing("rtu", func(s byte) modbus.Packager { return modbus.NewRTUPackager(s) }),
+		FramingConnection("rtu", segment),
+		SlaveFraming(2, "rtu"),
+	)
+
+	for _, tc := range []struct {
+		params   objx.Map
+		expected uint16
+	}{
+		{objx.Map{"slave_id": num("1")}, 1},
+		{objx.Map{"slave_id": num("2")}, 2},
+		{objx.Map{"slave_id": num("1"), "framing": "rtu"}, 2},
+		{objx.Map{"slave_id": num("2"), "framing": "tcp"}, 1},
+	} {
+		params := tc.params.Copy()
+		params["address"], params["quantity"] = num("0"), num("1")
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+		if err != nil {
+			t.Fatalf("%v: %v", tc.params, err)
+		}
+
+		if !reflect.DeepEqual(res, []interface{}{tc.expected}) {
+			t.Errorf("%v: expected %d but got %v", tc.params, tc.expected, res)
+		}
+	}
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "framing": "ascii"},
+	})
+	if err == nil {
+		t.Error("expected error of unknown framing")
+	}
+}
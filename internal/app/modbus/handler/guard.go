@@ -0,0 +1,144 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// methods which accept guard param
+var guardMethods = map[string]bool{ // nolint: gochecknoglobals
+	"modbus-write-coil":               true,
+	"modbus-write-multiple-coils":     true,
+	"modbus-write-register":           true,
+	"modbus-write-multiple-registers": true,
+}
+
+// writeGuard is register which should match value under mask before write
+type writeGuard struct {
+	address uint16
+	value   uint16
+	mask    uint16
+	// input register instead of holding one
+	input bool
+}
+
+// getWriteGuard returns guard param (nil if not passed)
+// its address is in the same base as request address
+func (s Service) getWriteGuard(params objx.Map) (*writeGuard, error) {
+	v := params.Get("guard")
+	if v.IsNil() {
+		return nil, nil
+	}
+
+	if !v.IsMSI() && !v.IsObjxMap() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "guard should be object with address, value and mask")
+	}
+
+	gp := v.ObjxMap()
+
+	addr, err := getInt64(gp, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := getInt64(params, "address_base", s.addressBase)
+	if err != nil {
+		return nil, err
+	}
+
+	addr -= base
+	if !(minUint16 <= addr && addr <= maxUint16) {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "guard address should be register address").
+			AddData("v", gp.Get("address").Data())
+	}
+
+	g := &writeGuard{address: uint16(addr), input: gp.Get("input").Bool()}
+
+	g.value, err = getUint16(gp, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	g.mask, err = getUint16(gp, "mask", maxUint16)
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func guardErr(g *writeGuard, actual uint16) error {
+	return jsonrpc.ErrServer.AddData("msg", "guard failed").AddData("address", g.address).
+		AddData("expected", g.value).AddData("mask", g.mask).AddData("actual", actual).SetCode(-32098)
+}
+
+// withGuard reads guard register of guard param and takes bus lock of slave until returned
+// release is called, so write of returned service goes right after guard read
+// (atomic for calls of this service but NOT for other masters on the bus)
+// it returns error with actual guard value if guard doesn't match
+func (s Service) withGuard(params objx.Map) (Service, func(), error) {
+	release := func() {}
+
+	g, err := s.getWriteGuard(params)
+	if err != nil || g == nil {
+		return s, release, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return s, nil, err
+	}
+
+	if _, bus := s.connection(slaveID); bus != nil {
+		if err := bus.acquire(); err != nil {
+			return s, nil, err
+		}
+
+		release = bus.release
+		s = s.withBusHeld(slaveID)
+	}
+
+	cli := s.getClient(slaveID)
+	read := cli.ReadHoldingRegisters
+
+	if g.input {
+		read = cli.ReadInputRegisters
+	}
+
+	// cache is bypassed, guard should be fresh
+	res, err := read(g.address, 1)
+	if err != nil {
+		release()
+		return s, nil, err
+	}
+
+	if len(res) != 2 {
+		release()
+		return s, nil, truncatedErr(2, len(res))
+	}
+
+	actual := parseResult(res)[0]
+	if actual&g.mask != g.value&g.mask {
+		release()
+		return s, nil, guardErr(g, actual)
+	}
+
+	return s, release, nil
+}
@@ -17,9 +17,14 @@
 package handler
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"math"
+	"strconv"
+	"time"
 
 	"github.com/stretchr/objx"
 
@@ -30,19 +35,666 @@ import (
 type PackagerFn func(byte) modbus.Packager
 
 type Service struct {
+	// done on service shutdown
+	ctx            context.Context
 	transport      modbus.Transporter
 	packagerGetter PackagerFn
+	// serializes transactions on the bus
+	bus *busLock
+	// silent interval after transaction
+	frameDelay time.Duration
+	// default orders used when request has no byte_order/word_order
+	byteOrder string
+	wordOrder string
+	// nil if cache disabled
+	cache *readCache
+	// nil if coalescing of identical reads disabled
+	flights *readFlights
+	// per slave rate limiters
+	limiters map[byte]*rateLimiter
+	// rate limiter of main bus transactions (nil if not limited)
+	busLimiter *rateLimiter
+	// max quantity allowed in one request (0 means protocol limit only)
+	maxQuantity uint16
+	// packagers available by framing name and default framing of slaves
+	framings      map[string]PackagerFn
+	slaveFramings map[byte]string
+	// transports of framings which main transport can't carry
+	framingConnections map[string]slaveConnection
+	// framing of current call (empty if it's the default one)
+	framing string
+	// default params of methods (request params override them)
+	defaults map[string]objx.Map
+	// 1 if addresses in requests are 1-based
+	addressBase int64
+	// register map points by name
+	points map[string]Point
+	// devices behind gateway with own register maps by name
+	devices map[string]Device
+	// vendor function code of extended addressing reads (0 if disabled)
+	extendedFunction byte
+	// slaves with disabled response checksum verification
+	skipChecksum map[byte]bool
+	// nil if retries disabled
+	retry *retryPolicy
+	// per slave overrides of timeout, frame delay and retries
+	slaveTransports map[byte]SlaveTransportConfig
+	// cumulative counters of bus transactions (nil in dry run)
+	metrics *busMetrics
+	// slaves with independent connections
+	connections map[byte]slaveConnection
+	// connections of devices with host by device name
+	deviceConnections map[string]slaveConnection
+	// slaves which transactions bypass bus lock
+	parallel map[byte]bool
+	// wrappers of slave transport (e.g. recorders of responses)
+	layers []func(modbus.Transporter) modbus.Transporter
+	// calls in progress and shutdown state
+	life *lifecycle
+	// last successfully read data used as fallback on read errors
+	lastGood *readCache
+	// previous reads of counters with detect_wrap param
+	counters *readCache
+	// results of writes by idempotency key (nil if disabled)
+	idempotency *idempotencyKeys
+	// callback of bus transactions (nil if not set)
+	trace func(TraceEvent)
+	// recorded transactions served instead of the bus (nil if not set)
+	replay *replayLog
+	// callback which checks or fixes up response frames (nil if not set)
+	validator ResponseValidatorFunc
+	// max size of response frame (0 if not limited)
+	maxResponseBytes int
+	// transactions longer than it are logged (0 disables it)
+	slowThreshold time.Duration
+	// method of current call (for logs)
+	method string
+	// reads of current call skip cache and last good fallback
+	noCache bool
+	// device param of current call (empty if not passed),
+	// it routes transactions of unit id to connection of device
+	device string
+	// max_age_ms param of current call (0 if not passed),
+	// cached reads and polled values older than it are read again
+	maxAge time.Duration
+	// default format of with_timestamp values
+	timestampFormat string
+	// requests matching any rule are denied
+	access []AccessRule
+	// active subscriptions (nil in dry run)
+	subs *subscriptions
+	// source of modbus tcp transaction ids (nil if not set)
+	transactionIDs modbus.TransactionIdSource
+	// polling of acknowledged writes by method
+	ackPolls map[string]AckPollConfig
+	// modbus-debug-call is enabled
+	debugCalls bool
+	// items of current read-multi or fan-out call get own latencies
+	itemLatency bool
+	// poll loop of points with poll_interval (nil if there are none)
+	poller *pointPoller
+	// reserved slave addresses and reads from broadcast address are rejected
+	strictSlaveIDs bool
+	// slaves which single writes are sent by multiple write functions
+	multipleWrite map[byte]bool
+	// slaves which multiple writes are accepted without address and quantity echo check
+	lenientEcho map[byte]bool
+	// slaves which verified register writes go by one FC23 transaction
+	readWriteVerify map[byte]bool
+	// timeout of establishing tcp connection (0 means transport default)
+	connectTimeout time.Duration
+	// connect_timeout param of current call (0 if not passed)
+	callConnectTimeout time.Duration
+	// locks of registers in read-modify-write (nil if disabled)
+	registerLocks *registerLocks
+	// retry budget of current batch call (nil if not limited)
+	retryBudget *retryBudget
+	// retry budget of each jsonrpc batch (zero values mean no limit)
+	batchRetries   int
+	batchRetryTime time.Duration
 }
 
-func New(transport modbus.Transporter, pGetter PackagerFn) Service {
-	return Service{transport, pGetter}
+type Option func(*Service)
+
+// DefaultByteOrder sets byte order (big or little) of registers
+// used when request has no byte_order param
+func DefaultByteOrder(order string) Option {
+	return func(s *Service) {
+		s.byteOrder = order
+	}
+}
+
+// CoalesceReads enables sharing of one bus transaction between
+// identical concurrent reads (same slave, function, address and quantity)
+func CoalesceReads(enabled bool) Option {
+	return func(s *Service) {
+		if enabled {
+			s.flights = newReadFlights()
+		}
+	}
+}
+
+// TransactionIDs sets source of modbus tcp transaction ids
+// services sharing connection should use sources with non-overlapping ranges
+// (transaction_id param still overrides it)
+func TransactionIDs(src modbus.TransactionIdSource) Option {
+	return func(s *Service) {
+		s.transactionIDs = src
+	}
+}
+
+// DefaultWordOrder sets word order (big or little) of multi register values
+// used when request has no word_order param
+func DefaultWordOrder(order string) Option {
+	return func(s *Service) {
+		s.wordOrder = order
+	}
+}
+
+// ReadCache enables cache of read results with given ttl
+// identical reads (same slave, function, address and quantity)
+// within ttl returns cached result without bus transaction
+func ReadCache(ttl time.Duration) Option {
+	return func(s *Service) {
+		if ttl > 0 {
+			s.cache = newReadCache(ttl)
+		}
+	}
+}
+
+// SlowThreshold enables warning logs and slow counter of stats
+// for transactions longer than d (0 disables it)
+func SlowThreshold(d time.Duration) Option {
+	return func(s *Service) {
+		s.slowThreshold = d
+	}
+}
+
+// MaxQuantity limits quantity param of requests
+// it's a policy limit checked before protocol one (0 disables it)
+func MaxQuantity(max uint16) Option {
+	return func(s *Service) {
+		s.maxQuantity = max
+	}
+}
+
+// MethodDefaults sets default params of methods
+// request params are merged over them (request values win),
+// so method with default address and quantity can be called without params
+func MethodDefaults(defaults map[string]objx.Map) Option {
+	return func(s *Service) {
+		s.defaults = defaults
+	}
+}
+
+// AddressBase sets base (0 or 1) of addresses in requests
+// request address_base param overrides it
+func AddressBase(base int64) Option {
+	return func(s *Service) {
+		s.addressBase = base
+	}
+}
+
+func New(transport modbus.Transporter, pGetter PackagerFn, o ...Option) Service {
+	s := &Service{
+		ctx:              context.Background(),
+		transport:        transport,
+		packagerGetter:   pGetter,
+		bus:              newBusLock(),
+		metrics:          newBusMetrics(),
+		life:             &lifecycle{},
+		lastGood:         newReadCache(math.MaxInt64),
+		counters:         newReadCache(math.MaxInt64),
+		maxResponseBytes: defaultMaxResponseBytes,
+		byteOrder:        orderBig,
+		wordOrder:        orderBig,
+		timestampFormat:  timestampRFC3339,
+		subs:             newSubscriptions(),
+	}
+
+	for _, f := range o {
+		f(s)
+	}
+
+	s.poller = newPointPoller(*s)
+	s.subs.srv = *s
+
+	if s.poller != nil {
+		go s.poller.run(*s)
+	}
+
+	return *s
+}
+
+// getTransport returns transport for slave (with slave overrides applied)
+// it holds bus lock while transaction in progress,
+// waits for slave rate limit and retries failed transactions (if configured)
+func (s Service) getTransport(slaveID byte) modbus.Transporter {
+	t, bus := s.connection(slaveID)
+
+	if s.replay != nil {
+		t = replayTransporter{s.replay, s.getPackager(slaveID), slaveID}
+	}
+
+	var (
+		delay          = s.frameDelay
+		retry          = s.retry
+		timeout        time.Duration
+		connectTimeout = s.connectTimeout
+	)
+
+	if c, ok := s.slaveTransports[slaveID]; ok {
+		timeout = c.Timeout
+
+		if c.ConnectTimeout > 0 {
+			connectTimeout = c.ConnectTimeout
+		}
+
+		if c.FrameDelay > 0 {
+			delay = c.FrameDelay
+		}
+
+		retry = s.slaveRetry(c)
+	}
+
+	// connect_timeout param overrides configured ones
+	if s.callConnectTimeout > 0 {
+		connectTimeout = s.callConnectTimeout
+	}
+
+	// other transporters are not wrapped so layers can see their methods
+	_, ts := t.(timeoutSender)
+	_, cs := t.(connectTimeoutSender)
+
+	if ts && timeout > 0 || cs && connectTimeout > 0 {
+		t = timeoutTransporter{t, timeout, connectTimeout}
+	}
+
+	for _, layer := range s.layers {
+		t = layer(t)
+	}
+
+	if s.maxResponseBytes > 0 {
+		t = responseLimitTransporter{t, s.maxResponseBytes}
+	}
+
+	if s.validator != nil {
+		t = validatorTransporter{t, s.validator, ResponseContext{SlaveID: slaveID, Method: s.method}}
+	}
+
+	t = framingTransporter{t, s.getPackager(slaveID)}
+
+	if s.metrics != nil {
+		t = metricsTransporter{t, s.getPackager(slaveID), s.metrics, slaveID, s.device, s.slowThreshold, s.method}
+	}
+
+	var span *traceSpan
+
+	if s.trace != nil {
+		span = &traceSpan{}
+		t = spanTransporter{t, span}
+	}
+
+	// it waits for token under the bus lock (slaves with own connection aren't on the main bus)
+	if s.busLimiter != nil && !s.ownConnection(slaveID) {
+		t = rateLimitedTransporter{t, s.busLimiter}
+	}
+
+	t = lockedTransporter{t, bus, delay}
+
+	if s.trace != nil {
+		t = traceTransporter{t, s.getPackager(slaveID), s.trace, span, slaveID, s.method}
+	}
+
+	if l, ok := s.limiters[slaveID]; ok {
+		t = rateLimitedTransporter{t, l}
+	}
+
+	if s.metrics != nil {
+		t = rejectionCounter{t, s.metrics, slaveID}
+	}
+
+	// acknowledge exception is not retried if it's polled
+	if c, ok := s.ackPolls[s.method]; ok {
+		t = ackPollTransporter{t, s.getPackager(slaveID), c, s.ctx}
+	}
+
+	if retry != nil {
+		t = retryTransporter{t, s.getPackager(slaveID), retry, s.retryBudget}
+	}
+
+	// denied frames don't wait for the bus and aren't retried
+	if len(s.access) > 0 {
+		t = accessTransporter{t, s.getPackager(slaveID), s.access, slaveID}
+	}
+
+	if s.strictSlaveIDs {
+		t = slaveIDTransporter{t, s.getPackager(slaveID), slaveID}
+	}
+
+	return t
 }
 
 func (s Service) getClient(slaveID byte) modbus.Client {
-	return modbus.NewClient2(s.packagerGetter(slaveID), s.transport)
+	cli := modbus.NewClient2(s.getPackager(slaveID), s.getTransport(slaveID))
+	if s.lenientEcho[slaveID] {
+		cli = lenientEchoClient{cli, s, slaveID}
+	}
+
+	if s.multipleWrite[slaveID] {
+		return multipleWriteClient{cli}
+	}
+
+	return cli
+}
+
+// blockSize returns expected size of read response data in bytes
+func blockSize(function byte, quantity uint16) int {
+	switch function {
+	case modbus.FuncCodeReadCoils, modbus.FuncCodeReadDiscreteInputs:
+		return (int(quantity) + 7) / 8
+	default:
+		return int(quantity) * 2
+	}
+}
+
+func truncatedErr(expected, actual int) error {
+	return jsonrpc.ErrServer.AddData("msg", "truncated response").
+		AddData("expected", expected).AddData("actual", actual).SetCode(-32098)
+}
+
+// readBlock reads coils, discrete inputs, input or holding registers
+// (depends on function) and returns raw result
+func (s Service) readBlock(slaveID, function byte, addr, quantity uint16) ([]byte, error) {
+	key := cacheKey{slaveID, function, addr, quantity, s.device}
+
+	if res, ok := s.cache.get(key, s.maxAge); ok && !s.noCache {
+		return res, nil
+	}
+
+	// no_cache read doesn't join read which started earlier
+	if s.noCache {
+		return s.readBlockFresh(key)
+	}
+
+	return s.flights.do(key, func() ([]byte, error) {
+		return s.readBlockFresh(key)
+	})
+}
+
+// readBlockFresh reads block from slave and caches it
+func (s Service) readBlockFresh(key cacheKey) ([]byte, error) {
+	slaveID, function, addr, quantity := key.slaveID, key.function, key.address, key.quantity
+	cli := s.getClient(slaveID)
+
+	var (
+		res []byte
+		err error
+	)
+
+	switch function {
+	case modbus.FuncCodeReadCoils:
+		res, err = cli.ReadCoils(addr, quantity)
+	case modbus.FuncCodeReadDiscreteInputs:
+		res, err = cli.ReadDiscreteInputs(addr, quantity)
+	case modbus.FuncCodeReadInputRegisters:
+		res, err = cli.ReadInputRegisters(addr, quantity)
+	case modbus.FuncCodeReadHoldingRegisters:
+		res, err = cli.ReadHoldingRegisters(addr, quantity)
+	default:
+		panic("readBlock: unsupported function")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// missing word shifts all next values so short response is an error
+	if expected := blockSize(function, quantity); len(res) != expected {
+		return nil, truncatedErr(expected, len(res))
+	}
+
+	s.cache.set(key, res)
+
+	return res, nil
+}
+
+var errEmptyResponse = errors.New("modbus: response data is empty")
+
+// send sends raw pdu to slave and checks response for modbus exception
+// it required for function codes which not implemented by modbus.Client
+func (s Service) send(slaveID byte, pdu *modbus.ProtocolDataUnit) (*modbus.ProtocolDataUnit, error) {
+	packager := s.getPackager(slaveID)
+
+	aduRequest, err := packager.Encode(pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	aduResponse, err := s.getTransport(slaveID).Send(aduRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = packager.Verify(aduRequest, aduResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := packager.Decode(aduResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.FunctionCode != pdu.FunctionCode {
+		mbErr := &modbus.ModbusError{FunctionCode: response.FunctionCode}
+		if len(response.Data) > 0 {
+			mbErr.ExceptionCode = response.Data[0]
+		}
+
+		return nil, mbErr
+	}
+
+	if len(response.Data) == 0 {
+		return nil, errEmptyResponse
+	}
+
+	return response, nil
+}
+
+// checkMaxQuantity checks quantity param against configured limit
+func (s Service) checkMaxQuantity(params objx.Map) error {
+	if s.maxQuantity == 0 || params.Get("quantity").IsNil() {
+		return nil
+	}
+
+	quantity, err := getUint16(params, "quantity")
+	if err != nil {
+		return err
+	}
+
+	if quantity > s.maxQuantity {
+		return jsonrpc.ErrInvalidParams.AddData("msg", "quantity exceeds limit").
+			AddData("quantity", quantity).AddData("max", s.maxQuantity)
+	}
+
+	return nil
+}
+
+// withDefaults returns request params merged over method defaults
+func (s Service) withDefaults(req jsonrpc.Request) objx.Map {
+	def, ok := s.defaults[req.Method]
+	if !ok {
+		return req.Params
+	}
+
+	params := def.Copy()
+	for k, v := range req.Params {
+		params[k] = v
+	}
+
+	return params
+}
+
+// addressParams contains params with register addresses
+var addressParams = []string{"address", "read_address", "write_address", "sign_address", "exponent_address"} // nolint: gochecknoglobals
+
+// toWireAddress converts address params to 0-based protocol addresses
+func (s Service) toWireAddress(params objx.Map) (objx.Map, error) {
+	base, err := getInt64(params, "address_base", s.addressBase)
+	if err != nil {
+		return nil, err
+	}
+
+	if base != 0 && base != 1 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "address_base should be 0 or 1")
+	}
+
+	if base == 0 {
+		return params, nil
+	}
+
+	params = params.Copy()
+
+	for _, k := range addressParams {
+		if params.Get(k).IsNil() {
+			continue
+		}
+
+		addr, err := getInt64(params, k)
+		if err != nil {
+			return nil, err
+		}
+
+		if addr < 1 {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be >= 1 if address_base is 1")
+		}
+
+		params[k] = json.Number(strconv.FormatInt(addr-1, 10))
+	}
+
+	return params, nil
 }
 
-func (s Service) Call(req jsonrpc.Request) (res interface{}, err error) {
+// Call executes request, after shutdown it returns error
+func (s Service) Call(req jsonrpc.Request) (interface{}, error) {
+	if !s.life.enter() {
+		return nil, errShutdown
+	}
+	defer s.life.leave()
+
+	// requests of jsonrpc batch after end of its time budget fail without transactions
+	if s.retryBudget.expired() {
+		return nil, errRetryBudget
+	}
+
+	res, err := s.call(req)
+
+	var ferr *framingError
+	if errors.As(err, &ferr) {
+		return nil, ferr.rpcError()
+	}
+
+	return res, err
+}
+
+// call executes request without shutdown check (it's used for nested calls)
+func (s Service) call(req jsonrpc.Request) (res interface{}, err error) {
+	req.Params = s.withDefaults(req)
+
+	err = validateParams(req.Method, req.Params)
+	if err != nil {
+		return
+	}
+
+	err = checkExclusive(req.Params)
+	if err != nil {
+		return
+	}
+
+	err = s.checkMaxQuantity(req.Params)
+	if err != nil {
+		return
+	}
+
+	s, err = s.withFraming(req.Params)
+	if err != nil {
+		return
+	}
+
+	// nested calls inherit it
+	if req.Params.Get("no_cache").Bool() {
+		s.noCache = true
+	}
+
+	if !req.Params.Get("max_age_ms").IsNil() {
+		if s.maxAge, err = getMaxAge(req.Params); err != nil {
+			return
+		}
+	}
+
+	if !req.Params.Get("connect_timeout").IsNil() {
+		if s.callConnectTimeout, err = getDuration(req.Params, "connect_timeout", 0); err != nil {
+			return
+		}
+	}
+
+	if req.Params.Get("dry_run").Bool() {
+		return s.dryRun(req)
+	}
+
+	if !req.Params.Get("idempotency_key").IsNil() {
+		return s.callIdempotent(req)
+	}
+
+	if req.Params.Get("with_latency").Bool() {
+		return s.callWithLatency(req)
+	}
+
+	if !req.Params.Get("format").IsNil() {
+		return s.callFormatted(req)
+	}
+
+	if isFanOut(req.Method, req.Params) {
+		return s.fanOut(req)
+	}
+
+	if req.Params.Get("with_transaction_id").Bool() {
+		return s.callWithTransactionID(req)
+	}
+
+	tid, ok, err := s.getTransactionID(req)
+	if err != nil {
+		return
+	}
+
+	if ok {
+		s = s.withTransactionID(tid)
+	}
+
+	wire, err := s.toWireAddress(req.Params)
+	if err != nil {
+		return
+	}
+
+	// subscriptions keep addresses as in request, every poll converts them again
+	if req.Method != "modbus-subscribe" {
+		req.Params = wire
+	}
+
+	s.method = req.Method
+	s.device = req.Params.Get("device").Str()
+
+	if guardMethods[req.Method] {
+		var release func()
+
+		s, release, err = s.withGuard(req.Params)
+		if err != nil {
+			return
+		}
+		defer release()
+	}
+
 	switch req.Method {
 	case "modbus-read-coil":
 		res, err = s.readCoils(req.Params)
@@ -60,12 +712,74 @@ func (s Service) Call(req jsonrpc.Request) (res interface{}, err error) {
 		res, err = s.writeSingleRegister(req.Params)
 	case "modbus-write-multiple-registers":
 		res, err = s.writeMultipleRegisters(req.Params)
-	// case "read-write-multiple-registers":
-	// 	res, err = s.h.ReadWriteMultipleRegisters(req.Params)
-	// case "mask-write-register":
-	// 	res, err = s.h.MaskWriteRegister(req.Params)
-	// case "read-fifo-queue":
-	// 	res, err = s.h.ReadFIFOQueue(req.Params)
+	case "modbus-read-file-record":
+		res, err = s.readFileRecord(req.Params)
+	case "modbus-write-file-record":
+		res, err = s.writeFileRecord(req.Params)
+	case "modbus-diagnostics":
+		res, err = s.diagnostics(req.Params)
+	case "modbus-comm-event-counter":
+		res, err = s.commEventCounter(req.Params)
+	case "modbus-comm-event-log":
+		res, err = s.commEventLog(req.Params)
+	case "modbus-read-write-registers":
+		res, err = s.readWriteMultipleRegisters(req.Params)
+	case "modbus-write-read-point":
+		res, err = s.writeReadPoint(req.Params)
+	case "modbus-read-point":
+		res, err = s.readPoint(req.Params)
+	case "modbus-read-points":
+		res, err = s.readPoints(req.Params)
+	case "modbus-write-point":
+		res, err = s.writePoint(req.Params)
+	case "modbus-set-bit":
+		res, err = s.setBit(req.Params)
+	case "modbus-compare-and-write":
+		res, err = s.compareAndWrite(req.Params)
+	case "modbus-write-bits":
+		res, err = s.writeBits(req.Params)
+	case "modbus-read-extended":
+		res, err = s.readExtended(req.Params)
+	case "modbus-wait-for":
+		res, err = s.waitFor(req.Params)
+	case "modbus-read-struct":
+		res, err = s.readStruct(req.Params)
+	case "modbus-scan":
+		res, err = s.scan(req.Params)
+	case "modbus-read-device-identification":
+		res, err = s.readDeviceIdentification(req.Params)
+	case "modbus-canopen":
+		res, err = s.canopenRequest(req.Params)
+	case "modbus-subscribe":
+		res, err = s.subscribe(req.Params)
+	case "modbus-subscribe-cancel":
+		res, err = s.subscribeCancel(req.Params)
+	case "modbus-subscriptions":
+		res, err = s.listSubscriptions()
+	case "modbus-stats":
+		res, err = s.stats(req.Params)
+	case "modbus-health":
+		res, err = s.health(req.Params)
+	case "modbus-slave-status":
+		res, err = s.slaveStatus(req.Params)
+	case "modbus-serial-config":
+		res, err = s.serialConfig(req.Params)
+	case "modbus-read-multi":
+		res, err = s.readMulti(req.Params)
+	case "modbus-benchmark":
+		res, err = s.benchmark(req.Params)
+	case "modbus-debug-call":
+		res, err = s.debugCall(req)
+	case "modbus-read-polled":
+		res, err = s.readPolled(req.Params)
+	case "modbus-read-all":
+		res, err = s.readAll(req.Params)
+	case "modbus-detect-endianness":
+		res, err = s.detectEndianness(req.Params)
+	case "modbus-read-clock":
+		res, err = s.readClock(req.Params)
+	case "modbus-config-dump":
+		res, err = s.configDump(req.Params)
 	default:
 		err = jsonrpc.ErrMethodNotFound.AddData("method", req.Method)
 	}
@@ -77,6 +791,9 @@ const (
 	maxUint16 = int64(^uint16(0))
 	minUint16 = int64(0)
 
+	maxInt16 = int64(math.MaxInt16)
+	minInt16 = int64(math.MinInt16)
+
 	maxByte = int64(255)
 	minByte = int64(0)
 )
@@ -85,14 +802,30 @@ func parseResultByteToBits(b []byte, quantity uint16) []uint16 {
 	// uint16 required here because json encode byte array as base64
 	result := make([]uint16, 0, quantity)
 
-	for _, bt := range b {
-		for bt != 0 {
-			result = append(result, uint16(bt&1))
-			bt >>= 1
+	// each byte contains 8 bits (lsb first) including zero ones
+	for i := 0; i < int(quantity) && i/8 < len(b); i++ {
+		result = append(result, uint16(b[i/8]>>(uint(i)%8)&1))
+	}
+
+	return result
+}
+
+// packBitmask packs bits to 64-bit words (lsb is first bit)
+// it returns single word if quantity <= 64
+func packBitmask(b []byte, quantity uint16) interface{} {
+	words := make([]uint64, (int(quantity)+63)/64)
+
+	for i := 0; i < int(quantity) && i/8 < len(b); i++ {
+		if b[i/8]>>(uint(i)%8)&1 == 1 {
+			words[i/64] |= 1 << (uint(i) % 64)
 		}
 	}
 
-	return result[:quantity]
+	if len(words) == 1 {
+		return words[0]
+	}
+
+	return words
 }
 
 func parseResult(b []byte) []uint16 {
@@ -105,6 +838,16 @@ func parseResult(b []byte) []uint16 {
 	return res
 }
 
+// dataBlock creates a sequence of uint16 data.
+func dataBlock(value ...uint16) []byte {
+	data := make([]byte, 2*len(value))
+	for i, v := range value {
+		binary.BigEndian.PutUint16(data[i*2:], v)
+	}
+
+	return data
+}
+
 func getInt64(params objx.Map, k string, def ...int64) (int64, error) {
 	val := params.Get(k)
 	if val.IsNil() {
@@ -115,21 +858,84 @@ func getInt64(params objx.Map, k string, def ...int64) (int64, error) {
 		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" required")
 	}
 
-	number, ok := val.Data().(json.Number)
-	if !ok {
-		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be number")
+	number, err := toNumber(k, val.Data())
+	if err != nil {
+		return 0, err
 	}
 
-	var (
-		value int64
-		err   error
-	)
+	return toInt64(k, number)
+}
+
+// toNumber converts param value k to json.Number
+func toNumber(k string, v interface{}) (json.Number, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v, nil
+	case string:
+		// some clients send all params as strings
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return "", jsonrpc.ErrInvalidParams.AddData("msg", k+" should be number")
+		}
 
-	if value, err = number.Int64(); err != nil {
-		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be int")
+		return json.Number(v), nil
+	case float64:
+		// params decoded without json.Number
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	default:
+		return "", jsonrpc.ErrInvalidParams.AddData("msg", k+" should be number")
 	}
+}
 
-	return value, nil
+func toInt64(k string, number json.Number) (int64, error) {
+	if value, err := number.Int64(); err == nil {
+		return value, nil
+	}
+
+	// some encoders send integers as whole floats (e.g. 100.0)
+	f, err := number.Float64()
+	if err != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be int (whole number)").AddData("v", number)
+	}
+
+	return int64(f), nil
+}
+
+// exclusiveParams contains groups of params which can't be used together
+// (client should choose one of them)
+var exclusiveParams = [][]string{ // nolint: gochecknoglobals
+	{"value", "values"},
+}
+
+func conflictErr(k1, k2 string) error {
+	return jsonrpc.ErrInvalidParams.AddData("msg", k1+" and "+k2+" can't be used together").
+		AddData("params", []string{k1, k2})
+}
+
+func checkExclusive(params objx.Map) error {
+	for _, group := range exclusiveParams {
+		found := ""
+
+		for _, k := range group {
+			if params.Get(k).IsNil() {
+				continue
+			}
+
+			if found != "" {
+				return conflictErr(found, k)
+			}
+
+			found = k
+		}
+	}
+
+	return nil
+}
+
+func quantityErr(quantity uint16, expected int) error {
+	return jsonrpc.ErrInvalidParams.AddData("msg", "quantity and value length mismatch").
+		AddData("params", []string{"quantity", "value"}).
+		AddData("quantity", quantity).
+		AddData("expected", expected)
 }
 
 func getSlaveID(params objx.Map) (byte, error) {
@@ -158,6 +964,33 @@ func getUint16(params objx.Map, k string, def ...int64) (uint16, error) {
 	return uint16(value), nil
 }
 
+// getRegisterValue returns value for register write
+// if signed flag (or int16 encoding) is set value accepted in int16 range
+// and converted to uint16 with the same bit pattern
+func getRegisterValue(params objx.Map, k string) (uint16, error) {
+	signed := params.Get("signed").Bool()
+	encoding := params.Get("encoding").Str(encUint16)
+
+	if signed && encoding != encInt16 && !params.Get("encoding").IsNil() {
+		return 0, conflictErr("signed", "encoding")
+	}
+
+	if !signed && encoding != encInt16 {
+		return getUint16(params, k)
+	}
+
+	value, err := getInt64(params, k)
+	if err != nil {
+		return 0, err
+	}
+
+	if !(minInt16 <= value && value <= maxInt16) {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be int16")
+	}
+
+	return uint16(int16(value)), nil
+}
+
 func getTwoUint16(params objx.Map, k1, k2 string) (uint16, uint16, error) {
 	v1, err := getUint16(params, k1)
 	if err != nil {
@@ -182,14 +1015,83 @@ func getArray(params objx.Map, k string) ([]interface{}, error) {
 	return v1.InterSlice(), nil
 }
 
+func emptyErr(k string) error {
+	return jsonrpc.ErrInvalidParams.AddData("msg", k+" must not be empty")
+}
+
+// base64Encodings contains accepted base64 variants in order of trying
+var base64Encodings = []*base64.Encoding{ // nolint: gochecknoglobals
+	base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+}
+
+// getBytes returns base64 encoded param as bytes
+// standard and url-safe alphabets are accepted with or without padding
+func getBytes(params objx.Map, k string) ([]byte, error) {
+	v := params.Get(k)
+
+	if !v.IsStr() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" required and should be base64 string")
+	}
+
+	var (
+		value []byte
+		err   error
+	)
+
+	for _, enc := range base64Encodings {
+		value, err = enc.DecodeString(v.Str())
+		if err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be base64 string")
+	}
+
+	if len(value) == 0 {
+		return nil, emptyErr(k)
+	}
+
+	return value, nil
+}
+
+// getRegistersBytes returns registers data from param
+// which can be base64 string or array of uint16
+func getRegistersBytes(params objx.Map, k string) ([]byte, error) {
+	if params.Get(k).IsStr() {
+		value, err := getBytes(params, k)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(value)%2 != 0 {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" should contain whole registers")
+		}
+
+		return value, nil
+	}
+
+	values, err := getArray(params, k)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, emptyErr(k)
+	}
+
+	return codec{encoding: encUint16}.encode(k, values)
+}
+
 func processIntArrayItem(k string, values []interface{}, callback func(int64) error) error {
 	for _, v := range values {
-		num, ok := v.(json.Number)
-		if !ok {
+		num, err := toNumber(k, v)
+		if err != nil {
 			return jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of numbers")
 		}
 
-		item, err := num.Int64()
+		item, err := toInt64(k, num)
 		if err != nil {
 			return jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of uint16")
 		}
@@ -200,18 +1102,56 @@ func processIntArrayItem(k string, values []interface{}, callback func(int64) er
 		}
 	}
 
-	return nil
+	return nil
+}
+
+func getAddrAndQuantity(params objx.Map) (uint16, uint16, error) {
+	return getTwoUint16(params, "address", "quantity")
+}
+
+func getAddrAndValue(params objx.Map) (uint16, uint16, error) {
+	return getTwoUint16(params, "address", "value")
+}
+
+func (s Service) readCoils(params objx.Map) (interface{}, error) {
+	return s.readBits(params, modbus.FuncCodeReadCoils)
+}
+
+func (s Service) readDiscreteInputs(params objx.Map) (interface{}, error) {
+	return s.readBits(params, modbus.FuncCodeReadDiscreteInputs)
+}
+
+type addressedBit struct {
+	Address int64       `json:"address"`
+	Value   interface{} `json:"value"`
 }
 
-func getAddrAndQuantity(params objx.Map) (uint16, uint16, error) {
-	return getTwoUint16(params, "address", "quantity")
+// bitsSummary is result of bits read with summary param
+type bitsSummary struct {
+	On     int         `json:"on"`
+	Off    int         `json:"off"`
+	Values interface{} `json:"values"`
 }
 
-func getAddrAndValue(params objx.Map) (uint16, uint16, error) {
-	return getTwoUint16(params, "address", "value")
+// summarize counts set and unset bits, values are bits as they are returned without summary
+func summarize(bits []uint16, values interface{}) bitsSummary {
+	res := bitsSummary{Values: values}
+
+	for _, v := range bits {
+		if v != 0 {
+			res.On++
+		} else {
+			res.Off++
+		}
+	}
+
+	return res
 }
 
-func (s Service) readCoils(params objx.Map) (interface{}, error) {
+// readBits reads coils or discrete inputs (depends on function)
+// in verbose mode each bit tagged with its address
+// with summary param counts of set and unset bits are returned with values
+func (s Service) readBits(params objx.Map, function byte) (interface{}, error) {
 	addr, quantity, err := getAddrAndQuantity(params)
 	if err != nil {
 		return nil, err
@@ -222,35 +1162,73 @@ func (s Service) readCoils(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
-	cli := s.getClient(slaveID)
-
-	res, err := cli.ReadCoils(addr, quantity)
+	coerce, err := getCoercion(params, params.Get("encoding").Str())
 	if err != nil {
 		return nil, err
 	}
 
-	return parseResultByteToBits(res, quantity), nil
-}
-
-func (s Service) readDiscreteInputs(params objx.Map) (interface{}, error) {
-	addr, quantity, err := getAddrAndQuantity(params)
+	err = checkTimestamp(params, false)
 	if err != nil {
 		return nil, err
 	}
 
-	slaveID, err := getSlaveID(params)
+	summary := params.Get("summary").Bool()
+	if summary && params.Get("encoding").Str() == encBitmask {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "summary can't be used with bitmask encoding")
+	}
+
+	if params.Get("detect_wrap").Bool() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "detect_wrap requires register read")
+	}
+
+	if params.Get("with_quality").Bool() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "with_quality requires register read")
+	}
+
+	if params.Get("shrink_quantity").Bool() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "shrink_quantity requires register read")
+	}
+
+	res, age, err := s.readLastGood(params, cacheKey{slaveID, function, addr, quantity, s.device}, func() ([]byte, error) {
+		return s.readBlock(slaveID, function, addr, quantity)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	cli := s.getClient(slaveID)
+	if params.Get("encoding").Str() == encBitmask {
+		return withStale(params, coerce.bitmask(packBitmask(res, quantity)), age), nil
+	}
+
+	bits := parseResultByteToBits(res, quantity)
+
+	if !params.Get("verbose").Bool() {
+		if summary {
+			return withStale(params, summarize(bits, coerce.bits(bits)), age), nil
+		}
 
-	res, err := cli.ReadDiscreteInputs(addr, quantity)
+		return withStale(params, coerce.bits(bits), age), nil
+	}
+
+	// addresses in the same base as in request
+	base, err := getInt64(params, "address_base", s.addressBase)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseResult(res), nil
+	result := make([]addressedBit, len(bits))
+	for i, v := range bits {
+		result[i] = addressedBit{Address: int64(addr) + base + int64(i), Value: v}
+		if coerce != nil {
+			result[i].Value = coerce.value(v)
+		}
+	}
+
+	if summary {
+		return withStale(params, summarize(bits, result), age), nil
+	}
+
+	return withStale(params, result, age), nil
 }
 
 const modbusTrueValue = 0xFF00
@@ -276,6 +1254,11 @@ func (s Service) writeSingleCoil(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
+	check, err := getWriteCheck(params)
+	if err != nil {
+		return nil, err
+	}
+
 	cli := s.getClient(slaveID)
 
 	res, err := cli.WriteSingleCoil(addr, value)
@@ -283,6 +1266,12 @@ func (s Service) writeSingleCoil(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadCoils, addr, 1)
+
+	if err := check.coils(s, slaveID, addr, 1, []byte{byte(value >> 15)}); err != nil {
+		return nil, err
+	}
+
 	result := parseResult(res)
 
 	if result[0] == modbusTrueValue {
@@ -316,24 +1305,73 @@ func buildProcessCoilsArray(k string, bytes []byte) func(int64) error {
 	}
 }
 
+// getCoilBools returns coils from k param (array of booleans) as array of 1 or 0
+func getCoilBools(params objx.Map, k string) ([]interface{}, error) {
+	values, err := getArray(params, k)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]interface{}, len(values))
+
+	for i, v := range values {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of booleans")
+		}
+
+		res[i] = json.Number("0")
+		if b {
+			res[i] = json.Number("1")
+		}
+	}
+
+	return res, nil
+}
+
+// writeMultipleCoils writes coils from value (array of 1 or 0)
+// or values (array of booleans, quantity is its length by default)
 func (s Service) writeMultipleCoils(params objx.Map) (interface{}, error) {
-	addr, quantity, err := getAddrAndQuantity(params)
+	var (
+		k      = "value"
+		def    []int64
+		values []interface{}
+		err    error
+	)
+
+	if params.Get("values").IsNil() {
+		values, err = getArray(params, k)
+	} else {
+		k = "values"
+		values, err = getCoilBools(params, k)
+		def = append(def, int64(len(values)))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, emptyErr(k)
+	}
+
+	addr, err := getUint16(params, "address")
 	if err != nil {
 		return nil, err
 	}
 
-	values, err := getArray(params, "value")
+	quantity, err := getUint16(params, "quantity", def...)
 	if err != nil {
 		return nil, err
 	}
 
 	if int(quantity) != len(values) {
-		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "wrong quantity")
+		return nil, quantityErr(quantity, len(values))
 	}
 
 	bytes := make([]byte, int(math.Ceil(float64(quantity)/8.0)))
 
-	err = processIntArrayItem("value", values, buildProcessCoilsArray("value", bytes))
+	err = processIntArrayItem(k, values, buildProcessCoilsArray(k, bytes))
 	if err != nil {
 		return nil, err
 	}
@@ -343,6 +1381,11 @@ func (s Service) writeMultipleCoils(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
+	check, err := getWriteCheck(params)
+	if err != nil {
+		return nil, err
+	}
+
 	cli := s.getClient(slaveID)
 
 	res, err := cli.WriteMultipleCoils(addr, quantity, bytes)
@@ -350,53 +1393,228 @@ func (s Service) writeMultipleCoils(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadCoils, addr, quantity)
+
+	if err := check.coils(s, slaveID, addr, quantity, bytes); err != nil {
+		return nil, err
+	}
+
 	return parseResult(res), nil
 }
 
 func (s Service) readInputRegisters(params objx.Map) (interface{}, error) {
+	return s.readRegisters(params, modbus.FuncCodeReadInputRegisters)
+}
+
+func (s Service) readHoldingRegisters(params objx.Map) (interface{}, error) {
+	return s.readRegisters(params, modbus.FuncCodeReadHoldingRegisters)
+}
+
+// readRegisters reads input or holding registers (depends on function)
+// and decodes result by requested encoding
+func (s Service) readRegisters(params objx.Map, function byte) (interface{}, error) {
+	if params.Get("enron").Bool() {
+		return s.readEnron(params, function)
+	}
+
+	if params.Get("encoding").Str() == encSignRegister {
+		return s.readSignRegister(params, function)
+	}
+
+	if params.Get("encoding").Str() == encScaleRegister {
+		return s.readScaleRegister(params, function)
+	}
+
 	addr, quantity, err := getAddrAndQuantity(params)
 	if err != nil {
 		return nil, err
 	}
 
+	c, err := s.getCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
 	slaveID, err := getSlaveID(params)
 	if err != nil {
 		return nil, err
 	}
 
-	cli := s.getClient(slaveID)
+	coerce, err := getCoercion(params, c.encoding)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := cli.ReadInputRegisters(addr, quantity)
+	c.null, err = getNullValue(params, c)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseResult(res), nil
-}
+	c.cal, err = getCalibration(params, c.encoding)
+	if err != nil {
+		return nil, err
+	}
 
-func (s Service) readHoldingRegisters(params objx.Map) (interface{}, error) {
-	addr, quantity, err := getAddrAndQuantity(params)
+	verbose := params.Get("verbose").Bool()
+
+	err = checkTimestamp(params, true)
 	if err != nil {
 		return nil, err
 	}
 
-	slaveID, err := getSlaveID(params)
+	detectWrap := params.Get("detect_wrap").Bool()
+	if detectWrap {
+		if err = checkDetectWrap(c); err != nil {
+			return nil, err
+		}
+	}
+
+	shrink := params.Get("shrink_quantity").Bool()
+	if shrink && params.Get("chunked").Bool() {
+		return nil, conflictErr("shrink_quantity", "chunked")
+	}
+
+	var stats responseStats
+
+	// sla_ms of with_quality needs time of transactions too
+	srv := s
+	if verbose || (params.Get("with_quality").Bool() && !params.Get("sla_ms").IsNil()) {
+		srv = s.withStats(slaveID, &stats)
+	}
+
+	res, age, err := s.readLastGood(params, cacheKey{slaveID, function, addr, quantity, s.device}, func() ([]byte, error) {
+		if params.Get("chunked").Bool() {
+			return srv.readRegistersChunked(slaveID, function, addr, quantity)
+		}
+
+		if shrink {
+			return srv.readShrinking(slaveID, function, addr, quantity, c.registers())
+		}
+
+		return srv.readBlock(slaveID, function, addr, quantity)
+	})
 	if err != nil {
+		if verbose && stats.reported != stats.received {
+			return nil, stats.mismatchErr(err)
+		}
+
 		return nil, err
 	}
 
-	cli := s.getClient(slaveID)
+	// stale data was read earlier
+	if age > 0 {
+		stats.completed = time.Now().Add(-age)
+	}
 
-	res, err := cli.ReadHoldingRegisters(addr, quantity)
+	quality, err := getReadQuality(params, age, stats)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseResult(res), nil
+	result, err := s.registersResult(params, c, coerce, quality, res, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	// stale data isn't compared with previous read
+	if detectWrap {
+		var wrapped []int
+
+		if age == 0 {
+			wrapped, err = s.detectWrap(cacheKey{slaveID, function, addr, quantity, s.device}, c, res)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result = withWrap(params, result, wrapped)
+	}
+
+	result = withShrink(params, result, quantity, res)
+
+	return withStale(params, result, age), nil
+}
+
+// registersResult converts read registers to result (raw, verbose or decoded values),
+// values are coerced and then qualified (if quality isn't nil)
+func (s Service) registersResult(params objx.Map, c codec, coerce *coercion, quality *readQuality, res []byte,
+	stats responseStats) (interface{}, error) {
+	if c.encoding == encRaw {
+		if quality != nil {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "with_quality can't be used with raw encoding")
+		}
+
+		return rawResult(params, res)
+	}
+
+	keyed := params.Get("keyed").Bool()
+
+	if params.Get("verbose").Bool() {
+		if keyed {
+			return nil, conflictErr("verbose", "keyed")
+		}
+
+		v, err := s.buildVerbose(params, c, res, stats)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Values = quality.values(coerce.values(v.Values))
+
+		return v, nil
+	}
+
+	// decode whole buffer so values on chunk boundary decoded correctly
+	values, err := c.decode(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyed {
+		// two values of each register have no own addresses
+		if c.encoding == encBytes {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "keyed can't be used with bytes encoding")
+		}
+
+		return s.keyedResult(params, c, quality.values(coerce.values(values)))
+	}
+
+	return quality.values(coerce.values(values)), nil
+}
+
+// keyedResult returns values by address of their first register
+// (addresses in the same base as in request)
+func (s Service) keyedResult(params objx.Map, c codec, values []interface{}) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := getInt64(params, "address_base", s.addressBase)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(values))
+
+	for i, v := range values {
+		result[strconv.FormatInt(int64(addr)+base+int64(i*c.registers()), 10)] = v
+	}
+
+	return result, nil
 }
 
 func (s Service) writeSingleRegister(params objx.Map) (interface{}, error) {
-	addr, value, err := getAddrAndValue(params)
+	if params.Get("enron").Bool() {
+		return s.writeEnron(params, true)
+	}
+
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := getRegisterValue(params, "value")
 	if err != nil {
 		return nil, err
 	}
@@ -406,6 +1624,26 @@ func (s Service) writeSingleRegister(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
+	check, err := getWriteCheck(params)
+	if err != nil {
+		return nil, err
+	}
+
+	c := codec{encoding: encUint16}
+	if params.Get("signed").Bool() || params.Get("encoding").Str() == encInt16 {
+		c.encoding = encInt16
+	}
+
+	written := []byte{byte(value >> 8), byte(value)}
+
+	if ok, err := check.writeVerified(s, slaveID, addr, 1, c, written); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		return parseResult(written), nil
+	}
+
 	cli := s.getClient(slaveID)
 
 	res, err := cli.WriteSingleRegister(addr, value)
@@ -413,79 +1651,147 @@ func (s Service) writeSingleRegister(params objx.Map) (interface{}, error) {
 		return nil, err
 	}
 
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, 1)
+
+	err = check.registers(s, params, slaveID, addr, 1, c, written)
+	if err != nil {
+		return nil, err
+	}
+
 	return parseResult(res), nil
 }
 
-func buildProcessRegistersArray(k string, bytes []byte) func(int64) error {
-	i := 0
+// getValues returns value param as array
+// single value is allowed and converted to array with one element
+func getValues(params objx.Map, k string) ([]interface{}, error) {
+	v := params.Get(k)
 
-	return func(item int64) error {
-		if !(minUint16 <= item && item <= maxUint16) {
-			return jsonrpc.ErrInvalidParams.AddData("msg", k+" should be array of uint16")
-		}
+	if v.IsNil() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" required")
+	}
 
-		binary.BigEndian.PutUint16(bytes[i:i+2], uint16(item))
-		i += 2
+	if !v.IsInterSlice() {
+		return []interface{}{v.Data()}, nil
+	}
 
-		return nil
+	if len(v.InterSlice()) == 0 {
+		return nil, emptyErr(k)
 	}
+
+	return v.InterSlice(), nil
 }
 
 func (s Service) writeMultipleRegisters(params objx.Map) (interface{}, error) {
-	addr, quantity, err := getAddrAndQuantity(params)
+	cmd, err := getCommandWord(params)
 	if err != nil {
 		return nil, err
 	}
 
-	values, err := getArray(params, "value")
+	if params.Get("enron").Bool() {
+		return s.writeEnron(params, false)
+	}
+
+	addr, err := getUint16(params, "address")
 	if err != nil {
 		return nil, err
 	}
 
-	if int(quantity) != len(values) {
-		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "wrong quantity")
+	c, err := s.getCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := getValues(params, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	// quantity includes command word
+	implied := len(values) * c.registers()
+	if cmd != nil {
+		implied++
 	}
 
-	bytes := make([]byte, quantity*2)
+	// quantity can be omitted for encoded values because it known from encoding
+	var def []int64
+	if !params.Get("encoding").IsNil() {
+		def = append(def, int64(implied))
+	}
+
+	quantity, err := getUint16(params, "quantity", def...)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(quantity) != implied {
+		return nil, quantityErr(quantity, implied)
+	}
 
-	err = processIntArrayItem("value", values, buildProcessRegistersArray("value", bytes))
+	bytes, clamped, err := c.encodeClamped("value", values)
 	if err != nil {
 		return nil, err
 	}
 
+	bytes = withCommandWord(cmd, bytes)
+
 	slaveID, err := getSlaveID(params)
 	if err != nil {
 		return nil, err
 	}
 
-	cli := s.getClient(slaveID)
+	check, err := getWriteCheck(params)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := cli.WriteMultipleRegisters(addr, quantity, bytes)
+	var res interface{}
+
+	// failed addresses are reported in the same base as in request
+	base, err := getInt64(params, "address_base", s.addressBase)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseResult(res), nil
-}
+	if !params.Get("chunked").Bool() {
+		if ok, err := check.writeVerified(s, slaveID, addr, quantity, c, bytes); ok {
+			if err != nil {
+				return nil, err
+			}
 
-// func (s Service) readWriteMultipleRegisters(params objx.Map) (interface{}, error) {
-// 	readAddr, readQuantity, err := getTwoUint16(params, "read_address", "read_quantity")
-// 	if err != nil {
-// 		return nil, err
-// 	}
+			// result is quantity as in echo of FC16
+			return withClamped(c, []uint16{quantity}, clamped), nil
+		}
+	}
 
-// 	writeAddr, writeQuantity, err := getTwoUint16(params, "write_address", "write_quantity")
-// 	if err != nil {
-// 		return nil, err
-// 	}
+	if params.Get("chunked").Bool() {
+		res, err = s.writeRegistersChunked(slaveID, addr, quantity, bytes, c.registers(), base)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		b, err := s.sendWriteRegisters(slaveID, addr, quantity, bytes)
 
-// 	value, err := getBytes(params, "value")
-// 	if err != nil {
-// 		return nil, err
-// 	}
+		var perr partialWriteError
+		if errors.As(err, &perr) {
+			s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, quantity)
+			return nil, writtenErr(err, int(perr.written), int64(addr)+int64(perr.written)+base)
+		}
 
-// 	return s.cli.ReadWriteMultipleRegisters(readAddr, readQuantity, writeAddr, writeQuantity, value)
-// }
+		if err != nil {
+			return nil, err
+		}
+
+		s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, quantity)
+
+		res = parseResult(b)
+	}
+
+	if err := check.registers(s, params, slaveID, addr, quantity, c, bytes); err != nil {
+		return nil, err
+	}
+
+	return withClamped(c, res, clamped), nil
+}
 
 // func (s Service) maskWriteRegister(params objx.Map) (interface{}, error) {
 // 	addr, andMask, err := getTwoUint16(params, "address", "and_mask")
@@ -0,0 +1,324 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// echoTransporter remembers request and sends it back as response
+// it's enough for single write functions because slave echoes them
+type echoTransporter struct {
+	sent []byte
+}
+
+func (t *echoTransporter) Send(adu []byte) ([]byte, error) {
+	t.sent = adu
+	return adu, nil
+}
+
+func newTestService(tr modbus.Transporter) Service {
+	return New(tr, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+}
+
+func TestWriteSingleRegisterSigned(t *testing.T) {
+	tr := &echoTransporter{}
+	srv := newTestService(tr)
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{
+			"address": json.Number("1"),
+			"value":   json.Number("-1"),
+			"signed":  true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mbap header (7 bytes) + function code + address
+	if tr.sent[10] != 0xFF || tr.sent[11] != 0xFF {
+		t.Fatalf("expected 0xFFFF on the wire, got % x", tr.sent[10:])
+	}
+}
+
+func TestWriteSingleRegisterSignedRange(t *testing.T) {
+	srv := newTestService(&echoTransporter{})
+
+	for _, tc := range []struct {
+		params objx.Map
+		ok     bool
+	}{
+		{objx.Map{"value": json.Number("-1")}, false},
+		{objx.Map{"value": json.Number("65535")}, true},
+		{objx.Map{"value": json.Number("-32768"), "encoding": "int16"}, true},
+		{objx.Map{"value": json.Number("-32769"), "encoding": "int16"}, false},
+		{objx.Map{"value": json.Number("32768"), "signed": true}, false},
+	} {
+		tc.params["address"] = json.Number("1")
+
+		_, err := srv.Call(jsonrpc.Request{Method: "modbus-write-register", Params: tc.params})
+		if (err == nil) != tc.ok {
+			t.Errorf("%v: unexpected error %v", tc.params, err)
+		}
+	}
+}
+
+// badEchoTransporter echoes request with changed last byte (value)
+type badEchoTransporter struct{}
+
+func (badEchoTransporter) Send(adu []byte) ([]byte, error) {
+	res := append([]byte{}, adu...)
+	res[len(res)-1]++
+
+	return res, nil
+}
+
+func TestWriteSingleEchoMismatch(t *testing.T) {
+	// echo of address and value verified by modbus client
+	// so write with wrong echo should fail
+	srv := newTestService(badEchoTransporter{})
+
+	for _, method := range []string{"modbus-write-coil", "modbus-write-register"} {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: method,
+			Params: objx.Map{"address": json.Number("1"), "value": json.Number("1")},
+		})
+		if err == nil {
+			t.Errorf("%s: expected error on echo mismatch", method)
+		}
+	}
+}
+
+func TestGetInt64WholeFloat(t *testing.T) {
+	for _, tc := range []struct {
+		v   interface{}
+		res int64
+		ok  bool
+	}{
+		{json.Number("100"), 100, true},
+		{json.Number("100.0"), 100, true},
+		{json.Number("-2.00"), -2, true},
+		{json.Number("1e3"), 1000, true},
+		{float64(7), 7, true},
+		{"5.0", 5, true},
+		{json.Number("100.5"), 0, false},
+		{float64(0.25), 0, false},
+		{json.Number("1e30"), 0, false},
+	} {
+		res, err := getInt64(objx.Map{"v": tc.v}, "v")
+		if (err == nil) != tc.ok || res != tc.res {
+			t.Errorf("%v: expected %d (ok %v) but got %d (%v)", tc.v, tc.res, tc.ok, res, err)
+		}
+	}
+
+	// items of arrays are converted the same way
+	m := &mockSlave{}
+
+	_, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": num("0"), "quantity": num("3"), "value": []interface{}{json.Number("100.0"), float64(2), "1e3"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.holding[0] != 100 || m.holding[1] != 2 || m.holding[2] != 1000 {
+		t.Errorf("unexpected registers %v", m.holding[:3])
+	}
+
+	_, err = newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "value": []interface{}{json.Number("1.5")}},
+	})
+	if err == nil {
+		t.Error("fractional item should be rejected")
+	}
+}
+
+func TestParseResultByteToBits(t *testing.T) {
+	// zero bits inside and at the end of byte should be kept
+	res := parseResultByteToBits([]byte{0x05, 0x01}, 10)
+	expected := []uint16{1, 0, 1, 0, 0, 0, 0, 0, 1, 0}
+
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+}
+
+func TestGetBytesBase64Variants(t *testing.T) {
+	expected := []byte{0xFB, 0xFF}
+
+	for _, v := range []string{"+/8=", "+/8", "-_8=", "-_8"} {
+		res, err := getBytes(objx.Map{"value": v}, "value")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", v, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s: expected % x but got % x", v, expected, res)
+		}
+	}
+
+	if _, err := getBytes(objx.Map{"value": "+/8*"}, "value"); err == nil {
+		t.Error("expected error on invalid base64")
+	}
+}
+
+func TestParsePoints(t *testing.T) {
+	points, err := parsePointsCSV([]byte("name,function,address,quantity,type,scale,unit\n" +
+		"temperature,holding,0x64,2,float32,0.1,°C\n\n" +
+		"alarm,coil,5,,,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(points) != 2 || points[0].Address != 100 || points[0].Encoding != encFloat32 ||
+		points[0].Scale != 0.1 || points[0].Unit != "°C" || points[1].Function != pointCoil {
+		t.Errorf("unexpected points %+v", points)
+	}
+
+	for _, tc := range []struct {
+		parse func([]byte) ([]Point, error)
+		data  string
+		err   string
+	}{
+		{parsePointsCSV, "name,function,address\na,holding,1\nb,holding,70000", "line 3: address should be 0-65535"},
+		{parsePointsCSV, "name,function,address\na,holding,1\na,input,2", "line 3: duplicate name a"},
+		{parsePointsCSV, "name,function,address,scale,scale_preset\na,holding,1,0.5,tenths", "line 2: scale and scale_preset can't be used together"},
+		{parsePointsJSON, "[\n  {\"name\": \"a\", \"function\": \"holding\"},\n  {\"name\": \"b\", \"function\": \"register\"}\n]",
+			"line 3: function should be coil, discrete, input or holding"},
+	} {
+		_, err := tc.parse([]byte(tc.data))
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("expected error %q but got %v", tc.err, err)
+		}
+	}
+}
+
+func TestCheckFraming(t *testing.T) {
+	p := modbus.NewRTUPackager(1)
+	adu, _ := p.Encode(&modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{0, 0, 0, 1}})
+
+	for _, tc := range []struct {
+		pdu    modbus.ProtocolDataUnit
+		reason string
+	}{
+		{modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{2, 0, 1}}, ""},
+		{modbus.ProtocolDataUnit{FunctionCode: 0x83, Data: []byte{2}}, ""},
+		{modbus.ProtocolDataUnit{FunctionCode: 0x04, Data: []byte{2, 0, 1}}, "function code mismatch"},
+		{modbus.ProtocolDataUnit{FunctionCode: 0x03, Data: []byte{4, 0, 1}}, "byte count mismatch"},
+	} {
+		res, _ := p.Encode(&tc.pdu)
+
+		err := checkFraming(p, adu, res)
+		if (err == nil && tc.reason != "") || (err != nil && err.reason != tc.reason) {
+			t.Errorf("% x: expected %q but got %v", res, tc.reason, err)
+		}
+	}
+
+	srv := newTestService(badEchoTransporter{})
+
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: objx.Map{
+		"address": json.Number("1"), "quantity": json.Number("1"),
+	}})
+	if _, ok := err.(jsonrpc.Error); !ok {
+		t.Errorf("expected framing error but got %v", err)
+	}
+}
+
+// bigTransporter sends response of size bytes
+type bigTransporter struct {
+	size int
+}
+
+func (t bigTransporter) Send(adu []byte) ([]byte, error) {
+	return make([]byte, t.size), nil
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	tr := responseLimitTransporter{bigTransporter{1 << 20}, 1024}
+	if _, err := tr.Send([]byte{0}); err == nil {
+		t.Error("expected error of too large response")
+	}
+
+	tr = responseLimitTransporter{bigTransporter{1024}, 1024}
+	if _, err := tr.Send([]byte{0}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCANopenRequest(t *testing.T) {
+	tr := &echoTransporter{}
+	srv := newTestService(tr)
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-canopen", Params: objx.Map{"data": "QBAQAA=="}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x40, 0x10, 0x10, 0x00}
+	if !reflect.DeepEqual(res, expected) || !reflect.DeepEqual(tr.sent[7:], append([]byte{0x2B, 0x0D}, expected...)) {
+		t.Errorf("unexpected result % x of request % x", res, tr.sent)
+	}
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-canopen", Params: objx.Map{"data": "QBAQAA==", "mei_type": json.Number("14")}})
+	if err == nil {
+		t.Error("expected error of wrong mei_type")
+	}
+
+	rtu := &expectEchoTransporter{}
+	srv = New(rtu, func(s byte) modbus.Packager { return modbus.NewRTUPackager(s) })
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-canopen", Params: objx.Map{"data": "QBAQAA=="}})
+	if err == nil {
+		t.Error("expected error of missing response_length in rtu mode")
+	}
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-canopen",
+		Params: objx.Map{"data": "QBAQAA==", "response_length": json.Number("4")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slave id, function code, mei type, data and crc
+	if !reflect.DeepEqual(res, expected) || rtu.expected != 9 {
+		t.Errorf("unexpected result % x (expected response of %d bytes)", res, rtu.expected)
+	}
+}
+
+// expectEchoTransporter is echoTransporter which records response length expected from rtu transport
+type expectEchoTransporter struct {
+	echoTransporter
+	expected int
+}
+
+func (t *expectEchoTransporter) SendExpect(adu []byte, length int) ([]byte, error) {
+	t.expected = length
+	return t.Send(adu)
+}
@@ -0,0 +1,130 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// connectedReporter is implemented by transporters which know their connection state
+type connectedReporter interface {
+	Connected() bool
+}
+
+type transportHealth struct {
+	// slaves with own connection (empty for main transport)
+	SlaveIDs []int `json:"slave_ids,omitempty"`
+	// framings with own transport
+	Framings []string `json:"framings,omitempty"`
+	// devices with host on the transport
+	Devices []string `json:"devices,omitempty"`
+	// nil if transport doesn't report its state
+	Connected *bool `json:"connected"`
+}
+
+type healthResult struct {
+	Transports []transportHealth `json:"transports"`
+	// time of last response by slave id
+	LastResponse map[string]time.Time `json:"last_response"`
+}
+
+func connectionState(t modbus.Transporter) *bool {
+	r, ok := t.(connectedReporter)
+	if !ok {
+		return nil
+	}
+
+	connected := r.Connected()
+
+	return &connected
+}
+
+// health reports connection state of transports and last responses of slaves
+// it doesn't send anything (connections are opened on demand and closed when idle,
+// so last response time is better sign of bus connectivity)
+func (s Service) health(objx.Map) (interface{}, error) {
+	res := healthResult{
+		Transports:   []transportHealth{{Connected: connectionState(s.transport)}},
+		LastResponse: make(map[string]time.Time),
+	}
+
+	index := make(map[modbus.Transporter]int)
+
+	ids := make([]int, 0, len(s.connections))
+	for id := range s.connections {
+		ids = append(ids, int(id))
+	}
+
+	sort.Ints(ids)
+
+	// transportIndex returns index of transport health adding it on first use
+	transportIndex := func(t modbus.Transporter) int {
+		i, ok := index[t]
+		if !ok {
+			i = len(res.Transports)
+			index[t] = i
+			res.Transports = append(res.Transports, transportHealth{Connected: connectionState(t)})
+		}
+
+		return i
+	}
+
+	for _, id := range ids {
+		i := transportIndex(s.connections[byte(id)].transport)
+		res.Transports[i].SlaveIDs = append(res.Transports[i].SlaveIDs, id)
+	}
+
+	framings := make([]string, 0, len(s.framingConnections))
+	for name := range s.framingConnections {
+		framings = append(framings, name)
+	}
+
+	sort.Strings(framings)
+
+	for _, name := range framings {
+		i := transportIndex(s.framingConnections[name].transport)
+		res.Transports[i].Framings = append(res.Transports[i].Framings, name)
+	}
+
+	names := make([]string, 0, len(s.deviceConnections))
+	for name := range s.deviceConnections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		i := transportIndex(s.deviceConnections[name].transport)
+		res.Transports[i].Devices = append(res.Transports[i].Devices, name)
+	}
+
+	if s.metrics != nil {
+		s.metrics.mx.Lock()
+		for id, at := range s.metrics.lastResponse {
+			res.LastResponse[strconv.Itoa(int(id))] = at
+		}
+		s.metrics.mx.Unlock()
+	}
+
+	return res, nil
+}
@@ -0,0 +1,172 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// writeMethods contains methods which change slave state
+var writeMethods = map[string]bool{ // nolint: gochecknoglobals
+	"modbus-write-coil":               true,
+	"modbus-write-multiple-coils":     true,
+	"modbus-write-register":           true,
+	"modbus-write-multiple-registers": true,
+	"modbus-write-file-record":        true,
+	"modbus-read-write-registers":     true,
+	"modbus-write-read-point":         true,
+	"modbus-write-point":              true,
+	"modbus-set-bit":                  true,
+	"modbus-compare-and-write":        true,
+	"modbus-write-bits":               true,
+}
+
+type idempotentEntry struct {
+	// closed when write is done
+	done chan struct{}
+	res  interface{}
+	err  error
+	at   time.Time
+}
+
+// idempotencyKeys remembers results of writes by idempotency key
+// failed writes are forgotten so they can be retried
+type idempotencyKeys struct {
+	ttl   time.Duration
+	size  int
+	mx    sync.Mutex
+	items map[string]*idempotentEntry
+}
+
+// IdempotencyKeys sets how long (ttl) and how many (size) results of writes
+// with idempotency_key param are remembered (0 ttl disables keys)
+// repeated write with the same key returns remembered result without bus transaction
+func IdempotencyKeys(ttl time.Duration, size int) Option {
+	return func(s *Service) {
+		s.idempotency = nil
+		if ttl > 0 && size > 0 {
+			s.idempotency = &idempotencyKeys{ttl: ttl, size: size, items: make(map[string]*idempotentEntry)}
+		}
+	}
+}
+
+// start returns entry of key and true if write with this key is new
+// (caller should execute it and call finish)
+func (k *idempotencyKeys) start(key string) (*idempotentEntry, bool) {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if e, ok := k.items[key]; ok {
+		select {
+		case <-e.done:
+			if time.Since(e.at) <= k.ttl {
+				return e, false
+			}
+		default:
+			// the same write in progress
+			return e, false
+		}
+	}
+
+	if len(k.items) >= k.size {
+		k.evict()
+	}
+
+	e := &idempotentEntry{done: make(chan struct{})}
+	k.items[key] = e
+
+	return e, true
+}
+
+// evict removes expired entries or the oldest one if there are no expired
+func (k *idempotencyKeys) evict() {
+	var (
+		oldest string
+		at     time.Time
+	)
+
+	for key, e := range k.items {
+		select {
+		case <-e.done:
+		default:
+			continue
+		}
+
+		if time.Since(e.at) > k.ttl {
+			delete(k.items, key)
+			continue
+		}
+
+		if oldest == "" || e.at.Before(at) {
+			oldest, at = key, e.at
+		}
+	}
+
+	if len(k.items) >= k.size && oldest != "" {
+		delete(k.items, oldest)
+	}
+}
+
+func (k *idempotencyKeys) finish(key string, e *idempotentEntry, res interface{}, err error) {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	e.res, e.err, e.at = res, err, time.Now()
+
+	if err != nil && k.items[key] == e {
+		delete(k.items, key)
+	}
+
+	close(e.done)
+}
+
+// callIdempotent executes write once per idempotency_key
+// concurrent duplicate waits for result of the first write
+func (s Service) callIdempotent(req jsonrpc.Request) (interface{}, error) {
+	if !writeMethods[req.Method] {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "idempotency_key can be used with writes only")
+	}
+
+	if s.idempotency == nil {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "idempotency keys are disabled")
+	}
+
+	key := req.Method + "\x00" + req.Params.Get("idempotency_key").Str()
+
+	params := req.Params.Copy()
+	delete(params, "idempotency_key")
+
+	for {
+		e, ok := s.idempotency.start(key)
+		if ok {
+			res, err := s.call(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params})
+			s.idempotency.finish(key, e, res, err)
+
+			return res, err
+		}
+
+		<-e.done
+
+		// failed write is forgotten, so duplicate executes it again
+		if e.err == nil {
+			return e.res, nil
+		}
+	}
+}
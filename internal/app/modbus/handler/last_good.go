@@ -0,0 +1,70 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/stretchr/objx"
+)
+
+// staleResult wraps read result if last_good param set
+type staleResult struct {
+	Result interface{} `json:"result"`
+	// true if read failed and result is last successfully read data
+	Stale bool  `json:"stale"`
+	AgeMs int64 `json:"age_ms,omitempty"`
+}
+
+// readLastGood calls read and remembers its result if last_good param set
+// on read error it returns last good data of key and its age
+// (first reads and no_cache reads have no fallback and return error)
+func (s Service) readLastGood(params objx.Map, k cacheKey,
+	read func() ([]byte, error)) ([]byte, time.Duration, error) {
+	res, err := read()
+	if !params.Get("last_good").Bool() {
+		return res, 0, err
+	}
+
+	if err == nil {
+		s.lastGood.set(k, res)
+		return res, 0, nil
+	}
+
+	e, ok := s.lastGood.entry(k)
+	if !ok || s.noCache {
+		return nil, 0, err
+	}
+
+	// age is never zero for stale data
+	age := time.Since(e.at)
+	if age <= 0 {
+		age = time.Nanosecond
+	}
+
+	return e.data, age, nil
+}
+
+// withStale wraps result if last_good param set
+// age is zero if result is fresh
+func withStale(params objx.Map, res interface{}, age time.Duration) interface{} {
+	if !params.Get("last_good").Bool() {
+		return res
+	}
+
+	return staleResult{Result: res, Stale: age > 0, AgeMs: age.Milliseconds()}
+}
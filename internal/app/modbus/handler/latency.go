@@ -0,0 +1,114 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// latencyRecorder sums time of bus transactions
+// (nested methods can send concurrently)
+type latencyRecorder struct {
+	nanos int64
+}
+
+// ms returns recorded time in milliseconds
+func (r *latencyRecorder) ms() float64 {
+	return ms(time.Duration(atomic.LoadInt64(&r.nanos)))
+}
+
+// latencyTransporter records transaction time, it's innermost layer
+// so bus lock wait and frame encoding are excluded
+type latencyTransporter struct {
+	modbus.Transporter
+	recorder *latencyRecorder
+}
+
+func (t latencyTransporter) Send(adu []byte) ([]byte, error) {
+	start := time.Now()
+	res, err := t.Transporter.Send(adu)
+	atomic.AddInt64(&t.recorder.nanos, int64(time.Since(start)))
+
+	return res, err
+}
+
+// withLatency returns service which records time of its transactions
+func (s Service) withLatency() (Service, *latencyRecorder) {
+	recorder := &latencyRecorder{}
+
+	s = s.withLayer(func(t modbus.Transporter) modbus.Transporter {
+		return latencyTransporter{t, recorder}
+	})
+
+	return s, recorder
+}
+
+type latencyResult struct {
+	Result interface{} `json:"result"`
+	// total time of bus transactions in milliseconds (0 if nothing was sent)
+	Latency float64 `json:"latency_ms"`
+}
+
+// callWithLatency calls method and wraps result with time of its bus transactions
+// items of read-multi and fan-out writes get own latencies too
+func (s Service) callWithLatency(req jsonrpc.Request) (interface{}, error) {
+	srv, recorder := s.withLatency()
+	srv.itemLatency = true
+
+	params := req.Params.Copy()
+	delete(params, "with_latency")
+
+	res, err := srv.call(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	return latencyResult{Result: res, Latency: recorder.ms()}, nil
+}
+
+// callItem calls method of read-multi or fan-out item
+// latency of item is set if with_latency param passed,
+// item fails without transactions if time budget of batch is over
+func (s Service) callItem(req jsonrpc.Request, result *multiItemResult) {
+	if s.retryBudget.expired() {
+		result.Error = toRPCError(errRetryBudget)
+		return
+	}
+
+	srv, recorder := s, (*latencyRecorder)(nil)
+	if s.itemLatency {
+		srv, recorder = s.withLatency()
+	}
+
+	res, err := srv.call(req)
+
+	if recorder != nil {
+		latency := recorder.ms()
+		result.Latency = &latency
+	}
+
+	if err != nil {
+		result.Error = toRPCError(err)
+		return
+	}
+
+	result.Result = res
+}
@@ -0,0 +1,80 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// protocol limit of coils in one FC15 request
+const maxWriteCoils = 1968
+
+// LenientEcho accepts multiple writes (FC15/FC16) of slaveID as successful
+// if response has the same function code, address and quantity echo isn't compared
+// it's a shim for gateways which echo different but equivalent address (e.g. with base offset)
+func LenientEcho(slaveID byte) Option {
+	return func(s *Service) {
+		if s.lenientEcho == nil {
+			s.lenientEcho = make(map[byte]bool)
+		}
+
+		s.lenientEcho[slaveID] = true
+	}
+}
+
+// lenientEchoClient sends multiple writes without echo verification
+// results are requested quantity (as results of strict writes)
+type lenientEchoClient struct {
+	modbus.Client
+	s       Service
+	slaveID byte
+}
+
+func (c lenientEchoClient) WriteMultipleCoils(address, quantity uint16, value []byte) ([]byte, error) {
+	if quantity < 1 || quantity > maxWriteCoils {
+		return nil, fmt.Errorf("modbus: quantity '%v' must be between '%v' and '%v',", quantity, 1, maxWriteCoils)
+	}
+
+	return c.s.sendLenientWrite(c.slaveID, modbus.FuncCodeWriteMultipleCoils, address, quantity, value)
+}
+
+func (c lenientEchoClient) WriteMultipleRegisters(address, quantity uint16, value []byte) ([]byte, error) {
+	if quantity < 1 || quantity > maxWriteRegisters {
+		return nil, fmt.Errorf("modbus: quantity '%v' must be between '%v' and '%v',", quantity, 1, maxWriteRegisters)
+	}
+
+	return c.s.sendLenientWrite(c.slaveID, modbus.FuncCodeWriteMultipleRegisters, address, quantity, value)
+}
+
+// sendLenientWrite sends multiple write request, response is checked
+// by function code only (exception response is returned as error by send)
+func (s Service) sendLenientWrite(slaveID, function byte, addr, quantity uint16, value []byte) ([]byte, error) {
+	data := make([]byte, 5+len(value))
+	binary.BigEndian.PutUint16(data, addr)
+	binary.BigEndian.PutUint16(data[2:], quantity)
+	data[4] = byte(len(value))
+	copy(data[5:], value)
+
+	if _, err := s.send(slaveID, &modbus.ProtocolDataUnit{FunctionCode: function, Data: data}); err != nil {
+		return nil, err
+	}
+
+	return data[2:4], nil
+}
@@ -0,0 +1,3714 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+	"github.com/Rightech/ric-edge/third_party/goburrow/serial"
+)
+
+func num(s string) json.Number {
+	return json.Number(s)
+}
+
+func TestMethods(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		method string
+		params objx.Map
+		setup  func(m *mockSlave)
+		pdu    []byte
+		result interface{}
+		check  func(t *testing.T, m *mockSlave)
+	}{
+		{
+			name:   "read coils",
+			method: "modbus-read-coil",
+			params: objx.Map{"address": num("1"), "quantity": num("10")},
+			setup:  func(m *mockSlave) { m.coils[1], m.coils[3], m.coils[9] = true, true, true },
+			pdu:    []byte{0x01, 0x00, 0x01, 0x00, 0x0A},
+			result: []uint16{1, 0, 1, 0, 0, 0, 0, 0, 1, 0},
+		},
+		{
+			name:   "read coils as bitmask",
+			method: "modbus-read-coil",
+			params: objx.Map{"address": num("0"), "quantity": num("10"), "encoding": "bitmask"},
+			setup:  func(m *mockSlave) { m.coils[0], m.coils[9] = true, true },
+			pdu:    []byte{0x01, 0x00, 0x00, 0x00, 0x0A},
+			result: uint64(0x201),
+		},
+		{
+			name:   "read coils with summary",
+			method: "modbus-read-coil",
+			params: objx.Map{"address": num("0"), "quantity": num("4"), "summary": true},
+			setup:  func(m *mockSlave) { m.coils[0], m.coils[2], m.coils[3] = true, true, true },
+			pdu:    []byte{0x01, 0x00, 0x00, 0x00, 0x04},
+			result: bitsSummary{On: 3, Off: 1, Values: []uint16{1, 0, 1, 1}},
+		},
+		{
+			name:   "read discrete inputs with verbose summary",
+			method: "modbus-read-discrete",
+			params: objx.Map{"address": num("5"), "quantity": num("2"), "summary": true, "verbose": true},
+			setup:  func(m *mockSlave) { m.discretes[6] = true },
+			pdu:    []byte{0x02, 0x00, 0x05, 0x00, 0x02},
+			result: bitsSummary{On: 1, Off: 1, Values: []addressedBit{{5, uint16(0)}, {6, uint16(1)}}},
+		},
+		{
+			name:   "read discrete inputs",
+			method: "modbus-read-discrete",
+			params: objx.Map{"address": num("0"), "quantity": num("3")},
+			setup:  func(m *mockSlave) { m.discretes[2] = true },
+			pdu:    []byte{0x02, 0x00, 0x00, 0x00, 0x03},
+			result: []uint16{0, 0, 1},
+		},
+		{
+			name:   "read holding registers",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("5"), "quantity": num("2")},
+			setup:  func(m *mockSlave) { m.holding[5], m.holding[6] = 1, 0xFFFF },
+			pdu:    []byte{0x03, 0x00, 0x05, 0x00, 0x02},
+			result: []interface{}{uint16(1), uint16(0xFFFF)},
+		},
+		{
+			name:   "read input registers as float32",
+			method: "modbus-read-input",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "float32"},
+			setup:  func(m *mockSlave) { m.inputs[0], m.inputs[1] = 0x4291, 0x0000 },
+			pdu:    []byte{0x04, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{float32(72.5)},
+		},
+		{
+			name:   "read input registers as float32 with named byte arrangement",
+			method: "modbus-read-input",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "float32_cdab"},
+			setup:  func(m *mockSlave) { m.inputs[0], m.inputs[1] = 0x0000, 0x4291 },
+			pdu:    []byte{0x04, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{float32(72.5)},
+		},
+		{
+			name:   "read holding registers keyed by address",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("100"), "quantity": num("4"), "encoding": "uint32", "keyed": true},
+			setup:  func(m *mockSlave) { m.holding[101], m.holding[103] = 42, 7 },
+			pdu:    []byte{0x03, 0x00, 0x64, 0x00, 0x04},
+			result: map[string]interface{}{"100": uint32(42), "102": uint32(7)},
+		},
+		{
+			name:   "read holding registers with two-point calibration",
+			method: "modbus-read-holding",
+			params: objx.Map{
+				"address": num("0"), "quantity": num("1"),
+				"cal_raw_low": num("4"), "cal_raw_high": num("20"), "cal_eng_low": num("100"), "cal_eng_high": num("0"),
+			},
+			setup:  func(m *mockSlave) { m.holding[0] = 12 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x01},
+			result: []interface{}{float64(50)},
+		},
+		{
+			name:   "read input registers as fixed decimals string",
+			method: "modbus-read-input",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "float32", "result_type": "string", "decimals": num("2")},
+			setup:  func(m *mockSlave) { m.inputs[0], m.inputs[1] = 0x4291, 0x0000 },
+			pdu:    []byte{0x04, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{"72.50"},
+		},
+		{
+			name:   "read coils as booleans",
+			method: "modbus-read-coil",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "result_type": "boolean"},
+			setup:  func(m *mockSlave) { m.coils[1] = true },
+			pdu:    []byte{0x01, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{false, true},
+		},
+		{
+			name:   "read holding registers as bcd32",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "bcd32", "word_order": "little"},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1] = 0x5678, 0x1234 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{uint32(12345678)},
+		},
+		{
+			name:   "read holding registers as fixed point",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "fixed", "fractional_bits": num("8")},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1] = 0x0180, 0xFF80 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{1.5, -0.5},
+		},
+		{
+			name:   "read holding registers as timestamp of device local time",
+			method: "modbus-read-holding",
+			params: objx.Map{
+				"address": num("0"), "quantity": num("2"), "encoding": "timestamp", "word_order": "little",
+				"tz_offset": "3h",
+			},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1] = 0xF100, 0x6553 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{"2023-11-14T22:13:20+03:00"},
+		},
+		{
+			name:   "read input registers as epoch timestamp",
+			method: "modbus-read-input",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "timestamp", "timestamp_format": "epoch_ms"},
+			setup:  func(m *mockSlave) { m.inputs[0], m.inputs[1] = 0x6553, 0xF100 },
+			pdu:    []byte{0x04, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{int64(1700000000000)},
+		},
+		{
+			name:   "read holding registers with large numbers as strings",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("4"), "encoding": "uint32", "number_as_string": true},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1], m.holding[3] = 0xFFFF, 0xFFFF, 7 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x04},
+			result: []interface{}{"4294967295", "7"},
+		},
+		{
+			name:   "read holding registers with small numbers as strings",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("1"), "number_as_string": true},
+			setup:  func(m *mockSlave) { m.holding[0] = 0xFFFF },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x01},
+			result: []interface{}{uint16(0xFFFF)},
+		},
+		{
+			name:   "read coils as bitmask string",
+			method: "modbus-read-coil",
+			params: objx.Map{"address": num("0"), "quantity": num("64"), "encoding": "bitmask", "number_as_string": true},
+			setup:  func(m *mockSlave) { m.coils[0], m.coils[63] = true, true },
+			pdu:    []byte{0x01, 0x00, 0x00, 0x00, 0x40},
+			result: "9223372036854775809",
+		},
+		{
+			name:   "read holding registers with transform",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "transform": "0.5 * raw^2 - (raw - 1) / 4"},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1] = 3, 5 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{4.0, 11.5},
+		},
+		{
+			name:   "read coils with transform",
+			method: "modbus-read-coil",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "transform": "-raw * 2"},
+			setup:  func(m *mockSlave) { m.coils[1] = true },
+			pdu:    []byte{0x01, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{0.0, -2.0},
+		},
+		{
+			name:   "read holding registers leniently",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("3"), "encoding": "bcd", "decode_mode": "lenient"},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1], m.holding[2] = 0x1234, 0x00FF, 0x0042 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x03},
+			result: []interface{}{uint16(1234), nil, uint16(42)},
+		},
+		{
+			name:   "read incomplete float32 leniently",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("3"), "encoding": "float32", "decode_mode": "lenient"},
+			setup:  func(m *mockSlave) { m.holding[0] = 0x3F80 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x03},
+			result: []interface{}{float32(1), nil},
+		},
+		{
+			name:   "read holding register with sign register",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("1"), "encoding": "sign_register", "sign_address": num("5")},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[5] = 100, 1 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x06},
+			result: []interface{}{int32(-100)},
+		},
+		{
+			name:   "read holding registers as bytes",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "bytes"},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1] = 0x0102, 0x0A0B },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x02},
+			result: []interface{}{uint8(1), uint8(2), uint8(10), uint8(11)},
+		},
+		{
+			name:   "read holding register as bytes with low byte first",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("1"), "encoding": "bytes", "byte_order": "little"},
+			setup:  func(m *mockSlave) { m.holding[0] = 0x0102 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x01},
+			result: []interface{}{uint8(2), uint8(1)},
+		},
+		{
+			name:   "read holding registers verbose with raw registers",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "float32", "word_order": "little", "verbose": true, "slave_id": num("3")},
+			setup:  func(m *mockSlave) { m.holding[1] = 0x3F80 },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x02},
+			result: verboseResult{
+				Method: "modbus-read-holding", SlaveID: 3,
+				Values: []interface{}{float32(1)}, Encoding: "float32", ByteOrder: "big", WordOrder: "little",
+				Raw: []byte{0x00, 0x00, 0x3F, 0x80}, RawRegisters: []uint16{0x0000, 0x3F80},
+				ReportedBytes: 4, ReceivedBytes: 4, FunctionCode: 0x03, PDULength: 6,
+			},
+		},
+		{
+			name:   "read holding registers with null value",
+			method: "modbus-read-holding",
+			params: objx.Map{"address": num("0"), "quantity": num("3"), "encoding": "int16", "null_value": num("32767")},
+			setup:  func(m *mockSlave) { m.holding[0], m.holding[1], m.holding[2] = 0x7FFF, 0, 0xFFFF },
+			pdu:    []byte{0x03, 0x00, 0x00, 0x00, 0x03},
+			result: []interface{}{nil, int16(0), int16(-1)},
+		},
+		{
+			name:   "write coil",
+			method: "modbus-write-coil",
+			params: objx.Map{"address": num("7"), "value": num("1")},
+			pdu:    []byte{0x05, 0x00, 0x07, 0xFF, 0x00},
+			check: func(t *testing.T, m *mockSlave) {
+				if !m.coils[7] {
+					t.Error("coil 7 should be set")
+				}
+			},
+		},
+		{
+			name:   "write multiple coils",
+			method: "modbus-write-multiple-coils",
+			params: objx.Map{"address": num("0"), "quantity": num("3"), "value": []interface{}{num("1"), num("0"), num("1")}},
+			pdu:    []byte{0x0F, 0x00, 0x00, 0x00, 0x03, 0x01, 0x05},
+			check: func(t *testing.T, m *mockSlave) {
+				if !m.coils[0] || m.coils[1] || !m.coils[2] {
+					t.Errorf("unexpected coils %v", m.coils[:3])
+				}
+			},
+		},
+		{
+			name:   "write multiple coils from booleans",
+			method: "modbus-write-multiple-coils",
+			params: objx.Map{"address": num("0"), "values": []interface{}{true, false, true}},
+			pdu:    []byte{0x0F, 0x00, 0x00, 0x00, 0x03, 0x01, 0x05},
+			check: func(t *testing.T, m *mockSlave) {
+				if !m.coils[0] || m.coils[1] || !m.coils[2] {
+					t.Errorf("unexpected coils %v", m.coils[:3])
+				}
+			},
+		},
+		{
+			name:   "write register",
+			method: "modbus-write-register",
+			params: objx.Map{"address": num("2"), "value": num("258")},
+			pdu:    []byte{0x06, 0x00, 0x02, 0x01, 0x02},
+			check: func(t *testing.T, m *mockSlave) {
+				if m.holding[2] != 258 {
+					t.Errorf("unexpected register %v", m.holding[2])
+				}
+			},
+		},
+		{
+			name:   "write multiple registers as int32",
+			method: "modbus-write-multiple-registers",
+			params: objx.Map{"address": num("10"), "value": num("-2"), "encoding": "int32", "word_order": "little"},
+			pdu:    []byte{0x10, 0x00, 0x0A, 0x00, 0x02, 0x04, 0xFF, 0xFE, 0xFF, 0xFF},
+			check: func(t *testing.T, m *mockSlave) {
+				if m.holding[10] != 0xFFFE || m.holding[11] != 0xFFFF {
+					t.Errorf("unexpected registers %v", m.holding[10:12])
+				}
+			},
+		},
+		{
+			name:   "write multiple registers clamped",
+			method: "modbus-write-multiple-registers",
+			params: objx.Map{"address": num("0"), "value": num("40000"), "encoding": "int16", "clamp": true},
+			pdu:    []byte{0x10, 0x00, 0x00, 0x00, 0x01, 0x02, 0x7F, 0xFF},
+			result: clampResult{Result: []uint16{1}, Clamped: true},
+		},
+		{
+			name:   "write multiple registers as bcd",
+			method: "modbus-write-multiple-registers",
+			params: objx.Map{"address": num("0"), "value": num("1985"), "encoding": "bcd"},
+			pdu:    []byte{0x10, 0x00, 0x00, 0x00, 0x01, 0x02, 0x19, 0x85},
+		},
+		{
+			name:   "read write registers",
+			method: "modbus-read-write-registers",
+			params: objx.Map{
+				"read_address": num("0"), "read_quantity": num("1"),
+				"write_address": num("0"), "value": []interface{}{num("3")},
+			},
+			pdu:    []byte{0x17, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x02, 0x00, 0x03},
+			result: []interface{}{uint16(3)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &mockSlave{}
+			if tc.setup != nil {
+				tc.setup(m)
+			}
+
+			res, err := newMockService(m).Call(jsonrpc.Request{Method: tc.method, Params: tc.params})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			m.assertPDU(t, tc.pdu)
+
+			if tc.result != nil && !reflect.DeepEqual(res, tc.result) {
+				t.Errorf("expected %#v but got %#v", tc.result, res)
+			}
+
+			if tc.check != nil {
+				tc.check(t, m)
+			}
+		})
+	}
+}
+
+func TestMethodException(t *testing.T) {
+	m := &mockSlave{}
+
+	_, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("255"), "quantity": num("2")},
+	})
+
+	var mbErr *modbus.ModbusError
+	if !errors.As(err, &mbErr) || mbErr.ExceptionCode != modbus.ExceptionCodeIllegalDataAddress {
+		t.Errorf("expected illegal data address exception but got %v", err)
+	}
+}
+
+func TestInvalidBCD(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 0x12A4
+
+	_, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "encoding": "bcd"},
+	})
+	if err == nil || err.Error() != "modbus: invalid bcd nibble 'A' at position 2 of '12 a4'" {
+		t.Errorf("expected invalid nibble error but got %v", err)
+	}
+}
+
+func TestRetryBusy(t *testing.T) {
+	params := objx.Map{"address": num("0"), "quantity": num("1")}
+
+	m := &mockSlave{busy: 2}
+
+	_, err := newMockService(m, Retry(2, time.Millisecond)).
+		Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if len(m.pdus) != 3 {
+		t.Errorf("expected 3 requests but got %d", len(m.pdus))
+	}
+
+	// permanent exceptions are not retried
+	m = &mockSlave{}
+	params["address"] = num("255")
+	params["quantity"] = num("2")
+
+	_, err = newMockService(m, Retry(2, time.Millisecond)).
+		Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err == nil || len(m.pdus) != 1 {
+		t.Errorf("expected one failed request but got %d (%v)", len(m.pdus), err)
+	}
+}
+
+func TestSlaveTransportRetry(t *testing.T) {
+	params := objx.Map{"address": num("0"), "quantity": num("1")}
+
+	// slave override enables retries disabled globally
+	m := &mockSlave{busy: 2}
+
+	_, err := newMockService(m, SlaveTransport(0, SlaveTransportConfig{RetryAttempts: 2})).
+		Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err != nil || len(m.pdus) != 3 {
+		t.Errorf("expected 3 requests but got %d (%v)", len(m.pdus), err)
+	}
+
+	// and disables retries enabled globally
+	m = &mockSlave{busy: 2}
+
+	_, err = newMockService(m, Retry(2, time.Millisecond),
+		SlaveTransport(0, SlaveTransportConfig{RetryAttempts: -1})).
+		Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err == nil || len(m.pdus) != 1 {
+		t.Errorf("expected one failed request but got %d (%v)", len(m.pdus), err)
+	}
+}
+
+func TestBusRateLimit(t *testing.T) {
+	conn := &mockSlave{}
+	srv := newMockService(&mockSlave{}, BusRateLimit(10, 0), SlaveConnection(5, conn))
+
+	read := func(slaveID string) error {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"slave_id": num(slaveID), "address": num("0"), "quantity": num("1")},
+		})
+
+		return err
+	}
+
+	if err := read("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// limit is shared by slaves of the bus
+	if err := read("2"); !errors.Is(err, errBusBusy) {
+		t.Errorf("expected bus busy error but got %v", err)
+	}
+
+	// slave with own connection isn't on the bus
+	if err := read("5"); err != nil || len(conn.pdus) != 1 {
+		t.Errorf("expected read of own connection but got %v", err)
+	}
+}
+
+func TestBusQueue(t *testing.T) {
+	srv := newMockService(&mockSlave{}, BusQueue(1, 10*time.Millisecond), UnsafeParallel(5))
+
+	// bus is taken by another transaction for longer than queue wait
+	if err := srv.bus.acquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1")},
+	})
+	if !errors.Is(err, errBusBusy) {
+		t.Errorf("expected bus busy error but got %v", err)
+	}
+
+	// parallel slave doesn't wait for the bus
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"slave_id": num("5"), "address": num("0"), "quantity": num("1")},
+	})
+	if err != nil {
+		t.Errorf("unexpected error of parallel slave %v", err)
+	}
+
+	srv.bus.release()
+}
+
+// roundingSlave stores written registers with lowest bit error (as some devices do)
+// and reads back registers written by FC23 with the same error
+type roundingSlave struct {
+	*mockSlave
+}
+
+func (m roundingSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.mockSlave.Send(adu)
+	if adu[7] == modbus.FuncCodeWriteMultipleRegisters {
+		m.holding[1]++
+	}
+
+	if adu[7] == modbus.FuncCodeReadWriteMultipleRegisters {
+		res[len(res)-1]++
+	}
+
+	return res, err
+}
+
+func TestWriteVerifyTolerance(t *testing.T) {
+	for _, tc := range []struct {
+		tolerance string
+		ok        bool
+	}{
+		{"0", false},
+		{"0.001", true},
+	} {
+		srv := New(roundingSlave{&mockSlave{}}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-write-multiple-registers",
+			Params: objx.Map{
+				"address": num("0"), "value": num("72.5"), "encoding": "float32",
+				"verify": true, "verify_tolerance": num(tc.tolerance),
+			},
+		})
+		if (err == nil) != tc.ok {
+			t.Errorf("tolerance %s: unexpected error %v", tc.tolerance, err)
+		}
+	}
+}
+
+func TestReadWriteVerify(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m, ReadWriteVerify(0))
+
+	for _, tc := range []struct {
+		method string
+		params objx.Map
+		pdus   [][]byte
+	}{
+		{
+			"modbus-write-register",
+			objx.Map{"address": num("4"), "value": num("7"), "verify": true},
+			[][]byte{{0x17, 0x00, 0x04, 0x00, 0x01, 0x00, 0x04, 0x00, 0x01, 0x02, 0x00, 0x07}},
+		},
+		{
+			"modbus-write-multiple-registers",
+			objx.Map{"address": num("5"), "quantity": num("2"), "value": []interface{}{num("1"), num("2")}, "verify": true},
+			[][]byte{{0x17, 0x00, 0x05, 0x00, 0x02, 0x00, 0x05, 0x00, 0x02, 0x04, 0x00, 0x01, 0x00, 0x02}},
+		},
+		// other slaves use write and read
+		{
+			"modbus-write-register",
+			objx.Map{"slave_id": num("2"), "address": num("4"), "value": num("8"), "verify": true},
+			[][]byte{{0x06, 0x00, 0x04, 0x00, 0x08}, {0x03, 0x00, 0x04, 0x00, 0x01}},
+		},
+	} {
+		m.pdus = nil
+
+		if _, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: tc.params}); err != nil {
+			t.Fatalf("%v: %v", tc.params, err)
+		}
+
+		if !reflect.DeepEqual(m.pdus, tc.pdus) {
+			t.Errorf("%v: unexpected pdus %x", tc.params, m.pdus)
+		}
+	}
+
+	if m.holding[4] != 8 || m.holding[6] != 2 {
+		t.Error("values are not written")
+	}
+
+	// written value is checked against read back one
+	srv = New(roundingSlave{m}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, ReadWriteVerify(0))
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("1"), "value": num("5"), "verify": true},
+	})
+	if err == nil {
+		t.Error("expected verification error")
+	}
+}
+
+func TestStats(t *testing.T) {
+	srv := newMockService(&mockSlave{})
+
+	for _, addr := range []string{"0", "255"} {
+		_, _ = srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"address": num(addr), "quantity": num("2")},
+		})
+	}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-stats", Params: objx.Map{"reset": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := res.(metricsResult)
+	if stats.Transactions != 2 || stats.Errors[errClassException] != 1 || stats.Slaves["0"].Transactions != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	res, _ = srv.Call(jsonrpc.Request{Method: "modbus-stats", Params: objx.Map{}})
+	if res.(metricsResult).Transactions != 0 {
+		t.Errorf("stats should be reset but got %+v", res)
+	}
+}
+
+func TestSlowThreshold(t *testing.T) {
+	srv := newMockService(&mockSlave{}, SlowThreshold(time.Nanosecond))
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, _ := srv.Call(jsonrpc.Request{Method: "modbus-stats", Params: objx.Map{}})
+	if stats := res.(metricsResult); stats.Slow != 1 || stats.Slaves["0"].Slow != 1 {
+		t.Errorf("expected slow transaction but got %+v", stats)
+	}
+}
+
+func TestReadMulti(t *testing.T) {
+	bus, conn := &mockSlave{}, &mockSlave{}
+	bus.holding[0], conn.holding[0] = 1, 2
+
+	srv := newMockService(bus, SlaveConnection(5, conn))
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-multi",
+		Params: objx.Map{"items": []interface{}{
+			map[string]interface{}{"slave_id": num("1"), "address": num("0"), "quantity": num("1")},
+			map[string]interface{}{"slave_id": num("5"), "address": num("0"), "quantity": num("1")},
+			map[string]interface{}{"slave_id": num("1"), "method": "modbus-write-coil"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := res.([]multiItemResult)
+
+	if !reflect.DeepEqual(results[0].Result, []interface{}{uint16(1)}) ||
+		!reflect.DeepEqual(results[1].Result, []interface{}{uint16(2)}) {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if results[2].Error == nil {
+		t.Error("write method should be rejected")
+	}
+
+	if len(bus.pdus) != 1 || len(conn.pdus) != 1 {
+		t.Errorf("expected one request per connection but got %d and %d", len(bus.pdus), len(conn.pdus))
+	}
+}
+
+// slowSlave holds transaction until released
+type slowSlave struct {
+	*mockSlave
+	started, release chan struct{}
+}
+
+func (m slowSlave) Send(adu []byte) ([]byte, error) {
+	close(m.started)
+	<-m.release
+
+	return m.mockSlave.Send(adu)
+}
+
+func TestShutdownDrainsCalls(t *testing.T) {
+	m := slowSlave{&mockSlave{}, make(chan struct{}), make(chan struct{})}
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	params := objx.Map{"address": num("0"), "quantity": num("1")}
+	errs := make(chan error, 1)
+
+	go func() {
+		_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+		errs <- err
+	}()
+
+	<-m.started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- srv.Shutdown(context.Background()) }()
+
+	for closed := false; !closed; {
+		srv.life.mx.Lock()
+		closed = srv.life.closed
+		srv.life.mx.Unlock()
+	}
+
+	// new calls are rejected while call in progress
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if !errors.Is(err, errShutdown) {
+		t.Errorf("expected shutdown error but got %v", err)
+	}
+
+	close(m.release)
+
+	if err := <-errs; err != nil {
+		t.Errorf("call in progress should complete but got %v", err)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("unexpected shutdown error %v", err)
+	}
+}
+
+// enronSlave answers reads with 32-bit registers (value is address + 0x10000)
+type enronSlave struct{}
+
+func (enronSlave) Send(adu []byte) ([]byte, error) {
+	addr := binary.BigEndian.Uint16(adu[8:])
+	quantity := binary.BigEndian.Uint16(adu[10:])
+
+	res := append([]byte{}, adu[:7]...)
+	res = append(res, adu[7], byte(quantity*4))
+
+	for i := uint16(0); i < quantity; i++ {
+		res = append(res, 0, 1, 0, 0)
+		binary.BigEndian.PutUint16(res[len(res)-2:], addr+i)
+	}
+
+	binary.BigEndian.PutUint16(res[4:], uint16(len(res)-6))
+
+	return res, nil
+}
+
+func TestReadEnron(t *testing.T) {
+	res, err := newTestService(enronSlave{}).Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("7000"), "quantity": num("2"), "enron": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{uint32(0x10000 + 7000), uint32(0x10000 + 7001)}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+}
+
+func TestLastGoodFallback(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 7
+
+	srv := newMockService(m)
+	params := objx.Map{"address": num("0"), "quantity": num("1"), "last_good": true}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err != nil || res.(staleResult).Stale {
+		t.Fatalf("expected fresh result but got %+v (%v)", res, err)
+	}
+
+	m.busy = 1
+
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := res.(staleResult)
+	if !stale.Stale || !reflect.DeepEqual(stale.Result, []interface{}{uint16(7)}) {
+		t.Errorf("expected stale last good value but got %+v", stale)
+	}
+
+	// no_cache bypasses fallback
+	m.busy = 1
+	params["no_cache"] = true
+
+	if _, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params}); err == nil {
+		t.Error("expected read error with no_cache")
+	}
+
+	// without opt-in read error is returned
+	m.busy = 1
+	delete(params, "last_good")
+	delete(params, "no_cache")
+
+	if _, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params}); err == nil {
+		t.Error("expected read error")
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 1
+
+	srv := newMockService(m, ReadCache(time.Minute))
+	read := func(params objx.Map) interface{} {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	read(objx.Map{"address": num("0"), "quantity": num("1")})
+
+	m.holding[0] = 2
+
+	// fresh read updates cache
+	for _, params := range []objx.Map{
+		{"address": num("0"), "quantity": num("1"), "no_cache": true},
+		{"address": num("0"), "quantity": num("1")},
+	} {
+		if res := read(params); !reflect.DeepEqual(res, []interface{}{uint16(2)}) {
+			t.Errorf("expected fresh value but got %v", res)
+		}
+	}
+
+	if len(m.pdus) != 2 {
+		t.Errorf("expected 2 bus transactions but got %d", len(m.pdus))
+	}
+}
+
+func TestReadPoints(t *testing.T) {
+	slave := &mockSlave{}
+	slave.holding[100] = 215
+	slave.coils[5] = true
+
+	srv := newMockService(slave, Profile(
+		Point{Name: "temperature", Function: pointHolding, Address: 100, Encoding: encInt16, ScalePreset: "tenths", Unit: "°C"},
+		Point{Name: "alarm", Function: pointCoil, Address: 5},
+	))
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-point",
+		Params: objx.Map{"point": "temperature", "with_units": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := res.(pointValue)
+	if math.Abs(v.Value.(float64)-21.5) > 1e-9 || v.Unit != "°C" {
+		t.Errorf("unexpected result %+v", v)
+	}
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-points",
+		Params: objx.Map{"points": []interface{}{"temperature", "alarm"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := res.(map[string]interface{})
+	if values["alarm"] != uint16(1) || math.Abs(values["temperature"].(float64)-21.5) > 1e-9 {
+		t.Errorf("unexpected result %v", values)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	slave := &mockSlave{}
+	srv := newMockService(slave, IdempotencyKeys(time.Minute, 10))
+
+	for i := 0; i < 2; i++ {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-write-register",
+			Params: objx.Map{"address": num("1"), "value": num("5"), "idempotency_key": "k1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(slave.pdus) != 1 {
+		t.Errorf("expected one write but got %v", len(slave.pdus))
+	}
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("1"), "quantity": num("1"), "idempotency_key": "k2"},
+	})
+	if err == nil {
+		t.Error("expected error of idempotency_key with read")
+	}
+}
+
+func TestTraceFunc(t *testing.T) {
+	var events []TraceEvent
+
+	srv := newMockService(&mockSlave{}, TraceFunc(func(e TraceEvent) {
+		events = append(events, e)
+	}))
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"slave_id": num("3"), "address": num("1"), "quantity": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected one event but got %v", len(events))
+	}
+
+	e := events[0]
+	if e.SlaveID != 3 || e.Err != nil ||
+		!reflect.DeepEqual(e.Request, []byte{0x03, 0x00, 0x01, 0x00, 0x01}) ||
+		!reflect.DeepEqual(e.Response, []byte{0x03, 0x02, 0x00, 0x00}) {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestWritePointSlaveOverride(t *testing.T) {
+	slave := &mockSlave{}
+	slaveID := byte(1)
+
+	var traced []byte
+
+	srv := newMockService(slave,
+		Profile(Point{Name: "setpoint", Function: pointHolding, SlaveID: &slaveID, Address: 7, Scale: 0.1}),
+		TraceFunc(func(e TraceEvent) { traced = append(traced, e.SlaveID) }),
+		AddressBase(1),
+	)
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-point",
+		Params: objx.Map{"point": "setpoint", "value": num("21.5"), "slave_id": num("9")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if slave.holding[7] != 215 || !reflect.DeepEqual(traced, []byte{9}) {
+		t.Errorf("unexpected register %v written to slaves %v", slave.holding[7], traced)
+	}
+}
+
+func TestPointImpliedQuantity(t *testing.T) {
+	slave := &mockSlave{}
+	srv := newMockService(slave, Profile(
+		Point{Name: "limits", Function: pointHolding, Address: 10, Encoding: encInt32},
+		Point{Name: "broken", Function: pointHolding, Address: 20, Encoding: encInt32, Quantity: 3},
+	))
+
+	// quantity of two int32 values is 4
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-point",
+		Params: objx.Map{"point": "limits", "value": []interface{}{num("-1"), num("2")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if slave.holding[10] != 0xFFFF || slave.holding[13] != 2 {
+		t.Errorf("unexpected registers %v", slave.holding[10:14])
+	}
+
+	for _, method := range []string{"modbus-write-point", "modbus-read-point"} {
+		_, err = srv.Call(jsonrpc.Request{Method: method, Params: objx.Map{"point": "broken", "value": num("1")}})
+		if err == nil {
+			t.Errorf("%s: quantity mismatch with encoding should be rejected", method)
+		}
+	}
+}
+
+func TestScanMaxFound(t *testing.T) {
+	slave := &timeoutsSlave{mockSlave: &mockSlave{}}
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	params := objx.Map{"to": num("10"), "max_found": num("3"), "delay_ms": num("1"), "timeout_ms": num("100")}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-scan", Params: params.Copy()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.(scanResult)
+	if len(r.Slaves) != 3 || r.Complete || len(slave.pdus) != 3 {
+		t.Errorf("unexpected result %+v after %v probes", r, len(slave.pdus))
+	}
+
+	if !reflect.DeepEqual(slave.timeouts, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond}) {
+		t.Errorf("unexpected probe timeouts %v", slave.timeouts)
+	}
+
+	// transport without per transaction timeout can't apply it
+	if _, err := newMockService(&mockSlave{}).Call(jsonrpc.Request{Method: "modbus-scan", Params: params.Copy()}); err == nil {
+		t.Error("expected error of unsupported timeout_ms")
+	}
+
+	// delay isn't slept if it would pass scan timeout
+	start := time.Now()
+
+	res, err = newMockService(&mockSlave{}).Call(jsonrpc.Request{
+		Method: "modbus-scan",
+		Params: objx.Map{"to": num("10"), "delay_ms": num("200"), "timeout": "50ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := res.(scanResult); len(r.Slaves) != 1 || r.Complete || time.Since(start) > 150*time.Millisecond {
+		t.Errorf("unexpected result %+v after %v", r, time.Since(start))
+	}
+}
+
+func TestHealth(t *testing.T) {
+	srv := newMockService(&mockSlave{}, SlaveConnection(5, &mockSlave{}), FramingConnection("rtu", &mockSlave{}))
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"slave_id": num("5"), "address": num("0"), "quantity": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-health", Params: objx.Map{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := res.(healthResult)
+	if len(h.Transports) != 3 || !reflect.DeepEqual(h.Transports[1].SlaveIDs, []int{5}) ||
+		!reflect.DeepEqual(h.Transports[2].Framings, []string{"rtu"}) {
+		t.Errorf("unexpected transports %+v", h.Transports)
+	}
+
+	if _, ok := h.LastResponse["5"]; !ok || len(h.LastResponse) != 1 {
+		t.Errorf("unexpected last responses %v", h.LastResponse)
+	}
+}
+
+func TestSlaveStatus(t *testing.T) {
+	srv := newMockService(&mockSlave{})
+
+	status := func() slaveStatus {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-slave-status", Params: objx.Map{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.(map[string]slaveStatus)["1"]
+	}
+
+	read := func(addr string) {
+		_, _ = srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"slave_id": num("1"), "address": num(addr), "quantity": num("2")},
+		})
+	}
+
+	read("0")
+	read("65535")
+	read("65535")
+
+	st := status()
+	if st.LastSuccess == nil || st.ConsecutiveFailures != 2 || st.LastError == nil || st.LastError.Type != errClassException ||
+		!strings.Contains(st.LastError.Message, "illegal data address") {
+		t.Errorf("unexpected status %+v (last error %+v)", st, st.LastError)
+	}
+
+	read("0")
+
+	if st = status(); st.ConsecutiveFailures != 0 || st.LastError == nil {
+		t.Errorf("success should reset failures only but got %+v", st)
+	}
+}
+
+func TestDevices(t *testing.T) {
+	// pump1 and pump2 are behind the same gateway, meter has the same unit id as pump1 on other gateway
+	devices := []Device{
+		{Name: "pump1", UnitID: 3, Host: "gw1", Points: []Point{{Name: "flow", Function: pointHolding, Address: 10}}},
+		{Name: "pump2", UnitID: 4, Host: "gw1", Points: []Point{{Name: "flow", Function: pointHolding, Address: 20}}},
+		{Name: "meter", UnitID: 3, Host: "gw2", Points: []Point{{Name: "flow", Function: pointHolding, Address: 10}}},
+	}
+
+	if err := ValidateDevices(devices); err != nil {
+		t.Fatal(err)
+	}
+
+	gw1 := &unitSlave{mockSlave: &mockSlave{}}
+	gw1.holding[10], gw1.holding[20] = 11, 22
+
+	gw2 := &unitSlave{mockSlave: &mockSlave{}}
+	gw2.holding[10] = 33
+
+	main := &mockSlave{}
+	main.holding[10] = 44
+
+	srv := newMockService(main, ReadCache(time.Minute),
+		DeviceConnection("pump1", gw1), DeviceConnection("pump2", gw1), DeviceConnection("meter", gw2),
+		Devices(devices...))
+
+	for _, tc := range []struct {
+		device   string
+		expected uint16
+	}{
+		{"pump1", 11},
+		{"pump2", 22},
+		// the same unit id and address as pump1 but not its cached value
+		{"meter", 33},
+	} {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"device": tc.device, "point": "flow"}})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.device, err)
+		}
+
+		if res != tc.expected {
+			t.Errorf("%s: expected %d but got %v", tc.device, tc.expected, res)
+		}
+	}
+
+	// slave id 3 without device goes to the main connection
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"slave_id": num("3"), "address": num("10"), "quantity": num("1")},
+	})
+	if err != nil || !reflect.DeepEqual(res, []interface{}{uint16(44)}) {
+		t.Errorf("unexpected read of slave 3 %v (%v)", res, err)
+	}
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-write-point", Params: objx.Map{"device": "pump2", "point": "flow", "value": num("7")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gw1.holding[20] != 7 || !reflect.DeepEqual(gw1.units, []byte{3, 4, 4}) || !reflect.DeepEqual(gw2.units, []byte{3}) {
+		t.Errorf("unexpected value %d or unit ids %v %v", gw1.holding[20], gw1.units, gw2.units)
+	}
+
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-slave-status", Params: objx.Map{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := res.(map[string]slaveStatus)
+	if st := statuses["pump1"]; st.UnitID != 3 || st.LastSuccess == nil {
+		t.Errorf("unexpected status of pump1 %+v", st)
+	}
+
+	if st := statuses["3"]; st.UnitID != 0 || st.LastSuccess == nil {
+		t.Errorf("unexpected status of slave 3 %+v", st)
+	}
+
+	for _, params := range []objx.Map{
+		{"device": "pump1", "point": "flow", "slave_id": num("5")},
+		{"device": "pump3", "point": "flow"},
+		{"device": "pump1", "point": "level"},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: params}); err == nil {
+			t.Errorf("expected error of %v", params)
+		}
+	}
+
+	slaveID := byte(3)
+
+	for _, devices := range [][]Device{
+		{{Name: "a", UnitID: 0}},
+		{{Name: "a", UnitID: 248}},
+		{{Name: "1", UnitID: 3}},
+		{{Name: "a", UnitID: 3, Host: "10.0.0.2:502"}, {Name: "b", UnitID: 3, Host: "10.0.0.2:502"}},
+		{{Name: "a", UnitID: 3, Points: []Point{{Name: "x", Function: pointHolding, SlaveID: &slaveID}}}},
+	} {
+		if err := ValidateDevices(devices); err == nil {
+			t.Errorf("expected error of devices %+v", devices)
+		}
+	}
+}
+
+func TestSetBit(t *testing.T) {
+	slave := &mockSlave{}
+	slave.holding[3] = 0x00F0
+	srv := newMockService(slave)
+
+	for _, tc := range []struct {
+		bit, value string
+		expected   uint16
+	}{
+		{"0", "1", 0x00F1},
+		{"4", "0", 0x00E1},
+	} {
+		res, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-set-bit",
+			Params: objx.Map{"address": num("3"), "bit": num(tc.bit), "value": num(tc.value)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.(setBitResult).Value != tc.expected || slave.holding[3] != tc.expected {
+			t.Errorf("expected %04X but got %+v (register %04X)", tc.expected, res, slave.holding[3])
+		}
+	}
+}
+
+func TestUDPTransport(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	slave := &mockSlave{}
+	slave.holding[0] = 42
+
+	go func() {
+		buf := make([]byte, 260)
+
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			res, _ := slave.Send(buf[:n])
+
+			// late response of previous transaction is dropped by transporter
+			stale := append([]byte{}, res...)
+			binary.BigEndian.PutUint16(stale, binary.BigEndian.Uint16(res)-1)
+
+			for _, b := range [][]byte{stale, res, res} {
+				_, _ = conn.WriteTo(b, addr)
+			}
+		}
+	}()
+
+	tr := modbus.NewUDPTransporter(conn.LocalAddr().String())
+	defer tr.Close()
+
+	srv := New(tr, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	// duplicate of first response is dropped on second transaction
+	for i := 0; i < 2; i++ {
+		res, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"address": num("0"), "quantity": num("1")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(res, []interface{}{uint16(42)}) {
+			t.Errorf("unexpected result %v", res)
+		}
+	}
+}
+
+func TestCoalesceReads(t *testing.T) {
+	m := slowSlave{&mockSlave{}, make(chan struct{}), make(chan struct{})}
+	m.holding[0] = 7
+
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, CoalesceReads(true))
+	params := objx.Map{"address": num("0"), "quantity": num("1")}
+
+	results := make(chan interface{}, 2)
+	read := func() {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params.Copy()})
+		if err != nil {
+			t.Error(err)
+		}
+		results <- res
+	}
+
+	go read()
+	<-m.started
+
+	go read()
+
+	key := cacheKey{0, modbus.FuncCodeReadHoldingRegisters, 0, 1, ""}
+	for joined := false; !joined; {
+		srv.flights.mx.Lock()
+		joined = srv.flights.items[key].waiters == 1
+		srv.flights.mx.Unlock()
+	}
+
+	close(m.release)
+
+	for i := 0; i < 2; i++ {
+		if res := <-results; !reflect.DeepEqual(res, []interface{}{uint16(7)}) {
+			t.Errorf("unexpected result %v", res)
+		}
+	}
+
+	if len(m.pdus) != 1 {
+		t.Errorf("expected one bus transaction but got %d", len(m.pdus))
+	}
+}
+
+func TestWithTimestamp(t *testing.T) {
+	srv := newMockService(&mockSlave{})
+	item := map[string]interface{}{
+		"address": num("0"), "quantity": num("1"), "verbose": true,
+		"with_timestamp": true, "timestamp_format": "epoch_ms",
+	}
+
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-multi",
+		Params: objx.Map{"items": []interface{}{item, item}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after := time.Now().UnixNano() / int64(time.Millisecond)
+
+	for _, r := range res.([]multiItemResult) {
+		v, ok := r.Result.(verboseResult)
+		if !ok {
+			t.Fatalf("unexpected result %+v", r)
+		}
+
+		if ts, ok := v.Timestamp.(int64); !ok || ts < before || ts > after {
+			t.Errorf("expected timestamp in [%d, %d] but got %v", before, after, v.Timestamp)
+		}
+	}
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "with_timestamp": true},
+	})
+	if err == nil {
+		t.Error("with_timestamp without verbose should be rejected")
+	}
+}
+
+func TestAccessPolicy(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m, AccessPolicy(
+		AccessRule{Function: pointCoil, From: 10, To: 10, Deny: accessWrite},
+		AccessRule{SlaveIDs: []byte{2}, From: 100, To: 199, Deny: accessAll},
+	))
+
+	for _, tc := range []struct {
+		method string
+		params objx.Map
+		denied bool
+	}{
+		{"modbus-write-coil", objx.Map{"address": num("10"), "value": num("1")}, true},
+		{"modbus-write-multiple-coils", objx.Map{"address": num("8"), "values": []interface{}{true, true, true}}, true},
+		{"modbus-read-coil", objx.Map{"address": num("10"), "quantity": num("1")}, false},
+		{"modbus-write-coil", objx.Map{"address": num("11"), "value": num("1")}, false},
+		{"modbus-read-holding", objx.Map{"slave_id": num("2"), "address": num("199"), "quantity": num("2")}, true},
+		{"modbus-read-holding", objx.Map{"slave_id": num("1"), "address": num("199"), "quantity": num("2")}, false},
+	} {
+		sent := len(m.pdus)
+
+		_, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: tc.params})
+		if (err != nil) != tc.denied {
+			t.Errorf("%s %v: unexpected error %v", tc.method, tc.params, err)
+		}
+
+		if tc.denied && len(m.pdus) != sent {
+			t.Errorf("%s %v: denied request was sent", tc.method, tc.params)
+		}
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	bus, conn := &mockSlave{}, &mockSlave{}
+	srv := newMockService(bus, SlaveConnection(5, conn))
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-coil",
+		Params: objx.Map{"slave_ids": []interface{}{num("1"), num("5"), num("300")}, "address": num("3"), "value": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := res.([]multiItemResult)
+	if len(results) != 3 || results[0].Error != nil || results[1].Error != nil || results[2].Error == nil {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	if results[1].SlaveID != 5 || !bus.coils[3] || !conn.coils[3] {
+		t.Errorf("expected writes of both slaves but got %+v", results)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	item := map[string]interface{}{"address": num("0"), "quantity": num("1")}
+	items := []interface{}{item, item, item}
+
+	// one retry is shared by all items
+	m := &mockSlave{busy: 100}
+
+	res, err := newMockService(m, Retry(3, time.Millisecond)).Call(jsonrpc.Request{
+		Method: "modbus-read-multi",
+		Params: objx.Map{"items": items, "retry_budget": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range res.([]multiItemResult) {
+		if r.Error == nil {
+			t.Errorf("expected busy error but got %+v", r)
+		}
+	}
+
+	if len(m.pdus) != 4 {
+		t.Errorf("expected 4 requests but got %d", len(m.pdus))
+	}
+
+	// items after end of time budget fail without requests
+	m = &mockSlave{}
+	srv := New(delaySlave{m, 20 * time.Millisecond}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-coil",
+		Params: objx.Map{"slave_ids": []interface{}{num("1"), num("2")}, "address": num("3"), "value": num("1"),
+			"retry_budget_time": "10ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := res.([]multiItemResult)
+	if results[0].Error != nil || results[1].Error == nil || len(m.pdus) != 1 {
+		t.Errorf("expected second write to be skipped but got %+v (%d requests)", results, len(m.pdus))
+	}
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-multi",
+		Params: objx.Map{"items": items, "retry_budget": num("-1")},
+	})
+	if err == nil {
+		t.Error("negative budget should be rejected")
+	}
+}
+
+func TestBatchRetryBudget(t *testing.T) {
+	read := jsonrpc.Request{Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("1")}}
+
+	// one retry is shared by requests of jsonrpc batch
+	m := &mockSlave{busy: 100}
+	srv := newMockService(m, Retry(3, time.Millisecond), BatchRetryBudget(1, 0))
+	batch := srv.Batch()
+
+	for i := 0; i < 2; i++ {
+		if _, err := batch.Call(read); err == nil {
+			t.Error("expected busy error")
+		}
+	}
+
+	if len(m.pdus) != 3 {
+		t.Errorf("expected 3 requests of batch but got %d", len(m.pdus))
+	}
+
+	// single request isn't limited by budget of batch
+	m.pdus = nil
+
+	if _, err := srv.Call(read); err == nil {
+		t.Error("expected busy error")
+	}
+
+	if len(m.pdus) != 4 {
+		t.Errorf("expected 4 requests of single call but got %d", len(m.pdus))
+	}
+
+	// requests after end of time budget fail without transactions
+	m = &mockSlave{}
+	srv = New(delaySlave{m, 20 * time.Millisecond}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) },
+		BatchRetryBudget(0, 10*time.Millisecond))
+	batch = srv.Batch()
+
+	if _, err := batch.Call(read); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := batch.Call(read); err != errRetryBudget || len(m.pdus) != 1 {
+		t.Errorf("expected second request to be skipped but got %v (%d requests)", err, len(m.pdus))
+	}
+}
+
+// testNotifier passes sent values to channel
+type testNotifier struct {
+	id     string
+	values chan interface{}
+}
+
+func (n testNotifier) ID() string {
+	return n.id
+}
+
+func (n testNotifier) Send(value interface{}) {
+	n.values <- value
+}
+
+// connectTestNotifier starts pending subscriptions with notifiers of values channel
+func connectTestNotifier(srv Service, values chan interface{}) {
+	count := 0
+
+	srv.subs.connect(func(objx.Map) notifier {
+		count++
+		return testNotifier{strconv.Itoa(count), values}
+	})
+}
+
+func TestSubscriptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "subscriptions.json")
+	m := &mockSlave{}
+	m.holding[0] = 7
+
+	srv := newMockService(m, SubscriptionsFile(path))
+	values := make(chan interface{}, 16)
+	connectTestNotifier(srv, values)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-subscribe",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "interval": "10ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := res.(notifier).ID()
+
+	if v := <-values; !reflect.DeepEqual(v, []interface{}{uint16(7)}) {
+		t.Errorf("unexpected notification %v", v)
+	}
+
+	list, _ := srv.Call(jsonrpc.Request{Method: "modbus-subscriptions", Params: objx.Map{}})
+	if infos := list.([]subscriptionInfo); len(infos) != 1 || infos[0].ProcessID != id || infos[0].LastAt == nil {
+		t.Errorf("unexpected subscriptions %+v", list)
+	}
+
+	defs, err := LoadSubscriptions(path)
+	if err != nil || len(defs) != 1 || defs[0].Method != "modbus-read-holding" || defs[0].Interval != "10ms" {
+		t.Fatalf("unexpected saved subscriptions %+v (%v)", defs, err)
+	}
+
+	// saved subscriptions are restarted by new service
+	restarted := newMockService(m, Subscriptions(defs...))
+	connectTestNotifier(restarted, make(chan interface{}, 16))
+
+	list, _ = restarted.Call(jsonrpc.Request{Method: "modbus-subscriptions", Params: objx.Map{}})
+	if len(list.([]subscriptionInfo)) != 1 {
+		t.Errorf("expected restarted subscription but got %+v", list)
+	}
+
+	if err := restarted.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-subscribe-cancel", Params: objx.Map{"process_id": id},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if defs, _ := LoadSubscriptions(path); len(defs) != 0 {
+		t.Errorf("cancelled subscription should be removed from file but got %+v", defs)
+	}
+}
+
+func TestSubscriptionBackoff(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 7
+	m.busy = subscriptionFailures
+
+	srv := newMockService(m, MaxSubscriptions(1))
+	values := make(chan interface{}, 16)
+	connectTestNotifier(srv, values)
+
+	params := objx.Map{"address": num("0"), "quantity": num("1"), "interval": "1ms"}
+
+	if _, err := srv.Call(jsonrpc.Request{Method: "modbus-subscribe", Params: params.Copy()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := srv.Call(jsonrpc.Request{Method: "modbus-subscribe", Params: params.Copy()}); err == nil {
+		t.Error("subscription over the limit should be rejected")
+	}
+
+	if e := (<-values).(subscriptionEvent); e.Event != eventBackoff || e.Failures != subscriptionFailures || e.Error == nil {
+		t.Errorf("expected backoff event but got %+v", e)
+	}
+
+	if e := (<-values).(subscriptionEvent); e.Event != eventRecovered {
+		t.Errorf("expected recovery event but got %+v", e)
+	}
+
+	if v := <-values; !reflect.DeepEqual(v, []interface{}{uint16(7)}) {
+		t.Errorf("unexpected notification %v", v)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+// shortEchoSlave echoes less registers than written in nth FC16 request
+type shortEchoSlave struct {
+	*mockSlave
+	nth, writes int
+}
+
+func (m *shortEchoSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.mockSlave.Send(adu)
+	if err != nil || adu[7] != modbus.FuncCodeWriteMultipleRegisters {
+		return res, err
+	}
+
+	m.writes++
+	if m.writes == m.nth {
+		binary.BigEndian.PutUint16(res[10:], binary.BigEndian.Uint16(res[10:])-2)
+	}
+
+	return res, nil
+}
+
+func TestPartialWrite(t *testing.T) {
+	values := make([]interface{}, 130)
+	for i := range values {
+		values[i] = num("1")
+	}
+
+	for _, tc := range []struct {
+		chunked bool
+		nth     int
+		data    []string
+	}{
+		{true, 2, []string{`"written_count":128`, `"failed_at_address":129`}},
+		{false, 1, []string{`"written_count":3`, `"failed_at_address":4`}},
+	} {
+		m := &shortEchoSlave{mockSlave: &mockSlave{}, nth: tc.nth}
+		srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+		params := objx.Map{
+			"address": num("1"), "value": values, "encoding": "uint16", "address_base": num("1"), "chunked": tc.chunked,
+		}
+		if !tc.chunked {
+			params["value"] = values[:5]
+		}
+
+		_, err := srv.Call(jsonrpc.Request{Method: "modbus-write-multiple-registers", Params: params})
+		if err == nil {
+			t.Fatalf("chunked %v: expected partial write error", tc.chunked)
+		}
+
+		// data of jsonrpc error is private
+		desc := fmt.Sprintf("%#v", err)
+		for _, s := range tc.data {
+			if !strings.Contains(desc, s) {
+				t.Errorf("chunked %v: expected %s in %s", tc.chunked, s, desc)
+			}
+		}
+	}
+}
+
+func TestTransactionIDs(t *testing.T) {
+	m := &mockSlave{}
+
+	a := newMockService(m, TransactionIDs(modbus.NewTransactionIdRange(1, 2)))
+	b := newMockService(m, TransactionIDs(modbus.NewTransactionIdRange(100, 199)))
+
+	call := func(srv Service, params objx.Map) uint16 {
+		params["address"] = num("0")
+		params["quantity"] = num("1")
+		params["with_transaction_id"] = true
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", ID: []byte("7"), Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.(transactionResult).TransactionID
+	}
+
+	// request id isn't used, ids of the source increase and wrap around
+	for _, want := range []uint16{1, 2, 1} {
+		if id := call(a, objx.Map{}); id != want {
+			t.Errorf("expected transaction id %d but %d given", want, id)
+		}
+	}
+
+	for _, want := range []uint16{100, 101} {
+		if id := call(b, objx.Map{}); id != want {
+			t.Errorf("expected transaction id %d but %d given", want, id)
+		}
+	}
+
+	// explicit transaction id wins
+	if id := call(b, objx.Map{"transaction_id": num("5")}); id != 5 {
+		t.Errorf("expected transaction id 5 but %d given", id)
+	}
+
+	if id := call(b, objx.Map{}); id != 102 {
+		t.Errorf("expected transaction id 102 but %d given", id)
+	}
+
+	// request id is used if it's asked only
+	if id := call(b, objx.Map{"transaction_id": "request"}); id != 7 {
+		t.Errorf("expected transaction id of request 7 but %d given", id)
+	}
+
+	c := newMockService(m)
+
+	res, err := c.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		ID:     []byte("40000"),
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "with_transaction_id": true},
+	})
+	if err != nil || res.(transactionResult).TransactionID == 40000 {
+		t.Errorf("request id shouldn't be used as transaction id by default (%v)", err)
+	}
+
+	if id := call(c, objx.Map{"transaction_id": "request"}); id != 7 {
+		t.Errorf("expected transaction id of request 7 but %d given", id)
+	}
+
+	_, err = c.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		ID:     []byte(`"abc"`),
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "transaction_id": "request"},
+	})
+	if err == nil {
+		t.Error("expected error of non-numeric request id")
+	}
+}
+
+func TestReadEnumPoint(t *testing.T) {
+	slave := &mockSlave{}
+	slave.holding[3] = 1
+
+	points := []Point{{Name: "state", Function: pointHolding, Address: 3, Enum: map[string]string{"0": "idle", "1": "run", "2": "fault"}}}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(slave, Profile(points...))
+
+	read := func(params objx.Map) interface{} {
+		params["point"] = "state"
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	if res := read(objx.Map{}); res != "run" {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	if res := read(objx.Map{"verbose": true}); res != (enumValue{Value: "run", Raw: uint16(1)}) {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	slave.holding[3] = 7
+
+	if res := read(objx.Map{}); res != (enumValue{Value: uint16(7), Unknown: true}) {
+		t.Errorf("unexpected result %+v", res)
+	}
+
+	err := ValidatePoints([]Point{{Name: "bad", Function: pointHolding, Enum: map[string]string{"on": "1"}}})
+	if err == nil {
+		t.Error("expected error of non-integer enum key")
+	}
+}
+
+func TestReadCompositePoint(t *testing.T) {
+	slave := &mockSlave{}
+	slave.holding[100] = 0x5678
+	slave.holding[200] = 0xfffe
+	slave.holding[10] = 0x0002
+	slave.holding[12] = 0x0001
+
+	points := []Point{
+		{Name: "split", Function: pointHolding, Encoding: encInt32, Parts: []PointPart{{Address: 100}, {Address: 200, Shift: 16}}},
+		{Name: "near", Function: pointHolding, Parts: []PointPart{{Address: 12, Shift: 16}, {Address: 10}}},
+	}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(slave, Profile(points...))
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": "split"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != int32(-0x1a988) || len(slave.pdus) != 2 {
+		t.Errorf("unexpected result %v (%d transactions)", res, len(slave.pdus))
+	}
+
+	// parts with small gap are read by one transaction
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": "near"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != uint32(0x10002) || len(slave.pdus) != 3 {
+		t.Errorf("unexpected result %v (%d transactions)", res, len(slave.pdus))
+	}
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-write-point", Params: objx.Map{"point": "split", "value": num("1")}})
+	if err == nil {
+		t.Error("expected error of composite point write")
+	}
+}
+
+// ackSlave acknowledges writes and answers polls of exception status
+// by busy exception until polls are exhausted
+type ackSlave struct {
+	*mockSlave
+	polls int
+}
+
+func (m *ackSlave) Send(adu []byte) ([]byte, error) {
+	res := append([]byte{}, adu[:7]...)
+
+	switch adu[7] {
+	case modbus.FuncCodeWriteSingleRegister:
+		_, _ = m.mockSlave.Send(adu)
+		res = append(res, adu[7]|0x80, modbus.ExceptionCodeAcknowledge)
+	case funcCodeReadExceptionStatus:
+		if m.polls > 0 {
+			m.polls--
+			res = append(res, adu[7]|0x80, modbus.ExceptionCodeServerDeviceBusy)
+		} else {
+			res = append(res, adu[7], 0x00)
+		}
+	default:
+		return m.mockSlave.Send(adu)
+	}
+
+	binary.BigEndian.PutUint16(res[4:], uint16(len(res)-6))
+
+	return res, nil
+}
+
+func TestAckPolling(t *testing.T) {
+	slave := &ackSlave{mockSlave: &mockSlave{}, polls: 2}
+
+	addr := uint16(9)
+	configs := []AckPollConfig{
+		{Method: "modbus-write-register", Interval: time.Millisecond},
+		{Method: "modbus-write-coil", Address: &addr, Interval: time.Millisecond, Timeout: 20 * time.Millisecond},
+	}
+	if err := ValidateAckPolling(configs); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, AckPolling(configs...))
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("3"), "value": num("42")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if slave.polls != 0 || slave.holding[3] != 42 {
+		t.Errorf("unexpected state: polls left %d, register %d", slave.polls, slave.holding[3])
+	}
+
+	// register poll which is not done in time
+	slave.holding[9] = 1
+
+	ack := &ackSlave{mockSlave: slave.mockSlave}
+	srv = New(coilAckSlave{ack}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, AckPolling(configs...))
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-coil",
+		Params: objx.Map{"address": num("1"), "value": num("1")},
+	})
+	if err == nil || !strings.Contains(fmt.Sprintf("%#v", err), "not completed within timeout") {
+		t.Errorf("expected timeout error but got %v", err)
+	}
+
+	slave.holding[9] = 0
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-coil",
+		Params: objx.Map{"address": num("1"), "value": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// coilAckSlave acknowledges coil writes
+type coilAckSlave struct {
+	*ackSlave
+}
+
+func (m coilAckSlave) Send(adu []byte) ([]byte, error) {
+	if adu[7] != modbus.FuncCodeWriteSingleCoil {
+		return m.ackSlave.Send(adu)
+	}
+
+	res, err := m.mockSlave.Send(adu)
+	if err != nil {
+		return nil, err
+	}
+
+	res = append(res[:7], adu[7]|0x80, modbus.ExceptionCodeAcknowledge)
+	binary.BigEndian.PutUint16(res[4:], 3)
+
+	return res, nil
+}
+
+func TestBenchmark(t *testing.T) {
+	m := &mockSlave{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := newMockService(m, Context(ctx))
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-benchmark", Params: objx.Map{"count": num("5")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.(benchmarkResult)
+	if r.Count != 5 || r.Errors != 0 || !r.Complete || r.Min > r.P50 || r.P50 > r.P95 || r.P95 > r.Max {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	if len(m.pdus) != 5 || m.pdus[4][0] != modbus.FuncCodeDiagnostics {
+		t.Errorf("expected 5 loopback transactions but got %x", m.pdus)
+	}
+
+	m.busy = 1
+
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-benchmark", Params: objx.Map{"count": num("4"), "mode": "read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r = res.(benchmarkResult); r.Count != 4 || r.Errors != 1 || r.ErrorRate != 0.25 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	// stopped benchmark returns what it has measured
+	cancel()
+
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-benchmark", Params: objx.Map{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r = res.(benchmarkResult); r.Count != 0 || r.Complete {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestWritePointRoundTrip(t *testing.T) {
+	m := &mockSlave{}
+
+	points := []Point{
+		{Name: "target_pressure", Function: pointHolding, Address: 20, Encoding: encInt32, WordOrder: orderLittle, Scale: 0.01, Offset: -100},
+		{Name: "flow", Function: pointHolding, Address: 30, Encoding: encFloat32, ScalePreset: "tenths"},
+		{Name: "levels", Function: pointHolding, Address: 40, Encoding: encInt16, Scale: 0.5, Quantity: 2},
+	}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	for _, c := range []struct {
+		point string
+		value interface{}
+	}{
+		{"target_pressure", num("3.5")},
+		{"target_pressure", num("-12.34")},
+		{"flow", num("12.25")},
+		{"levels", []interface{}{num("-1.5"), num("20")}},
+	} {
+		_, err := srv.Call(jsonrpc.Request{Method: "modbus-write-point", Params: objx.Map{"point": c.point, "value": c.value}})
+		if err != nil {
+			t.Fatalf("%s: %v", c.point, err)
+		}
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": c.point}})
+		if err != nil {
+			t.Fatalf("%s: %v", c.point, err)
+		}
+
+		values, ok := res.([]interface{})
+		if !ok {
+			values = []interface{}{res}
+		}
+
+		expected, ok := c.value.([]interface{})
+		if !ok {
+			expected = []interface{}{c.value}
+		}
+
+		for i, v := range values {
+			want, _ := expected[i].(json.Number).Float64()
+			if got := v.(float64); math.Abs(got-want) > 1e-6 {
+				t.Errorf("%s: expected %v but got %v", c.point, want, got)
+			}
+		}
+	}
+
+	// raw value is (value / scale - offset) in wire word order
+	if m.holding[20] != 0xfb92 || m.holding[21] != 0xffff {
+		t.Errorf("unexpected registers %x %x", m.holding[20], m.holding[21])
+	}
+}
+
+func TestDebugCall(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[4] = 0x0102
+
+	req := jsonrpc.Request{Method: "modbus-debug-call", Params: objx.Map{
+		"method": "modbus-read-holding",
+		"params": map[string]interface{}{"address": num("4"), "quantity": num("1")},
+	}}
+
+	if _, err := newMockService(m).Call(req); err == nil {
+		t.Error("expected error of disabled debug calls")
+	}
+
+	srv := newMockService(m, DebugCalls(true), ReadCache(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		res, err := srv.Call(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// cached result isn't returned, frame is always sent
+		r := res.(debugResult)
+		if r.Error != nil || !reflect.DeepEqual(r.Result, []interface{}{uint16(0x0102)}) || len(r.Frames) != 1 {
+			t.Fatalf("unexpected result %+v", r)
+		}
+
+		if f := r.Frames[0]; !bytes.Equal(f.Request, []byte{0x03, 0x00, 0x04, 0x00, 0x01}) ||
+			!bytes.Equal(f.Response, []byte{0x03, 0x02, 0x01, 0x02}) || len(f.RequestADU) != 12 {
+			t.Errorf("unexpected frame %+v", f)
+		}
+	}
+
+	m.busy = 1
+
+	res, err := srv.Call(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := res.(debugResult); r.Error == nil || len(r.Frames) != 1 || r.Frames[0].Response[0] != 0x83 {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestWithLatency(t *testing.T) {
+	delay := 5 * time.Millisecond
+	srv := New(delaySlave{&mockSlave{}, delay}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "with_latency": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.(latencyResult)
+	if r.Latency < ms(delay) || !reflect.DeepEqual(r.Result, []interface{}{uint16(0)}) {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-multi",
+		Params: objx.Map{"with_latency": true, "items": []interface{}{
+			map[string]interface{}{"address": num("0"), "quantity": num("1")},
+			map[string]interface{}{"address": num("1"), "quantity": num("1")},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = res.(latencyResult)
+	items := r.Result.([]multiItemResult)
+
+	if r.Latency < 2*ms(delay) || len(items) != 2 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	for _, item := range items {
+		if item.Latency == nil || *item.Latency < ms(delay) || *item.Latency > r.Latency {
+			t.Errorf("unexpected item latency %v", item.Latency)
+		}
+	}
+}
+
+// emptySlave answers first reads by response with zero byte count
+type emptySlave struct {
+	*mockSlave
+	empty int
+}
+
+func (m *emptySlave) Send(adu []byte) ([]byte, error) {
+	if m.empty == 0 || adu[7] != modbus.FuncCodeReadHoldingRegisters {
+		return m.mockSlave.Send(adu)
+	}
+
+	m.empty--
+
+	res := append(append([]byte{}, adu[:7]...), adu[7], 0)
+	binary.BigEndian.PutUint16(res[4:], 3)
+
+	return res, nil
+}
+
+func TestEmptyResponse(t *testing.T) {
+	slave := &emptySlave{mockSlave: &mockSlave{}, empty: 2}
+	slave.holding[0] = 7
+
+	read := jsonrpc.Request{Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("1")}}
+
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+	if _, err := srv.Call(read); err == nil {
+		t.Error("expected error of empty response")
+	}
+
+	// empty response is retried
+	srv = New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, Retry(1, time.Millisecond))
+
+	res, err := srv.Call(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, []interface{}{uint16(7)}) {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	stats, _ := srv.Call(jsonrpc.Request{Method: "modbus-stats", Params: objx.Map{}})
+	if errs := stats.(metricsResult).Errors; errs[errClassEmpty] != 1 {
+		t.Errorf("expected empty error in stats but got %v", errs)
+	}
+}
+
+func TestPollLoop(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[1], m.holding[50] = 10, 20, 30
+
+	points := []Point{
+		{Name: "pressure", Function: pointHolding, Address: 0, Scale: 0.1, PollInterval: 5 * time.Millisecond},
+		{Name: "flow", Function: pointHolding, Address: 1, PollInterval: 5 * time.Millisecond},
+		{Name: "total", Function: pointHolding, Address: 50, PollInterval: time.Hour},
+		{Name: "setpoint", Function: pointHolding, Address: 60},
+	}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	read := func(params objx.Map) map[string]polledValue {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-polled", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.(map[string]polledValue)
+	}
+
+	var values map[string]polledValue
+
+	for i := 0; i < 100; i++ {
+		if values = read(objx.Map{}); values["total"].Timestamp != nil && values["flow"].Timestamp != nil {
+			break
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if len(values) != 3 || values["flow"].Value != uint16(20) || values["total"].Value != uint16(30) ||
+		math.Abs(values["pressure"].Value.(float64)-1) > 1e-9 {
+		t.Fatalf("unexpected values %+v", values)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// fast points are read together, slow one once
+	fast, slow := 0, 0
+
+	for _, pdu := range m.pdus {
+		switch addr := binary.BigEndian.Uint16(pdu[1:]); {
+		case addr == 0 && binary.BigEndian.Uint16(pdu[3:]) == 2:
+			fast++
+		case addr == 50:
+			slow++
+		default:
+			t.Errorf("unexpected pdu %x", pdu)
+		}
+	}
+
+	if fast < 2 || slow != 1 {
+		t.Errorf("unexpected reads: %d fast, %d slow", fast, slow)
+	}
+
+	_, err := newMockService(m, Profile(points...)).Call(jsonrpc.Request{
+		Method: "modbus-read-polled", Params: objx.Map{"points": []interface{}{"setpoint"}},
+	})
+	if err == nil {
+		t.Error("expected error of point which isn't polled")
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[50] = 1, 10
+
+	points := []Point{{Name: "total", Function: pointHolding, Address: 50, PollInterval: time.Hour}}
+	srv := newMockService(m, ReadCache(time.Minute), Profile(points...))
+
+	call := func(method string, params objx.Map) interface{} {
+		res, err := srv.Call(jsonrpc.Request{Method: method, Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res
+	}
+
+	for i := 0; i < 100 && call("modbus-read-polled", objx.Map{}).(map[string]polledValue)["total"].Timestamp == nil; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	call("modbus-read-holding", objx.Map{"address": num("0"), "quantity": num("1")})
+
+	m.holding[0], m.holding[50] = 2, 20
+
+	time.Sleep(20 * time.Millisecond)
+
+	for _, tc := range []struct {
+		maxAge   string
+		expected uint16
+	}{
+		{"1000", 1},
+		{"10", 2},
+	} {
+		params := objx.Map{"address": num("0"), "quantity": num("1"), "max_age_ms": num(tc.maxAge)}
+		if res := call("modbus-read-holding", params); !reflect.DeepEqual(res, []interface{}{tc.expected}) {
+			t.Errorf("max age %s: expected %d but got %v", tc.maxAge, tc.expected, res)
+		}
+
+		params = objx.Map{"max_age_ms": num(tc.maxAge)}
+		if v := call("modbus-read-polled", params).(map[string]polledValue)["total"]; v.Value != tc.expected*10 {
+			t.Errorf("max age %s: expected polled %d but got %v", tc.maxAge, tc.expected*10, v.Value)
+		}
+	}
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("1"), "max_age_ms": num("0")},
+	})
+	if err == nil {
+		t.Error("expected error of zero max_age_ms")
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// prefixSlave is gateway which prepends spurious byte to responses
+type prefixSlave struct {
+	*mockSlave
+}
+
+func (m prefixSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.mockSlave.Send(adu)
+
+	return append([]byte{0xff}, res...), err
+}
+
+func TestResponseValidator(t *testing.T) {
+	slave := prefixSlave{&mockSlave{}}
+	slave.holding[2] = 42
+
+	read := jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"slave_id": num("4"), "address": num("2"), "quantity": num("1")},
+	}
+
+	var contexts []ResponseContext
+
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) },
+		ResponseValidator(func(ctx ResponseContext, res []byte) ([]byte, error) {
+			contexts = append(contexts, ctx)
+			if len(res) == 0 || res[0] != 0xff {
+				return nil, errors.New("no gateway prefix")
+			}
+
+			return res[1:], nil
+		}))
+
+	res, err := srv.Call(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, []interface{}{uint16(42)}) {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	if len(contexts) != 1 || contexts[0].SlaveID != 4 || contexts[0].Method != "modbus-read-holding" ||
+		!reflect.DeepEqual(contexts[0].Request[7:], []byte{0x03, 0x00, 0x02, 0x00, 0x01}) {
+		t.Errorf("unexpected contexts %+v", contexts)
+	}
+
+	// rejected response fails transaction
+	_, err = New(slave.mockSlave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) },
+		ResponseValidator(func(ctx ResponseContext, res []byte) ([]byte, error) {
+			return nil, errors.New("bad gateway")
+		})).Call(read)
+	if err == nil || !strings.Contains(fmt.Sprintf("%#v", err), "bad gateway") {
+		t.Errorf("expected rejected response error but got %#v", err)
+	}
+}
+
+func TestStrictSlaveIDs(t *testing.T) {
+	slave := &mockSlave{}
+	srv := newMockService(slave, StrictSlaveIDs(true))
+
+	for _, tc := range []struct {
+		method  string
+		slaveID string
+		ok      bool
+	}{
+		{"modbus-read-holding", "1", true},
+		{"modbus-read-holding", "247", true},
+		{"modbus-read-holding", "248", false},
+		{"modbus-read-holding", "0", false},
+		{"modbus-write-register", "255", false},
+		{"modbus-write-register", "0", true},
+	} {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: tc.method,
+			Params: objx.Map{"slave_id": num(tc.slaveID), "address": num("0"), "quantity": num("1"), "value": num("1")},
+		})
+		if (err == nil) != tc.ok {
+			t.Errorf("%s to slave %s: unexpected error %v", tc.method, tc.slaveID, err)
+		}
+	}
+
+	// rejected frames are not sent
+	if len(slave.pdus) != 3 {
+		t.Errorf("expected 3 sent frames but got %v", len(slave.pdus))
+	}
+
+	// permissive by default
+	if _, err := newMockService(slave).Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"slave_id": num("250"), "address": num("0"), "quantity": num("1")},
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[2] = 215, 3
+	m.coils[6] = true
+
+	points := []Point{
+		{Name: "pressure", Function: pointHolding, Address: 0, Scale: 0.1, Unit: "bar"},
+		{Name: "mode", Function: pointHolding, Address: 2, Enum: map[string]string{"3": "auto"}},
+		{Name: "pump", Function: pointCoil, Address: 6},
+		{Name: "missing", Function: pointInput, Address: 300},
+	}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := newMockService(m, Profile(points...)).Call(jsonrpc.Request{Method: "modbus-read-all", Params: objx.Map{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := res.(map[string]snapshotValue)
+	if len(values) != 4 || values["pressure"].Unit != "bar" ||
+		math.Abs(values["pressure"].Value.(float64)-21.5) > 1e-9 ||
+		values["mode"].Value != "auto" || values["pump"].Value != uint16(1) {
+		t.Errorf("unexpected values %+v", values)
+	}
+
+	if values["missing"].Error == nil || values["missing"].Value != nil {
+		t.Errorf("expected error of missing point but got %+v", values["missing"])
+	}
+
+	// holding registers are read together
+	if len(m.pdus) != 3 {
+		t.Errorf("expected 3 transactions but got %x", m.pdus)
+	}
+}
+
+func TestForceMultipleWrite(t *testing.T) {
+	slave := &mockSlave{}
+	srv := newMockService(slave, ForceMultipleWrite(4))
+
+	for _, req := range []jsonrpc.Request{
+		{Method: "modbus-write-register", Params: objx.Map{"slave_id": num("4"), "address": num("3"), "value": num("513")}},
+		{Method: "modbus-write-coil", Params: objx.Map{"slave_id": num("4"), "address": num("2"), "value": num("1")}},
+		{Method: "modbus-write-register", Params: objx.Map{"slave_id": num("5"), "address": num("4"), "value": num("7")}},
+	} {
+		if _, err := srv.Call(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := [][]byte{
+		{0x10, 0x00, 0x03, 0x00, 0x01, 0x02, 0x02, 0x01},
+		{0x0F, 0x00, 0x02, 0x00, 0x01, 0x01, 0x01},
+		// other slaves use single write
+		{0x06, 0x00, 0x04, 0x00, 0x07},
+	}
+	if !reflect.DeepEqual(slave.pdus, expected) {
+		t.Errorf("unexpected pdus %x", slave.pdus)
+	}
+
+	if slave.holding[3] != 513 || !slave.coils[2] || slave.holding[4] != 7 {
+		t.Error("values are not written")
+	}
+}
+
+// offsetEchoSlave echoes address of multiple writes with base offset
+type offsetEchoSlave struct {
+	*mockSlave
+}
+
+func (m offsetEchoSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.mockSlave.Send(adu)
+	if err == nil && (res[7] == modbus.FuncCodeWriteMultipleCoils || res[7] == modbus.FuncCodeWriteMultipleRegisters) {
+		binary.BigEndian.PutUint16(res[8:], binary.BigEndian.Uint16(res[8:])+1)
+	}
+
+	return res, err
+}
+
+func TestLenientEcho(t *testing.T) {
+	slave := offsetEchoSlave{&mockSlave{}}
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, LenientEcho(6))
+
+	for _, req := range []jsonrpc.Request{
+		{Method: "modbus-write-multiple-registers",
+			Params: objx.Map{"slave_id": num("6"), "address": num("3"), "quantity": num("2"), "value": []interface{}{num("1"), num("2")}}},
+		{Method: "modbus-write-multiple-coils",
+			Params: objx.Map{"slave_id": num("6"), "address": num("2"), "quantity": num("2"), "value": []interface{}{num("1"), num("1")}}},
+	} {
+		if _, err := srv.Call(req); err != nil {
+			t.Fatalf("%v: %v", req.Method, err)
+		}
+	}
+
+	if slave.holding[4] != 2 || !slave.coils[3] {
+		t.Error("values are not written")
+	}
+
+	// other slaves are strict
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"slave_id": num("5"), "address": num("3"), "quantity": num("1"), "value": []interface{}{num("1")}}})
+	if err == nil {
+		t.Error("expected echo mismatch error")
+	}
+
+	// exceptions are errors in lenient mode too
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"slave_id": num("6"), "address": num("255"), "quantity": num("2"), "value": []interface{}{num("1"), num("2")}}})
+	if err == nil {
+		t.Error("expected exception error")
+	}
+}
+
+// timeoutsSlave records timeouts passed by SendTimeouts
+type timeoutsSlave struct {
+	*mockSlave
+	connectTimeouts []time.Duration
+	timeouts        []time.Duration
+}
+
+func (m *timeoutsSlave) SendTimeout(adu []byte, timeout time.Duration) ([]byte, error) {
+	return m.SendTimeouts(adu, 0, timeout)
+}
+
+func (m *timeoutsSlave) SendTimeouts(adu []byte, connectTimeout, timeout time.Duration) ([]byte, error) {
+	m.connectTimeouts = append(m.connectTimeouts, connectTimeout)
+	m.timeouts = append(m.timeouts, timeout)
+
+	return m.mockSlave.Send(adu)
+}
+
+func TestConnectTimeout(t *testing.T) {
+	slave := &timeoutsSlave{mockSlave: &mockSlave{}}
+
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) },
+		ConnectTimeout(2*time.Second),
+		SlaveTransport(5, SlaveTransportConfig{Timeout: 10 * time.Second, ConnectTimeout: time.Second}),
+	)
+
+	for _, params := range []objx.Map{
+		{"slave_id": num("1")},
+		{"slave_id": num("5")},
+		{"slave_id": num("5"), "connect_timeout": "500ms"},
+	} {
+		params["address"], params["quantity"] = num("0"), num("1")
+
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectedConnect := []time.Duration{2 * time.Second, time.Second, 500 * time.Millisecond}
+	expected := []time.Duration{0, 10 * time.Second, 10 * time.Second}
+
+	if !reflect.DeepEqual(slave.connectTimeouts, expectedConnect) || !reflect.DeepEqual(slave.timeouts, expected) {
+		t.Errorf("unexpected connect timeouts %v and timeouts %v", slave.connectTimeouts, slave.timeouts)
+	}
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "connect_timeout": "never"},
+	})
+	if err == nil {
+		t.Error("expected error of invalid connect_timeout")
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	for expr, expected := range map[string]float64{
+		"raw":               3,
+		"2 + raw * 4":       14,
+		"(2 + raw) * 4":     20,
+		"2 ^ raw ^ 2":       512,
+		"-raw ^ 2":          -9,
+		"10 / 4 - -1":       3.5,
+		" 0.5*raw-1.25 ":    0.25,
+		"raw / (raw - raw)": math.Inf(1),
+	} {
+		tr, err := parseTransform(expr)
+		if err != nil {
+			t.Errorf("%q: %v", expr, err)
+			continue
+		}
+
+		if v := tr(3); v != expected {
+			t.Errorf("%q: expected %v but got %v", expr, expected, v)
+		}
+	}
+
+	for _, expr := range []string{"", "raw +", "(raw", "raw)", "x * 2", "rawx", "1..2", "os.Exit(1)"} {
+		if _, err := parseTransform(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+
+	// invalid expressions are rejected on validation
+	err := ValidatePoints([]Point{{Name: "level", Function: pointInput, Transform: "raw *"}})
+	if err == nil || !strings.Contains(err.Error(), "transform") {
+		t.Errorf("expected transform error but got %v", err)
+	}
+}
+
+func TestReadTransformPoint(t *testing.T) {
+	m := &mockSlave{}
+	m.inputs[4] = 10
+
+	points := []Point{{Name: "level", Function: pointInput, Address: 4, Transform: "0.01 * raw^2 + 4"}}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": "level"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(res.(float64)-5) > 1e-9 {
+		t.Errorf("unexpected value %v", res)
+	}
+}
+
+func TestDetectEndianness(t *testing.T) {
+	m := &mockSlave{}
+	// 1.0 is 0x3F800000, device sends low word first
+	m.holding[10], m.holding[11] = 0x0000, 0x3F80
+
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-detect-endianness",
+		Params: objx.Map{"address": num("10"), "value": num("1.0")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.(endiannessResult)
+	if len(r.Candidates) != 4 || len(r.Matches) != 1 ||
+		r.Matches[0].ByteOrder != orderBig || r.Matches[0].WordOrder != orderLittle ||
+		r.Matches[0].Value != float32(1) {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	m.inputs[0], m.inputs[1] = 0x3412, 0x7856
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-detect-endianness",
+		Params: objx.Map{"address": num("0"), "value": num("305419896"), "encoding": "uint32", "input": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := res.(endiannessResult); len(r.Matches) != 1 || r.Matches[0].ByteOrder != orderLittle ||
+		r.Matches[0].WordOrder != orderBig {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestRegisterLocks(t *testing.T) {
+	m := &mockSlave{}
+
+	// slave bypasses bus lock, so only register lock keeps set-bit atomic
+	srv := New(delaySlave{m, time.Millisecond}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) },
+		UnsafeParallel(1), RegisterLocks(true))
+
+	var wg sync.WaitGroup
+
+	for bit := 0; bit < 8; bit++ {
+		wg.Add(1)
+
+		go func(bit int) {
+			defer wg.Done()
+
+			_, err := srv.Call(jsonrpc.Request{
+				Method: "modbus-set-bit",
+				Params: objx.Map{
+					"slave_id": num("1"), "address": num("3"), "bit": num(strconv.Itoa(bit)), "value": num("1"),
+				},
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(bit)
+	}
+
+	wg.Wait()
+
+	if m.holding[3] != 0xFF {
+		t.Errorf("expected all bits set but got %#x", m.holding[3])
+	}
+
+	if len(srv.registerLocks.locks) != 0 {
+		t.Errorf("expected released locks but got %v", srv.registerLocks.locks)
+	}
+}
+
+func TestReadStatusWordPoint(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[8] = 0x0005
+
+	points := []Point{{Name: "status", Function: pointHolding, Address: 8, BitLabels: map[string]string{"0": "running", "1": "fault"}}}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := newMockService(m, Profile(points...)).Call(jsonrpc.Request{
+		Method: "modbus-read-point", Params: objx.Map{"point": "status"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	word := res.(map[string]bool)
+	if len(word) != 16 || !word["running"] || word["fault"] || !word["2"] || word["3"] {
+		t.Errorf("unexpected status word %v", word)
+	}
+
+	for _, p := range []Point{
+		{Name: "status", Function: pointCoil, BitLabels: map[string]string{"0": "running"}},
+		{Name: "status", Function: pointHolding, BitLabels: map[string]string{"16": "running"}},
+		{Name: "status", Function: pointHolding, BitLabels: map[string]string{"0": "on", "1": "on"}},
+		{Name: "status", Function: pointHolding, Encoding: "float32", BitLabels: map[string]string{"0": "on"}},
+	} {
+		if err := ValidatePoints([]Point{p}); err == nil {
+			t.Errorf("expected error of point %+v", p)
+		}
+	}
+}
+
+func TestDetectWrap(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[1] = 0xFFFF, 0xFFF0
+
+	srv := newMockService(m, Profile(Point{Name: "total", Function: pointHolding, Address: 4, Encoding: "int32"}))
+	params := objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "uint32", "detect_wrap": true}
+
+	read := func() wrapResult {
+		t.Helper()
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.(wrapResult)
+	}
+
+	if res := read(); res.Wrapped {
+		t.Errorf("first read can't be wrapped but got %+v", res)
+	}
+
+	m.holding[0], m.holding[1] = 0, 5
+
+	if res := read(); !res.Wrapped || !reflect.DeepEqual(res.WrappedIndexes, []int{0}) ||
+		!reflect.DeepEqual(res.Result, []interface{}{uint32(5)}) {
+		t.Errorf("expected wrapped counter but got %+v", res)
+	}
+
+	// small decrease is reset of counter
+	m.holding[1] = 1
+
+	if res := read(); res.Wrapped {
+		t.Errorf("reset counter shouldn't be wrapped but got %+v", res)
+	}
+
+	// signed point overflows from max to min value
+	m.holding[4], m.holding[5] = 0x7FFF, 0xFFFF
+	pointParams := objx.Map{"point": "total", "detect_wrap": true}
+
+	for _, wrapped := range []bool{false, true} {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: pointParams})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.(wrapResult).Wrapped != wrapped {
+			t.Errorf("expected wrapped %v but got %+v", wrapped, res)
+		}
+
+		m.holding[4], m.holding[5] = 0x8000, 0x0000
+	}
+
+	params["encoding"] = "float32"
+
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err == nil {
+		t.Error("detect_wrap of float32 should be rejected")
+	}
+}
+
+func TestMethodDefaults(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[10], m.holding[11] = 0x0001, 0x0002
+
+	srv := newMockService(m, MethodDefaults(map[string]objx.Map{
+		"modbus-read-holding": {"address": num("10"), "quantity": num("2"), "encoding": "uint32"},
+	}))
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, []interface{}{uint32(0x00010002)}) {
+		t.Errorf("expected default read but got %v", res)
+	}
+
+	// request params override defaults
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("11"), "quantity": num("1"), "encoding": "uint16"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, []interface{}{uint16(2)}) {
+		t.Errorf("expected overridden read but got %v", res)
+	}
+}
+
+func TestDiagnosticsCounters(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	diag := func(subFunction string) diagnosticsResult {
+		t.Helper()
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-diagnostics", Params: objx.Map{"sub_function": num(subFunction)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.(diagnosticsResult)
+	}
+
+	for i := 0; i < 3; i++ {
+		diag("0")
+	}
+
+	if res := diag("10"); !res.Cleared || res.Counter != "" {
+		t.Errorf("expected clear ack but got %+v", res)
+	}
+
+	expected := diagnosticsResult{SubFunction: 0x0B, Data: 1, Counter: "bus_message"}
+	if res := diag("11"); res != expected {
+		t.Errorf("expected %+v but got %+v", expected, res)
+	}
+
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-diagnostics", Params: objx.Map{"sub_function": num("19")}})
+	if err == nil {
+		t.Error("unsupported sub-function should be rejected")
+	}
+}
+
+func TestConfigDump(t *testing.T) {
+	srv := newMockService(&mockSlave{},
+		MethodDefaults(map[string]objx.Map{"modbus-read-holding": {"slave_id": num("1")}}),
+		Profile(Point{Name: "b", Function: pointHolding, Address: 1}, Point{Name: "a", Function: pointCoil}),
+		Retry(2, time.Second),
+		SlaveTransport(5, SlaveTransportConfig{Timeout: 3 * time.Second}),
+		UnsafeParallel(7),
+		AccessPolicy(AccessRule{SlaveIDs: []byte{3}, Function: pointHolding, From: 10, To: 20, Deny: accessWrite}),
+	)
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-config-dump"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dump := res.(configDump)
+
+	if len(dump.Points) != 2 || dump.Points[0].Name != "a" || dump.Defaults["modbus-read-holding"] == nil {
+		t.Errorf("unexpected points or defaults %+v", dump)
+	}
+
+	if dump.Retry == nil || dump.Retry.Attempts != 2 || dump.Retry.Backoff != "1s" ||
+		!reflect.DeepEqual(dump.Retry.Exceptions, []int{5, 6}) {
+		t.Errorf("unexpected retry %+v", dump.Retry)
+	}
+
+	if dump.SlaveTransports["5"].Timeout != "3s" || !reflect.DeepEqual(dump.UnsafeParallel, []int{7}) {
+		t.Errorf("unexpected slave overrides %+v", dump)
+	}
+
+	expected := []accessRuleDump{{SlaveIDs: []int{3}, Function: pointHolding, From: 10, To: 20, Deny: accessWrite}}
+	if !reflect.DeepEqual(dump.Access, expected) {
+		t.Errorf("expected access %+v but got %+v", expected, dump.Access)
+	}
+}
+
+func TestReadByteLabelsPoint(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[3] = 0x0207
+
+	points := []Point{{Name: "version", Function: pointHolding, Address: 3, Encoding: "bytes", ByteLabels: []string{"hardware", "firmware_minor"}}}
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := newMockService(m, Profile(points...)).Call(jsonrpc.Request{
+		Method: "modbus-read-point", Params: objx.Map{"point": "version"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"hardware": uint8(2), "firmware_minor": uint8(7)}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+
+	for _, p := range []Point{
+		{Name: "version", Function: pointHolding, ByteLabels: []string{"a", "b"}},
+		{Name: "version", Function: pointHolding, Encoding: "bytes", ByteLabels: []string{"a"}},
+		{Name: "version", Function: pointHolding, Encoding: "bytes", ByteLabels: []string{"a", "a"}},
+		{Name: "version", Function: pointCoil, Encoding: "bytes", ByteLabels: []string{"a", "b"}},
+	} {
+		if err := ValidatePoints([]Point{p}); err == nil {
+			t.Errorf("expected error of point %+v", p)
+		}
+	}
+
+	_, err = newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": num("3"), "value": []interface{}{num("1")}, "encoding": "bytes"},
+	})
+	if err == nil {
+		t.Error("write of bytes encoding should be rejected")
+	}
+}
+
+func TestWriteGuard(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[20] = 0x0003
+
+	srv := newMockService(m)
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("5"), "value": num("42"),
+			"guard": map[string]interface{}{"address": num("20"), "value": num("2"), "mask": num("2")}},
+	})
+	if err != nil || m.holding[5] != 42 {
+		t.Fatalf("expected guarded write but got %v (%d)", err, m.holding[5])
+	}
+
+	m.pdus = nil
+
+	// guard address in the same base as request address
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("6"), "value": num("42"), "address_base": num("1"),
+			"guard": map[string]interface{}{"address": num("21"), "value": num("4")}},
+	})
+
+	expected := guardErr(&writeGuard{address: 20, value: 4, mask: 0xFFFF}, 3)
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected %v but got %v", expected, err)
+	}
+
+	if len(m.pdus) != 1 || m.holding[5] != 42 {
+		t.Errorf("expected only guard read but got %d requests", len(m.pdus))
+	}
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-coil",
+		Params: objx.Map{"address": num("1"), "value": num("1"), "guard": "20"},
+	})
+	if err == nil {
+		t.Error("guard which isn't object should be rejected")
+	}
+}
+
+func TestReadQuality(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0], m.holding[1] = 0xFFFF, 5
+
+	srv := newMockService(m, Profile(Point{Name: "level", Function: pointHolding, Address: 1}))
+	params := objx.Map{"address": num("0"), "quantity": num("2"), "null_value": num("65535"),
+		"with_quality": true, "last_good": true}
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{
+		qualityValue{Value: nil, Quality: qualityBad, Reason: "null"},
+		qualityValue{Value: uint16(5), Quality: qualityGood},
+	}
+	if !reflect.DeepEqual(res.(staleResult).Result, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+
+	// stale value is uncertain, null value is still bad
+	m.busy = 1
+
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected[1] = qualityValue{Value: uint16(5), Quality: qualityUncertain, Reason: "stale"}
+	if !reflect.DeepEqual(res.(staleResult).Result, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+
+	res, err = srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": "level", "with_quality": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != (qualityValue{Value: uint16(5), Quality: qualityGood}) {
+		t.Errorf("expected good point value but got %v", res)
+	}
+
+	// read longer than sla is uncertain
+	srv = New(delaySlave{m, 5 * time.Millisecond}, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("1"), "quantity": num("1"), "with_quality": true, "sla_ms": num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []interface{}{qualityValue{Value: uint16(5), Quality: qualityUncertain, Reason: "sla_exceeded"}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+}
+
+func TestWriteBits(t *testing.T) {
+	params := objx.Map{"address": num("4"), "bits": map[string]interface{}{"0": num("1"), "3": num("0"), "15": num("1")}}
+
+	for _, maskWrite := range []bool{true, false} {
+		m := &mockSlave{maskWrite: maskWrite}
+		m.holding[4] = 0x000A
+
+		res, err := newMockService(m).Call(jsonrpc.Request{Method: "modbus-write-bits", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := res.(writeBitsResult)
+
+		if m.holding[4] != 0x8003 || result.AndMask != 0x7FF6 || result.OrMask != 0x8001 {
+			t.Errorf("expected 0x8003 but got %#x (%+v)", m.holding[4], result)
+		}
+
+		mechanism := mechanismMaskWrite
+		if !maskWrite {
+			mechanism = mechanismReadModifyWrite
+
+			if *result.Previous != 0x000A || *result.Value != 0x8003 {
+				t.Errorf("unexpected register values %+v", result)
+			}
+		}
+
+		if result.Mechanism != mechanism {
+			t.Errorf("expected %s but got %s", mechanism, result.Mechanism)
+		}
+	}
+
+	for _, bits := range []interface{}{
+		map[string]interface{}{},
+		map[string]interface{}{"16": num("1")},
+		map[string]interface{}{"1": num("2")},
+		"1",
+	} {
+		_, err := newMockService(&mockSlave{}).Call(jsonrpc.Request{
+			Method: "modbus-write-bits", Params: objx.Map{"address": num("4"), "bits": bits},
+		})
+		if err == nil {
+			t.Errorf("bits %v should be rejected", bits)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[10] = 1
+	m.holding[11] = 0xFFFF
+	m.holding[12] = 2
+	m.coils[4] = true
+
+	points := []Point{
+		{Name: "temp", Function: pointHolding, Address: 12, Scale: 0.5},
+		{Name: "mode", Function: pointHolding, Address: 10, Enum: map[string]string{"1": "auto, remote"}},
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	for _, tc := range []struct {
+		method string
+		params objx.Map
+		csv    string
+	}{
+		{"modbus-read-holding", objx.Map{"address": num("10"), "quantity": num("3"), "encoding": "int16"}, "10,1\n11,-1\n12,2\n"},
+		{"modbus-read-holding", objx.Map{"address": num("11"), "quantity": num("2"), "encoding": "uint32"}, "11,4294901762\n"},
+		{"modbus-read-holding", objx.Map{"address": num("11"), "quantity": num("1"), "address_base": num("1")}, "11,1\n"},
+		{"modbus-read-coil", objx.Map{"address": num("3"), "quantity": num("2")}, "3,0\n4,1\n"},
+		{"modbus-read-point", objx.Map{"point": "temp"}, "temp,1\n"},
+		{"modbus-read-points", objx.Map{"points": []interface{}{"temp", "mode"}}, "mode,\"auto, remote\"\ntemp,1\n"},
+		{"modbus-read-holding", objx.Map{"address": num("10"), "quantity": num("1"), "format": "json"}, ""},
+	} {
+		params := tc.params.Copy()
+		if params.Get("format").IsNil() {
+			params["format"] = "csv"
+		}
+
+		res, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: params})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.method, err)
+		}
+
+		if tc.csv == "" {
+			if _, ok := res.(string); ok {
+				t.Errorf("json format expected but got %q", res)
+			}
+
+			continue
+		}
+
+		if res != tc.csv {
+			t.Errorf("%s %v: expected %q but got %q", tc.method, tc.params, tc.csv, res)
+		}
+	}
+
+	for _, tc := range []struct {
+		method string
+		params objx.Map
+	}{
+		{"modbus-read-holding", objx.Map{"address": num("10"), "quantity": num("1"), "format": "xml"}},
+		{"modbus-read-holding", objx.Map{"address": num("10"), "quantity": num("1"), "format": "csv", "verbose": true}},
+		{"modbus-read-coil", objx.Map{"address": num("3"), "quantity": num("2"), "format": "csv", "summary": true}},
+		{"modbus-write-register", objx.Map{"address": num("10"), "value": num("1"), "format": "csv"}},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: tc.params}); err == nil {
+			t.Errorf("%s %v: expected error", tc.method, tc.params)
+		}
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 0x4049
+	m.holding[1] = 0x0FDB
+	m.coils[2] = true
+
+	requests := []jsonrpc.Request{
+		{Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "float32"}},
+		{Method: "modbus-read-coil", Params: objx.Map{"address": num("0"), "quantity": num("4")}},
+		{Method: "modbus-write-register", Params: objx.Map{"address": num("0"), "value": num("7")}},
+		{Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("1"), "verbose": true}},
+		{Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("2"), "encoding": "float32"}},
+		{Method: "modbus-read-holding", Params: objx.Map{"address": num("65535"), "quantity": num("2")}},
+	}
+
+	var buf bytes.Buffer
+
+	call := func(srv Service) []interface{} {
+		var results []interface{}
+
+		for _, req := range requests {
+			res, err := srv.Call(req)
+			if err != nil {
+				res = err.Error()
+			}
+
+			results = append(results, res)
+		}
+
+		return results
+	}
+
+	recorded := call(newMockService(m, TraceFunc(RecordTrace(&buf))))
+
+	records, err := parseTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != len(requests) || records[0].Method != "modbus-read-holding" || records[0].Request != "0300000002" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+
+	replayed := call(New(nil, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, Replay(records...)))
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("expected %v but got %v", recorded, replayed)
+	}
+
+	_, err = New(nil, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, Replay(records...)).
+		Call(jsonrpc.Request{Method: "modbus-read-input", Params: objx.Map{"address": num("0"), "quantity": num("1")}})
+	if err == nil {
+		t.Error("request which isn't recorded should fail")
+	}
+}
+
+func TestCommandWord(t *testing.T) {
+	m := &mockSlave{}
+
+	cmd := uint16(0x0010)
+	points := []Point{{Name: "speed", Function: pointHolding, Address: 20, Encoding: "uint32", CommandWord: &cmd}}
+
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": num("10"), "quantity": num("3"), "value": []interface{}{num("1"), num("2")}, "command_word": num("7")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.holding[10] != 7 || m.holding[11] != 1 || m.holding[12] != 2 {
+		t.Errorf("command word should be written first but got %v", m.holding[10:13])
+	}
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-write-point", Params: objx.Map{"point": "speed", "value": num("65537")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.holding[20] != 0x0010 || m.holding[21] != 1 || m.holding[22] != 1 {
+		t.Errorf("command word should be written before point value but got %v", m.holding[20:23])
+	}
+
+	for _, params := range []objx.Map{
+		// quantity doesn't include command word
+		{"address": num("10"), "quantity": num("2"), "value": []interface{}{num("1"), num("2")}, "command_word": num("7")},
+		{"address": num("10"), "quantity": num("3"), "value": []interface{}{num("1"), num("2")}, "command_word": num("7"), "verify": true},
+		{"address": num("10"), "quantity": num("3"), "value": []interface{}{num("1"), num("2")}, "command_word": num("70000")},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-write-multiple-registers", Params: params}); err == nil {
+			t.Errorf("expected error of %v", params)
+		}
+	}
+
+	if err := ValidatePoints([]Point{{Name: "run", Function: pointCoil, CommandWord: &cmd}}); err == nil {
+		t.Error("command_word of coil should be rejected")
+	}
+}
+
+func TestShrinkQuantity(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[253] = 1
+	m.holding[254] = 2
+	m.holding[255] = 3
+
+	srv := newMockService(m)
+
+	for _, tc := range []struct {
+		params   objx.Map
+		expected interface{}
+	}{
+		{
+			objx.Map{"address": num("253"), "quantity": num("10")},
+			shrinkResult{Result: []interface{}{uint16(1), uint16(2), uint16(3)}, Truncated: true, Quantity: 3},
+		},
+		{
+			// whole values only
+			objx.Map{"address": num("253"), "quantity": num("6"), "encoding": "uint32"},
+			shrinkResult{Result: []interface{}{uint32(0x00010002)}, Truncated: true, Quantity: 2},
+		},
+		{
+			objx.Map{"address": num("254"), "quantity": num("2")},
+			shrinkResult{Result: []interface{}{uint16(2), uint16(3)}, Quantity: 2},
+		},
+	} {
+		params := tc.params.Copy()
+		params["shrink_quantity"] = true
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(res, tc.expected) {
+			t.Errorf("%v: expected %+v but got %+v", tc.params, tc.expected, res)
+		}
+	}
+
+	for _, params := range []objx.Map{
+		// nothing readable
+		{"address": num("256"), "quantity": num("4"), "shrink_quantity": true},
+		{"address": num("253"), "quantity": num("10")},
+		{"address": num("253"), "quantity": num("10"), "shrink_quantity": true, "chunked": true},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params}); err == nil {
+			t.Errorf("expected error of %v", params)
+		}
+	}
+}
+
+// serialSlave is mock slave on serial port
+type serialSlave struct {
+	*mockSlave
+	config serial.Config
+}
+
+func (s *serialSlave) LineConfig() serial.Config {
+	return s.config
+}
+
+func (s *serialSlave) SetLineConfig(c serial.Config) error {
+	if c.BaudRate == 1800 {
+		return errors.New("serial: unsupported baud rate 1800")
+	}
+
+	s.config = c
+
+	return nil
+}
+
+func TestSerialConfig(t *testing.T) {
+	port := &serialSlave{mockSlave: &mockSlave{}, config: serial.Config{Address: "/dev/ttyS0"}}
+	srv := New(port, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	for _, tc := range []struct {
+		params   objx.Map
+		expected serialConfigResult
+	}{
+		{objx.Map{}, serialConfigResult{BaudRate: 19200, DataBits: 8, Parity: "E", StopBits: 1}},
+		{objx.Map{"baud_rate": num("9600"), "parity": "N", "stop_bits": num("2")}, serialConfigResult{9600, 8, "N", 2}},
+		{objx.Map{"data_bits": num("7")}, serialConfigResult{9600, 7, "N", 2}},
+	} {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-serial-config", Params: tc.params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res != tc.expected {
+			t.Errorf("%v: expected %+v but got %+v", tc.params, tc.expected, res)
+		}
+	}
+
+	for _, params := range []objx.Map{
+		{"data_bits": num("9")},
+		{"parity": "X"},
+		{"baud_rate": num("1800")},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-serial-config", Params: params}); err == nil {
+			t.Errorf("expected error of %v", params)
+		}
+	}
+
+	if port.config.BaudRate != 9600 {
+		t.Errorf("failed config shouldn't be applied but got %+v", port.config)
+	}
+
+	_, err := newMockService(&mockSlave{}).Call(jsonrpc.Request{Method: "modbus-serial-config", Params: objx.Map{}})
+	if err == nil {
+		t.Error("tcp transport should be rejected")
+	}
+}
+
+func TestScaleRegister(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[10] = 1234
+	m.holding[11] = 0xFFFF // -1 as int16
+	m.holding[12] = 0xFFFE // -2 as exponent
+	m.holding[13] = 0x00FD // -3 as int8 exponent
+	m.holding[200] = 0x0001
+
+	exp, far := uint16(12), uint16(200)
+	points := []Point{
+		{Name: "power", Function: pointHolding, Address: 10, ExponentAddress: &exp, Unit: "kW"},
+		{Name: "energy", Function: pointHolding, Address: 10, ExponentAddress: &far, Scale: 0.5},
+	}
+
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	for _, tc := range []struct {
+		method   string
+		params   objx.Map
+		expected interface{}
+		pdus     int
+	}{
+		{
+			"modbus-read-holding",
+			objx.Map{"address": num("10"), "quantity": num("2"), "encoding": "scale_register", "exponent_address": num("12"),
+				"value_encoding": "int16"},
+			[]interface{}{12.34, -0.01}, 1,
+		},
+		{
+			"modbus-read-holding",
+			objx.Map{"address": num("10"), "quantity": num("1"), "encoding": "scale_register", "exponent_address": num("13"),
+				"exponent_encoding": "int8"},
+			[]interface{}{1.234}, 1,
+		},
+		{"modbus-read-point", objx.Map{"point": "power"}, 12.34, 1},
+		// exponent is too far for one transaction
+		{"modbus-read-point", objx.Map{"point": "energy"}, float64(6170), 2},
+	} {
+		m.pdus = nil
+
+		res, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: tc.params})
+		if err != nil {
+			t.Fatalf("%v: %v", tc.params, err)
+		}
+
+		if !reflect.DeepEqual(res, tc.expected) || len(m.pdus) != tc.pdus {
+			t.Errorf("%v: expected %v by %d pdus but got %v by %d", tc.params, tc.expected, tc.pdus, res, len(m.pdus))
+		}
+	}
+
+	m.holding[12] = 0x8000
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": "power"}})
+	if err != nil || res != nil {
+		t.Errorf("value of not implemented exponent should be null but got %v (%v)", res, err)
+	}
+
+	for _, params := range []objx.Map{
+		{"address": num("10"), "quantity": num("1"), "encoding": "scale_register"},
+		{"address": num("10"), "quantity": num("1"), "encoding": "scale_register", "exponent_address": num("12"), "value_encoding": "float32"},
+		{"address": num("10"), "quantity": num("1"), "encoding": "scale_register", "exponent_address": num("12"), "verbose": true},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params}); err == nil {
+			t.Errorf("expected error of %v", params)
+		}
+	}
+
+	if _, err := srv.Call(jsonrpc.Request{Method: "modbus-write-point", Params: objx.Map{"point": "power", "value": num("1")}}); err == nil {
+		t.Error("point with exponent_address should be read only")
+	}
+
+	if err := ValidatePoints([]Point{{Name: "x", Function: pointHolding, Encoding: "float32", ExponentAddress: &exp}}); err == nil {
+		t.Error("float point with exponent_address should be rejected")
+	}
+}
+
+func TestRecordPoint(t *testing.T) {
+	m := &mockSlave{}
+	m.inputs[100] = 7
+	m.inputs[101], m.inputs[102] = 0x0000, 0x4148 // 12.5 with little word order
+	m.inputs[103] = 0xFF6A                        // -150
+	m.inputs[104], m.inputs[105] = 0x0001, 0x0002
+
+	fields := []PointField{
+		{Name: "status"},
+		{Name: "speed", Offset: 1, Type: "float32", WordOrder: "little"},
+		{Name: "current", Offset: 3, Type: "int16", Scale: 0.01},
+		{Name: "hours", Offset: 4, Type: "uint32"},
+	}
+	points := []Point{{Name: "drive", Function: pointInput, Address: 100, Quantity: 6, Fields: fields}}
+
+	if err := ValidatePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newMockService(m, Profile(points...))
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-point", Params: objx.Map{"point": "drive"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := json.Marshal(res)
+	if expected := `{"current":-1.5,"hours":65538,"speed":12.5,"status":7}`; string(b) != expected {
+		t.Errorf("expected %s but got %s", expected, b)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadInputRegisters, 0x00, 0x64, 0x00, 0x06})
+
+	if _, err := srv.Call(jsonrpc.Request{Method: "modbus-write-point", Params: objx.Map{"point": "drive", "value": num("1")}}); err == nil {
+		t.Error("record point should be read only")
+	}
+
+	for _, fields := range [][]PointField{
+		{{Name: "a", Type: "uint32"}, {Name: "b", Offset: 1}},
+		{{Name: "a"}, {Name: "a", Offset: 1}},
+		{{Name: "a", Offset: 5, Type: "uint32"}},
+		{{Name: "a", Type: "unknown"}},
+	} {
+		p := Point{Name: "x", Function: pointInput, Quantity: 6, Fields: fields}
+		if err := ValidatePoints([]Point{p}); err == nil {
+			t.Errorf("expected error of fields %v", fields)
+		}
+	}
+}
+
+func TestReadClock(t *testing.T) {
+	device := time.Now().Add(90 * time.Second).Truncate(time.Second)
+	local := device.In(time.FixedZone("", 3*3600))
+
+	m := &mockSlave{}
+	m.holding[10], m.holding[11] = uint16(device.Unix()>>16), uint16(device.Unix())
+
+	for i, v := range []int{local.Year() % 100, int(local.Month()), local.Day(), local.Hour(), local.Minute(), local.Second()} {
+		m.inputs[20+i] = uint16(v)
+	}
+
+	points := []Point{{Name: "rtc", Function: pointInput, Address: 20}}
+	srv := newMockService(m, Profile(points...))
+
+	for _, params := range []objx.Map{
+		{"address": num("10"), "timestamp_format": "epoch_ms"},
+		{"point": "rtc", "layout": "ymdhms", "tz_offset": "3h", "timestamp_format": "epoch_ms"},
+	} {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-clock", Params: params})
+		if err != nil {
+			t.Fatalf("%v: %v", params, err)
+		}
+
+		clock := res.(clockResult)
+		if clock.DeviceTime != device.UnixNano()/int64(time.Millisecond) {
+			t.Errorf("%v: expected device time %v but got %v", params, device, clock.DeviceTime)
+		}
+
+		// device clock is 89-90 seconds ahead
+		if clock.SkewMs < 88000 || clock.SkewMs > 90000 {
+			t.Errorf("%v: unexpected skew %d ms", params, clock.SkewMs)
+		}
+	}
+
+	m.inputs[21] = 13
+
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-clock", Params: objx.Map{"point": "rtc", "layout": "ymdhms"}})
+	if err == nil {
+		t.Error("expected error of invalid month")
+	}
+
+	_, err = srv.Call(jsonrpc.Request{Method: "modbus-read-clock", Params: objx.Map{"address": num("10"), "layout": "bcd"}})
+	if err == nil {
+		t.Error("expected error of unknown layout")
+	}
+}
+
+func TestCompareAndWrite(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[5] = 100
+	m.holding[6], m.holding[7] = 0xFFFF, 0xFFFE // -2 as int32
+
+	srv := newMockService(m)
+
+	for _, tc := range []struct {
+		params   objx.Map
+		expected compareWriteResult
+		pdu      []byte
+	}{
+		{
+			objx.Map{"address": num("5"), "expected": num("100"), "value": num("120")},
+			compareWriteResult{Written: true, Current: uint16(120)},
+			[]byte{0x06, 0x00, 0x05, 0x00, 0x78},
+		},
+		// register was changed by someone else
+		{
+			objx.Map{"address": num("5"), "expected": num("100"), "value": num("130")},
+			compareWriteResult{Current: uint16(120)},
+			[]byte{0x03, 0x00, 0x05, 0x00, 0x01},
+		},
+		{
+			objx.Map{"address": num("6"), "expected": num("-2"), "value": num("70000"), "encoding": "int32"},
+			compareWriteResult{Written: true, Current: int32(70000)},
+			[]byte{0x10, 0x00, 0x06, 0x00, 0x02, 0x04, 0x00, 0x01, 0x11, 0x70},
+		},
+	} {
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-compare-and-write", Params: tc.params})
+		if err != nil {
+			t.Fatalf("%v: %v", tc.params, err)
+		}
+
+		if res != tc.expected {
+			t.Errorf("%v: expected %v but got %v", tc.params, tc.expected, res)
+		}
+
+		m.assertPDU(t, tc.pdu)
+	}
+
+	if m.holding[5] != 120 {
+		t.Errorf("mismatched write should be skipped but register is %d", m.holding[5])
+	}
+
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-compare-and-write",
+		Params: objx.Map{"address": num("5"), "expected": []interface{}{num("1")}, "value": num("1")}})
+	if err == nil {
+		t.Error("expected error of array value")
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	regs := []SimRegister{
+		{Function: pointHolding, Address: 100, Value: -5},
+		{Function: pointCoil, Address: 7, Value: 1},
+		{Function: pointInput, Address: 60000, Value: 200, Amplitude: 50, Period: time.Second},
+	}
+
+	if err := ValidateSimRegisters(regs); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(NewSimulator(regs...), func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	call := func(method string, params objx.Map) interface{} {
+		t.Helper()
+
+		res, err := srv.Call(jsonrpc.Request{Method: method, Params: params})
+		if err != nil {
+			t.Fatalf("%s %v: %v", method, params, err)
+		}
+
+		return res
+	}
+
+	if res := call("modbus-read-holding", objx.Map{"address": num("100"), "quantity": num("1"), "encoding": "int16"}); !reflect.DeepEqual(res, []interface{}{int16(-5)}) {
+		t.Errorf("unexpected seeded register %v", res)
+	}
+
+	if res := call("modbus-read-coil", objx.Map{"address": num("6"), "quantity": num("2")}); !reflect.DeepEqual(res, []uint16{0, 1}) {
+		t.Errorf("unexpected seeded coils %v", res)
+	}
+
+	call("modbus-write-multiple-registers", objx.Map{"address": num("65000"), "value": []interface{}{num("1.5")}, "encoding": "float32"})
+
+	if res := call("modbus-read-holding", objx.Map{"address": num("65000"), "quantity": num("2"), "encoding": "float32"}); !reflect.DeepEqual(res, []interface{}{float32(1.5)}) {
+		t.Errorf("written value should be read back but got %v", res)
+	}
+
+	wave := call("modbus-read-input", objx.Map{"address": num("60000"), "quantity": num("1")}).([]interface{})
+	if v := wave[0].(uint16); v < 150 || v > 250 {
+		t.Errorf("wave value should be 150-250 but got %v", v)
+	}
+
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding", Params: objx.Map{"address": num("0"), "quantity": num("126")},
+	}); err == nil {
+		t.Error("read over 125 registers should fail")
+	}
+
+	if res := call("modbus-read-write-registers", objx.Map{
+		"read_address": num("99"), "read_quantity": num("3"), "write_address": num("101"), "value": []interface{}{num("9")},
+	}); !reflect.DeepEqual(res, []interface{}{uint16(0), uint16(0xFFFB), uint16(9)}) {
+		t.Errorf("read/write should read written value but got %v", res)
+	}
+
+	call("modbus-write-file-record", objx.Map{
+		"file_number": num("4"), "record_number": num("9999"), "value": []interface{}{num("1711")},
+	})
+
+	records := call("modbus-read-file-record", objx.Map{"records": []interface{}{
+		map[string]interface{}{"file_number": num("4"), "record_number": num("9998"), "record_length": num("2")},
+		map[string]interface{}{"file_number": num("5"), "record_number": num("0"), "record_length": num("1")},
+	}})
+	if !reflect.DeepEqual(records, []fileRecord{
+		{FileNumber: 4, RecordNumber: 9998, Data: []uint16{0, 0x06AF}},
+		{FileNumber: 5, RecordNumber: 0, Data: []uint16{0}},
+	}) {
+		t.Errorf("written file record should be read back but got %v", records)
+	}
+
+	expectedID := deviceIdentification{ConformityLevel: simDeviceIDLevel, Objects: []deviceObject{
+		{ID: 0x00, Name: "vendor_name", Value: "Rightech"},
+		{ID: 0x01, Name: "product_code", Value: "ric-edge"},
+		{ID: 0x02, Name: "major_minor_revision", Value: "1.0"},
+		{ID: 0x04, Name: "product_name", Value: "modbus simulator"},
+	}}
+	if res := call("modbus-read-device-identification", nil); !reflect.DeepEqual(res, expectedID) {
+		t.Errorf("unexpected device identification %+v", res)
+	}
+
+	call("modbus-diagnostics", objx.Map{"sub_function": num("10")})
+
+	expectedDiag := diagnosticsResult{SubFunction: 0x0B, Data: 1, Counter: "bus_message"}
+	if res := call("modbus-diagnostics", objx.Map{"sub_function": num("11")}); res != expectedDiag {
+		t.Errorf("expected %+v but got %+v", expectedDiag, res)
+	}
+
+	for _, r := range []SimRegister{
+		{Function: "fifo"},
+		{Function: pointCoil, Value: 2},
+		{Function: pointHolding, Value: 70000},
+		{Function: pointHolding, Amplitude: 1},
+	} {
+		if err := ValidateSimRegisters([]SimRegister{r}); err == nil {
+			t.Errorf("expected error of %+v", r)
+		}
+	}
+}
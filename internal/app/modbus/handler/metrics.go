@@ -0,0 +1,309 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// error classes of metrics
+const (
+	errClassTimeout     = "timeout"
+	errClassTransport   = "transport"
+	errClassException   = "exception"
+	errClassBusBusy     = "bus_busy"
+	errClassRateLimited = "rate_limited"
+	errClassFraming     = "framing"
+	errClassEmpty       = "empty"
+)
+
+// count of last latencies used for percentiles
+const latencyWindow = 1024
+
+type slaveMetrics struct {
+	Transactions uint64            `json:"transactions"`
+	Errors       map[string]uint64 `json:"errors"`
+	// transactions longer than slow threshold
+	Slow uint64 `json:"slow"`
+}
+
+type latencyMetrics struct {
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+type metricsResult struct {
+	slaveMetrics
+	// in milliseconds, percentiles are calculated over last transactions
+	LatencyMs latencyMetrics          `json:"latency_ms"`
+	Slaves    map[string]slaveMetrics `json:"slaves"`
+}
+
+// busMetrics contains cumulative counters of bus transactions
+type busMetrics struct {
+	mx sync.Mutex
+
+	total  slaveMetrics
+	slaves map[byte]*slaveMetrics
+
+	latencySum time.Duration
+	// ring buffer of last latencies
+	latencies []time.Duration
+	next      int
+
+	// time of last response of slaves (it's not cleared by reset)
+	lastResponse map[byte]time.Time
+	// health of slaves and devices by name (it's not cleared by reset)
+	status       map[byte]*slaveStatus
+	deviceStatus map[string]*slaveStatus
+}
+
+func newBusMetrics() *busMetrics {
+	m := &busMetrics{
+		lastResponse: make(map[byte]time.Time),
+		status:       make(map[byte]*slaveStatus),
+		deviceStatus: make(map[string]*slaveStatus),
+	}
+	m.reset()
+
+	return m
+}
+
+// reset clears metrics, caller must hold the mutex (except on creation)
+func (m *busMetrics) reset() {
+	m.total = slaveMetrics{Errors: make(map[string]uint64)}
+	m.slaves = make(map[byte]*slaveMetrics)
+	m.latencySum = 0
+	m.latencies = make([]time.Duration, 0, latencyWindow)
+	m.next = 0
+}
+
+// slave returns metrics of slave, caller must hold the mutex
+func (m *busMetrics) slave(slaveID byte) *slaveMetrics {
+	slave, ok := m.slaves[slaveID]
+	if !ok {
+		slave = &slaveMetrics{Errors: make(map[string]uint64)}
+		m.slaves[slaveID] = slave
+	}
+
+	return slave
+}
+
+// recordSlow counts transaction longer than slow threshold
+func (m *busMetrics) recordSlow(slaveID byte) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.total.Slow++
+	m.slave(slaveID).Slow++
+}
+
+// record counts transaction or rejected request (if latency is 0)
+// errClass is empty for successful transactions
+func (m *busMetrics) record(slaveID byte, errClass string, latency time.Duration) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	slave := m.slave(slaveID)
+
+	if errClass != "" {
+		m.total.Errors[errClass]++
+		slave.Errors[errClass]++
+	}
+
+	// rejected requests don't reach the bus
+	if latency == 0 {
+		return
+	}
+
+	m.total.Transactions++
+	slave.Transactions++
+
+	// exception is response too
+	if errClass == "" || errClass == errClassException {
+		m.lastResponse[slaveID] = time.Now()
+	}
+
+	m.latencySum += latency
+
+	if len(m.latencies) < latencyWindow {
+		m.latencies = append(m.latencies, latency)
+	} else {
+		m.latencies[m.next] = latency
+		m.next = (m.next + 1) % latencyWindow
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// percentile returns percentile of sorted latencies in milliseconds
+func percentile(sorted []time.Duration, p int) float64 {
+	return ms(sorted[(len(sorted)-1)*p/100])
+}
+
+// snapshot returns copy of metrics and resets them if reset is true
+func (m *busMetrics) snapshot(reset bool) metricsResult {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if reset {
+		defer m.reset()
+	}
+
+	res := metricsResult{
+		slaveMetrics: slaveMetrics{Transactions: m.total.Transactions, Errors: make(map[string]uint64), Slow: m.total.Slow},
+		Slaves:       make(map[string]slaveMetrics, len(m.slaves)),
+	}
+
+	for k, v := range m.total.Errors {
+		res.Errors[k] = v
+	}
+
+	for id, slave := range m.slaves {
+		errs := make(map[string]uint64, len(slave.Errors))
+		for k, v := range slave.Errors {
+			errs[k] = v
+		}
+
+		res.Slaves[strconv.Itoa(int(id))] = slaveMetrics{Transactions: slave.Transactions, Errors: errs, Slow: slave.Slow}
+	}
+
+	if len(m.latencies) == 0 {
+		return res
+	}
+
+	sorted := append([]time.Duration{}, m.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	res.LatencyMs = latencyMetrics{
+		Avg: ms(m.latencySum / time.Duration(m.total.Transactions)),
+		P50: percentile(sorted, 50),
+		P95: percentile(sorted, 95),
+		P99: percentile(sorted, 99),
+	}
+
+	return res
+}
+
+// errClass returns metrics class of transport error
+func errClass(err error) string {
+	var (
+		netErr net.Error
+		frmErr *framingError
+	)
+
+	switch {
+	case errors.As(err, &frmErr):
+		return errClassFraming
+	case errors.Is(err, errEmptyResponse):
+		return errClassEmpty
+	case errors.Is(err, errBusBusy):
+		return errClassBusBusy
+	case errors.Is(err, errRateLimited):
+		return errClassRateLimited
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errClassTimeout
+	default:
+		return errClassTransport
+	}
+}
+
+// metricsTransporter records transactions on the bus
+type metricsTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+	metrics  *busMetrics
+	slaveID  byte
+	// device of unit id (empty for transactions without device param)
+	device string
+	// transactions longer than slow are logged (0 disables it)
+	slow   time.Duration
+	method string
+}
+
+func (t metricsTransporter) Send(adu []byte) ([]byte, error) {
+	start := time.Now()
+	res, err := t.Transporter.Send(adu)
+	latency := time.Since(start)
+
+	var (
+		class   string
+		failure = err
+	)
+
+	if err != nil {
+		class = errClass(err)
+	} else if code := exceptionCode(t.packager, adu, res); code != 0 {
+		class = errClassException
+		failure = exceptionErr(t.packager, adu, code)
+	}
+
+	t.metrics.record(t.slaveID, class, latency)
+	t.metrics.recordStatus(t.slaveID, t.device, class, failure)
+
+	if t.slow > 0 && latency > t.slow {
+		t.metrics.recordSlow(t.slaveID)
+
+		log.WithFields(log.Fields{
+			"method": t.method, "slave_id": t.slaveID, "duration_ms": ms(latency),
+		}).Warn("slow modbus transaction")
+	}
+
+	return res, err
+}
+
+// rejectionCounter records requests rejected before the bus
+// (bus busy and rate limit errors)
+type rejectionCounter struct {
+	modbus.Transporter
+	metrics *busMetrics
+	slaveID byte
+}
+
+func (t rejectionCounter) Send(adu []byte) ([]byte, error) {
+	res, err := t.Transporter.Send(adu)
+
+	if errors.Is(err, errBusBusy) || errors.Is(err, errRateLimited) {
+		t.metrics.record(t.slaveID, errClass(err), 0)
+	}
+
+	return res, err
+}
+
+// stats returns cumulative metrics of bus transactions
+// metrics are reset after response if reset param set
+func (s Service) stats(params objx.Map) (interface{}, error) {
+	if s.metrics == nil {
+		return metricsResult{}, nil
+	}
+
+	return s.metrics.snapshot(params.Get("reset").Bool()), nil
+}
@@ -0,0 +1,104 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// mockBankSize is address space of mock slave,
+// requests beyond it are answered by illegal data address exception
+const mockBankSize = 256
+
+// mockSlave is simulator which remembers all received pdu (function code and data)
+// and can answer by exceptions of busy or limited device
+type mockSlave struct {
+	Simulator
+
+	// count of requests answered by slave device busy exception
+	busy int
+	// mask write (FC22) is supported
+	maskWrite bool
+
+	pdus [][]byte
+}
+
+func newMockService(slave *mockSlave, o ...Option) Service {
+	return New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) }, o...)
+}
+
+func (m *mockSlave) Send(adu []byte) ([]byte, error) {
+	pdu := adu[7:]
+	m.pdus = append(m.pdus, append([]byte{}, pdu...))
+
+	switch {
+	case m.busy > 0:
+		m.busy--
+		return simResponse(adu, nil, modbus.ExceptionCodeServerDeviceBusy), nil
+	case pdu[0] == modbus.FuncCodeMaskWriteRegister && !m.maskWrite:
+		return simResponse(adu, nil, modbus.ExceptionCodeIllegalFunction), nil
+	case !inBank(pdu):
+		return simResponse(adu, nil, modbus.ExceptionCodeIllegalDataAddress), nil
+	}
+
+	return m.Simulator.Send(adu)
+}
+
+func inRange(addr, quantity uint16) bool {
+	return int(addr)+int(quantity) <= mockBankSize
+}
+
+// inBank checks addresses of request to coils and registers are in mock bank
+func inBank(pdu []byte) bool {
+	if len(pdu) < 5 {
+		return true
+	}
+
+	addr := binary.BigEndian.Uint16(pdu[1:])
+	quantity := binary.BigEndian.Uint16(pdu[3:])
+
+	switch pdu[0] {
+	case modbus.FuncCodeWriteSingleCoil, modbus.FuncCodeWriteSingleRegister, modbus.FuncCodeMaskWriteRegister:
+		return inRange(addr, 1)
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		return len(pdu) < 9 || inRange(addr, quantity) &&
+			inRange(binary.BigEndian.Uint16(pdu[5:]), binary.BigEndian.Uint16(pdu[7:]))
+	case modbus.FuncCodeReadCoils, modbus.FuncCodeReadDiscreteInputs,
+		modbus.FuncCodeReadHoldingRegisters, modbus.FuncCodeReadInputRegisters,
+		modbus.FuncCodeWriteMultipleCoils, modbus.FuncCodeWriteMultipleRegisters:
+		return inRange(addr, quantity)
+	default:
+		return true
+	}
+}
+
+// assertPDU checks last received pdu
+func (m *mockSlave) assertPDU(t *testing.T, expected []byte) {
+	t.Helper()
+
+	if len(m.pdus) == 0 {
+		t.Fatalf("expected pdu %x but nothing was sent", expected)
+	}
+
+	if last := m.pdus[len(m.pdus)-1]; !bytes.Equal(last, expected) {
+		t.Errorf("expected pdu %x but got %x", expected, last)
+	}
+}
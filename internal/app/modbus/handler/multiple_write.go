@@ -0,0 +1,70 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// ForceMultipleWrite sends single writes of slaveID by multiple write functions with quantity 1
+// (write-register as FC16 instead of FC06, write-coil as FC15 instead of FC05)
+// it's a shim for gateways which don't support single write functions,
+// note that it changes function code on the wire (access rules and traces see FC15/FC16)
+func ForceMultipleWrite(slaveID byte) Option {
+	return func(s *Service) {
+		if s.multipleWrite == nil {
+			s.multipleWrite = make(map[byte]bool)
+		}
+
+		s.multipleWrite[slaveID] = true
+	}
+}
+
+// multipleWriteClient sends single writes by multiple write functions
+// results are the same as results of single writes (written value)
+type multipleWriteClient struct {
+	modbus.Client
+}
+
+func (c multipleWriteClient) WriteSingleCoil(address, value uint16) ([]byte, error) {
+	var bits byte
+	if value == modbusTrueValue {
+		bits = 1
+	}
+
+	if _, err := c.WriteMultipleCoils(address, 1, []byte{bits}); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 2)
+	binary.BigEndian.PutUint16(res, value)
+
+	return res, nil
+}
+
+func (c multipleWriteClient) WriteSingleRegister(address, value uint16) ([]byte, error) {
+	res := make([]byte, 2)
+	binary.BigEndian.PutUint16(res, value)
+
+	if _, err := c.WriteMultipleRegisters(address, 1, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,278 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestConflictingParams(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	for _, tc := range []struct {
+		method string
+		params objx.Map
+	}{
+		{"modbus-write-multiple-registers", objx.Map{"address": num("0"), "value": num("1"), "values": num("2")}},
+		{"modbus-write-register", objx.Map{"address": num("0"), "value": num("-1"), "signed": true, "encoding": "uint16"}},
+		{"modbus-write-multiple-coils", objx.Map{"address": num("0"), "quantity": num("3"), "value": []interface{}{true, false}}},
+		{"modbus-write-multiple-registers", objx.Map{"address": num("0"), "quantity": num("1"), "value": num("1"), "encoding": "uint32"}},
+	} {
+		_, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: tc.params})
+
+		if _, ok := err.(jsonrpc.Error); !ok {
+			t.Errorf("%s %v: expected invalid params error but got %v", tc.method, tc.params, err)
+		}
+	}
+
+	if len(m.pdus) != 0 {
+		t.Errorf("conflicting requests shouldn't be sent but got %x", m.pdus)
+	}
+
+	// signed with int16 encoding isn't conflict
+	_, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register",
+		Params: objx.Map{"address": num("0"), "value": num("-1"), "signed": true, "encoding": "int16"},
+	})
+	if err != nil || m.holding[0] != 0xFFFF {
+		t.Errorf("unexpected result %v (%x)", err, m.holding[0])
+	}
+}
+
+func TestNumericStringParams(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[3] = 7
+	srv := newMockService(m)
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": "3", "quantity": "1", "slave_id": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadHoldingRegisters, 0x00, 0x03, 0x00, 0x01})
+
+	if !reflect.DeepEqual(res, []interface{}{uint16(7)}) {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	if _, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register", Params: objx.Map{"address": "4", "value": "1000"},
+	}); err != nil || m.holding[4] != 1000 {
+		t.Errorf("expected register written from string but got %v (%v)", m.holding[4], err)
+	}
+
+	// arrays items are parsed as single params
+	if _, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": "5", "quantity": "2", "value": []interface{}{"2", num("3")}},
+	}); err != nil || m.holding[5] != 2 || m.holding[6] != 3 {
+		t.Errorf("expected registers written from strings but got %v (%v)", m.holding[5:7], err)
+	}
+
+	if _, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-coils",
+		Params: objx.Map{"address": "1", "quantity": "3", "value": []interface{}{"1", "0", num("1")}},
+	}); err != nil || !m.coils[1] || m.coils[2] || !m.coils[3] {
+		t.Errorf("expected coils written from strings but got %v (%v)", m.coils[1:4], err)
+	}
+
+	if _, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-multiple-registers",
+		Params: objx.Map{"address": "5", "quantity": "1", "value": []interface{}{"two"}},
+	}); err == nil {
+		t.Error("expected error of non-numeric string in array")
+	}
+
+	for _, v := range []string{"three", "", "0x03"} {
+		_, err = srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding", Params: objx.Map{"address": v, "quantity": "1"},
+		})
+		if err == nil {
+			t.Errorf("%q: expected error of non-numeric string", v)
+		}
+	}
+}
+
+func TestMaxQuantity(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m, MaxQuantity(10))
+
+	read := func(quantity string, dryRun bool) error {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-coil",
+			Params: objx.Map{"address": num("0"), "quantity": num(quantity), "dry_run": dryRun},
+		})
+
+		return err
+	}
+
+	if err := read("10", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// limit is checked before request is sent (and before dry run)
+	for _, dryRun := range []bool{false, true} {
+		if err := read("11", dryRun); err == nil {
+			t.Errorf("dry run %v: expected error of quantity over limit", dryRun)
+		}
+	}
+
+	if len(m.pdus) != 1 {
+		t.Errorf("expected 1 request but got %d", len(m.pdus))
+	}
+
+	// protocol limit is used without policy limit
+	if _, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-coil", Params: objx.Map{"address": num("0"), "quantity": num("11")},
+	}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestEmptyWriteValue(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	for _, tc := range []struct {
+		method string
+		params objx.Map
+	}{
+		{"modbus-write-multiple-coils", objx.Map{"address": num("0"), "quantity": num("0"), "value": []interface{}{}}},
+		{"modbus-write-multiple-registers", objx.Map{"address": num("0"), "value": []interface{}{}}},
+		{"modbus-write-file-record", objx.Map{"file_number": num("1"), "record_number": num("0"), "value": []interface{}{}}},
+	} {
+		_, err := srv.Call(jsonrpc.Request{Method: tc.method, Params: tc.params})
+
+		// data of jsonrpc error is private
+		if desc := fmt.Sprintf("%#v", err); !strings.Contains(desc, "value must not be empty") {
+			t.Errorf("%s: expected error of empty value but got %s", tc.method, desc)
+		}
+	}
+
+	if len(m.pdus) != 0 {
+		t.Errorf("empty writes shouldn't be sent but got %x", m.pdus)
+	}
+}
+
+// unitSlave is gateway which remembers unit ids of requests
+type unitSlave struct {
+	*mockSlave
+	units []byte
+}
+
+func (m *unitSlave) Send(adu []byte) ([]byte, error) {
+	m.units = append(m.units, adu[6])
+
+	return m.mockSlave.Send(adu)
+}
+
+func TestMethodDefaultsScope(t *testing.T) {
+	m := &unitSlave{mockSlave: &mockSlave{}}
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) },
+		MethodDefaults(map[string]objx.Map{
+			"modbus-write-register": {"slave_id": num("4"), "signed": true},
+		}))
+
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register", Params: objx.Map{"address": num("1"), "value": num("-1")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeWriteSingleRegister, 0x00, 0x01, 0xFF, 0xFF})
+
+	// defaults of one method don't apply to others
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding", Params: objx.Map{"address": num("1"), "quantity": num("1")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(m.units, []byte{4, 0}) {
+		t.Errorf("unexpected unit ids %v", m.units)
+	}
+
+	// without signed default negative value is out of range
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-register", Params: objx.Map{"address": num("1"), "value": num("-1"), "signed": false},
+	}); err == nil {
+		t.Error("expected error of negative value with signed overridden")
+	}
+}
+
+func TestAddressBase(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[0] = 5
+	srv := newMockService(m, AddressBase(1))
+
+	call := func(method string, params objx.Map) (interface{}, error) {
+		return srv.Call(jsonrpc.Request{Method: method, Params: params})
+	}
+
+	// 1-based address 1 is protocol address 0
+	res, err := call("modbus-read-holding", objx.Map{"address": num("1"), "quantity": num("1")})
+	if err != nil || !reflect.DeepEqual(res, []interface{}{uint16(5)}) {
+		t.Errorf("unexpected result %v (%v)", res, err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadHoldingRegisters, 0x00, 0x00, 0x00, 0x01})
+
+	// all address params are converted
+	_, err = call("modbus-read-write-registers", objx.Map{
+		"read_address": num("3"), "read_quantity": num("1"), "write_address": num("5"), "value": []interface{}{num("1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadWriteMultipleRegisters, 0x00, 0x02, 0x00, 0x01, 0x00, 0x04, 0x00, 0x01, 0x02, 0x00, 0x01})
+
+	// request can use other base
+	if _, err = call("modbus-read-holding", objx.Map{"address": num("1"), "quantity": num("1"), "address_base": num("0")}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadHoldingRegisters, 0x00, 0x01, 0x00, 0x01})
+
+	sent := len(m.pdus)
+
+	for _, params := range []objx.Map{
+		{"address": num("0"), "quantity": num("1")},
+		{"address": num("1"), "quantity": num("1"), "address_base": num("2")},
+	} {
+		if _, err := call("modbus-read-holding", params); err == nil {
+			t.Errorf("%v: expected error", params)
+		}
+	}
+
+	if len(m.pdus) != sent {
+		t.Error("invalid addresses shouldn't be sent")
+	}
+}
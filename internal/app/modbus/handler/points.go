@@ -0,0 +1,424 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// pointFunctions contains read function code of point functions
+var pointFunctions = map[string]byte{ // nolint: gochecknoglobals
+	pointCoil:     modbus.FuncCodeReadCoils,
+	pointDiscrete: modbus.FuncCodeReadDiscreteInputs,
+	pointInput:    modbus.FuncCodeReadInputRegisters,
+	pointHolding:  modbus.FuncCodeReadHoldingRegisters,
+}
+
+// pointValue is point value with engineering unit (with_units param)
+type pointValue struct {
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit"`
+}
+
+// enumValue is value of enum point which has no label (unknown is set)
+// or any value in verbose mode (raw is set)
+type enumValue struct {
+	Value   interface{} `json:"value"`
+	Raw     interface{} `json:"raw,omitempty"`
+	Unknown bool        `json:"unknown,omitempty"`
+}
+
+// enumLabel returns label of integer value v
+func (p Point) enumLabel(v interface{}) (string, bool) {
+	f, ok := toFloat64(v)
+	if !ok || f != math.Trunc(f) {
+		return "", false
+	}
+
+	label, ok := p.Enum[strconv.FormatInt(int64(f), 10)]
+
+	return label, ok
+}
+
+// enumValue returns label of value, value itself marked as unknown if it has no label
+func (p Point) enumValue(v interface{}, verbose bool) interface{} {
+	label, ok := p.enumLabel(v)
+
+	switch {
+	case !ok:
+		return enumValue{Value: v, Unknown: true}
+	case verbose:
+		return enumValue{Value: label, Raw: v}
+	default:
+		return label
+	}
+}
+
+// readPointValue reads point value
+// single value returned as is, several values as array
+// (wrapped with rollover flag if detect_wrap param set)
+func (s Service) readPointValue(p Point, params objx.Map) (interface{}, error) {
+	if params.Get("detect_wrap").Bool() {
+		return s.readCounterPoint(p, params)
+	}
+
+	values, err := s.readPointValues(p, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.value(values, params), nil
+}
+
+// value applies scale (or transform) and enum, bit labels (or number_as_string) of point to raw values,
+// values of point with byte labels are returned by name
+func (p Point) value(values []interface{}, params objx.Map) interface{} {
+	if p.transform != nil {
+		for i, v := range values {
+			values[i] = p.transform.apply(v)
+		}
+	}
+
+	if p.scaled() {
+		for i, v := range values {
+			if f, ok := toFloat64(v); ok {
+				values[i] = p.engineering(f)
+			}
+		}
+	}
+
+	switch {
+	case len(p.Enum) > 0:
+		verbose := params.Get("verbose").Bool()
+
+		for i, v := range values {
+			values[i] = p.enumValue(v, verbose)
+		}
+	case len(p.BitLabels) > 0:
+		for i, v := range values {
+			values[i] = p.statusWord(v)
+		}
+	case params.Get("number_as_string").Bool():
+		for i, v := range values {
+			if str, ok := wideString(v); ok {
+				values[i] = str
+			}
+		}
+	}
+
+	// point reads have no fallback and sla, so only null values aren't good
+	if params.Get("with_quality").Bool() {
+		values = (&readQuality{}).values(values)
+	}
+
+	var value interface{} = values
+
+	switch {
+	case len(p.ByteLabels) > 0:
+		value = p.byteFields(values)
+	case len(values) == 1:
+		value = values[0]
+	}
+
+	if params.Get("with_units").Bool() {
+		return pointValue{Value: value, Unit: p.Unit}
+	}
+
+	return value
+}
+
+// pointBlock is block of registers or bits which holds point value
+type pointBlock struct {
+	slaveID  byte
+	function byte
+	address  uint16
+	count    uint16
+	bits     bool
+	codec    codec
+}
+
+// getPointBlock returns block of point described by params
+func (s Service) getPointBlock(p Point, params objx.Map) (pointBlock, error) {
+	b := pointBlock{
+		function: pointFunctions[p.Function],
+		bits:     p.Function == pointCoil || p.Function == pointDiscrete,
+		count:    1,
+	}
+
+	var err error
+
+	if b.bits {
+		b.count, err = getUint16(params, "quantity", 1)
+	} else {
+		b.codec, err = s.getCodec(params)
+		if err == nil {
+			b.count, err = pointQuantity(params, b.codec, 0)
+		}
+	}
+
+	if err != nil {
+		return pointBlock{}, err
+	}
+
+	b.address, err = getUint16(params, "address")
+	if err != nil {
+		return pointBlock{}, err
+	}
+
+	b.slaveID, err = getSlaveID(params)
+	if err != nil {
+		return pointBlock{}, err
+	}
+
+	return b, nil
+}
+
+// decode returns raw values of block data
+func (b pointBlock) decode(res []byte) ([]interface{}, error) {
+	if !b.bits {
+		return b.codec.decode(res)
+	}
+
+	var values []interface{}
+	for _, v := range parseResultByteToBits(res, b.count) {
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// readPointValues reads raw values of point (before scale and enum applied)
+func (s Service) readPointValues(p Point, params objx.Map) ([]interface{}, error) {
+	if len(p.Parts) > 0 {
+		v, err := s.readComposite(p, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return []interface{}{v}, nil
+	}
+
+	if len(p.Fields) > 0 {
+		v, err := s.readRecord(p, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return []interface{}{v}, nil
+	}
+
+	b, err := s.getPointBlock(p, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.ExponentAddress != nil {
+		sr := scaleRegister{codec: b.codec, address: *p.ExponentAddress, encoding: p.ExponentEncoding}
+		if sr.encoding == "" {
+			sr.encoding = expInt16
+		}
+
+		return s.readScaled(b.slaveID, b.function, b.address, b.count, sr)
+	}
+
+	res, err := s.readBlock(b.slaveID, b.function, b.address, b.count)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.decode(res)
+}
+
+// readPoint reads value of register map point by name
+func (s Service) readPoint(params objx.Map) (interface{}, error) {
+	p, pp, err := s.getPoint(params, "point")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.readPointValue(p, pp)
+}
+
+// readPoints reads values of several register map points
+// result is object of values by point name
+func (s Service) readPoints(params objx.Map) (interface{}, error) {
+	names, err := getArray(params, "points")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return nil, emptyErr("points")
+	}
+
+	result := make(map[string]interface{}, len(names))
+
+	for _, name := range names {
+		if _, ok := name.(string); !ok {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "points should be array of strings")
+		}
+
+		pointParams := params.Copy()
+		pointParams["point"] = name
+
+		p, pp, err := s.getPoint(pointParams, "point")
+		if err != nil {
+			return nil, err
+		}
+
+		result[p.Name], err = s.readPointValue(p, pp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// pointQuantity returns quantity of register point implied by encoding
+// (registers of encoding times count of values, one value for reads)
+// passed quantity should match count of values (reads can have several whole values)
+func pointQuantity(params objx.Map, c codec, values int) (uint16, error) {
+	implied := c.registers()
+	if values > 0 {
+		implied *= values
+	}
+
+	quantity, err := getUint16(params, "quantity", int64(implied))
+	if err != nil {
+		return 0, err
+	}
+
+	if values > 0 && int(quantity) != implied {
+		return 0, quantityErr(quantity, implied)
+	}
+
+	if quantity == 0 || int(quantity)%c.registers() != 0 {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", "quantity should be multiple of "+
+			strconv.Itoa(c.registers())+" registers of "+c.encoding).AddData("v", quantity)
+	}
+
+	return quantity, nil
+}
+
+// rawValues converts point values to raw ones
+// (rounded for integer encodings)
+func (p Point) rawValues(values []interface{}, c codec) ([]interface{}, error) {
+	raw := make([]interface{}, len(values))
+
+	for i, v := range values {
+		f, err := getFloat64(objx.Map{"value": v}, "value", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		f = p.raw(f)
+		if c.encoding != encFloat32 {
+			f = math.Round(f)
+		}
+
+		raw[i] = json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+
+	return raw, nil
+}
+
+// writePoint writes value of register map point by name
+// value is converted to raw one by point scale and offset before write
+func (s Service) writePoint(params objx.Map) (interface{}, error) {
+	p, pp, err := s.getPoint(params, "point")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Parts) > 0 {
+		return nil, compositeErr(p)
+	}
+
+	if p.ExponentAddress != nil {
+		return nil, exponentErr(p)
+	}
+
+	if len(p.Fields) > 0 {
+		return nil, recordErr(p)
+	}
+
+	if p.Transform != "" {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "point with transform is read only").AddData("v", p.Name)
+	}
+
+	// point address is protocol address
+	pp["address_base"] = json.Number("0")
+
+	switch p.Function {
+	case pointCoil:
+		return s.call(jsonrpc.Request{Method: "modbus-write-coil", Params: pp})
+	case pointHolding:
+	default:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "point should be coil or holding register").
+			AddData("v", p.Name)
+	}
+
+	c, err := s.getCodec(pp)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := getValues(pp, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.scaled() {
+		if values, err = p.rawValues(values, c); err != nil {
+			return nil, err
+		}
+
+		if pp.Get("value").IsInterSlice() {
+			pp["value"] = values
+		} else {
+			pp["value"] = values[0]
+		}
+	}
+
+	quantity, err := pointQuantity(pp, c, len(values))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.CommandWord != nil {
+		pp["command_word"] = json.Number(strconv.Itoa(int(*p.CommandWord)))
+	}
+
+	// command word is sent by multiple registers write only
+	if !pp.Get("command_word").IsNil() {
+		quantity++
+	} else if quantity == 1 {
+		return s.call(jsonrpc.Request{Method: "modbus-write-register", Params: pp})
+	}
+
+	pp["quantity"] = json.Number(strconv.Itoa(int(quantity)))
+
+	return s.call(jsonrpc.Request{Method: "modbus-write-multiple-registers", Params: pp})
+}
@@ -0,0 +1,305 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pointRow is a row of register map file
+// type is encoding of point value
+type pointRow struct {
+	Name     string  `json:"name"`
+	Function string  `json:"function"`
+	SlaveID  *byte   `json:"slave_id"`
+	Address  uint16  `json:"address"`
+	Quantity uint16  `json:"quantity"`
+	Type     string  `json:"type"`
+	Scale    float64 `json:"scale"`
+	// named scale (tenths, hundredths, thousandths or permille)
+	ScalePreset string `json:"scale_preset"`
+	Unit        string `json:"unit"`
+	ByteOrder   string `json:"byte_order"`
+	WordOrder   string `json:"word_order"`
+	// duration string like "1s"
+	PollInterval rowDuration `json:"poll_interval"`
+	// expression of raw value like "0.01 * raw^2 + 4"
+	Transform string `json:"transform"`
+}
+
+// rowDuration is duration decoded from string
+type rowDuration time.Duration
+
+func (d *rowDuration) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return errors.New("poll_interval should be duration string")
+	}
+
+	return d.parse(v)
+}
+
+func (d *rowDuration) parse(v string) error {
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return errors.New("poll_interval should be duration like 1s")
+	}
+
+	*d = rowDuration(parsed)
+
+	return nil
+}
+
+func (r pointRow) point() Point {
+	return Point{
+		Name:         r.Name,
+		Function:     r.Function,
+		SlaveID:      r.SlaveID,
+		Address:      r.Address,
+		Quantity:     r.Quantity,
+		Encoding:     r.Type,
+		ByteOrder:    r.ByteOrder,
+		WordOrder:    r.WordOrder,
+		Scale:        r.Scale,
+		ScalePreset:  r.ScalePreset,
+		Unit:         r.Unit,
+		PollInterval: time.Duration(r.PollInterval),
+		Transform:    r.Transform,
+	}
+}
+
+// LoadPoints reads register map from json or csv file (by extension)
+//
+// json file is array of objects, csv file has header row
+// with columns name,function,address,quantity,type,scale,unit
+// (slave_id, scale_preset, byte_order, word_order, poll_interval and transform columns are supported too)
+//
+// points are validated, error contains line number of invalid row
+func LoadPoints(path string) ([]Point, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parsePointsJSON(data)
+	case ".csv":
+		return parsePointsCSV(data)
+	default:
+		return nil, errors.New("register map file should be .json or .csv")
+	}
+}
+
+func lineErr(line int, err error) error {
+	return errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+}
+
+// addPoint validates point and appends it to points
+func addPoint(points []Point, names map[string]bool, p Point, line int) ([]Point, error) {
+	if err := p.validate(); err != nil {
+		return nil, lineErr(line, err)
+	}
+
+	if names[p.Name] {
+		return nil, lineErr(line, errors.New("duplicate name "+p.Name))
+	}
+
+	names[p.Name] = true
+
+	return append(points, p), nil
+}
+
+func parsePointsJSON(data []byte) ([]Point, error) {
+	src := bytes.NewReader(data)
+	dec := json.NewDecoder(src)
+	dec.DisallowUnknownFields()
+
+	// line of first non-space byte after decoder position
+	line := func() int {
+		buffered, _ := io.Copy(ioutil.Discard, dec.Buffered())
+		offset := len(data) - src.Len() - int(buffered)
+
+		for offset < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+			offset++
+		}
+
+		return 1 + bytes.Count(data[:offset], []byte("\n"))
+	}
+
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		return nil, lineErr(1, errors.New("register map should be array of objects"))
+	}
+
+	var (
+		points []Point
+		names  = make(map[string]bool)
+	)
+
+	for dec.More() {
+		rowLine := line()
+
+		var r pointRow
+		if err := dec.Decode(&r); err != nil {
+			return nil, lineErr(rowLine, err)
+		}
+
+		var err error
+
+		points, err = addPoint(points, names, r.point(), rowLine)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, lineErr(line(), err)
+	}
+
+	return points, nil
+}
+
+// csvSetters parse csv column value to row field
+var csvSetters = map[string]func(r *pointRow, v string) error{ // nolint: gochecknoglobals
+	"name":         func(r *pointRow, v string) error { r.Name = v; return nil },
+	"function":     func(r *pointRow, v string) error { r.Function = v; return nil },
+	"type":         func(r *pointRow, v string) error { r.Type = v; return nil },
+	"unit":         func(r *pointRow, v string) error { r.Unit = v; return nil },
+	"scale_preset": func(r *pointRow, v string) error { r.ScalePreset = v; return nil },
+	"byte_order":   func(r *pointRow, v string) error { r.ByteOrder = v; return nil },
+	"word_order":   func(r *pointRow, v string) error { r.WordOrder = v; return nil },
+	"transform":    func(r *pointRow, v string) error { r.Transform = v; return nil },
+	"slave_id": func(r *pointRow, v string) error {
+		id, err := strconv.ParseUint(v, 0, 8)
+		if err != nil {
+			return errors.New("slave_id should be 0-255")
+		}
+
+		slaveID := byte(id)
+		r.SlaveID = &slaveID
+
+		return nil
+	},
+	"address": func(r *pointRow, v string) error {
+		address, err := strconv.ParseUint(v, 0, 16)
+		if err != nil {
+			return errors.New("address should be 0-65535")
+		}
+
+		r.Address = uint16(address)
+
+		return nil
+	},
+	"quantity": func(r *pointRow, v string) error {
+		quantity, err := strconv.ParseUint(v, 0, 16)
+		if err != nil {
+			return errors.New("quantity should be 0-65535")
+		}
+
+		r.Quantity = uint16(quantity)
+
+		return nil
+	},
+	"scale": func(r *pointRow, v string) error {
+		scale, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return errors.New("scale should be number")
+		}
+
+		r.Scale = scale
+
+		return nil
+	},
+	"poll_interval": func(r *pointRow, v string) error {
+		return r.PollInterval.parse(v)
+	},
+}
+
+// parsePointsCSV parses csv register map
+// rows are parsed line by line, so quoted values can't contain line breaks
+func parsePointsCSV(data []byte) ([]Point, error) {
+	var (
+		header []string
+		points []Point
+		names  = make(map[string]bool)
+	)
+
+	for i, text := range strings.Split(string(data), "\n") {
+		line := i + 1
+
+		text = strings.TrimSuffix(text, "\r")
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		reader := csv.NewReader(strings.NewReader(text))
+		reader.TrimLeadingSpace = true
+
+		record, err := reader.Read()
+		if err != nil {
+			return nil, lineErr(line, err)
+		}
+
+		if header == nil {
+			for _, column := range record {
+				column = strings.ToLower(strings.TrimSpace(column))
+
+				if _, ok := csvSetters[column]; !ok {
+					return nil, lineErr(line, errors.New("unknown column "+column))
+				}
+
+				header = append(header, column)
+			}
+
+			continue
+		}
+
+		if len(record) != len(header) {
+			return nil, lineErr(line, errors.New("wrong number of columns"))
+		}
+
+		var r pointRow
+
+		for i, v := range record {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+
+			if err := csvSetters[header[i]](&r, v); err != nil {
+				return nil, lineErr(line, err)
+			}
+		}
+
+		points, err = addPoint(points, names, r.point(), line)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return points, nil
+}
@@ -0,0 +1,348 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// protocol limit of bits in one FC01/FC02 request
+const maxReadBits = 2000
+
+var errNoPolledPoints = jsonrpc.ErrInvalidRequest.AddData("msg", "profile has no points with poll_interval")
+
+// polledPoint is point read by poll loop with its last value
+type polledPoint struct {
+	point Point
+	block pointBlock
+	next  time.Time
+
+	value   interface{}
+	err     error
+	updated time.Time
+}
+
+// pointPoller reads profile points with poll_interval, each at its own rate
+// points which are due at the same time are read by as few transactions as possible
+type pointPoller struct {
+	mx     sync.Mutex
+	points map[string]*polledPoint
+}
+
+// newPointPoller returns poller of points with poll_interval (nil if there are none)
+func newPointPoller(s Service) *pointPoller {
+	poller := &pointPoller{points: make(map[string]*polledPoint)}
+
+	for name, p := range s.points {
+		if p.PollInterval <= 0 {
+			continue
+		}
+
+		pp := &polledPoint{point: p}
+
+		if !p.unplanned() {
+			b, err := s.getPointBlock(p, p.params())
+			if err != nil {
+				log.WithError(err).WithField("point", name).Error("poll point")
+				continue
+			}
+
+			pp.block = b
+		}
+
+		poller.points[name] = pp
+	}
+
+	if len(poller.points) == 0 {
+		return nil
+	}
+
+	return poller
+}
+
+// run polls points until service context is done or service is shut down
+func (poller *pointPoller) run(s Service) {
+	s.noCache = true
+
+	for {
+		if !s.life.enter() {
+			return
+		}
+
+		next := poller.pollDue(s, time.Now())
+		s.life.leave()
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// pollRead is one transaction of poll loop
+type pollRead struct {
+	block  pointBlock
+	points []*polledPoint
+}
+
+// planReads groups points of one slave and function into reads
+// (points with small gaps between them are read together)
+func planReads(points []*polledPoint) []pollRead {
+	sort.Slice(points, func(i, j int) bool {
+		a, b := points[i].block, points[j].block
+		if a.slaveID != b.slaveID {
+			return a.slaveID < b.slaveID
+		}
+
+		if a.function != b.function {
+			return a.function < b.function
+		}
+
+		return a.address < b.address
+	})
+
+	var reads []pollRead
+
+	for _, p := range points {
+		b := p.block
+
+		if n := len(reads); n > 0 {
+			r := &reads[n-1]
+
+			limit := maxReadRegisters
+			if b.bits {
+				limit = maxReadBits
+			}
+
+			start, end := int(r.block.address), int(r.block.address)+int(r.block.count)
+			if r.block.slaveID == b.slaveID && r.block.function == b.function &&
+				int(b.address)-end <= maxReadGap && int(b.address)+int(b.count)-start <= limit {
+				if newEnd := int(b.address) + int(b.count); newEnd > end {
+					r.block.count = uint16(newEnd - start)
+				}
+
+				r.points = append(r.points, p)
+
+				continue
+			}
+		}
+
+		reads = append(reads, pollRead{block: b, points: []*polledPoint{p}})
+	}
+
+	return reads
+}
+
+// values returns raw values of point from data of read
+func (r pollRead) values(p *polledPoint, res []byte) ([]interface{}, error) {
+	offset := int(p.block.address - r.block.address)
+
+	if p.block.bits {
+		bits := parseResultByteToBits(res, r.block.count)[offset : offset+int(p.block.count)]
+
+		values := make([]interface{}, len(bits))
+		for i, v := range bits {
+			values[i] = v
+		}
+
+		return values, nil
+	}
+
+	return p.block.decode(res[offset*2 : (offset+int(p.block.count))*2])
+}
+
+// readPlanned sends planned reads and passes raw values (or error) of each point to fn
+func (s Service) readPlanned(reads []pollRead, fn func(p *polledPoint, values []interface{}, err error)) {
+	for _, r := range reads {
+		res, err := s.readBlock(r.block.slaveID, r.block.function, r.block.address, r.block.count)
+
+		for _, p := range r.points {
+			if err != nil {
+				fn(p, nil, err)
+				continue
+			}
+
+			values, err := r.values(p, res)
+			fn(p, values, err)
+		}
+	}
+}
+
+// pollDue reads points which are due and returns time when next point is due
+func (poller *pointPoller) pollDue(s Service, now time.Time) time.Time {
+	poller.mx.Lock()
+
+	var due []*polledPoint
+
+	for _, p := range poller.points {
+		if p.next.After(now) {
+			continue
+		}
+
+		if p.next = p.next.Add(p.point.PollInterval); p.next.Before(now) {
+			p.next = now.Add(p.point.PollInterval)
+		}
+
+		due = append(due, p)
+	}
+
+	poller.mx.Unlock()
+
+	poller.read(s, due)
+
+	poller.mx.Lock()
+	defer poller.mx.Unlock()
+
+	var next time.Time
+	for _, p := range poller.points {
+		if next.IsZero() || p.next.Before(next) {
+			next = p.next
+		}
+	}
+
+	return next
+}
+
+// read reads points (composite ones by own transactions) and updates their values
+func (poller *pointPoller) read(s Service, points []*polledPoint) {
+	var planned []*polledPoint
+
+	for _, p := range points {
+		if !p.point.unplanned() {
+			planned = append(planned, p)
+			continue
+		}
+
+		values, err := s.readPointValues(p.point, p.point.params())
+		poller.update(p, values, err)
+	}
+
+	s.readPlanned(planReads(planned), poller.update)
+}
+
+// update sets last value (or error) of point
+func (poller *pointPoller) update(p *polledPoint, values []interface{}, err error) {
+	poller.mx.Lock()
+	defer poller.mx.Unlock()
+
+	p.err = err
+	if err == nil {
+		p.value = p.point.value(values, objx.Map{})
+		p.updated = time.Now()
+	}
+}
+
+type polledValue struct {
+	Value interface{} `json:"value"`
+	// time of last successful read (nil if point was never read)
+	Timestamp interface{} `json:"timestamp"`
+	// error of last read
+	Error *jsonrpc.Error `json:"error,omitempty"`
+}
+
+// stale returns points of names which weren't read successfully after since
+func (poller *pointPoller) stale(names []string, since time.Time) []*polledPoint {
+	poller.mx.Lock()
+	defer poller.mx.Unlock()
+
+	var res []*polledPoint
+
+	for _, name := range names {
+		if p := poller.points[name]; p.updated.Before(since) {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// readPolled returns last values of polled points (points param or all)
+// it doesn't send anything unless values are older than max_age_ms param
+func (s Service) readPolled(params objx.Map) (interface{}, error) {
+	if s.poller == nil {
+		return nil, errNoPolledPoints
+	}
+
+	var names []string
+
+	if params.Get("points").IsNil() {
+		for name := range s.poller.points {
+			names = append(names, name)
+		}
+	} else {
+		items, err := getArray(params, "points")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range items {
+			name, ok := v.(string)
+			if !ok {
+				return nil, jsonrpc.ErrInvalidParams.AddData("msg", "points should be array of strings")
+			}
+
+			if _, ok := s.poller.points[name]; !ok {
+				return nil, jsonrpc.ErrInvalidParams.AddData("msg", "point isn't polled").AddData("v", name)
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	// values older than max_age_ms are read on demand
+	if s.maxAge > 0 {
+		s.poller.read(s, s.poller.stale(names, time.Now().Add(-s.maxAge)))
+	}
+
+	s.poller.mx.Lock()
+	defer s.poller.mx.Unlock()
+
+	result := make(map[string]polledValue, len(names))
+
+	for _, name := range names {
+		p := s.poller.points[name]
+		v := polledValue{Value: p.value}
+
+		if !p.updated.IsZero() {
+			ts, err := s.formatTimestamp(params, p.updated)
+			if err != nil {
+				return nil, err
+			}
+
+			v.Timestamp = ts
+		}
+
+		if p.err != nil {
+			v.Error = toRPCError(p.err)
+		}
+
+		result[name] = v
+	}
+
+	return result, nil
+}
@@ -0,0 +1,368 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// scalePresets contains multipliers of named scales
+var scalePresets = map[string]float64{ // nolint: gochecknoglobals
+	"tenths":      0.1,
+	"hundredths":  0.01,
+	"thousandths": 0.001,
+	"permille":    0.001,
+}
+
+// point functions (register tables)
+const (
+	pointCoil     = "coil"
+	pointDiscrete = "discrete"
+	pointInput    = "input"
+	pointHolding  = "holding"
+)
+
+// Point is a named value of register map (profile)
+// empty fields are not set and can be passed in request
+type Point struct {
+	Name string `mapstructure:"name" json:"name"`
+	// coil, discrete, input or holding
+	Function  string `mapstructure:"function" json:"function"`
+	SlaveID   *byte  `mapstructure:"slave_id" json:"slave_id,omitempty"`
+	Address   uint16 `mapstructure:"address" json:"address"`
+	Quantity  uint16 `mapstructure:"quantity" json:"quantity,omitempty"`
+	Encoding  string `mapstructure:"encoding" json:"encoding,omitempty"`
+	ByteOrder string `mapstructure:"byte_order" json:"byte_order,omitempty"`
+	WordOrder string `mapstructure:"word_order" json:"word_order,omitempty"`
+	// multiplier of value (0 means not scaled)
+	Scale float64 `mapstructure:"scale" json:"scale,omitempty"`
+	// named scale (tenths, hundredths, thousandths or permille),
+	// it can't be used together with scale
+	ScalePreset string `mapstructure:"scale_preset" json:"scale_preset,omitempty"`
+	// added to raw value before scale (value = (raw + offset) * scale)
+	Offset float64 `mapstructure:"offset" json:"offset,omitempty"`
+	// engineering unit of value (e.g. °C)
+	Unit string `mapstructure:"unit" json:"unit,omitempty"`
+	// labels of integer values (e.g. "0" = "idle"), read value is returned as label
+	Enum map[string]string `mapstructure:"enum" json:"enum,omitempty"`
+	// names of bits of status word (e.g. "0" = "running"), read value is returned
+	// as object of bit states by name (unlabeled bits by index)
+	BitLabels map[string]string `mapstructure:"bit_labels" json:"bit_labels,omitempty"`
+	// names of byte values of bytes encoding point (high byte of each register first),
+	// read value is returned as object of byte values by name
+	ByteLabels []string `mapstructure:"byte_labels" json:"byte_labels,omitempty"`
+	// source registers of composite point (address and quantity are not used)
+	Parts []PointPart `mapstructure:"parts" json:"parts,omitempty"`
+	// point is read by poll loop with this interval (0 means not polled)
+	PollInterval time.Duration `mapstructure:"poll_interval" json:"poll_interval,omitempty"`
+	// expression of raw value for nonlinear sensors (e.g. "0.01 * raw^2 + 4"),
+	// point with transform is read only
+	Transform string `mapstructure:"transform" json:"transform,omitempty"`
+	// word written before value by devices which expect command word
+	// at the start of each multiple registers write (holding only)
+	CommandWord *uint16 `mapstructure:"command_word" json:"command_word,omitempty"`
+	// register of decimal exponent of value (value = raw * 10^exponent), encoding of value
+	// should be 16 or 32-bit integer, point with exponent register is read only
+	ExponentAddress *uint16 `mapstructure:"exponent_address" json:"exponent_address,omitempty"`
+	// int16 (default) or int8 (signed low byte)
+	ExponentEncoding string `mapstructure:"exponent_encoding" json:"exponent_encoding,omitempty"`
+	// fields of record point decoded from quantity registers read by one transaction,
+	// read value is returned as object of field values by name, record point is read only
+	Fields []PointField `mapstructure:"fields" json:"fields,omitempty"`
+
+	// compiled transform (set by Profile)
+	transform transform
+}
+
+func (p Point) validate() error {
+	if p.Name == "" {
+		return errors.New("name required")
+	}
+
+	switch p.Function {
+	case pointCoil, pointDiscrete, pointInput, pointHolding:
+	default:
+		return errors.New("function should be coil, discrete, input or holding")
+	}
+
+	if p.PollInterval < 0 {
+		return errors.New("poll_interval should not be negative")
+	}
+
+	if len(p.Parts) > 0 {
+		if err := p.validateParts(); err != nil {
+			return err
+		}
+	}
+
+	if p.Encoding != "" && !isValidEncoding(p.Encoding) {
+		return errors.New("unsupported encoding " + p.Encoding)
+	}
+
+	if p.ScalePreset != "" {
+		if _, ok := scalePresets[p.ScalePreset]; !ok {
+			return errors.New("scale_preset should be tenths, hundredths, thousandths or permille")
+		}
+
+		if p.Scale != 0 {
+			return errors.New("scale and scale_preset can't be used together")
+		}
+	}
+
+	if p.scaled() && (p.Function == pointCoil || p.Function == pointDiscrete) {
+		return errors.New("scale and offset can't be used with bits")
+	}
+
+	for _, order := range []string{p.ByteOrder, p.WordOrder} {
+		if order != "" && !IsValidOrder(order) {
+			return errors.New("order should be big or little")
+		}
+	}
+
+	if p.Transform != "" {
+		if p.scaled() || len(p.Enum) > 0 {
+			return errors.New("transform can't be used with scale, offset or enum")
+		}
+
+		if _, err := parseTransform(p.Transform); err != nil {
+			return err
+		}
+	}
+
+	if len(p.Fields) > 0 {
+		if err := p.validateFields(); err != nil {
+			return err
+		}
+	}
+
+	if p.ExponentAddress != nil {
+		if err := p.validateExponent(); err != nil {
+			return err
+		}
+	}
+
+	if p.CommandWord != nil && p.Function != pointHolding {
+		return errors.New("command_word can be used with holding registers only")
+	}
+
+	if len(p.BitLabels) > 0 {
+		if err := p.validateBitLabels(); err != nil {
+			return err
+		}
+	}
+
+	if len(p.ByteLabels) > 0 {
+		if err := p.validateByteLabels(); err != nil {
+			return err
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		if p.Function == pointCoil || p.Function == pointDiscrete || p.scaled() {
+			return errors.New("enum can't be used with bits, scale or offset")
+		}
+
+		for k := range p.Enum {
+			if _, err := strconv.ParseInt(k, 10, 64); err != nil {
+				return errors.New("enum keys should be integer values but " + k + " given")
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateExponent checks point with exponent register
+func (p Point) validateExponent() error {
+	if p.Function != pointInput && p.Function != pointHolding {
+		return errors.New("exponent_address can be used with registers only")
+	}
+
+	if len(p.Parts) > 0 || len(p.BitLabels) > 0 || len(p.ByteLabels) > 0 || p.CommandWord != nil {
+		return errors.New("exponent_address can't be used with parts, bit_labels, byte_labels or command_word")
+	}
+
+	encoding := p.Encoding
+	if encoding == "" {
+		encoding = encUint16
+	}
+
+	c, ok := encodingAliases[encoding]
+	if !ok {
+		c = codec{encoding: encoding}
+	}
+
+	exp := p.ExponentEncoding
+	if exp == "" {
+		exp = expInt16
+	}
+
+	return checkScaleRegister(c, exp)
+}
+
+// unplanned reports whether point is read by own transactions
+// (composite, record point or point with exponent register), so its reads aren't merged with others
+func (p Point) unplanned() bool {
+	return len(p.Parts) > 0 || len(p.Fields) > 0 || p.ExponentAddress != nil
+}
+
+// scale returns multiplier of point value (0 if not scaled)
+func (p Point) scale() float64 {
+	if p.ScalePreset != "" {
+		return scalePresets[p.ScalePreset]
+	}
+
+	return p.Scale
+}
+
+// scaled reports whether point value differs from raw value
+func (p Point) scaled() bool {
+	return p.scale() != 0 || p.Offset != 0
+}
+
+// engineering converts raw value to point value
+func (p Point) engineering(raw float64) float64 {
+	scale := p.scale()
+	if scale == 0 {
+		scale = 1
+	}
+
+	return (raw + p.Offset) * scale
+}
+
+// raw converts point value to raw value (it's inverse of engineering)
+func (p Point) raw(value float64) float64 {
+	scale := p.scale()
+	if scale == 0 {
+		scale = 1
+	}
+
+	return value/scale - p.Offset
+}
+
+// params returns request params described by point
+func (p Point) params() objx.Map {
+	params := objx.Map{
+		"address": json.Number(strconv.Itoa(int(p.Address))),
+	}
+
+	if p.SlaveID != nil {
+		params["slave_id"] = json.Number(strconv.Itoa(int(*p.SlaveID)))
+	}
+
+	if p.Quantity != 0 {
+		params["quantity"] = json.Number(strconv.Itoa(int(p.Quantity)))
+	}
+
+	for k, v := range map[string]string{
+		"encoding": p.Encoding, "byte_order": p.ByteOrder, "word_order": p.WordOrder,
+	} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+
+	return params
+}
+
+// Profile sets register map points available by name
+// points should be checked by ValidatePoints before
+func Profile(points ...Point) Option {
+	return func(s *Service) {
+		if s.points == nil {
+			s.points = make(map[string]Point, len(points))
+		}
+
+		for _, p := range points {
+			s.points[p.Name] = compilePoint(p)
+		}
+	}
+}
+
+// compilePoint returns point with compiled transform
+func compilePoint(p Point) Point {
+	if p.Transform != "" {
+		// it's checked by ValidatePoints
+		p.transform, _ = parseTransform(p.Transform)
+	}
+
+	return p
+}
+
+// ValidatePoints checks points definitions and names uniqueness
+func ValidatePoints(points []Point) error {
+	names := make(map[string]bool, len(points))
+
+	for i, p := range points {
+		if err := p.validate(); err != nil {
+			return errors.New("point " + strconv.Itoa(i) + ": " + err.Error())
+		}
+
+		if names[p.Name] {
+			return errors.New("point " + strconv.Itoa(i) + ": duplicate name " + p.Name)
+		}
+
+		names[p.Name] = true
+	}
+
+	return nil
+}
+
+// getPoint returns point by name from k param (point of device if device param passed)
+// and params of point merged over request params
+// (slave_id of request overrides slave_id of point)
+func (s Service) getPoint(params objx.Map, k string) (Point, objx.Map, error) {
+	name := params.Get(k).Str()
+	if name == "" {
+		return Point{}, nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" required and should be string")
+	}
+
+	p, ok := s.points[name]
+
+	switch {
+	case !params.Get("device").IsNil():
+		var err error
+
+		p, err = s.devicePoint(params, name)
+		if err != nil {
+			return Point{}, nil, err
+		}
+	case !ok:
+		return Point{}, nil, jsonrpc.ErrInvalidParams.AddData("msg", "unknown point").AddData("v", name)
+	}
+
+	pp := p.params()
+	for k, v := range params {
+		if _, ok := pp[k]; !ok {
+			pp[k] = v
+		}
+	}
+
+	// one profile can be used by several identical slaves
+	if !params.Get("slave_id").IsNil() {
+		pp["slave_id"] = params["slave_id"]
+	}
+
+	return p, pp, nil
+}
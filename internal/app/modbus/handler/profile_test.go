@@ -0,0 +1,63 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestWriteReadPoint(t *testing.T) {
+	m := &mockSlave{}
+	m.holding[20], m.holding[21] = 0x0001, 0x0002
+
+	srv := newMockService(m, Profile(
+		Point{Name: "setpoint", Function: pointHolding, Address: 10, Encoding: "int16"},
+		Point{Name: "counter", Function: pointHolding, Address: 20, Encoding: "uint32"},
+		Point{Name: "level", Function: pointInput, Address: 30},
+	))
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-write-read-point",
+		Params: objx.Map{"write_point": "setpoint", "read_point": "counter", "value": num("-2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// write and read in one transaction
+	m.assertPDU(t, []byte{modbus.FuncCodeReadWriteMultipleRegisters,
+		0x00, 0x14, 0x00, 0x02, 0x00, 0x0A, 0x00, 0x01, 0x02, 0xFF, 0xFE})
+
+	if !reflect.DeepEqual(res, []interface{}{uint32(0x00010002)}) || m.holding[10] != 0xFFFE {
+		t.Errorf("unexpected result %v (%x)", res, m.holding[10])
+	}
+
+	// points should be holding registers
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-write-read-point",
+		Params: objx.Map{"write_point": "setpoint", "read_point": "level", "value": num("1")},
+	})
+	if err == nil || len(m.pdus) != 1 {
+		t.Errorf("expected error of input point without request but got %v", err)
+	}
+}
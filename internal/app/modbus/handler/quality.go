@@ -0,0 +1,99 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/stretchr/objx"
+)
+
+// quality of values (with_quality param)
+const (
+	qualityGood      = "good"
+	qualityUncertain = "uncertain"
+	qualityBad       = "bad"
+)
+
+// qualityValue is value with its quality and reason of not good quality
+type qualityValue struct {
+	Value   interface{} `json:"value"`
+	Quality string      `json:"quality"`
+	// null, stale or sla_exceeded
+	Reason string `json:"reason,omitempty"`
+}
+
+// readQuality is quality of values of one read (zero value means good read)
+// precedence of reasons: null value (null_value sentinel or undecodable value) is bad,
+// stale value (last_good fallback) is uncertain, value of read which exceeded sla_ms is uncertain
+type readQuality struct {
+	quality string
+	reason  string
+}
+
+// getReadQuality returns quality of read from its age and stats (nil if with_quality param isn't set)
+func getReadQuality(params objx.Map, age time.Duration, stats responseStats) (*readQuality, error) {
+	if !params.Get("with_quality").Bool() {
+		return nil, nil
+	}
+
+	if age > 0 {
+		return &readQuality{qualityUncertain, "stale"}, nil
+	}
+
+	if params.Get("sla_ms").IsNil() {
+		return &readQuality{}, nil
+	}
+
+	sla, err := getInt64(params, "sla_ms")
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.elapsed > time.Duration(sla)*time.Millisecond {
+		return &readQuality{qualityUncertain, "sla_exceeded"}, nil
+	}
+
+	return &readQuality{}, nil
+}
+
+// value attaches quality to value
+func (q *readQuality) value(v interface{}) qualityValue {
+	if v == nil {
+		return qualityValue{Value: v, Quality: qualityBad, Reason: "null"}
+	}
+
+	if q.quality == "" {
+		return qualityValue{Value: v, Quality: qualityGood}
+	}
+
+	return qualityValue{Value: v, Quality: q.quality, Reason: q.reason}
+}
+
+// values attaches quality to each value (values are returned as is for nil quality)
+func (q *readQuality) values(values []interface{}) []interface{} {
+	if q == nil {
+		return values
+	}
+
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = q.value(v)
+	}
+
+	return res
+}
@@ -0,0 +1,122 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+var errRateLimited = errors.New("modbus: slave rate limit exceeded")
+
+// rateLimiter is a token bucket with capacity of one token
+// requests over the limit wait for next token (no longer than maxWait)
+type rateLimiter struct {
+	interval time.Duration
+	maxWait  time.Duration
+	// error of requests over the limit
+	err error
+
+	mx   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(rate float64, maxWait time.Duration) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		maxWait:  maxWait,
+		err:      errRateLimited,
+	}
+}
+
+// wait blocks until request allowed
+// it returns error of limiter if wait time greater than maxWait
+func (l *rateLimiter) wait() error {
+	l.mx.Lock()
+
+	now := time.Now()
+
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+
+	delay := at.Sub(now)
+	if delay > l.maxWait {
+		l.mx.Unlock()
+		return l.err
+	}
+
+	// reserve token so concurrent requests queued one by one
+	l.next = at.Add(l.interval)
+
+	l.mx.Unlock()
+
+	time.Sleep(delay)
+
+	return nil
+}
+
+// rateLimitedTransporter waits for limiter before each request
+type rateLimitedTransporter struct {
+	modbus.Transporter
+	l *rateLimiter
+}
+
+func (t rateLimitedTransporter) Send(adu []byte) ([]byte, error) {
+	if err := t.l.wait(); err != nil {
+		return nil, err
+	}
+
+	return t.Transporter.Send(adu)
+}
+
+// SlaveRateLimit limits requests to given slave to rate per second
+// requests over the limit are queued but no longer than maxWait
+func SlaveRateLimit(slaveID byte, rate float64, maxWait time.Duration) Option {
+	return func(s *Service) {
+		if rate <= 0 {
+			return
+		}
+
+		if s.limiters == nil {
+			s.limiters = make(map[byte]*rateLimiter)
+		}
+
+		s.limiters[slaveID] = newRateLimiter(rate, maxWait)
+	}
+}
+
+// BusRateLimit limits transactions on the main bus to rate per second regardless of slave
+// (for shared segment which bandwidth is the constraint), limit is enforced while bus lock
+// is held, transactions over the limit wait no longer than maxWait and fail with bus busy error
+// it composes with slave limits (the more restrictive wins), slaves with own connection
+// are not limited
+func BusRateLimit(rate float64, maxWait time.Duration) Option {
+	return func(s *Service) {
+		s.busLimiter = nil
+		if rate <= 0 {
+			return
+		}
+
+		s.busLimiter = newRateLimiter(rate, maxWait)
+		s.busLimiter.err = errBusBusy
+	}
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+func TestSlaveRateLimit(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m, SlaveRateLimit(1, 20, 0), SlaveRateLimit(2, 20, time.Second))
+
+	read := func(slaveID string) error {
+		_, err := srv.Call(jsonrpc.Request{
+			Method: "modbus-read-holding",
+			Params: objx.Map{"address": num("0"), "quantity": num("1"), "slave_id": num(slaveID)},
+		})
+
+		return err
+	}
+
+	if err := read("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// request over the limit without wait fails and isn't sent
+	if err := read("1"); !errors.Is(err, errRateLimited) || len(m.pdus) != 1 {
+		t.Errorf("expected rate limit error without request but got %v (%d)", err, len(m.pdus))
+	}
+
+	// other slaves have own limit
+	start := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if err := read("2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// second request waits for next token (50ms)
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected request to wait for limit but it took %v", elapsed)
+	}
+
+	// not limited slave isn't delayed
+	start = time.Now()
+
+	for i := 0; i < 3; i++ {
+		if err := read("3"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("not limited slave took %v", elapsed)
+	}
+}
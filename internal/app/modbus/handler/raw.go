@@ -0,0 +1,52 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	"github.com/stretchr/objx"
+)
+
+type compressedResult struct {
+	// gzip compressed registers bytes
+	Data       []byte `json:"data"`
+	Compressed bool   `json:"compressed"`
+}
+
+// rawResult returns registers bytes (base64 in json)
+// if compress param passed bytes are gzip compressed
+func rawResult(params objx.Map, b []byte) (interface{}, error) {
+	if !params.Get("compress").Bool() {
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return compressedResult{Data: buf.Bytes(), Compressed: true}, nil
+}
@@ -0,0 +1,91 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+var errEmptyProfile = jsonrpc.ErrInvalidRequest.AddData("msg", "profile has no points")
+
+// snapshotValue is value of point in device snapshot
+// (failed points have error instead of value)
+type snapshotValue struct {
+	Value interface{}    `json:"value"`
+	Unit  string         `json:"unit,omitempty"`
+	Error *jsonrpc.Error `json:"error,omitempty"`
+}
+
+// readAll reads all profile points by as few transactions as possible
+// result is object of values by point name, errors of points don't fail the call
+func (s Service) readAll(params objx.Map) (interface{}, error) {
+	if len(s.points) == 0 {
+		return nil, errEmptyProfile
+	}
+
+	result := make(map[string]snapshotValue, len(s.points))
+	pointParams := make(map[*polledPoint]objx.Map, len(s.points))
+
+	var planned []*polledPoint
+
+	for name := range s.points {
+		pp := params.Copy()
+		pp["point"] = name
+		// units are always included
+		delete(pp, "with_units")
+
+		p, pp, err := s.getPoint(pp, "point")
+		if err != nil {
+			result[name] = snapshotValue{Error: toRPCError(err)}
+			continue
+		}
+
+		if p.unplanned() {
+			values, err := s.readPointValues(p, pp)
+			result[name] = snapshotOf(p, pp, values, err)
+
+			continue
+		}
+
+		b, err := s.getPointBlock(p, pp)
+		if err != nil {
+			result[name] = snapshotValue{Unit: p.Unit, Error: toRPCError(err)}
+			continue
+		}
+
+		pt := &polledPoint{point: p, block: b}
+		pointParams[pt] = pp
+		planned = append(planned, pt)
+	}
+
+	s.readPlanned(planReads(planned), func(p *polledPoint, values []interface{}, err error) {
+		result[p.point.Name] = snapshotOf(p.point, pointParams[p], values, err)
+	})
+
+	return result, nil
+}
+
+// snapshotOf returns snapshot value of point from its raw values
+func snapshotOf(p Point, params objx.Map, values []interface{}, err error) snapshotValue {
+	if err != nil {
+		return snapshotValue{Unit: p.Unit, Error: toRPCError(err)}
+	}
+
+	return snapshotValue{Value: p.value(values, params), Unit: p.Unit}
+}
@@ -0,0 +1,185 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+const defaultMultiWorkers = 4
+
+// methods allowed in read-multi items
+var multiMethods = map[string]bool{ // nolint: gochecknoglobals
+	"modbus-read-coil":     true,
+	"modbus-read-discrete": true,
+	"modbus-read-input":    true,
+	"modbus-read-holding":  true,
+}
+
+type multiItemResult struct {
+	SlaveID byte           `json:"slave_id"`
+	Method  string         `json:"method"`
+	Result  interface{}    `json:"result,omitempty"`
+	Error   *jsonrpc.Error `json:"error,omitempty"`
+	// time of bus transactions in milliseconds (with_latency param)
+	Latency *float64 `json:"latency_ms,omitempty"`
+}
+
+// multiItem is one read of read-multi request
+type multiItem struct {
+	index  int
+	method string
+	params objx.Map
+}
+
+func toRPCError(err error) *jsonrpc.Error {
+	var ferr *framingError
+	if errors.As(err, &ferr) {
+		rerr := ferr.rpcError()
+		return &rerr
+	}
+
+	rerr, ok := err.(jsonrpc.Error)
+	if !ok {
+		rerr = jsonrpc.ErrServer.AddData("msg", err.Error()).SetCode(-32098)
+	}
+
+	return &rerr
+}
+
+func getMultiItems(params objx.Map) ([]objx.Map, int, error) {
+	items, err := getArray(params, "items")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(items) == 0 {
+		return nil, 0, emptyErr("items")
+	}
+
+	res := make([]objx.Map, 0, len(items))
+
+	for _, v := range items {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, 0, jsonrpc.ErrInvalidParams.AddData("msg", "items should be array of objects")
+		}
+
+		res = append(res, objx.Map(item).Copy())
+	}
+
+	workers, err := getInt64(params, "workers", defaultMultiWorkers)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if workers < 1 {
+		return nil, 0, jsonrpc.ErrInvalidParams.AddData("msg", "workers should be greater than 0")
+	}
+
+	return res, int(workers), nil
+}
+
+// readMulti executes reads of several slaves and aggregates results
+// reads of slaves with independent connections go concurrently (no more than workers at a time),
+// reads of slaves on the same bus are serialized
+func (s Service) readMulti(params objx.Map) (interface{}, error) {
+	items, workers, err := getMultiItems(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err = s.withRetryBudget(params)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]multiItemResult, len(items))
+
+	// items grouped by bus lock in order of appearance
+	// (items without lock go in own groups)
+	groups := make([][]multiItem, 0)
+	index := make(map[*busLock]int)
+
+	for i, item := range items {
+		method := item.Get("method").Str("modbus-read-holding")
+		delete(item, "method")
+
+		results[i].Method = method
+
+		slaveID, err := getSlaveID(item)
+		if err != nil {
+			results[i].Error = toRPCError(err)
+			continue
+		}
+
+		results[i].SlaveID = slaveID
+
+		if !multiMethods[method] {
+			results[i].Error = toRPCError(jsonrpc.ErrInvalidParams.
+				AddData("msg", "method should be register or bit read").AddData("v", method))
+			continue
+		}
+
+		_, bus := s.connection(slaveID)
+
+		g, ok := index[bus]
+		if !ok || bus == nil {
+			g = len(groups)
+			groups = append(groups, nil)
+			index[bus] = g
+		}
+
+		groups[g] = append(groups[g], multiItem{i, method, item})
+	}
+
+	queue := make(chan []multiItem, len(groups))
+	for _, group := range groups {
+		queue <- group
+	}
+
+	close(queue)
+
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for group := range queue {
+				for _, item := range group {
+					s.callItem(jsonrpc.Request{Method: item.method, Params: item.params}, &results[item.index])
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,215 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// readWriteRegisters writes and reads holding registers in one transaction (FC23)
+// write is performed before read
+func (s Service) readWriteRegisters(slaveID byte, readAddr, readQuantity, writeAddr, writeQuantity uint16,
+	value []byte) ([]byte, error) {
+	cli := s.getClient(slaveID)
+
+	res, err := cli.ReadWriteMultipleRegisters(readAddr, readQuantity, writeAddr, writeQuantity, value)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, writeAddr, writeQuantity)
+
+	if len(res) != int(readQuantity)*2 {
+		return nil, truncatedErr(int(readQuantity)*2, len(res))
+	}
+
+	return res, nil
+}
+
+// getWriteValue encodes value param by codec and checks quantity
+// it also returns true if value was clamped
+func getWriteValue(params objx.Map, c codec, quantityKey string) (uint16, []byte, bool, error) {
+	values, err := getValues(params, "value")
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	expected := len(values) * c.registers()
+
+	quantity, err := getUint16(params, quantityKey, int64(expected))
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	if int(quantity) != expected {
+		return 0, nil, false, quantityErr(quantity, expected)
+	}
+
+	value, clamped, err := c.encodeClamped("value", values)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	return quantity, value, clamped, nil
+}
+
+// withClamped wraps result if clamp was requested
+func withClamped(c codec, res interface{}, clamped bool) interface{} {
+	if !c.clamp {
+		return res
+	}
+
+	return clampResult{Result: res, Clamped: clamped}
+}
+
+// readWriteMultipleRegisters writes value to write_address and
+// reads read_quantity registers from read_address (FC23)
+// value and result are encoded by common encoding and orders params
+func (s Service) readWriteMultipleRegisters(params objx.Map) (interface{}, error) {
+	readAddr, readQuantity, err := getTwoUint16(params, "read_address", "read_quantity")
+	if err != nil {
+		return nil, err
+	}
+
+	writeAddr, err := getUint16(params, "write_address")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.getCodec(params)
+	if err != nil {
+		return nil, err
+	}
+
+	writeQuantity, value, clamped, err := getWriteValue(params, c, "write_quantity")
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.readWriteRegisters(slaveID, readAddr, readQuantity, writeAddr, writeQuantity, value)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := c.decode(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return withClamped(c, values, clamped), nil
+}
+
+// holdingPoint returns holding register point by name from k param
+// with its codec and params
+func (s Service) holdingPoint(params objx.Map, k string) (codec, objx.Map, error) {
+	p, pp, err := s.getPoint(params, k)
+	if err != nil {
+		return codec{}, nil, err
+	}
+
+	if p.Function != pointHolding {
+		return codec{}, nil, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be holding register point").
+			AddData("v", p.Name)
+	}
+
+	if len(p.Parts) > 0 {
+		return codec{}, nil, compositeErr(p)
+	}
+
+	if p.ExponentAddress != nil {
+		return codec{}, nil, exponentErr(p)
+	}
+
+	if len(p.Fields) > 0 {
+		return codec{}, nil, recordErr(p)
+	}
+
+	c, err := s.getCodec(pp)
+	if err != nil {
+		return codec{}, nil, err
+	}
+
+	return c, pp, nil
+}
+
+// writeReadPoint writes value to write_point and reads read_point
+// in one bus transaction (FC23)
+func (s Service) writeReadPoint(params objx.Map) (interface{}, error) {
+	wc, wp, err := s.holdingPoint(params, "write_point")
+	if err != nil {
+		return nil, err
+	}
+
+	rc, rp, err := s.holdingPoint(params, "read_point")
+	if err != nil {
+		return nil, err
+	}
+
+	writeAddr, err := getUint16(wp, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	writeQuantity, value, clamped, err := getWriteValue(wp, wc, "quantity")
+	if err != nil {
+		return nil, err
+	}
+
+	readAddr, err := getUint16(rp, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	readQuantity, err := getUint16(rp, "quantity", int64(rc.registers()))
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(wp)
+	if err != nil {
+		return nil, err
+	}
+
+	readSlaveID, err := getSlaveID(rp)
+	if err != nil {
+		return nil, err
+	}
+
+	if slaveID != readSlaveID {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "write_point and read_point should have same slave_id")
+	}
+
+	res, err := s.readWriteRegisters(slaveID, readAddr, readQuantity, writeAddr, writeQuantity, value)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := rc.decode(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return withClamped(wc, values, clamped), nil
+}
@@ -0,0 +1,48 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+// ReadWriteVerify sends verified register writes of slaveID (verify param)
+// by one FC23 transaction which writes registers and reads them back,
+// so there is no window between write and read-back
+// (slave should support read-write multiple registers function)
+func ReadWriteVerify(slaveID byte) Option {
+	return func(s *Service) {
+		if s.readWriteVerify == nil {
+			s.readWriteVerify = make(map[byte]bool)
+		}
+
+		s.readWriteVerify[slaveID] = true
+	}
+}
+
+// writeVerified writes holding registers and reads them back by one FC23 transaction
+// if write is verified and slave supports it, it returns false if write should go
+// by two calls (write and read-back)
+func (w *writeCheck) writeVerified(s Service, slaveID byte, addr, quantity uint16, c codec,
+	written []byte) (bool, error) {
+	if w == nil || !s.readWriteVerify[slaveID] || quantity > maxReadWriteRegisters {
+		return false, nil
+	}
+
+	res, err := s.readWriteRegisters(slaveID, addr, quantity, addr, quantity, written)
+	if err != nil {
+		return true, err
+	}
+
+	return true, w.compare(c, written, res)
+}
@@ -0,0 +1,204 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+var errNotRecorded = errors.New("modbus: request is not recorded")
+
+// TraceRecord is bus transaction in record file (one JSON object per line)
+type TraceRecord struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method,omitempty"`
+	SlaveID byte      `json:"slave_id"`
+	// hex of function code and data of request and response
+	Request  string `json:"request"`
+	Response string `json:"response,omitempty"`
+	// time on the bus in milliseconds
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// RecordTrace returns trace callback (see TraceFunc) which writes transactions to w
+// as JSON lines, the record can be served by Replay option later
+func RecordTrace(w io.Writer) func(TraceEvent) {
+	var mx sync.Mutex
+
+	enc := json.NewEncoder(w)
+
+	return func(e TraceEvent) {
+		r := TraceRecord{
+			Time:     time.Now(),
+			Method:   e.Method,
+			SlaveID:  e.SlaveID,
+			Request:  hex.EncodeToString(e.Request),
+			Response: hex.EncodeToString(e.Response),
+			Duration: ms(e.Duration),
+		}
+
+		if e.Err != nil {
+			r.Error = e.Err.Error()
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+
+		if err := enc.Encode(r); err != nil {
+			log.WithError(err).Warn("transaction is not recorded")
+		}
+	}
+}
+
+// LoadTrace reads record file written by RecordTrace
+func LoadTrace(path string) ([]TraceRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseTrace(f)
+}
+
+func parseTrace(r io.Reader) ([]TraceRecord, error) {
+	var records []TraceRecord
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		var rec TraceRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, lineErr(line, err)
+		}
+
+		if _, err := hex.DecodeString(rec.Request); err != nil {
+			return nil, lineErr(line, errors.New("request should be hex"))
+		}
+
+		if _, err := hex.DecodeString(rec.Response); err != nil {
+			return nil, lineErr(line, errors.New("response should be hex"))
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, sc.Err()
+}
+
+// replayLog serves recorded responses in the order of record
+// (the last one is repeated when the responses of request are over)
+type replayLog struct {
+	mx sync.Mutex
+	// records by slave id and request
+	records map[string][]TraceRecord
+}
+
+func replayKey(slaveID byte, request string) string {
+	return strconv.Itoa(int(slaveID)) + "/" + request
+}
+
+// next returns recorded response of request
+func (l *replayLog) next(slaveID byte, request []byte) (TraceRecord, bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	key := replayKey(slaveID, hex.EncodeToString(request))
+
+	records := l.records[key]
+	if len(records) == 0 {
+		return TraceRecord{}, false
+	}
+
+	if len(records) > 1 {
+		l.records[key] = records[1:]
+	}
+
+	return records[0], true
+}
+
+// Replay sets recorded transactions which are served instead of the bus
+// requests are matched by slave id and request frame (function code and data)
+func Replay(records ...TraceRecord) Option {
+	return func(s *Service) {
+		s.replay = &replayLog{records: make(map[string][]TraceRecord)}
+
+		for _, r := range records {
+			key := replayKey(r.SlaveID, r.Request)
+			s.replay.records[key] = append(s.replay.records[key], r)
+		}
+	}
+}
+
+// replayTransporter answers requests by recorded responses in framing of packager
+type replayTransporter struct {
+	log      *replayLog
+	packager modbus.Packager
+	slaveID  byte
+}
+
+func (t replayTransporter) Send(adu []byte) ([]byte, error) {
+	pdu, err := t.packager.Decode(adu)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, ok := t.log.next(t.slaveID, append([]byte{pdu.FunctionCode}, pdu.Data...))
+	if !ok {
+		return nil, errNotRecorded
+	}
+
+	if rec.Error != "" {
+		return nil, errors.New(rec.Error)
+	}
+
+	// validated on load
+	res, _ := hex.DecodeString(rec.Response)
+	if len(res) == 0 {
+		return nil, errNotRecorded
+	}
+
+	// response has transaction id of request
+	if _, ok := t.packager.(*modbus.TCPPackager); ok {
+		frame := make([]byte, 7, 7+len(res))
+		copy(frame, adu[:7])
+		binary.BigEndian.PutUint16(frame[4:], uint16(1+len(res)))
+
+		return append(frame, res...), nil
+	}
+
+	return t.packager.Encode(&modbus.ProtocolDataUnit{FunctionCode: res[0], Data: res[1:]})
+}
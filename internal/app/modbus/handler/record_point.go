@@ -0,0 +1,182 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// PointField is a field of record point
+// its value is decoded from registers at offset from point address
+type PointField struct {
+	Name string `mapstructure:"name" json:"name"`
+	// offset in registers from point address
+	Offset uint16 `mapstructure:"offset" json:"offset"`
+	// encoding of field (uint16 by default)
+	Type      string `mapstructure:"type" json:"type,omitempty"`
+	ByteOrder string `mapstructure:"byte_order" json:"byte_order,omitempty"`
+	WordOrder string `mapstructure:"word_order" json:"word_order,omitempty"`
+	// multiplier of field value (0 means not scaled)
+	Scale float64 `mapstructure:"scale" json:"scale,omitempty"`
+}
+
+func (f PointField) encoding() string {
+	if f.Type == "" {
+		return encUint16
+	}
+
+	return f.Type
+}
+
+// registers returns count of registers of field
+func (f PointField) registers() int {
+	if c, ok := encodingAliases[f.encoding()]; ok {
+		return c.registers()
+	}
+
+	return encodingRegisters[f.encoding()]
+}
+
+// validateFields checks layout of record point,
+// fields should not overlap and should fit within point quantity (record span)
+func (p Point) validateFields() error {
+	if p.Function != pointInput && p.Function != pointHolding {
+		return errors.New("record point should be input or holding register")
+	}
+
+	if p.Quantity == 0 {
+		return errors.New("record point should have quantity (span of fields)")
+	}
+
+	if p.Encoding != "" || p.scaled() || p.Transform != "" || len(p.Enum) > 0 || len(p.Parts) > 0 ||
+		len(p.BitLabels) > 0 || len(p.ByteLabels) > 0 || p.ExponentAddress != nil || p.CommandWord != nil {
+		return errors.New("record point can't have encoding, scale, offset, transform, enum, parts, " +
+			"bit_labels, byte_labels, exponent_address or command_word")
+	}
+
+	fields := append([]PointField{}, p.Fields...)
+	names := make(map[string]bool, len(fields))
+
+	for _, f := range fields {
+		if f.Name == "" || names[f.Name] {
+			return errors.New("field name of record point should be unique string but " + f.Name + " given")
+		}
+
+		names[f.Name] = true
+
+		if !isValidEncoding(f.encoding()) {
+			return errors.New("unsupported encoding of field " + f.Name)
+		}
+
+		for _, order := range []string{f.ByteOrder, f.WordOrder} {
+			if order != "" && !IsValidOrder(order) {
+				return errors.New("order of field " + f.Name + " should be big or little")
+			}
+		}
+
+		if int(f.Offset)+f.registers() > int(p.Quantity) {
+			return errors.New("field " + f.Name + " doesn't fit within quantity of record point")
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Offset < fields[j].Offset })
+
+	for i := 1; i < len(fields); i++ {
+		if prev := fields[i-1]; int(prev.Offset)+prev.registers() > int(fields[i].Offset) {
+			return errors.New("field " + fields[i].Name + " overlaps field " + prev.Name)
+		}
+	}
+
+	return nil
+}
+
+func recordErr(p Point) error {
+	return jsonrpc.ErrInvalidParams.AddData("msg", "record point can be read only").AddData("v", p.Name)
+}
+
+// readRecord reads span of record point in one transaction
+// and returns object of decoded (and scaled) field values by name
+func (s Service) readRecord(p Point, params objx.Map) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.readBlock(slaveID, pointFunctions[p.Function], addr, p.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(p.Fields))
+
+	for _, f := range p.Fields {
+		// field can override orders of point
+		fp := objx.Map{
+			"encoding":   f.encoding(),
+			"byte_order": params.Get("byte_order").Str(s.byteOrder),
+			"word_order": params.Get("word_order").Str(s.wordOrder),
+		}
+
+		if f.ByteOrder != "" {
+			fp["byte_order"] = f.ByteOrder
+		}
+
+		if f.WordOrder != "" {
+			fp["word_order"] = f.WordOrder
+		}
+
+		c, err := s.getCodec(fp)
+		if err != nil {
+			return nil, err
+		}
+
+		start := int(f.Offset) * 2
+
+		values, err := c.decode(res[start : start+c.registers()*2])
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Scale != 0 {
+			for i, v := range values {
+				if n, ok := toFloat64(v); ok {
+					values[i] = n * f.Scale
+				}
+			}
+		}
+
+		// field of bytes encoding has high and low byte values
+		if len(values) > 1 {
+			result[f.Name] = values
+			continue
+		}
+
+		result[f.Name] = values[0]
+	}
+
+	return result, nil
+}
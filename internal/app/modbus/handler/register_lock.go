@@ -0,0 +1,86 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sync"
+)
+
+// RegisterLocks enables per register locks of read-modify-write helpers (modbus-set-bit),
+// sequence of read and write holds lock of register so concurrent calls don't lose updates
+// even if slave bypasses bus lock (UnsafeParallel)
+//
+// it protects only against calls of this service, not against other masters on the bus
+func RegisterLocks(enabled bool) Option {
+	return func(s *Service) {
+		if enabled {
+			s.registerLocks = &registerLocks{locks: make(map[registerKey]*registerLock)}
+		} else {
+			s.registerLocks = nil
+		}
+	}
+}
+
+// registerKey is holding register of slave
+type registerKey struct {
+	slaveID byte
+	address uint16
+}
+
+type registerLock struct {
+	mx sync.Mutex
+	// count of holders and waiters (lock is removed when it's zero)
+	refs int
+}
+
+// registerLocks contains locks of registers which are in use
+type registerLocks struct {
+	mx    sync.Mutex
+	locks map[registerKey]*registerLock
+}
+
+// lock locks register and returns function which unlocks it
+// (it's noop if register locks are disabled)
+func (l *registerLocks) lock(slaveID byte, address uint16) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	key := registerKey{slaveID, address}
+
+	l.mx.Lock()
+	rl, ok := l.locks[key]
+	if !ok {
+		rl = &registerLock{}
+		l.locks[key] = rl
+	}
+	rl.refs++
+	l.mx.Unlock()
+
+	rl.mx.Lock()
+
+	return func() {
+		rl.mx.Unlock()
+
+		l.mx.Lock()
+		defer l.mx.Unlock()
+
+		if rl.refs--; rl.refs == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// defaultMaxResponseBytes is default limit of response frame size
+const defaultMaxResponseBytes = 64 << 10
+
+// MaxResponseBytes limits size of response frame (0 disables it)
+// larger responses are rejected before they are parsed
+// (built-in transports read no more than max frame size,
+// but custom ones can return anything)
+func MaxResponseBytes(max int) Option {
+	return func(s *Service) {
+		s.maxResponseBytes = max
+	}
+}
+
+// responseLimitTransporter rejects responses larger than max bytes
+type responseLimitTransporter struct {
+	modbus.Transporter
+	max int
+}
+
+func (t responseLimitTransporter) Send(adu []byte) ([]byte, error) {
+	res, err := t.Transporter.Send(adu)
+	if err == nil && len(res) > t.max {
+		return nil, jsonrpc.ErrServer.AddData("msg", "response too large").
+			AddData("size", len(res)).AddData("max", t.max).SetCode(-32098)
+	}
+
+	return res, err
+}
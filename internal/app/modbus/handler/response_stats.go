@@ -0,0 +1,134 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// responseStats contains byte count reported by slave in read responses,
+// count of data bytes actually received and time of bus transactions
+type responseStats struct {
+	reported int
+	received int
+	elapsed  time.Duration
+	// completion time of last successful transaction
+	completed time.Time
+	// function code and pdu length of last response
+	functionCode byte
+	pduLength    int
+}
+
+// statsRecorder sums stats of read responses
+type statsRecorder struct {
+	modbus.Transporter
+	packager modbus.Packager
+	stats    *responseStats
+}
+
+func (t statsRecorder) Send(adu []byte) ([]byte, error) {
+	start := time.Now()
+	res, err := t.Transporter.Send(adu)
+	t.stats.elapsed += time.Since(start)
+
+	if err != nil {
+		return res, err
+	}
+
+	t.stats.completed = time.Now()
+
+	// broken frame will be reported by client
+	if t.packager.Verify(adu, res) != nil {
+		return res, nil
+	}
+
+	pdu, err := t.packager.Decode(res)
+	if err != nil {
+		return res, nil
+	}
+
+	t.stats.functionCode = pdu.FunctionCode
+	t.stats.pduLength = 1 + len(pdu.Data)
+
+	// exception responses have no byte count
+	if pdu.FunctionCode&0x80 == 0 && len(pdu.Data) > 0 {
+		t.stats.reported += int(pdu.Data[0])
+		t.stats.received += len(pdu.Data) - 1
+	}
+
+	return res, nil
+}
+
+// withStats returns service which records stats of responses
+func (s Service) withStats(slaveID byte, stats *responseStats) Service {
+	packager := s.getPackager(slaveID)
+
+	s = s.withLayer(func(t modbus.Transporter) modbus.Transporter {
+		return statsRecorder{t, packager, stats}
+	})
+	// cached result has no response
+	s.cache = nil
+
+	return s
+}
+
+func (c responseStats) mismatchErr(err error) error {
+	return jsonrpc.ErrServer.AddData("msg", err.Error()).
+		AddData("reported_bytes", c.reported).AddData("received_bytes", c.received).SetCode(-32098)
+}
+
+// buildVerbose decodes registers with stats of responses
+// method and slave_id echo request, within_sla is set if sla_ms param passed, timestamp if with_timestamp
+func (s Service) buildVerbose(params objx.Map, c codec, b []byte, stats responseStats) (verboseResult, error) {
+	res, err := c.decodeVerbose(b, stats)
+	if err != nil {
+		return verboseResult{}, err
+	}
+
+	res.Method = s.method
+
+	res.SlaveID, err = getSlaveID(params)
+	if err != nil {
+		return verboseResult{}, err
+	}
+
+	if params.Get("with_timestamp").Bool() {
+		res.Timestamp, err = s.formatTimestamp(params, stats.completed)
+		if err != nil {
+			return verboseResult{}, err
+		}
+	}
+
+	if params.Get("sla_ms").IsNil() {
+		return res, nil
+	}
+
+	sla, err := getInt64(params, "sla_ms")
+	if err != nil {
+		return verboseResult{}, err
+	}
+
+	within := stats.elapsed <= time.Duration(sla)*time.Millisecond
+	res.WithinSLA = &within
+
+	return res, nil
+}
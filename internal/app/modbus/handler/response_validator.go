@@ -0,0 +1,75 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// ResponseContext describes transaction of response passed to response validator
+type ResponseContext struct {
+	SlaveID byte
+	// method of request (empty for internal reads)
+	Method string
+	// request frame sent to the slave
+	Request []byte
+}
+
+// ResponseValidatorFunc checks raw response frame before it's verified and decoded
+// it returns frame to use instead (fixed up one or the same) or error which fails transaction
+type ResponseValidatorFunc func(ctx ResponseContext, res []byte) ([]byte, error)
+
+// ResponseValidator sets callback for device quirks which is called with each response frame
+// (including retries), by default responses are used as they are
+func ResponseValidator(fn ResponseValidatorFunc) Option {
+	return func(s *Service) {
+		s.validator = fn
+	}
+}
+
+// validatorTransporter passes responses through response validator
+type validatorTransporter struct {
+	modbus.Transporter
+	validate ResponseValidatorFunc
+	ctx      ResponseContext
+}
+
+func (t validatorTransporter) Send(adu []byte) ([]byte, error) {
+	res, err := t.Transporter.Send(adu)
+	if err != nil {
+		return res, err
+	}
+
+	ctx := t.ctx
+	ctx.Request = adu
+
+	res, err = t.validate(ctx, res)
+	if err == nil {
+		return res, nil
+	}
+
+	var rpcErr jsonrpc.Error
+	if errors.As(err, &rpcErr) {
+		return nil, err
+	}
+
+	return nil, jsonrpc.ErrServer.AddData("msg", "response rejected").
+		AddData("reason", err.Error()).SetCode(-32098)
+}
@@ -0,0 +1,207 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+const (
+	resultNumber  = "number"
+	resultString  = "string"
+	resultBoolean = "boolean"
+)
+
+// coercion converts decoded values to JSON type expected by consumer
+type coercion struct {
+	typ string
+	// fixed decimals of string values (-1 means shortest representation)
+	decimals int
+	// 32 and 64-bit values are strings (number_as_string param)
+	numberAsString bool
+	// expression applied to values before conversion (nil if not set)
+	transform transform
+}
+
+// getCoercion returns coercion from result_type, decimals, number_as_string and transform params
+// (nil if none of them passed)
+func getCoercion(params objx.Map, encoding string) (*coercion, error) {
+	numberAsString := params.Get("number_as_string").Bool()
+
+	t, err := getTransform(params, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Get("result_type").IsNil() {
+		if !params.Get("decimals").IsNil() {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "decimals allowed with string result_type only")
+		}
+
+		if !numberAsString && t == nil {
+			return nil, nil
+		}
+
+		return &coercion{typ: resultNumber, decimals: -1, numberAsString: numberAsString, transform: t}, nil
+	}
+
+	c := coercion{
+		typ: params.Get("result_type").Str(), decimals: -1, numberAsString: numberAsString, transform: t,
+	}
+
+	switch c.typ {
+	case resultNumber, resultString, resultBoolean:
+	default:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "result_type should be number, string or boolean").
+			AddData("v", params.Get("result_type").Data())
+	}
+
+	switch encoding {
+	case encRaw, encBitmask, encTimestamp:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "result_type can't be used with "+encoding+" encoding")
+	case encFloat32, encFixed, encFixed32:
+		// nonzero is ambiguous for floats (e.g. 1e-9)
+		if c.typ == resultBoolean {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", encoding+" can't be coerced to boolean")
+		}
+	}
+
+	if params.Get("decimals").IsNil() {
+		return &c, nil
+	}
+
+	if c.typ != resultString {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "decimals allowed with string result_type only")
+	}
+
+	decimals, err := getInt64(params, "decimals")
+	if err != nil {
+		return nil, err
+	}
+
+	if !(0 <= decimals && decimals <= 15) {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "decimals should be in range 0-15")
+	}
+
+	c.decimals = int(decimals)
+
+	return &c, nil
+}
+
+// value coerces one decoded value (unsigned, signed or float)
+func (c *coercion) value(v interface{}) interface{} {
+	v = c.typed(c.transform.apply(v))
+
+	if c.numberAsString {
+		if s, ok := wideString(v); ok {
+			return s
+		}
+	}
+
+	return v
+}
+
+// typed converts one decoded value to result_type
+func (c *coercion) typed(v interface{}) interface{} {
+	f, ok := toFloat64(v)
+	if !ok {
+		return v
+	}
+
+	switch c.typ {
+	case resultString:
+		if c.decimals >= 0 {
+			return strconv.FormatFloat(f, 'f', c.decimals, 64)
+		}
+
+		return fmt.Sprint(v)
+	case resultBoolean:
+		return f != 0
+	default:
+		return v
+	}
+}
+
+func (c *coercion) values(values []interface{}) []interface{} {
+	if c == nil {
+		return values
+	}
+
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = c.value(v)
+	}
+
+	return res
+}
+
+// bits coerces coils or discrete inputs (without coercion they are numbers)
+func (c *coercion) bits(bits []uint16) interface{} {
+	if c == nil {
+		return bits
+	}
+
+	res := make([]interface{}, len(bits))
+	for i, v := range bits {
+		res[i] = c.value(v)
+	}
+
+	return res
+}
+
+// wideString returns 32 and 64-bit numbers as decimal strings
+// (JSON consumers like JavaScript lose precision of large numbers)
+func wideString(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case uint32:
+		return strconv.FormatUint(uint64(n), 10), true
+	case int32:
+		return strconv.FormatInt(int64(n), 10), true
+	case uint64:
+		return strconv.FormatUint(n, 10), true
+	case int64:
+		return strconv.FormatInt(n, 10), true
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// bitmask converts bitmask (one word or array of them) to strings if number_as_string is set
+func (c *coercion) bitmask(v interface{}) interface{} {
+	if c == nil || !c.numberAsString {
+		return v
+	}
+
+	words, ok := v.([]uint64)
+	if !ok {
+		return c.value(v)
+	}
+
+	res := make([]interface{}, len(words))
+	for i, w := range words {
+		res[i] = c.value(w)
+	}
+
+	return res
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+	// exception codes which mean "try again later"
+	exceptions map[byte]bool
+}
+
+// Retry enables retries of failed transactions
+// transport errors and retryable exceptions (acknowledge and slave device busy
+// by default) are retried up to attempts times with exponential backoff
+func Retry(attempts int, backoff time.Duration) Option {
+	return func(s *Service) {
+		if attempts <= 0 {
+			s.retry = nil
+			return
+		}
+
+		s.retry = &retryPolicy{
+			attempts:   attempts,
+			backoff:    backoff,
+			exceptions: defaultRetryExceptions(),
+		}
+	}
+}
+
+func defaultRetryExceptions() map[byte]bool {
+	return map[byte]bool{
+		modbus.ExceptionCodeAcknowledge:      true,
+		modbus.ExceptionCodeServerDeviceBusy: true,
+	}
+}
+
+// RetryExceptions sets exception codes which are retried
+// it should be used after Retry option
+func RetryExceptions(codes ...byte) Option {
+	return func(s *Service) {
+		if s.retry == nil {
+			return
+		}
+
+		s.retry.exceptions = make(map[byte]bool, len(codes))
+		for _, c := range codes {
+			s.retry.exceptions[c] = true
+		}
+	}
+}
+
+// retryTransporter repeats transactions on transport errors
+// and retryable exception responses
+type retryTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+	policy   *retryPolicy
+	// retries are limited by it too (nil if not set)
+	budget *retryBudget
+}
+
+// exceptionCode returns exception code of response (0 if it's not an exception)
+func exceptionCode(packager modbus.Packager, adu, res []byte) byte {
+	if packager.Verify(adu, res) != nil {
+		return 0
+	}
+
+	pdu, err := packager.Decode(res)
+	if err != nil || pdu.FunctionCode&0x80 == 0 || len(pdu.Data) == 0 {
+		return 0
+	}
+
+	return pdu.Data[0]
+}
+
+func (t retryTransporter) Send(adu []byte) ([]byte, error) {
+	backoff := t.policy.backoff
+
+	for i := 0; ; i++ {
+		res, err := t.Transporter.Send(adu)
+
+		// these errors are not related to the bus
+		if errors.Is(err, errDryRun) || errors.Is(err, errRateLimited) ||
+			errors.Is(err, errBusBusy) {
+			return res, err
+		}
+
+		retry := err != nil || t.policy.exceptions[exceptionCode(t.packager, adu, res)]
+		if !retry || i >= t.policy.attempts || !t.budget.take(backoff) {
+			return res, err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,153 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+var errRetryBudget = errors.New("modbus: retry budget of batch exhausted")
+
+// retryBudget is shared by items of batch call (read-multi or fan-out),
+// retries of all items are counted together so worst case time of batch is bounded
+type retryBudget struct {
+	mx sync.Mutex
+	// retries left (-1 if not limited)
+	retries int
+	// end of batch time budget (zero if not limited)
+	deadline time.Time
+}
+
+// getRetryBudget returns budget of retry_budget and retry_budget_time params
+// (nil if both aren't passed)
+func getRetryBudget(params objx.Map) (*retryBudget, error) {
+	if params.Get("retry_budget").IsNil() && params.Get("retry_budget_time").IsNil() {
+		return nil, nil
+	}
+
+	retries := int64(-1)
+
+	if !params.Get("retry_budget").IsNil() {
+		var err error
+
+		retries, err = getInt64(params, "retry_budget")
+		if err != nil {
+			return nil, err
+		}
+
+		if retries < 0 {
+			return nil, jsonrpc.ErrInvalidParams.AddData("msg", "retry_budget should not be negative")
+		}
+	}
+
+	d, err := getDuration(params, "retry_budget_time", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRetryBudget(int(retries), d), nil
+}
+
+// newRetryBudget returns budget of retries (-1 means no limit)
+// which ends after d (0 means no limit)
+func newRetryBudget(retries int, d time.Duration) *retryBudget {
+	b := &retryBudget{retries: retries}
+
+	if d > 0 {
+		b.deadline = time.Now().Add(d)
+	}
+
+	return b
+}
+
+// take reserves retry which starts after backoff,
+// it returns false if there are no retries left or retry starts after deadline
+func (b *retryBudget) take(backoff time.Duration) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if !b.deadline.IsZero() && time.Now().Add(backoff).After(b.deadline) {
+		return false
+	}
+
+	if b.retries == 0 {
+		return false
+	}
+
+	if b.retries > 0 {
+		b.retries--
+	}
+
+	return true
+}
+
+// expired reports whether time budget is over
+// (items which aren't started yet fail without transactions)
+func (b *retryBudget) expired() bool {
+	return b != nil && !b.deadline.IsZero() && !time.Now().Before(b.deadline)
+}
+
+// withRetryBudget returns service which retries share budget of batch params
+func (s Service) withRetryBudget(params objx.Map) (Service, error) {
+	budget, err := getRetryBudget(params)
+	if err != nil {
+		return s, err
+	}
+
+	if budget != nil {
+		s.retryBudget = budget
+	}
+
+	return s, nil
+}
+
+// BatchRetryBudget limits retries of all requests of one jsonrpc batch
+// by total count of retries and time of batch (zero values mean no limit),
+// so worst case time of batch is bounded when the bus is flaky
+func BatchRetryBudget(retries int, d time.Duration) Option {
+	return func(s *Service) {
+		s.batchRetries = retries
+		s.batchRetryTime = d
+	}
+}
+
+// Batch returns service which requests of one jsonrpc batch share retry budget
+// (it implements jsonrpc.BatchCaller)
+func (s Service) Batch() jsonrpc.Caller {
+	if s.batchRetries <= 0 && s.batchRetryTime <= 0 {
+		return s
+	}
+
+	retries := s.batchRetries
+	if retries <= 0 {
+		retries = -1
+	}
+
+	s.retryBudget = newRetryBudget(retries, s.batchRetryTime)
+
+	return s
+}
@@ -0,0 +1,216 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// encScaleRegister is integer value with decimal exponent in separate register
+// (exponent_address param, value = raw * 10^exponent), it's common convention of smart meters
+const encScaleRegister = "scale_register"
+
+// exponent encodings
+const (
+	expInt16 = "int16"
+	// signed low byte of register
+	expInt8 = "int8"
+)
+
+// expNotImplemented is exponent of values which device doesn't provide
+const expNotImplemented = math.MinInt16
+
+// maxExponent limits exponent so values stay finite
+const maxExponent = 10
+
+// scaleRegister describes values scaled by exponent register
+type scaleRegister struct {
+	// codec of values (16 or 32-bit integer)
+	codec    codec
+	address  uint16
+	encoding string
+}
+
+// checkScaleRegister checks encoding of values and exponent
+func checkScaleRegister(c codec, expEncoding string) error {
+	switch c.encoding {
+	case encUint16, encInt16, encUint32, encInt32:
+	default:
+		return errors.New("value_encoding of scale_register should be uint16, int16, uint32 or int32")
+	}
+
+	switch expEncoding {
+	case expInt16, expInt8:
+	default:
+		return errors.New("exponent_encoding should be int16 or int8")
+	}
+
+	return nil
+}
+
+// getScaleRegister returns scale register of exponent_address, exponent_encoding
+// and value_encoding params (value is uint16 by default)
+func (s Service) getScaleRegister(params objx.Map) (scaleRegister, error) {
+	vp := params.Copy()
+	vp["encoding"] = params.Get("value_encoding").Str(encUint16)
+
+	c, err := s.getCodec(vp)
+	if err != nil {
+		return scaleRegister{}, err
+	}
+
+	addr, err := getUint16(params, "exponent_address")
+	if err != nil {
+		return scaleRegister{}, err
+	}
+
+	sr := scaleRegister{codec: c, address: addr, encoding: params.Get("exponent_encoding").Str(expInt16)}
+
+	if err := checkScaleRegister(c, sr.encoding); err != nil {
+		return scaleRegister{}, jsonrpc.ErrInvalidParams.AddData("msg", err.Error())
+	}
+
+	return sr, nil
+}
+
+// exponent returns signed exponent of register
+func (sr scaleRegister) exponent(reg uint16) int {
+	if sr.encoding == expInt8 {
+		return int(int8(reg))
+	}
+
+	return int(int16(reg))
+}
+
+// readScaled reads quantity registers of values from addr and exponent register
+// in one transaction if they are within 125 registers and returns scaled values
+// (values are null if device doesn't implement exponent)
+func (s Service) readScaled(slaveID, function byte, addr, quantity uint16, sr scaleRegister) ([]interface{}, error) {
+	if quantity == 0 || int(quantity)%sr.codec.registers() != 0 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "quantity should be multiple of "+
+			strconv.Itoa(sr.codec.registers())+" registers of "+sr.codec.encoding).AddData("v", quantity)
+	}
+
+	from, to := int(addr), int(addr)+int(quantity)-1
+	if int(sr.address) < from {
+		from = int(sr.address)
+	}
+
+	if int(sr.address) > to {
+		to = int(sr.address)
+	}
+
+	var data, exp []byte
+
+	if to-from+1 <= maxReadRegisters {
+		res, err := s.readBlock(slaveID, function, uint16(from), uint16(to-from+1))
+		if err != nil {
+			return nil, err
+		}
+
+		data = res[(int(addr)-from)*2 : (int(addr)-from+int(quantity))*2]
+		exp = res[(int(sr.address)-from)*2:]
+	} else {
+		var err error
+
+		if data, err = s.readBlock(slaveID, function, addr, quantity); err != nil {
+			return nil, err
+		}
+
+		if exp, err = s.readBlock(slaveID, function, sr.address, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	raw, err := sr.codec.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	e := binary.BigEndian.Uint16(exp)
+	if sr.encoding == expInt16 && int16(e) == expNotImplemented {
+		return make([]interface{}, len(raw)), nil
+	}
+
+	n := sr.exponent(e)
+	if n < -maxExponent || n > maxExponent {
+		return nil, jsonrpc.ErrServer.AddData("msg", "exponent of scale register is out of range -10..10").
+			AddData("v", n).SetCode(-32098)
+	}
+
+	values := make([]interface{}, len(raw))
+
+	for i, v := range raw {
+		f, _ := toFloat64(v)
+
+		// division keeps decimal values exact (1234 / 100 rather than 1234 * 0.01)
+		if n < 0 {
+			values[i] = f / math.Pow10(-n)
+		} else {
+			values[i] = f * math.Pow10(n)
+		}
+	}
+
+	return values, nil
+}
+
+func exponentErr(p Point) error {
+	return jsonrpc.ErrInvalidParams.AddData("msg", "point with exponent_address can be read only").AddData("v", p.Name)
+}
+
+// readScaleRegister reads values scaled by exponent register (scale_register encoding)
+func (s Service) readScaleRegister(params objx.Map, function byte) (interface{}, error) {
+	for _, k := range []string{"verbose", "chunked", "keyed", "last_good", "null_value", "cal_raw_low"} {
+		if !params.Get(k).IsNil() {
+			return nil, conflictErr("encoding", k)
+		}
+	}
+
+	addr, quantity, err := getAddrAndQuantity(params)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	coerce, err := getCoercion(params, encScaleRegister)
+	if err != nil {
+		return nil, err
+	}
+
+	sr, err := s.getScaleRegister(params)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.readScaled(slaveID, function, addr, quantity, sr)
+	if err != nil {
+		return nil, err
+	}
+
+	return coerce.values(values), nil
+}
@@ -0,0 +1,195 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+const (
+	scanFirstSlave = 1
+	scanLastSlave  = 247
+
+	scanTimeout = time.Minute
+)
+
+type scanSlave struct {
+	SlaveID byte `json:"slave_id"`
+	// latency in milliseconds
+	Latency int64 `json:"latency_ms"`
+}
+
+type scanResult struct {
+	Slaves []scanSlave `json:"slaves"`
+	// false if scan was stopped by timeout or max_found
+	Complete bool `json:"complete"`
+}
+
+// scanPacing makes scan gentle on the bus
+type scanPacing struct {
+	// gap between probes
+	delay time.Duration
+	// response timeout of probe (0 means transport timeout)
+	timeout time.Duration
+	// stop after found slaves (0 means no limit)
+	maxFound int
+}
+
+func getScanPacing(params objx.Map) (scanPacing, error) {
+	var p scanPacing
+
+	for k, d := range map[string]*time.Duration{"delay_ms": &p.delay, "timeout_ms": &p.timeout} {
+		ms, err := getInt64(params, k, 0)
+		if err != nil {
+			return scanPacing{}, err
+		}
+
+		if ms < 0 {
+			return scanPacing{}, jsonrpc.ErrInvalidParams.AddData("msg", k+" should not be negative")
+		}
+
+		*d = time.Duration(ms) * time.Millisecond
+	}
+
+	maxFound, err := getInt64(params, "max_found", 0)
+	if err != nil {
+		return scanPacing{}, err
+	}
+
+	if maxFound < 0 {
+		return scanPacing{}, jsonrpc.ErrInvalidParams.AddData("msg", "max_found should not be negative")
+	}
+
+	p.maxFound = int(maxFound)
+
+	return p, nil
+}
+
+// getDuration returns duration param (string like "10s")
+func getDuration(params objx.Map, k string, def time.Duration) (time.Duration, error) {
+	v := params.Get(k)
+	if v.IsNil() {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v.Str())
+	if err != nil || d <= 0 {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be positive duration (e.g. 10s)")
+	}
+
+	return d, nil
+}
+
+func getScanParams(params objx.Map) (byte, byte, uint16, time.Duration, error) {
+	from, err := getInt64(params, "from", scanFirstSlave)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	to, err := getInt64(params, "to", scanLastSlave)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if !(minByte <= from && from <= to && to <= maxByte) {
+		return 0, 0, 0, 0, jsonrpc.ErrInvalidParams.AddData("msg", "from and to should be bytes and from <= to")
+	}
+
+	addr, err := getUint16(params, "address", 0)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	timeout, err := getDuration(params, "timeout", scanTimeout)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	return byte(from), byte(to), addr, timeout, nil
+}
+
+// scan probes slaves in range by reading one holding register
+// slave is present if it responds (modbus exception is response too)
+// scan stops when timeout expired or max_found slaves found
+func (s Service) scan(params objx.Map) (interface{}, error) {
+	from, to, addr, timeout, err := getScanParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pacing, err := getScanPacing(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if pacing.timeout > 0 {
+		// transport should support per transaction timeout
+		for id := int(from); id <= int(to); id++ {
+			if t, _ := s.connection(byte(id)); !isTimeoutSender(t) {
+				return nil, jsonrpc.ErrInvalidParams.AddData("msg",
+					"timeout_ms isn't supported by transport of slave "+strconv.Itoa(id))
+			}
+		}
+
+		s = s.withLayer(func(t modbus.Transporter) modbus.Transporter {
+			return timeoutTransporter{Transporter: t, timeout: pacing.timeout}
+		})
+	}
+
+	deadline := time.Now().Add(timeout)
+	res := scanResult{Slaves: []scanSlave{}, Complete: true}
+
+	for id := int(from); id <= int(to); id++ {
+		var gap time.Duration
+		if id > int(from) {
+			gap = pacing.delay
+		}
+
+		// gap before probe shouldn't take scan over its timeout
+		if pacing.maxFound > 0 && len(res.Slaves) >= pacing.maxFound ||
+			time.Now().Add(gap).After(deadline) {
+			res.Complete = false
+			break
+		}
+
+		time.Sleep(gap)
+
+		slaveID := byte(id)
+		start := time.Now()
+
+		_, err := s.getClient(slaveID).ReadHoldingRegisters(addr, 1)
+
+		var mbErr *modbus.ModbusError
+		if err != nil && !errors.As(err, &mbErr) {
+			continue
+		}
+
+		res.Slaves = append(res.Slaves, scanSlave{
+			SlaveID: slaveID,
+			Latency: time.Since(start).Milliseconds(),
+		})
+	}
+
+	return res, nil
+}
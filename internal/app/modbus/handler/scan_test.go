@@ -0,0 +1,84 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// presentSlave is gateway with devices of some unit ids only,
+// other units time out
+type presentSlave struct {
+	*mockSlave
+	// unit ids of devices and exception they respond with (0 means normal response)
+	units map[byte]byte
+}
+
+func (m *presentSlave) Send(adu []byte) ([]byte, error) {
+	exception, ok := m.units[adu[6]]
+
+	switch {
+	case !ok:
+		return nil, errors.New("i/o timeout")
+	case exception != 0:
+		return simResponse(adu, nil, exception), nil
+	default:
+		return m.mockSlave.Send(adu)
+	}
+}
+
+func TestScan(t *testing.T) {
+	m := &presentSlave{mockSlave: &mockSlave{}, units: map[byte]byte{2: 0, 5: modbus.ExceptionCodeIllegalDataAddress, 9: 0}}
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	res, err := srv.Call(jsonrpc.Request{Method: "modbus-scan", Params: objx.Map{"from": num("2"), "to": num("8"), "address": num("40")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.(scanResult)
+
+	// slave responding with exception is present too
+	var found []byte
+	for _, s := range r.Slaves {
+		found = append(found, s.SlaveID)
+	}
+
+	if !bytes.Equal(found, []byte{2, 5}) || !r.Complete {
+		t.Errorf("unexpected scan result %+v", r)
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadHoldingRegisters, 0x00, 0x28, 0x00, 0x01})
+
+	for _, params := range []objx.Map{
+		{"from": num("9"), "to": num("8")},
+		{"to": num("256")},
+		{"timeout": "-1s"},
+		{"delay_ms": num("-1")},
+	} {
+		if _, err := srv.Call(jsonrpc.Request{Method: "modbus-scan", Params: params}); err == nil {
+			t.Errorf("%v: expected error", params)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"sort"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// param types of schema
+const (
+	typeUint16 = "uint16"
+	typeByte   = "byte"
+	typeInt    = "int"
+	typeBool   = "bool"
+	typeString = "string"
+	typeNumber = "number"
+	typeArray  = "array"
+	// any value (e.g. number, array or base64 string)
+	typeAny = "any"
+)
+
+type paramSpec struct {
+	typ      string
+	required bool
+}
+
+// schema describes params of method
+type schema map[string]paramSpec
+
+func required(typ string) paramSpec {
+	return paramSpec{typ: typ, required: true}
+}
+
+func optional(typ string) paramSpec {
+	return paramSpec{typ: typ}
+}
+
+// commonSchema contains params accepted by all methods
+var commonSchema = schema{ // nolint: gochecknoglobals
+	"slave_id":            optional(typeByte),
+	"dry_run":             optional(typeBool),
+	"verbose":             optional(typeBool),
+	"chunked":             optional(typeBool),
+	"encoding":            optional(typeString),
+	"byte_order":          optional(typeString),
+	"word_order":          optional(typeString),
+	"address_base":        optional(typeInt),
+	"framing":             optional(typeString),
+	"transaction_id":      optional(typeAny),
+	"with_transaction_id": optional(typeBool),
+	"clamp":               optional(typeBool),
+	"sla_ms":              optional(typeInt),
+	"compress":            optional(typeBool),
+	"idempotency_key":     optional(typeString),
+	"no_cache":            optional(typeBool),
+	"max_age_ms":          optional(typeInt),
+	"with_latency":        optional(typeBool),
+	"connect_timeout":     optional(typeString),
+}
+
+var (
+	// nolint: gochecknoglobals
+	readSchema = schema{
+		"address": required(typeUint16), "quantity": required(typeUint16),
+		"result_type": optional(typeString), "decimals": optional(typeInt), "null_value": optional(typeInt),
+		"enron": optional(typeBool), "last_good": optional(typeBool), "keyed": optional(typeBool),
+		"cal_raw_low": optional(typeNumber), "cal_raw_high": optional(typeNumber),
+		"cal_eng_low": optional(typeNumber), "cal_eng_high": optional(typeNumber),
+		"fractional_bits": optional(typeInt), "with_timestamp": optional(typeBool),
+		"timestamp_format": optional(typeString), "decode_mode": optional(typeString),
+		"sign_address": optional(typeUint16), "tz_offset": optional(typeString),
+		"number_as_string": optional(typeBool), "transform": optional(typeString), "summary": optional(typeBool),
+		"detect_wrap": optional(typeBool), "with_quality": optional(typeBool), "format": optional(typeString),
+		"shrink_quantity": optional(typeBool), "exponent_address": optional(typeUint16),
+		"exponent_encoding": optional(typeString), "value_encoding": optional(typeString),
+	}
+
+	// nolint: gochecknoglobals
+	methodSchemas = map[string]schema{
+		"modbus-read-coil":     readSchema,
+		"modbus-read-discrete": readSchema,
+		"modbus-read-input":    readSchema,
+		"modbus-read-holding":  readSchema,
+		"modbus-write-coil": {
+			"address": required(typeUint16), "value": required(typeUint16), "verify": optional(typeBool),
+			"slave_ids": optional(typeArray), "guard": optional(typeAny),
+			"retry_budget": optional(typeInt), "retry_budget_time": optional(typeString),
+		},
+		"modbus-write-multiple-coils": {
+			"address": required(typeUint16), "quantity": optional(typeUint16),
+			"value": optional(typeArray), "values": optional(typeArray),
+			"verify": optional(typeBool), "guard": optional(typeAny),
+		},
+		"modbus-write-register": {
+			"address": required(typeUint16), "value": required(typeAny), "signed": optional(typeBool),
+			"verify": optional(typeBool), "verify_tolerance": optional(typeNumber), "enron": optional(typeBool),
+			"slave_ids": optional(typeArray), "guard": optional(typeAny),
+			"retry_budget": optional(typeInt), "retry_budget_time": optional(typeString),
+		},
+		"modbus-write-multiple-registers": {
+			"address": required(typeUint16), "quantity": optional(typeUint16), "value": required(typeAny),
+			"verify": optional(typeBool), "verify_tolerance": optional(typeNumber), "enron": optional(typeBool),
+			"guard": optional(typeAny), "command_word": optional(typeUint16),
+		},
+		"modbus-read-file-record": {"records": required(typeArray)},
+		"modbus-write-file-record": {
+			"file_number": required(typeUint16), "record_number": required(typeUint16),
+			"record_length": optional(typeUint16), "value": required(typeAny),
+		},
+		"modbus-diagnostics":        {"sub_function": required(typeUint16), "data": optional(typeUint16)},
+		"modbus-comm-event-counter": {},
+		"modbus-comm-event-log":     {},
+		"modbus-read-write-registers": {
+			"read_address": required(typeUint16), "read_quantity": required(typeUint16),
+			"write_address": required(typeUint16), "write_quantity": optional(typeUint16), "value": required(typeAny),
+		},
+		"modbus-write-read-point": {
+			"write_point": required(typeString), "read_point": required(typeString), "value": required(typeAny),
+			"device": optional(typeString),
+		},
+		"modbus-read-point": {
+			"point": required(typeString), "with_units": optional(typeBool), "verbose": optional(typeBool),
+			"number_as_string": optional(typeBool), "detect_wrap": optional(typeBool), "with_quality": optional(typeBool),
+			"format": optional(typeString), "device": optional(typeString),
+		},
+		"modbus-read-points": {
+			"points": required(typeArray), "with_units": optional(typeBool), "verbose": optional(typeBool),
+			"number_as_string": optional(typeBool), "detect_wrap": optional(typeBool), "with_quality": optional(typeBool),
+			"format": optional(typeString), "device": optional(typeString),
+		},
+		"modbus-write-point": {
+			"point": required(typeString), "value": required(typeAny), "guard": optional(typeAny),
+			"command_word": optional(typeUint16), "device": optional(typeString),
+		},
+		"modbus-set-bit":           {"address": required(typeUint16), "bit": required(typeInt), "value": required(typeUint16)},
+		"modbus-compare-and-write": {"address": required(typeUint16), "expected": required(typeNumber), "value": required(typeNumber)},
+		"modbus-write-bits":        {"address": required(typeUint16), "bits": required(typeAny)},
+		"modbus-read-extended":     {"address": required(typeInt), "quantity": required(typeUint16)},
+		"modbus-wait-for": {
+			"address": required(typeUint16), "value": required(typeUint16), "mask": optional(typeUint16),
+			"interval": optional(typeString), "timeout": optional(typeString), "input": optional(typeBool),
+		},
+		"modbus-read-struct": {"address": required(typeUint16), "fields": required(typeArray), "input": optional(typeBool)},
+		"modbus-scan": {
+			"from": optional(typeByte), "to": optional(typeByte), "address": optional(typeUint16), "timeout": optional(typeString),
+			"delay_ms": optional(typeInt), "timeout_ms": optional(typeInt), "max_found": optional(typeInt),
+		},
+		"modbus-read-device-identification": {},
+		"modbus-canopen":                    {"data": required(typeString), "mei_type": optional(typeInt), "response_length": optional(typeInt)},
+		"modbus-stats":                      {"reset": optional(typeBool)},
+		"modbus-subscribe":                  {"method": optional(typeString), "interval": optional(typeString)},
+		"modbus-subscribe-cancel":           {"process_id": required(typeString)},
+		"modbus-subscriptions":              {},
+		"modbus-health":                     {},
+		"modbus-slave-status":               {},
+		"modbus-serial-config": {
+			"baud_rate": optional(typeInt), "data_bits": optional(typeInt), "parity": optional(typeString),
+			"stop_bits": optional(typeInt),
+		},
+		"modbus-config-dump": {},
+		"modbus-read-multi": {
+			"items": required(typeArray), "workers": optional(typeInt),
+			"retry_budget": optional(typeInt), "retry_budget_time": optional(typeString),
+		},
+		"modbus-debug-call": {"method": required(typeString), "params": optional(typeAny)},
+		"modbus-benchmark": {
+			"mode": optional(typeString), "count": optional(typeInt), "address": optional(typeUint16),
+			"timeout": optional(typeString),
+		},
+		"modbus-read-polled": {"points": optional(typeArray), "timestamp_format": optional(typeString)},
+		"modbus-read-all":    {"number_as_string": optional(typeBool), "format": optional(typeString)},
+		"modbus-detect-endianness": {
+			"address": required(typeUint16), "value": required(typeNumber), "input": optional(typeBool),
+		},
+		"modbus-read-clock": {
+			"point": optional(typeString), "device": optional(typeString), "address": optional(typeUint16), "input": optional(typeBool),
+			"layout": optional(typeString), "tz_offset": optional(typeString), "timestamp_format": optional(typeString),
+		},
+	}
+)
+
+// checkType returns error message if value of k param has wrong type
+func checkType(params objx.Map, k, typ string) string {
+	v := params.Get(k)
+
+	switch typ {
+	case typeUint16:
+		if _, err := getUint16(params, k); err != nil {
+			return "should be uint16"
+		}
+	case typeByte:
+		if n, err := getInt64(params, k); err != nil || !(minByte <= n && n <= maxByte) {
+			return "should be byte"
+		}
+	case typeInt:
+		if _, err := getInt64(params, k); err != nil {
+			return "should be int"
+		}
+	case typeNumber:
+		if _, err := getFloat64(params, k, 0); err != nil {
+			return "should be number"
+		}
+	case typeBool:
+		if !v.IsBool() {
+			return "should be bool"
+		}
+	case typeString:
+		if !v.IsStr() {
+			return "should be string"
+		}
+	case typeArray:
+		if !v.IsInterSlice() {
+			return "should be array"
+		}
+	}
+
+	return ""
+}
+
+type paramError struct {
+	Param string `json:"param"`
+	Msg   string `json:"msg"`
+}
+
+func (sc schema) validate(params objx.Map, errs []paramError) []paramError {
+	for k, spec := range sc {
+		if params.Get(k).IsNil() {
+			if spec.required {
+				errs = append(errs, paramError{k, "required"})
+			}
+
+			continue
+		}
+
+		if msg := checkType(params, k, spec.typ); msg != "" {
+			errs = append(errs, paramError{k, msg})
+		}
+	}
+
+	return errs
+}
+
+// validateParams checks params of method by its schema
+// and returns all failed params at once
+func validateParams(method string, params objx.Map) error {
+	sc, ok := methodSchemas[method]
+	if !ok {
+		return nil
+	}
+
+	errs := commonSchema.validate(params, nil)
+	errs = sc.validate(params, errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// map order is random
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Param < errs[j].Param })
+
+	return jsonrpc.ErrInvalidParams.AddData("msg", "invalid params").AddData("errors", errs)
+}
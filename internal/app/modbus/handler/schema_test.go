@@ -0,0 +1,66 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+func TestValidateParams(t *testing.T) {
+	m := &mockSlave{}
+
+	_, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"quantity": "x", "slave_id": num("300"), "verbose": "yes"},
+	})
+
+	// all failed params are reported at once sorted by name (data of jsonrpc error is private)
+	desc := fmt.Sprintf("%#v", err)
+
+	var last int
+	for _, e := range []paramError{
+		{"address", "required"},
+		{"quantity", "should be uint16"},
+		{"slave_id", "should be byte"},
+		{"verbose", "should be bool"},
+	} {
+		i := strings.Index(desc, fmt.Sprintf("%#v", e))
+		if i < last {
+			t.Errorf("expected %+v after previous errors in %s", e, desc)
+		}
+
+		last = i
+	}
+
+	if len(m.pdus) != 0 {
+		t.Error("invalid request shouldn't be sent")
+	}
+
+	// valid params pass schema
+	if _, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		Params: objx.Map{"address": num("0"), "quantity": "1", "slave_id": num("255"), "verbose": false},
+	}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
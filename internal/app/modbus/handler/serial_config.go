@@ -0,0 +1,146 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/serial"
+)
+
+var errNotSerial = jsonrpc.ErrInvalidRequest.AddData("msg", "transport of slave isn't serial (rtu or ascii)")
+
+// lineConfigurer is implemented by serial transporters
+type lineConfigurer interface {
+	LineConfig() serial.Config
+	SetLineConfig(serial.Config) error
+}
+
+// serialConfigResult is line configuration of serial port
+// (zero values of port config are reported as serial defaults)
+type serialConfigResult struct {
+	BaudRate int    `json:"baud_rate"`
+	DataBits int    `json:"data_bits"`
+	Parity   string `json:"parity"`
+	StopBits int    `json:"stop_bits"`
+}
+
+func serialConfigOf(c serial.Config) serialConfigResult {
+	res := serialConfigResult{BaudRate: c.BaudRate, DataBits: c.DataBits, Parity: c.Parity, StopBits: c.StopBits}
+
+	if res.BaudRate == 0 {
+		res.BaudRate = 19200
+	}
+
+	if res.DataBits == 0 {
+		res.DataBits = 8
+	}
+
+	if res.Parity == "" {
+		res.Parity = "E"
+	}
+
+	if res.StopBits == 0 {
+		res.StopBits = 1
+	}
+
+	return res
+}
+
+// getLineConfig returns config c changed by baud_rate, data_bits, parity and stop_bits params
+// it reports whether any of them is passed
+func getLineConfig(params objx.Map, c serial.Config) (serial.Config, bool, error) {
+	changed := false
+
+	for _, p := range []struct {
+		k        string
+		v        *int
+		min, max int64
+	}{
+		{"baud_rate", &c.BaudRate, 50, 4000000},
+		{"data_bits", &c.DataBits, 5, 8},
+		{"stop_bits", &c.StopBits, 1, 2},
+	} {
+		if params.Get(p.k).IsNil() {
+			continue
+		}
+
+		v, err := getInt64(params, p.k)
+		if err != nil {
+			return c, false, err
+		}
+
+		if v < p.min || v > p.max {
+			return c, false, jsonrpc.ErrInvalidParams.AddData("msg", p.k+" should be in range "+
+				strconv.FormatInt(p.min, 10)+"-"+strconv.FormatInt(p.max, 10)).AddData("v", v)
+		}
+
+		*p.v = int(v)
+		changed = true
+	}
+
+	if !params.Get("parity").IsNil() {
+		switch parity := params.Get("parity").Str(); parity {
+		case "N", "E", "O":
+			c.Parity = parity
+			changed = true
+		default:
+			return c, false, jsonrpc.ErrInvalidParams.AddData("msg", "parity should be N, E or O").AddData("v", parity)
+		}
+	}
+
+	return c, changed, nil
+}
+
+// serialConfig changes line configuration of serial port of slave (baud_rate, data_bits,
+// parity and stop_bits params) and returns applied one, without params it returns current one
+// port is reopened after transaction in progress, other transactions wait for it
+func (s Service) serialConfig(params objx.Map) (interface{}, error) {
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	t, bus := s.connection(slaveID)
+
+	port, ok := t.(lineConfigurer)
+	if !ok {
+		return nil, errNotSerial
+	}
+
+	c, changed, err := getLineConfig(params, port.LineConfig())
+	if err != nil || !changed {
+		return serialConfigOf(port.LineConfig()), err
+	}
+
+	if bus != nil {
+		if err := bus.acquire(); err != nil {
+			return nil, err
+		}
+		defer bus.release()
+	}
+
+	if err := port.SetLineConfig(c); err != nil {
+		return nil, jsonrpc.ErrServer.AddData("msg", "serial port can't be opened with this config").
+			AddData("err", err.Error()).SetCode(-32098)
+	}
+
+	return serialConfigOf(port.LineConfig()), nil
+}
@@ -0,0 +1,121 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+type setBitResult struct {
+	// register value before and after write
+	Previous uint16 `json:"previous"`
+	Value    uint16 `json:"value"`
+}
+
+// withBusHeld returns service which doesn't take bus lock of slaveID
+// (caller should hold it)
+func (s Service) withBusHeld(slaveID byte) Service {
+	parallel := make(map[byte]bool, len(s.parallel)+1)
+	for k, v := range s.parallel {
+		parallel[k] = v
+	}
+
+	parallel[slaveID] = true
+	s.parallel = parallel
+
+	return s
+}
+
+// setBit sets one bit of holding register by read-modify-write (FC3 + FC6)
+// for slaves without mask write support
+//
+// read and write go under the same bus lock (and register lock if enabled),
+// so it's atomic for calls of this service but NOT for other masters on the bus
+// (they can write register between read and write)
+func (s Service) setBit(params objx.Map) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	bit, err := getInt64(params, "bit")
+	if err != nil {
+		return nil, err
+	}
+
+	if !(0 <= bit && bit <= 15) {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "bit should be in range 0-15")
+	}
+
+	value, err := getUint16(params, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	if value > 1 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "bad value. only 0 or 1 allowed").
+			AddData("v", value)
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// register lock is taken before bus lock, so waiting for it doesn't block the bus
+	unlock := s.registerLocks.lock(slaveID, addr)
+	defer unlock()
+
+	if _, bus := s.connection(slaveID); bus != nil {
+		if err := bus.acquire(); err != nil {
+			return nil, err
+		}
+		defer bus.release()
+
+		s = s.withBusHeld(slaveID)
+	}
+
+	cli := s.getClient(slaveID)
+
+	// cache is bypassed, register should be fresh
+	res, err := cli.ReadHoldingRegisters(addr, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) != 2 {
+		return nil, truncatedErr(2, len(res))
+	}
+
+	result := setBitResult{Previous: parseResult(res)[0]}
+
+	result.Value = result.Previous &^ (1 << uint(bit))
+	if value == 1 {
+		result.Value |= 1 << uint(bit)
+	}
+
+	if _, err := cli.WriteSingleRegister(addr, result.Value); err != nil {
+		return nil, err
+	}
+
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, 1)
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// shrinkResult wraps read result if shrink_quantity param set
+type shrinkResult struct {
+	Result interface{} `json:"result"`
+	// true if block was read partially (its end is out of slave range)
+	Truncated bool `json:"truncated"`
+	// count of read registers
+	Quantity uint16 `json:"quantity"`
+}
+
+func isIllegalAddress(err error) bool {
+	var mbErr *modbus.ModbusError
+	return errors.As(err, &mbErr) && mbErr.ExceptionCode == modbus.ExceptionCodeIllegalDataAddress
+}
+
+// readShrinking reads registers, on illegal data address exception it binary searches
+// the largest readable quantity from addr (in whole values of size registers)
+// and returns its data, so result can be shorter than quantity
+// (it's error only if nothing is readable)
+func (s Service) readShrinking(slaveID, function byte, addr, quantity uint16, size int) ([]byte, error) {
+	res, err := s.readBlock(slaveID, function, addr, quantity)
+	if !isIllegalAddress(err) || int(quantity) <= size {
+		return res, err
+	}
+
+	// lo values are readable, hi aren't
+	lo, hi := 0, int(quantity)/size
+
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+
+		b, rerr := s.readBlock(slaveID, function, addr, uint16(mid*size))
+
+		switch {
+		case rerr == nil:
+			lo, res = mid, b
+		case isIllegalAddress(rerr):
+			hi = mid
+		default:
+			return nil, rerr
+		}
+	}
+
+	if lo == 0 {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// withShrink wraps result if shrink_quantity param set
+func withShrink(params objx.Map, res interface{}, requested uint16, data []byte) interface{} {
+	if !params.Get("shrink_quantity").Bool() {
+		return res
+	}
+
+	quantity := uint16(len(data) / 2)
+
+	return shrinkResult{Result: res, Truncated: quantity < requested, Quantity: quantity}
+}
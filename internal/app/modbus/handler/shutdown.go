@@ -0,0 +1,106 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+var errShutdown = errors.New("modbus: service is shut down")
+
+// lifecycle tracks calls in progress to drain them on shutdown
+type lifecycle struct {
+	mx       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+// enter registers new call, it returns false after shutdown
+func (l *lifecycle) enter() bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.closed {
+		return false
+	}
+
+	l.inflight.Add(1)
+
+	return true
+}
+
+func (l *lifecycle) leave() {
+	l.inflight.Done()
+}
+
+// Shutdown stops accepting new calls, waits for calls in progress
+// (so transactions on the bus are not cut) and closes connections
+// if ctx is done before calls complete connections are closed anyway
+// and ctx error is returned
+func (s Service) Shutdown(ctx context.Context) error {
+	s.life.mx.Lock()
+	s.life.closed = true
+	s.life.mx.Unlock()
+
+	drained := make(chan struct{})
+
+	go func() {
+		s.life.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	closed := make(map[io.Closer]bool)
+
+	closers := []interface{}{s.transport}
+	for _, c := range s.connections {
+		closers = append(closers, c.transport)
+	}
+
+	for _, c := range s.framingConnections {
+		closers = append(closers, c.transport)
+	}
+
+	for _, c := range s.deviceConnections {
+		closers = append(closers, c.transport)
+	}
+
+	for _, t := range closers {
+		c, ok := t.(io.Closer)
+		if !ok || closed[c] {
+			continue
+		}
+
+		closed[c] = true
+
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,97 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// encSignRegister is unsigned magnitude with sign in separate register
+// (sign_address param, nonzero sign register means negative values)
+const encSignRegister = "sign_register"
+
+// readSignRegister reads magnitude registers and sign register in one transaction
+// (block from lower to higher address) and returns signed values
+func (s Service) readSignRegister(params objx.Map, function byte) (interface{}, error) {
+	for _, k := range []string{"verbose", "chunked", "keyed", "last_good", "null_value"} {
+		if !params.Get(k).IsNil() {
+			return nil, conflictErr("encoding", k)
+		}
+	}
+
+	addr, quantity, err := getAddrAndQuantity(params)
+	if err != nil {
+		return nil, err
+	}
+
+	signAddr, err := getUint16(params, "sign_address")
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	coerce, err := getCoercion(params, encSignRegister)
+	if err != nil {
+		return nil, err
+	}
+
+	cal, err := getCalibration(params, encSignRegister)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := int(addr), int(addr)+int(quantity)-1
+	if int(signAddr) < from {
+		from = int(signAddr)
+	}
+
+	if int(signAddr) > to {
+		to = int(signAddr)
+	}
+
+	if to-from+1 > maxReadRegisters {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "sign_address and address should be within 125 registers").
+			AddData("params", []string{"address", "sign_address"})
+	}
+
+	res, err := s.readBlock(slaveID, function, uint16(from), uint16(to-from+1))
+	if err != nil {
+		return nil, err
+	}
+
+	sign := int32(1)
+	if binary.BigEndian.Uint16(res[(int(signAddr)-from)*2:]) != 0 {
+		sign = -1
+	}
+
+	values := make([]interface{}, quantity)
+
+	for i := range values {
+		magnitude := binary.BigEndian.Uint16(res[(int(addr)-from+i)*2:])
+		values[i] = cal.apply(sign * int32(magnitude))
+	}
+
+	return coerce.values(values), nil
+}
@@ -0,0 +1,572 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// simBankSize is size of each table of simulator (whole address space)
+const simBankSize = 1 << 16
+
+// limits of bits quantity by modbus spec
+const (
+	simMaxReadBits  = 2000
+	simMaxWriteBits = 1968
+)
+
+const (
+	// max length of comm event log
+	simEventLogSize = 64
+	// comm events of received message and sent response
+	simReceiveEvent = 0x80
+	simSendEvent    = 0x40
+
+	// conformity level of simulator: regular identification, stream and individual access
+	simDeviceIDLevel = 0x82
+	// read device id code of individual access
+	simDeviceIDIndividual = 0x04
+)
+
+// SimRegister is initial value of simulator coil or register
+// register with period follows sine wave value + amplitude * sin(2π t / period)
+// (writes to it are overridden by wave)
+type SimRegister struct {
+	// coil, discrete, input or holding
+	Function string `mapstructure:"function"`
+	Address  uint16 `mapstructure:"address"`
+	// 0 or 1 for coils, -32768-65535 for registers (negative values are int16)
+	Value     int64         `mapstructure:"value"`
+	Amplitude float64       `mapstructure:"amplitude"`
+	Period    time.Duration `mapstructure:"period"`
+}
+
+func (r SimRegister) validate() error {
+	switch r.Function {
+	case pointCoil, pointDiscrete:
+		if r.Value != 0 && r.Value != 1 {
+			return errors.New("value of bit should be 0 or 1")
+		}
+
+		if r.Period != 0 {
+			return errors.New("period can be used with registers only")
+		}
+	case pointInput, pointHolding:
+		if r.Value < minInt16 || r.Value > maxUint16 {
+			return errors.New("value of register should be -32768-65535")
+		}
+	default:
+		return errors.New("function should be coil, discrete, input or holding")
+	}
+
+	if r.Period < 0 || r.Amplitude != 0 && r.Period == 0 {
+		return errors.New("amplitude requires positive period")
+	}
+
+	return nil
+}
+
+// ValidateSimRegisters checks initial values of simulator
+func ValidateSimRegisters(regs []SimRegister) error {
+	for i, r := range regs {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("register %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// simDeviceID is device identification objects of simulator (basic and regular categories)
+var simDeviceID = map[byte]string{ // nolint: gochecknoglobals
+	0x00: "Rightech",
+	0x01: "ric-edge",
+	0x02: "1.0",
+	0x04: "modbus simulator",
+}
+
+// Simulator is in-memory modbus tcp slave for demos and development without hardware
+// all slave ids share its tables, writes are readable back
+// zero value is ready to use slave with all tables cleared
+type Simulator struct {
+	mx sync.Mutex
+
+	coils     [simBankSize]bool
+	discretes [simBankSize]bool
+	inputs    [simBankSize]uint16
+	holding   [simBankSize]uint16
+
+	// file records (FC20, FC21) by file number, created on first write
+	files map[uint16][]uint16
+	// requests received since last clear counters diagnostics
+	messages uint16
+	// successful requests since last clear counters (comm event counter)
+	events uint16
+	// comm events (FC12), most recent first
+	eventLog []byte
+
+	// registers with sine wave
+	waves map[*uint16]SimRegister
+	start time.Time
+}
+
+// NewSimulator creates simulator with initial values
+// regs should be checked by ValidateSimRegisters before
+func NewSimulator(regs ...SimRegister) *Simulator {
+	s := &Simulator{
+		waves: make(map[*uint16]SimRegister),
+		start: time.Now(),
+	}
+
+	for _, r := range regs {
+		switch r.Function {
+		case pointCoil:
+			s.coils[r.Address] = r.Value == 1
+		case pointDiscrete:
+			s.discretes[r.Address] = r.Value == 1
+		case pointInput, pointHolding:
+			bank := s.holding[:]
+			if r.Function == pointInput {
+				bank = s.inputs[:]
+			}
+
+			bank[r.Address] = uint16(r.Value)
+
+			if r.Period > 0 {
+				s.waves[&bank[r.Address]] = r
+			}
+		}
+	}
+
+	return s
+}
+
+// Send handles modbus tcp request frame
+func (s *Simulator) Send(adu []byte) ([]byte, error) {
+	if len(adu) < 8 {
+		return nil, fmt.Errorf("modbus: request length '%v' is too short", len(adu))
+	}
+
+	pdu := adu[7:]
+
+	s.mx.Lock()
+	s.messages++
+	s.updateWaves()
+	res, exception := s.handle(pdu[0], pdu[1:])
+
+	// event counter skips exceptions and its own polls
+	if exception == 0 && pdu[0] != modbus.FuncCodeGetCommEventCounter &&
+		pdu[0] != modbus.FuncCodeGetCommEventLog {
+		s.events++
+	}
+
+	s.logEvents(simReceiveEvent, simExceptionEvent(exception))
+	s.mx.Unlock()
+
+	return simResponse(adu, res, exception), nil
+}
+
+// simExceptionEvent returns send event with bit of exception code
+func simExceptionEvent(exception byte) byte {
+	switch exception {
+	case 0:
+		return simSendEvent
+	case modbus.ExceptionCodeIllegalFunction, modbus.ExceptionCodeIllegalDataAddress,
+		modbus.ExceptionCodeIllegalDataValue:
+		return simSendEvent | 0x01
+	case modbus.ExceptionCodeServerDeviceFailure:
+		return simSendEvent | 0x02
+	case modbus.ExceptionCodeAcknowledge, modbus.ExceptionCodeServerDeviceBusy:
+		return simSendEvent | 0x04
+	default:
+		return simSendEvent | 0x08
+	}
+}
+
+// logEvents adds events to comm event log (in order of occurrence)
+func (s *Simulator) logEvents(events ...byte) {
+	for _, e := range events {
+		s.eventLog = append([]byte{e}, s.eventLog...)
+	}
+
+	if len(s.eventLog) > simEventLogSize {
+		s.eventLog = s.eventLog[:simEventLogSize]
+	}
+}
+
+// simResponse builds tcp response frame to request adu of response pdu or exception code
+func simResponse(adu, res []byte, exception byte) []byte {
+	if exception != 0 {
+		res = []byte{adu[7] | 0x80, exception}
+	}
+
+	header := make([]byte, 7, 7+len(res))
+	copy(header, adu[:7])
+	binary.BigEndian.PutUint16(header[4:], uint16(len(res)+1))
+
+	return append(header, res...)
+}
+
+// updateWaves sets registers with sine wave to current value
+func (s *Simulator) updateWaves() {
+	t := time.Since(s.start).Seconds()
+
+	for reg, r := range s.waves {
+		v := float64(r.Value) + r.Amplitude*math.Sin(2*math.Pi*t/r.Period.Seconds())
+		*reg = uint16(int64(math.Round(v)))
+	}
+}
+
+func simRange(addr, quantity uint16, max int) byte {
+	switch {
+	case quantity == 0 || int(quantity) > max:
+		return modbus.ExceptionCodeIllegalDataValue
+	case int(addr)+int(quantity) > simBankSize:
+		return modbus.ExceptionCodeIllegalDataAddress
+	default:
+		return 0
+	}
+}
+
+func simBits(bits []bool) []byte {
+	res := make([]byte, (len(bits)+7)/8)
+
+	for i, v := range bits {
+		if v {
+			res[i/8] |= 1 << (uint(i) % 8)
+		}
+	}
+
+	return append([]byte{byte(len(res))}, res...)
+}
+
+func simRegisters(regs []uint16) []byte {
+	res := make([]byte, 1+len(regs)*2)
+	res[0] = byte(len(regs) * 2)
+
+	for i, v := range regs {
+		binary.BigEndian.PutUint16(res[1+i*2:], v)
+	}
+
+	return res
+}
+
+// handle returns response pdu or exception code, caller must hold the mutex
+func (s *Simulator) handle(fc byte, data []byte) ([]byte, byte) {
+	switch fc {
+	case modbus.FuncCodeReadFileRecord:
+		return s.readFileRecord(data)
+	case modbus.FuncCodeWriteFileRecord:
+		return s.writeFileRecord(data)
+	case modbus.FuncCodeEncapsulatedInterface:
+		return s.readDeviceID(data)
+	case modbus.FuncCodeGetCommEventCounter:
+		res := []byte{fc, 0, 0, 0, 0}
+		binary.BigEndian.PutUint16(res[3:], s.events)
+
+		return res, 0
+	case modbus.FuncCodeGetCommEventLog:
+		res := make([]byte, 8, 8+len(s.eventLog))
+		res[0], res[1] = fc, byte(6+len(s.eventLog))
+		binary.BigEndian.PutUint16(res[4:], s.events)
+		binary.BigEndian.PutUint16(res[6:], s.messages)
+
+		return append(res, s.eventLog...), 0
+	}
+
+	if len(data) < 4 {
+		return nil, modbus.ExceptionCodeIllegalDataValue
+	}
+
+	addr := binary.BigEndian.Uint16(data)
+	value := binary.BigEndian.Uint16(data[2:])
+
+	switch fc {
+	case modbus.FuncCodeReadCoils, modbus.FuncCodeReadDiscreteInputs:
+		if e := simRange(addr, value, simMaxReadBits); e != 0 {
+			return nil, e
+		}
+
+		bank := s.coils[:]
+		if fc == modbus.FuncCodeReadDiscreteInputs {
+			bank = s.discretes[:]
+		}
+
+		return append([]byte{fc}, simBits(bank[addr:int(addr)+int(value)])...), 0
+	case modbus.FuncCodeReadInputRegisters, modbus.FuncCodeReadHoldingRegisters:
+		if e := simRange(addr, value, maxReadRegisters); e != 0 {
+			return nil, e
+		}
+
+		bank := s.holding[:]
+		if fc == modbus.FuncCodeReadInputRegisters {
+			bank = s.inputs[:]
+		}
+
+		return append([]byte{fc}, simRegisters(bank[addr:int(addr)+int(value)])...), 0
+	case modbus.FuncCodeWriteSingleCoil:
+		if value != 0 && value != modbusTrueValue {
+			return nil, modbus.ExceptionCodeIllegalDataValue
+		}
+
+		s.coils[addr] = value == modbusTrueValue
+
+		return append([]byte{fc}, data[:4]...), 0
+	case modbus.FuncCodeWriteSingleRegister:
+		s.holding[addr] = value
+
+		return append([]byte{fc}, data[:4]...), 0
+	case modbus.FuncCodeWriteMultipleCoils:
+		if e := simRange(addr, value, simMaxWriteBits); e != 0 {
+			return nil, e
+		}
+
+		if len(data) < 5+(int(value)+7)/8 {
+			return nil, modbus.ExceptionCodeIllegalDataValue
+		}
+
+		for i := 0; i < int(value); i++ {
+			s.coils[int(addr)+i] = data[5+i/8]>>(uint(i)%8)&1 == 1
+		}
+
+		return append([]byte{fc}, data[:4]...), 0
+	case modbus.FuncCodeWriteMultipleRegisters:
+		if e := simRange(addr, value, maxWriteRegisters); e != 0 {
+			return nil, e
+		}
+
+		if len(data) < 5+int(value)*2 {
+			return nil, modbus.ExceptionCodeIllegalDataValue
+		}
+
+		for i := 0; i < int(value); i++ {
+			s.holding[int(addr)+i] = binary.BigEndian.Uint16(data[5+i*2:])
+		}
+
+		return append([]byte{fc}, data[:4]...), 0
+	case modbus.FuncCodeReadWriteMultipleRegisters:
+		return s.readWriteRegisters(addr, value, data)
+	case modbus.FuncCodeMaskWriteRegister:
+		if len(data) < 6 {
+			return nil, modbus.ExceptionCodeIllegalDataValue
+		}
+
+		andMask, orMask := value, binary.BigEndian.Uint16(data[4:])
+		s.holding[addr] = s.holding[addr]&andMask | orMask&^andMask
+
+		return append([]byte{fc}, data[:6]...), 0
+	case modbus.FuncCodeDiagnostics:
+		// loopback, clear counters and bus message count sub-functions are supported
+		switch addr {
+		case diagReturnQueryData:
+		case diagClearCounters:
+			s.messages, s.events = 0, 0
+		case diagReturnBusMessageCount:
+			res := append([]byte{fc}, data[:4]...)
+			binary.BigEndian.PutUint16(res[3:], s.messages)
+
+			return res, 0
+		default:
+			return nil, modbus.ExceptionCodeIllegalFunction
+		}
+
+		return append([]byte{fc}, data...), 0
+	default:
+		return nil, modbus.ExceptionCodeIllegalFunction
+	}
+}
+
+// readWriteRegisters handles read/write multiple registers request, write is done before read
+func (s *Simulator) readWriteRegisters(addr, quantity uint16, data []byte) ([]byte, byte) {
+	if len(data) < 9 {
+		return nil, modbus.ExceptionCodeIllegalDataValue
+	}
+
+	writeAddr := binary.BigEndian.Uint16(data[4:])
+	writeQuantity := binary.BigEndian.Uint16(data[6:])
+
+	if e := simRange(addr, quantity, maxReadRegisters); e != 0 {
+		return nil, e
+	}
+
+	if e := simRange(writeAddr, writeQuantity, maxReadWriteRegisters); e != 0 {
+		return nil, e
+	}
+
+	if int(data[8]) != int(writeQuantity)*2 || len(data) < 9+int(data[8]) {
+		return nil, modbus.ExceptionCodeIllegalDataValue
+	}
+
+	for i := 0; i < int(writeQuantity); i++ {
+		s.holding[int(writeAddr)+i] = binary.BigEndian.Uint16(data[9+i*2:])
+	}
+
+	return append([]byte{modbus.FuncCodeReadWriteMultipleRegisters},
+		simRegisters(s.holding[addr:int(addr)+int(quantity)])...), 0
+}
+
+// file returns records of file, file is created on first access
+func (s *Simulator) file(number uint16) []uint16 {
+	if s.files == nil {
+		s.files = make(map[uint16][]uint16)
+	}
+
+	if _, ok := s.files[number]; !ok {
+		s.files[number] = make([]uint16, fileRecordMaxNumber+1)
+	}
+
+	return s.files[number]
+}
+
+// simFileBlock parses file number, record number and record length of file record sub-request
+func simFileBlock(sub []byte) (file uint16, record, length int, exception byte) {
+	if len(sub) < fileSubRequestSize {
+		return 0, 0, 0, modbus.ExceptionCodeIllegalDataValue
+	}
+
+	file = binary.BigEndian.Uint16(sub[1:])
+	record = int(binary.BigEndian.Uint16(sub[3:]))
+	length = int(binary.BigEndian.Uint16(sub[5:]))
+
+	if sub[0] != fileRecordReferenceType || file == 0 || length == 0 ||
+		record+length > fileRecordMaxNumber+1 {
+		return 0, 0, 0, modbus.ExceptionCodeIllegalDataAddress
+	}
+
+	return file, record, length, 0
+}
+
+// simFileByteCount checks byte count of file record request
+func simFileByteCount(data []byte) byte {
+	if len(data) < 1 || int(data[0]) != len(data)-1 ||
+		data[0] < fileSubRequestSize || data[0] > fileRecordMaxBytes {
+		return modbus.ExceptionCodeIllegalDataValue
+	}
+
+	return 0
+}
+
+// readFileRecord handles read file record request of any number of sub-requests
+func (s *Simulator) readFileRecord(data []byte) ([]byte, byte) {
+	if e := simFileByteCount(data); e != 0 {
+		return nil, e
+	}
+
+	if (len(data)-1)%fileSubRequestSize != 0 {
+		return nil, modbus.ExceptionCodeIllegalDataValue
+	}
+
+	res := []byte{modbus.FuncCodeReadFileRecord, 0}
+
+	for sub := data[1:]; len(sub) > 0; sub = sub[fileSubRequestSize:] {
+		file, record, length, e := simFileBlock(sub)
+		if e != 0 {
+			return nil, e
+		}
+
+		if len(res)+2+length*2 > 2+fileRecordMaxBytes {
+			return nil, modbus.ExceptionCodeIllegalDataValue
+		}
+
+		res = append(res, byte(1+length*2), fileRecordReferenceType)
+		res = append(res, simRegisters(s.file(file)[record : record+length])[1:]...)
+	}
+
+	res[1] = byte(len(res) - 2)
+
+	return res, 0
+}
+
+// writeFileRecord handles write file record request, response is echo of request
+func (s *Simulator) writeFileRecord(data []byte) ([]byte, byte) {
+	if e := simFileByteCount(data); e != 0 {
+		return nil, e
+	}
+
+	for sub := data[1:]; len(sub) > 0; {
+		file, record, length, e := simFileBlock(sub)
+		if e != 0 {
+			return nil, e
+		}
+
+		if len(sub) < fileSubRequestSize+length*2 {
+			return nil, modbus.ExceptionCodeIllegalDataValue
+		}
+
+		records := s.file(file)
+		for i := 0; i < length; i++ {
+			records[record+i] = binary.BigEndian.Uint16(sub[fileSubRequestSize+i*2:])
+		}
+
+		sub = sub[fileSubRequestSize+length*2:]
+	}
+
+	return append([]byte{modbus.FuncCodeWriteFileRecord}, data...), 0
+}
+
+// readDeviceID handles read device identification request,
+// all objects of category fit into one response
+func (s *Simulator) readDeviceID(data []byte) ([]byte, byte) {
+	if len(data) < 3 || data[0] != meiReadDeviceID {
+		return nil, modbus.ExceptionCodeIllegalFunction
+	}
+
+	code, id := data[1], data[2]
+	res := []byte{modbus.FuncCodeEncapsulatedInterface, meiReadDeviceID, code, simDeviceIDLevel, 0, 0, 0}
+
+	object := func(id byte) {
+		v := simDeviceID[id]
+		res = append(append(res, id, byte(len(v))), v...)
+		res[6]++
+	}
+
+	switch code {
+	case deviceIDBasic, deviceIDRegular, deviceIDExtended:
+		first, last := int(deviceIDFirstObject[code]), 0xFF
+		if code < deviceIDExtended {
+			last = int(deviceIDFirstObject[code+1]) - 1
+		}
+
+		// unknown object id restarts stream from the first object of category
+		if _, ok := simDeviceID[id]; !ok || int(id) < first || int(id) > last {
+			id = byte(first)
+		}
+
+		for i := int(id); i <= last; i++ {
+			if _, ok := simDeviceID[byte(i)]; ok {
+				object(byte(i))
+			}
+		}
+	case simDeviceIDIndividual:
+		if _, ok := simDeviceID[id]; !ok {
+			return nil, modbus.ExceptionCodeIllegalDataAddress
+		}
+
+		object(id)
+	default:
+		return nil, modbus.ExceptionCodeIllegalDataValue
+	}
+
+	return res, 0
+}
@@ -0,0 +1,80 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// maxSlaveID is the last valid slave address (248-255 are reserved)
+const maxSlaveID = 247
+
+// broadcastFunctions contains functions which can be broadcast (slave_id 0),
+// slaves don't answer broadcast so functions which read can't use it
+var broadcastFunctions = map[byte]bool{ // nolint: gochecknoglobals
+	modbus.FuncCodeWriteSingleCoil:        true,
+	modbus.FuncCodeWriteMultipleCoils:     true,
+	modbus.FuncCodeWriteSingleRegister:    true,
+	modbus.FuncCodeWriteMultipleRegisters: true,
+	modbus.FuncCodeMaskWriteRegister:      true,
+	modbus.FuncCodeWriteFileRecord:        true,
+}
+
+// StrictSlaveIDs rejects requests to reserved slave addresses 248-255
+// and reads from broadcast address 0 (by default any byte is accepted)
+// note that modbus tcp devices often use unit id 0 or 255, it's meant for serial lines
+func StrictSlaveIDs(enabled bool) Option {
+	return func(s *Service) {
+		s.strictSlaveIDs = enabled
+	}
+}
+
+// checkSlaveID returns error if function can't be sent to slave address
+func checkSlaveID(slaveID, function byte) error {
+	switch {
+	case slaveID > maxSlaveID:
+		return jsonrpc.ErrInvalidParams.AddData("msg", "slave_id is reserved address, valid addresses are 1-247").
+			AddData("v", slaveID)
+	case slaveID == 0 && !broadcastFunctions[function]:
+		return jsonrpc.ErrInvalidParams.AddData("msg", "slave_id 0 is broadcast address, it can't be used by reads").
+			AddData("function", function)
+	default:
+		return nil
+	}
+}
+
+// slaveIDTransporter rejects frames to invalid slave addresses without sending
+// (it checks each frame so nested and raw requests are covered too)
+type slaveIDTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+	slaveID  byte
+}
+
+func (t slaveIDTransporter) Send(adu []byte) ([]byte, error) {
+	pdu, err := t.packager.Decode(adu)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSlaveID(t.slaveID, pdu.FunctionCode); err != nil {
+		return nil, err
+	}
+
+	return t.Transporter.Send(adu)
+}
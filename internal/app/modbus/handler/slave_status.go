@@ -0,0 +1,127 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// slaveError is the last failed transaction of slave
+type slaveError struct {
+	Time time.Time `json:"time"`
+	// error class of metrics (e.g. timeout or exception)
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// slaveStatus is health of slave (it's not cleared by stats reset)
+type slaveStatus struct {
+	LastSuccess *time.Time  `json:"last_success"`
+	LastError   *slaveError `json:"last_error"`
+	// failed transactions since the last success
+	ConsecutiveFailures uint64 `json:"consecutive_failures"`
+	// unit id of device (set by slaveStatus for devices)
+	UnitID int `json:"unit_id,omitempty"`
+}
+
+// recordStatus updates status of slave or device (if it's not empty) by transaction result
+// (err is nil for successful transactions)
+func (m *busMetrics) recordStatus(slaveID byte, device string, class string, err error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	var status *slaveStatus
+
+	if device != "" {
+		status = m.deviceStatus[device]
+		if status == nil {
+			status = &slaveStatus{}
+			m.deviceStatus[device] = status
+		}
+	} else {
+		status = m.status[slaveID]
+		if status == nil {
+			status = &slaveStatus{}
+			m.status[slaveID] = status
+		}
+	}
+
+	status.update(class, err)
+}
+
+// update sets last success or error by transaction result
+func (st *slaveStatus) update(class string, err error) {
+	now := time.Now()
+
+	if err == nil {
+		st.LastSuccess = &now
+		st.ConsecutiveFailures = 0
+
+		return
+	}
+
+	st.LastError = &slaveError{Time: now, Type: class, Message: err.Error()}
+	st.ConsecutiveFailures++
+}
+
+// exceptionErr returns error of exception response to request adu
+func exceptionErr(packager modbus.Packager, adu []byte, code byte) error {
+	e := &modbus.ModbusError{ExceptionCode: code}
+
+	if pdu, err := packager.Decode(adu); err == nil {
+		e.FunctionCode = pdu.FunctionCode
+	}
+
+	return e
+}
+
+// slaveStatus returns status of slaves which had transactions by slave id
+// and of devices by name (devices on different hosts may have the same unit id)
+// there is no circuit breaker in the handler, so failed slaves are still polled
+func (s Service) slaveStatus(objx.Map) (interface{}, error) {
+	res := make(map[string]slaveStatus)
+
+	// devices without transactions have empty status
+	for name, d := range s.devices {
+		res[name] = slaveStatus{UnitID: d.UnitID}
+	}
+
+	if s.metrics == nil {
+		return res, nil
+	}
+
+	s.metrics.mx.Lock()
+	defer s.metrics.mx.Unlock()
+
+	for id, status := range s.metrics.status {
+		res[strconv.Itoa(int(id))] = *status
+	}
+
+	for name, status := range s.metrics.deviceStatus {
+		v := *status
+		v.UnitID = s.devices[name].UnitID
+
+		res[name] = v
+	}
+
+	return res, nil
+}
@@ -0,0 +1,128 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// SlaveTransportConfig overrides transport settings of one slave
+// zero values mean global settings
+type SlaveTransportConfig struct {
+	// response timeout
+	Timeout time.Duration `mapstructure:"timeout"`
+	// timeout of establishing connection (tcp only)
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
+	// silent interval after transaction
+	FrameDelay time.Duration `mapstructure:"frame_delay"`
+	// retries of failed transactions (negative disables retries)
+	RetryAttempts int `mapstructure:"retry_attempts"`
+	// delay before first retry
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+}
+
+// SlaveTransport overrides transport settings for slaveID
+// it should be used after Retry and RetryExceptions options
+func SlaveTransport(slaveID byte, c SlaveTransportConfig) Option {
+	return func(s *Service) {
+		if s.slaveTransports == nil {
+			s.slaveTransports = make(map[byte]SlaveTransportConfig)
+		}
+
+		s.slaveTransports[slaveID] = c
+	}
+}
+
+// timeoutSender is implemented by transporters which support
+// per transaction timeout
+type timeoutSender interface {
+	SendTimeout(adu []byte, timeout time.Duration) ([]byte, error)
+}
+
+func isTimeoutSender(t modbus.Transporter) bool {
+	_, ok := t.(timeoutSender)
+	return ok
+}
+
+// connectTimeoutSender is implemented by transporters which support
+// per transaction connect timeout (zero timeouts mean defaults of transporter)
+type connectTimeoutSender interface {
+	SendTimeouts(adu []byte, connectTimeout, timeout time.Duration) ([]byte, error)
+}
+
+// ConnectTimeout sets timeout of establishing connection (tcp only)
+// separate from response timeout, so dead hosts fail fast but slow transactions don't
+// (0 means transport default which is its response timeout)
+func ConnectTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.connectTimeout = d
+	}
+}
+
+// timeoutTransporter sends with timeouts if transporter supports them
+// (zero timeout means default one)
+type timeoutTransporter struct {
+	modbus.Transporter
+	timeout        time.Duration
+	connectTimeout time.Duration
+}
+
+func (t timeoutTransporter) Send(adu []byte) ([]byte, error) {
+	if cs, ok := t.Transporter.(connectTimeoutSender); ok && t.connectTimeout > 0 {
+		return cs.SendTimeouts(adu, t.connectTimeout, t.timeout)
+	}
+
+	if ts, ok := t.Transporter.(timeoutSender); ok && t.timeout > 0 {
+		return ts.SendTimeout(adu, t.timeout)
+	}
+
+	return t.Transporter.Send(adu)
+}
+
+// slaveRetry returns retry policy of slave (nil if retries disabled)
+func (s Service) slaveRetry(c SlaveTransportConfig) *retryPolicy {
+	if c.RetryAttempts < 0 {
+		return nil
+	}
+
+	if c.RetryAttempts == 0 && c.RetryBackoff == 0 {
+		return s.retry
+	}
+
+	policy := retryPolicy{}
+	if s.retry != nil {
+		policy = *s.retry
+	} else {
+		policy.exceptions = defaultRetryExceptions()
+	}
+
+	if c.RetryAttempts > 0 {
+		policy.attempts = c.RetryAttempts
+	}
+
+	if c.RetryBackoff > 0 {
+		policy.backoff = c.RetryBackoff
+	}
+
+	if policy.attempts == 0 {
+		return nil
+	}
+
+	return &policy
+}
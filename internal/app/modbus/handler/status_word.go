@@ -0,0 +1,92 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"strconv"
+)
+
+// validateBitLabels checks that point is unsigned input or holding register
+// and labels are unique names of bits in its width
+func (p Point) validateBitLabels() error {
+	if p.Function != pointInput && p.Function != pointHolding {
+		return errors.New("bit_labels can be used with input or holding registers only")
+	}
+
+	if p.scaled() || len(p.Enum) > 0 || p.Transform != "" || len(p.Parts) > 0 {
+		return errors.New("bit_labels can't be used with scale, offset, enum, transform or parts")
+	}
+
+	width := 16
+
+	switch p.Encoding {
+	case "", encUint16:
+	case encUint32:
+		width = 32
+	default:
+		return errors.New("bit_labels can be used with uint16 or uint32 encoding only")
+	}
+
+	names := make(map[string]bool, len(p.BitLabels))
+
+	for k, name := range p.BitLabels {
+		bit, err := strconv.Atoi(k)
+		if err != nil || bit < 0 || bit >= width {
+			return errors.New("bit_labels keys should be bit numbers 0-" + strconv.Itoa(width-1) + " but " + k + " given")
+		}
+
+		if name == "" || names[name] {
+			return errors.New("bit_labels names should be unique and not empty")
+		}
+
+		names[name] = true
+	}
+
+	return nil
+}
+
+// statusWord returns states of value bits by label (or by index if bit has no label)
+func (p Point) statusWord(v interface{}) interface{} {
+	var (
+		word  uint64
+		width int
+	)
+
+	switch n := v.(type) {
+	case uint16:
+		word, width = uint64(n), 16
+	case uint32:
+		word, width = uint64(n), 32
+	default:
+		// null of lenient decoding
+		return v
+	}
+
+	res := make(map[string]bool, width)
+
+	for bit := 0; bit < width; bit++ {
+		k := strconv.Itoa(bit)
+		if label, ok := p.BitLabels[k]; ok {
+			k = label
+		}
+
+		res[k] = word&(1<<uint(bit)) != 0
+	}
+
+	return res
+}
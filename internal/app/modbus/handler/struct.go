@@ -0,0 +1,137 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+type structField struct {
+	name string
+	c    codec
+	// offset in registers from start address
+	offset int
+}
+
+// getStructFields parses fields param (array of {name, type})
+// and calculates offset of each field
+// it returns fields and total count of registers
+func (s Service) getStructFields(params objx.Map) ([]structField, int, error) {
+	items, err := getArray(params, "fields")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(items) == 0 {
+		return nil, 0, emptyErr("fields")
+	}
+
+	fields := make([]structField, 0, len(items))
+	names := make(map[string]bool, len(items))
+	offset := 0
+
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, 0, jsonrpc.ErrInvalidParams.AddData("msg", "fields should be array of objects")
+		}
+
+		field := objx.New(m)
+
+		name := field.Get("name").Str()
+		if name == "" || names[name] {
+			return nil, 0, jsonrpc.ErrInvalidParams.AddData("msg", "field name should be unique string").
+				AddData("v", name)
+		}
+
+		names[name] = true
+
+		// field can override orders of request
+		fp := objx.Map{
+			"encoding":   field.Get("type").Data(),
+			"byte_order": field.Get("byte_order").Str(params.Get("byte_order").Str(s.byteOrder)),
+			"word_order": field.Get("word_order").Str(params.Get("word_order").Str(s.wordOrder)),
+		}
+
+		c, err := s.getCodec(fp)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		fields = append(fields, structField{name: name, c: c, offset: offset})
+		offset += c.registers()
+	}
+
+	return fields, offset, nil
+}
+
+// readStruct reads registers block in one transaction
+// and decodes fields of different types at their offsets
+func (s Service) readStruct(params objx.Map) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	function := byte(modbus.FuncCodeReadHoldingRegisters)
+	if params.Get("input").Bool() {
+		function = modbus.FuncCodeReadInputRegisters
+	}
+
+	fields, quantity, err := s.getStructFields(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRange(addr, quantity); err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.readBlock(slaveID, function, addr, uint16(quantity))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(fields))
+
+	for _, f := range fields {
+		start := f.offset * 2
+
+		values, err := f.c.decode(res[start : start+f.c.registers()*2])
+		if err != nil {
+			return nil, err
+		}
+
+		// field of bytes encoding has high and low byte values
+		if len(values) > 1 {
+			result[f.name] = values
+			continue
+		}
+
+		result[f.name] = values[0]
+	}
+
+	return result, nil
+}
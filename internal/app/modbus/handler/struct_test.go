@@ -0,0 +1,125 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func TestReadStruct(t *testing.T) {
+	m := &mockSlave{}
+	copy(m.inputs[10:], []uint16{0xFFFF, 0x0000, 0x3FC0, 0x0001, 0x0000, 0x0102})
+
+	res, err := newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-struct",
+		Params: objx.Map{"address": num("10"), "input": true, "fields": []interface{}{
+			map[string]interface{}{"name": "status", "type": "int16"},
+			map[string]interface{}{"name": "level", "type": "float32", "word_order": "little"},
+			map[string]interface{}{"name": "total", "type": "uint32"},
+			map[string]interface{}{"name": "flags", "type": "bytes"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fields are read by one request
+	if len(m.pdus) != 1 {
+		t.Errorf("expected 1 request but got %d", len(m.pdus))
+	}
+
+	m.assertPDU(t, []byte{modbus.FuncCodeReadInputRegisters, 0x00, 0x0A, 0x00, 0x06})
+
+	expected := map[string]interface{}{
+		"status": int16(-1),
+		"level":  float32(1.5),
+		"total":  uint32(0x00010000),
+		"flags":  []interface{}{uint8(1), uint8(2)},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+
+	// field names should be unique
+	_, err = newMockService(m).Call(jsonrpc.Request{
+		Method: "modbus-read-struct",
+		Params: objx.Map{"address": num("10"), "fields": []interface{}{
+			map[string]interface{}{"name": "a", "type": "int16"},
+			map[string]interface{}{"name": "a", "type": "int16"},
+		}},
+	})
+	if err == nil {
+		t.Error("expected error of duplicate field")
+	}
+}
+
+// shortReadSlave drops last register (or byte of coils) of read responses
+// keeping them consistent (byte count matches data)
+type shortReadSlave struct {
+	*mockSlave
+}
+
+func (m shortReadSlave) Send(adu []byte) ([]byte, error) {
+	res, err := m.mockSlave.Send(adu)
+	if err != nil || res[7]&0x80 != 0 {
+		return res, err
+	}
+
+	drop := 2
+	if res[7] == modbus.FuncCodeReadCoils || res[7] == modbus.FuncCodeReadDiscreteInputs {
+		drop = 1
+	}
+
+	res = res[:len(res)-drop]
+	res[8] -= byte(drop)
+	binary.BigEndian.PutUint16(res[4:], uint16(len(res)-6))
+
+	return res, nil
+}
+
+func TestTruncatedRead(t *testing.T) {
+	slave := shortReadSlave{&mockSlave{}}
+	srv := New(slave, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	for _, params := range []objx.Map{
+		{"address": num("0"), "quantity": num("3")},
+		{"address": num("0"), "quantity": num("2"), "encoding": "float32"},
+	} {
+		_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", Params: params})
+
+		// data of jsonrpc error is private
+		desc := fmt.Sprintf("%#v", err)
+		if !strings.Contains(desc, "truncated response") || !strings.Contains(desc, `"expected":`) {
+			t.Errorf("%v: expected truncated response error but got %s", params, desc)
+		}
+	}
+
+	// coils are packed into bytes, so 17 coils need 3 bytes
+	_, err := srv.Call(jsonrpc.Request{Method: "modbus-read-coil", Params: objx.Map{"address": num("0"), "quantity": num("17")}})
+	if desc := fmt.Sprintf("%#v", err); !strings.Contains(desc, "truncated response") {
+		t.Errorf("expected truncated coils error but got %s", desc)
+	}
+}
@@ -0,0 +1,434 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+const (
+	defaultSubscriptionInterval = "1s"
+	// consecutive failed reads before backoff
+	subscriptionFailures = 3
+	// max delay of reads in backoff (unless interval is longer)
+	subscriptionMaxBackoff = 5 * time.Minute
+)
+
+// events of subscription sent as notifications besides values
+const (
+	eventBackoff   = "backoff"
+	eventRecovered = "recovered"
+)
+
+// subscriptionEvent is notification of subscription state change
+type subscriptionEvent struct {
+	Event    string         `json:"event"`
+	Error    *jsonrpc.Error `json:"error,omitempty"`
+	Failures int            `json:"failures,omitempty"`
+}
+
+// methods which can be subscribed
+var subscribeMethods = map[string]bool{ // nolint: gochecknoglobals
+	"modbus-read-coil":     true,
+	"modbus-read-discrete": true,
+	"modbus-read-input":    true,
+	"modbus-read-holding":  true,
+	"modbus-read-point":    true,
+	"modbus-read-points":   true,
+	"modbus-read-polled":   true,
+	"modbus-read-all":      true,
+}
+
+// SubscriptionDef is definition of subscription
+// (it's persisted to subscriptions file and restarted on startup)
+type SubscriptionDef struct {
+	Method   string                 `json:"method"`
+	Interval string                 `json:"interval"`
+	Params   map[string]interface{} `json:"params"`
+}
+
+// notifier sends notifications of one subscription
+// (it's jsonrpc.NotificationService)
+type notifier interface {
+	ID() string
+	Send(value interface{})
+}
+
+// subscription polls read method with interval
+// and sends notification when result changes
+type subscription struct {
+	def      SubscriptionDef
+	interval time.Duration
+	nf       notifier
+	stop     chan struct{}
+
+	mx     sync.Mutex
+	last   interface{}
+	lastAt time.Time
+	// consecutive failed reads
+	failures int
+}
+
+// fail counts failed read and returns count of consecutive failures
+func (sub *subscription) fail() int {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+
+	sub.failures++
+
+	return sub.failures
+}
+
+// succeed resets failures and returns true if subscription was in backoff
+func (sub *subscription) succeed() bool {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+
+	backoff := sub.failures >= subscriptionFailures
+	sub.failures = 0
+
+	return backoff
+}
+
+// update remembers result and returns true if it differs from last one
+func (sub *subscription) update(res interface{}) bool {
+	sub.mx.Lock()
+	defer sub.mx.Unlock()
+
+	changed := sub.lastAt.IsZero() || !reflect.DeepEqual(sub.last, res)
+	sub.last, sub.lastAt = res, time.Now()
+
+	return changed
+}
+
+// subscriptions contains active subscriptions of service
+// they are started when rpc (sender of notifications) is injected
+type subscriptions struct {
+	mx  sync.Mutex
+	srv Service
+	// nil until rpc is injected
+	newNotifier func(params objx.Map) notifier
+	items       map[string]*subscription
+	// definitions waiting for rpc
+	pending []SubscriptionDef
+	// file of active definitions (empty if not persisted)
+	file string
+	// max count of active subscriptions (0 if not limited)
+	max int
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{items: make(map[string]*subscription)}
+}
+
+// Subscriptions adds subscriptions which are started
+// when service is connected to core
+func Subscriptions(defs ...SubscriptionDef) Option {
+	return func(s *Service) {
+		s.subs.pending = append(s.subs.pending, defs...)
+	}
+}
+
+// SubscriptionsFile enables persisting of active subscriptions to file
+// (use LoadSubscriptions and Subscriptions to restart them)
+func SubscriptionsFile(path string) Option {
+	return func(s *Service) {
+		s.subs.file = path
+	}
+}
+
+// MaxSubscriptions limits count of active subscriptions
+// (new subscriptions over the limit are rejected)
+func MaxSubscriptions(max int) Option {
+	return func(s *Service) {
+		s.subs.max = max
+	}
+}
+
+// LoadSubscriptions reads subscriptions file (missing file has no subscriptions)
+func LoadSubscriptions(path string) ([]SubscriptionDef, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []SubscriptionDef
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&defs); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+// InjectRPC sets sender of notifications and starts pending subscriptions
+func (s Service) InjectRPC(rpc jsonrpc.RPC) {
+	s.subs.connect(func(params objx.Map) notifier {
+		return rpc.NewNotification(params)
+	})
+}
+
+func (subs *subscriptions) connect(newNotifier func(params objx.Map) notifier) {
+	subs.mx.Lock()
+	defer subs.mx.Unlock()
+
+	subs.newNotifier = newNotifier
+
+	for _, def := range subs.pending {
+		if _, err := subs.start(def); err != nil {
+			log.WithError(err).WithField("method", def.Method).Error("restart subscription")
+		}
+	}
+
+	subs.pending = nil
+	subs.save()
+}
+
+var errNoSubscriptions = jsonrpc.ErrInvalidRequest.AddData("msg", "subscriptions not available")
+
+// subscribe starts polling of read method (method param) with params of request
+func (s Service) subscribe(params objx.Map) (interface{}, error) {
+	if s.subs == nil {
+		return nil, errNoSubscriptions
+	}
+
+	def := SubscriptionDef{
+		Method:   params.Get("method").Str("modbus-read-holding"),
+		Interval: params.Get("interval").Str(defaultSubscriptionInterval),
+		Params:   params.Copy(),
+	}
+
+	delete(def.Params, "method")
+	delete(def.Params, "interval")
+
+	s.subs.mx.Lock()
+	defer s.subs.mx.Unlock()
+
+	if s.subs.newNotifier == nil {
+		return nil, errNoSubscriptions
+	}
+
+	sub, err := s.subs.start(def)
+	if err != nil {
+		return nil, err
+	}
+
+	s.subs.save()
+
+	return sub.nf, nil
+}
+
+// start validates definition and starts polling, caller must hold the mutex
+func (subs *subscriptions) start(def SubscriptionDef) (*subscription, error) {
+	if !subscribeMethods[def.Method] {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "method should be register, bit or point read").
+			AddData("v", def.Method)
+	}
+
+	interval, err := getDuration(objx.Map{"interval": def.Interval}, "interval", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateParams(def.Method, def.Params); err != nil {
+		return nil, err
+	}
+
+	if subs.max > 0 && len(subs.items) >= subs.max {
+		return nil, jsonrpc.ErrInvalidRequest.AddData("msg", "too many subscriptions").AddData("max", subs.max)
+	}
+
+	sub := &subscription{
+		def:      def,
+		interval: interval,
+		nf:       subs.newNotifier(objx.Map(def.Params)),
+		stop:     make(chan struct{}),
+	}
+
+	subs.items[sub.nf.ID()] = sub
+
+	go subs.srv.poll(sub)
+
+	return sub, nil
+}
+
+// save writes active definitions to file, caller must hold the mutex
+func (subs *subscriptions) save() {
+	if subs.file == "" {
+		return
+	}
+
+	defs := make([]SubscriptionDef, 0, len(subs.items))
+	for _, sub := range subs.items {
+		defs = append(defs, sub.def)
+	}
+
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err == nil {
+		// rename is atomic so file is never half written
+		tmp := subs.file + ".tmp"
+
+		err = ioutil.WriteFile(tmp, data, 0600)
+		if err == nil {
+			err = os.Rename(tmp, subs.file)
+		}
+	}
+
+	if err != nil {
+		log.WithError(err).WithField("file", subs.file).Error("save subscriptions")
+	}
+}
+
+// poll reads with interval until subscription is cancelled or service is shut down
+// after subscriptionFailures consecutive failures delay of reads doubles
+// (up to subscriptionMaxBackoff) until read succeeds
+func (s Service) poll(sub *subscription) {
+	maxDelay := subscriptionMaxBackoff
+	if sub.interval > maxDelay {
+		maxDelay = sub.interval
+	}
+
+	delay := sub.interval
+
+	for {
+		if !s.life.enter() {
+			return
+		}
+
+		res, err := s.call(jsonrpc.Request{Method: sub.def.Method, Params: objx.Map(sub.def.Params).Copy()})
+		s.life.leave()
+
+		if err != nil {
+			log.WithError(err).WithField("process_id", sub.nf.ID()).Warn("subscription read")
+
+			failures := sub.fail()
+			if failures == subscriptionFailures {
+				sub.nf.Send(subscriptionEvent{Event: eventBackoff, Error: toRPCError(err), Failures: failures})
+			}
+
+			if failures >= subscriptionFailures && delay < maxDelay {
+				delay *= 2
+				if delay > maxDelay {
+					delay = maxDelay
+				}
+			}
+		} else {
+			if sub.succeed() {
+				sub.nf.Send(subscriptionEvent{Event: eventRecovered})
+			}
+
+			delay = sub.interval
+
+			if sub.update(res) {
+				sub.nf.Send(res)
+			}
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-sub.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// subscribeCancel stops subscription by process_id param
+func (s Service) subscribeCancel(params objx.Map) (interface{}, error) {
+	if s.subs == nil {
+		return nil, errNoSubscriptions
+	}
+
+	id := params.Get("process_id").Str()
+
+	s.subs.mx.Lock()
+	defer s.subs.mx.Unlock()
+
+	sub, ok := s.subs.items[id]
+	if !ok {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "subscription not found").AddData("v", id)
+	}
+
+	close(sub.stop)
+	delete(s.subs.items, id)
+
+	s.subs.save()
+
+	return true, nil
+}
+
+type subscriptionInfo struct {
+	ProcessID string `json:"process_id"`
+	SubscriptionDef
+	// result and time of last successful read
+	LastValue interface{} `json:"last_value"`
+	LastAt    *time.Time  `json:"last_at,omitempty"`
+	// consecutive failed reads (backoff after 3)
+	Failures int `json:"failures"`
+}
+
+// listSubscriptions returns active subscriptions with their last sent values
+func (s Service) listSubscriptions() (interface{}, error) {
+	if s.subs == nil {
+		return nil, errNoSubscriptions
+	}
+
+	s.subs.mx.Lock()
+	defer s.subs.mx.Unlock()
+
+	res := make([]subscriptionInfo, 0, len(s.subs.items))
+
+	for id, sub := range s.subs.items {
+		info := subscriptionInfo{ProcessID: id, SubscriptionDef: sub.def}
+
+		sub.mx.Lock()
+		if !sub.lastAt.IsZero() {
+			at := sub.lastAt
+			info.LastValue, info.LastAt = sub.last, &at
+		}
+
+		info.Failures = sub.failures
+		sub.mx.Unlock()
+
+		res = append(res, info)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].ProcessID < res[j].ProcessID })
+
+	return res, nil
+}
@@ -0,0 +1,86 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+func TestSubscriptionAddressBase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "subscriptions.json")
+	m := &mockSlave{}
+	m.holding[1] = 8
+
+	srv := newMockService(m, AddressBase(1), SubscriptionsFile(path))
+	values := make(chan interface{}, 16)
+	connectTestNotifier(srv, values)
+
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-subscribe",
+		Params: objx.Map{"address": num("2"), "quantity": num("1"), "interval": "10ms"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := <-values; !reflect.DeepEqual(v, []interface{}{uint16(8)}) {
+		t.Errorf("unexpected notification %v", v)
+	}
+
+	if _, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-subscribe",
+		Params: objx.Map{"address": num("0"), "quantity": num("1")},
+	}); err == nil {
+		t.Error("address 0 should be rejected if address_base is 1")
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	// saved subscription keeps address of request
+	defs, err := LoadSubscriptions(path)
+	if err != nil || len(defs) != 1 {
+		t.Fatalf("unexpected saved subscriptions %+v (%v)", defs, err)
+	}
+
+	restarted := newMockService(m, AddressBase(1), Subscriptions(defs...))
+	values = make(chan interface{}, 16)
+	connectTestNotifier(restarted, values)
+
+	if v := <-values; !reflect.DeepEqual(v, []interface{}{uint16(8)}) {
+		t.Errorf("unexpected notification of restarted subscription %v", v)
+	}
+
+	if err := restarted.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
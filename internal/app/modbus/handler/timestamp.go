@@ -0,0 +1,117 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// formats of with_timestamp values
+const (
+	timestampRFC3339 = "rfc3339"
+	timestampEpochMs = "epoch_ms"
+)
+
+// IsValidTimestampFormat reports whether format can be used as timestamp_format
+func IsValidTimestampFormat(format string) bool {
+	return format == timestampRFC3339 || format == timestampEpochMs
+}
+
+// TimestampFormat sets format (rfc3339 or epoch_ms) of with_timestamp values
+// used when request has no timestamp_format param
+func TimestampFormat(format string) Option {
+	return func(s *Service) {
+		s.timestampFormat = format
+	}
+}
+
+var errTimestampVerbose = jsonrpc.ErrInvalidParams.AddData("msg", "with_timestamp requires verbose register read")
+
+// checkTimestamp returns error if with_timestamp can't be used with params
+func checkTimestamp(params objx.Map, registers bool) error {
+	if !params.Get("with_timestamp").Bool() {
+		return nil
+	}
+
+	if !registers || !params.Get("verbose").Bool() {
+		return errTimestampVerbose
+	}
+
+	return nil
+}
+
+// formatTimestamp returns time of transaction completion in format from params (or default)
+func (s Service) formatTimestamp(params objx.Map, t time.Time) (interface{}, error) {
+	switch format := params.Get("timestamp_format").Str(s.timestampFormat); format {
+	case timestampRFC3339:
+		return t.UTC().Format(time.RFC3339Nano), nil
+	case timestampEpochMs:
+		return t.UnixNano() / int64(time.Millisecond), nil
+	default:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "timestamp_format should be rfc3339 or epoch_ms").
+			AddData("v", format)
+	}
+}
+
+// maxTZOffset is the largest offset of time zones from UTC
+const maxTZOffset = 14 * time.Hour
+
+// getTimestampEncoding returns format of timestamp encoding values (timestamp_format param or default)
+// and tz_offset param (e.g. "3h" if device clock is set to UTC+3)
+func (s Service) getTimestampEncoding(params objx.Map, c codec) (string, time.Duration, error) {
+	if c.encoding != encTimestamp {
+		if !params.Get("tz_offset").IsNil() {
+			return "", 0, jsonrpc.ErrInvalidParams.AddData("msg", "tz_offset supported by timestamp encoding only")
+		}
+
+		return "", 0, nil
+	}
+
+	format := params.Get("timestamp_format").Str(s.timestampFormat)
+	if !IsValidTimestampFormat(format) {
+		return "", 0, jsonrpc.ErrInvalidParams.AddData("msg", "timestamp_format should be rfc3339 or epoch_ms").
+			AddData("v", format)
+	}
+
+	if params.Get("tz_offset").IsNil() {
+		return format, 0, nil
+	}
+
+	offset, err := time.ParseDuration(params.Get("tz_offset").Str())
+	if err != nil || offset%time.Minute != 0 || offset < -maxTZOffset || offset > maxTZOffset {
+		return "", 0, jsonrpc.ErrInvalidParams.AddData("msg", "tz_offset should be duration in range -14h-14h "+
+			"in whole minutes (e.g. 3h or -5h30m)").AddData("v", params.Get("tz_offset").Data())
+	}
+
+	return format, offset, nil
+}
+
+// timestamp converts unix time of device clock to value in timestamp format
+// rfc3339 values keep offset of device clock
+func (c codec) timestamp(v uint32) interface{} {
+	t := time.Unix(int64(v), 0).Add(-c.tzOffset)
+
+	if c.timestampFormat == timestampEpochMs {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+
+	return t.In(time.FixedZone("", int(c.tzOffset/time.Second))).Format(time.RFC3339)
+}
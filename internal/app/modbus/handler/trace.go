@@ -0,0 +1,96 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// TraceEvent describes one bus transaction
+type TraceEvent struct {
+	SlaveID byte
+	// method of call which sent transaction
+	Method string
+	// function code and data of request
+	Request []byte
+	// function code and data of response (nil if there is no valid response)
+	Response []byte
+	// time on the bus (without waiting for it)
+	Duration time.Duration
+	Err      error
+}
+
+// TraceFunc sets callback called after each bus transaction (including retries)
+// it's called outside of bus lock, so slow callback doesn't stall the bus
+func TraceFunc(fn func(TraceEvent)) Option {
+	return func(s *Service) {
+		s.trace = fn
+	}
+}
+
+// traceSpan is time of the last transaction on the bus
+// (shared by transporters inside and outside of bus lock)
+type traceSpan struct {
+	duration time.Duration
+}
+
+// spanTransporter measures transaction time inside bus lock
+type spanTransporter struct {
+	modbus.Transporter
+	span *traceSpan
+}
+
+func (t spanTransporter) Send(adu []byte) ([]byte, error) {
+	start := time.Now()
+	res, err := t.Transporter.Send(adu)
+	t.span.duration = time.Since(start)
+
+	return res, err
+}
+
+// traceTransporter calls trace callback after bus lock is released
+type traceTransporter struct {
+	modbus.Transporter
+	packager modbus.Packager
+	trace    func(TraceEvent)
+	span     *traceSpan
+	slaveID  byte
+	method   string
+}
+
+func (t traceTransporter) Send(adu []byte) ([]byte, error) {
+	t.span.duration = 0
+	res, err := t.Transporter.Send(adu)
+
+	e := TraceEvent{SlaveID: t.slaveID, Method: t.method, Duration: t.span.duration, Err: err}
+
+	if pdu, err := t.packager.Decode(adu); err == nil {
+		e.Request = append([]byte{pdu.FunctionCode}, pdu.Data...)
+	}
+
+	if err == nil && t.packager.Verify(adu, res) == nil {
+		if pdu, err := t.packager.Decode(res); err == nil {
+			e.Response = append([]byte{pdu.FunctionCode}, pdu.Data...)
+		}
+	}
+
+	t.trace(e)
+
+	return res, err
+}
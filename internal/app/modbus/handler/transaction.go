@@ -0,0 +1,133 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func (s Service) isTCP() bool {
+	_, ok := s.packagerGetter(0).(*modbus.TCPPackager)
+	return ok
+}
+
+// requestTransactionID derives modbus tcp transaction id from json-rpc request id
+// it works only for numeric ids (or strings with number)
+func requestTransactionID(req jsonrpc.Request) (uint16, bool) {
+	id, err := strconv.ParseUint(strings.Trim(string(req.ID), `"`), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint16(id), true
+}
+
+// transaction_id param value which derives transaction id from request id
+const transactionIDFromRequest = "request"
+
+// getTransactionID returns transaction id from transaction_id param
+// (number or "request" to derive it from request id)
+func (s Service) getTransactionID(req jsonrpc.Request) (uint16, bool, error) {
+	if req.Params.Get("transaction_id").IsNil() {
+		return 0, false, nil
+	}
+
+	if !s.isTCP() {
+		return 0, false, jsonrpc.ErrInvalidParams.AddData("msg", "transaction_id supported in tcp mode only")
+	}
+
+	if req.Params.Get("transaction_id").Str() == transactionIDFromRequest {
+		id, ok := requestTransactionID(req)
+		if !ok {
+			return 0, false, jsonrpc.ErrInvalidParams.AddData("msg", "request id should be a number for transaction_id request")
+		}
+
+		return id, true, nil
+	}
+
+	id, err := getUint16(req.Params, "transaction_id")
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// withTransactionID returns service which uses given transaction id
+// in all modbus tcp frames
+func (s Service) withTransactionID(id uint16) Service {
+	getter := s.packagerGetter
+
+	s.packagerGetter = func(slaveID byte) modbus.Packager {
+		p := getter(slaveID)
+		if tp, ok := p.(*modbus.TCPPackager); ok {
+			tp.SetTransactionId(id)
+		}
+
+		return p
+	}
+
+	return s
+}
+
+// transactionRecorder remembers transaction id of last sent tcp frame
+type transactionRecorder struct {
+	modbus.Transporter
+	id *uint16
+}
+
+func (t transactionRecorder) Send(adu []byte) ([]byte, error) {
+	if len(adu) >= 2 {
+		*t.id = binary.BigEndian.Uint16(adu)
+	}
+
+	return t.Transporter.Send(adu)
+}
+
+type transactionResult struct {
+	Result        interface{} `json:"result"`
+	TransactionID uint16      `json:"transaction_id"`
+}
+
+// callWithTransactionID calls method and wraps result with transaction id
+// used in the last sent frame
+func (s Service) callWithTransactionID(req jsonrpc.Request) (interface{}, error) {
+	if !s.isTCP() {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "with_transaction_id supported in tcp mode only")
+	}
+
+	var id uint16
+
+	srv := s.withLayer(func(t modbus.Transporter) modbus.Transporter {
+		return transactionRecorder{t, &id}
+	})
+
+	params := req.Params.Copy()
+	delete(params, "with_transaction_id")
+
+	res, err := srv.call(jsonrpc.Request{Method: req.Method, ID: req.ID, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	return transactionResult{Result: res, TransactionID: id}, nil
+}
@@ -0,0 +1,70 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+func TestTransactionID(t *testing.T) {
+	m := &mockSlave{}
+	srv := newMockService(m)
+
+	call := func(params objx.Map) uint16 {
+		params["address"] = num("0")
+		params["quantity"] = num("1")
+		params["with_transaction_id"] = true
+
+		res, err := srv.Call(jsonrpc.Request{Method: "modbus-read-holding", ID: []byte("7"), Params: params})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.(transactionResult).TransactionID
+	}
+
+	if id := call(objx.Map{"transaction_id": num("5")}); id != 5 {
+		t.Errorf("expected transaction id 5 but %d given", id)
+	}
+
+	// request id is used if it's asked only
+	if id := call(objx.Map{"transaction_id": "request"}); id != 7 {
+		t.Errorf("expected transaction id of request 7 but %d given", id)
+	}
+
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		ID:     []byte("40000"),
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "with_transaction_id": true},
+	})
+	if err != nil || res.(transactionResult).TransactionID == 40000 {
+		t.Errorf("request id shouldn't be used as transaction id by default (%v)", err)
+	}
+
+	_, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-read-holding",
+		ID:     []byte(`"abc"`),
+		Params: objx.Map{"address": num("0"), "quantity": num("1"), "transaction_id": "request"},
+	})
+	if err == nil {
+		t.Error("expected error of non-numeric request id")
+	}
+}
@@ -0,0 +1,250 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+)
+
+// maxTransformLength limits size of transform expression
+const maxTransformLength = 256
+
+// transform is compiled arithmetic expression of raw value
+// (e.g. "0.5 * raw^2 - 3 * raw + 10"), it has no access to anything but raw
+type transform func(raw float64) float64
+
+// parseTransform compiles expression of numbers, raw variable, parentheses
+// and + - * / ^ (power) operators
+func parseTransform(expr string) (transform, error) {
+	if len(expr) > maxTransformLength {
+		return nil, errors.New("transform should be no longer than " + strconv.Itoa(maxTransformLength) + " characters")
+	}
+
+	p := transformParser{expr: expr}
+
+	t, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipSpaces(); p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected " + strconv.Quote(p.expr[p.pos:p.pos+1]))
+	}
+
+	return t, nil
+}
+
+// transformParser is recursive descent parser of transform expression
+type transformParser struct {
+	expr string
+	pos  int
+}
+
+func (p *transformParser) errorf(msg string) error {
+	return errors.New("transform: " + msg + " at position " + strconv.Itoa(p.pos))
+}
+
+func (p *transformParser) skipSpaces() {
+	for p.pos < len(p.expr) && p.expr[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// next returns next operator character and skips it if it's one of ops
+func (p *transformParser) next(ops string) (byte, bool) {
+	p.skipSpaces()
+
+	if p.pos < len(p.expr) && strings.IndexByte(ops, p.expr[p.pos]) >= 0 {
+		p.pos++
+		return p.expr[p.pos-1], true
+	}
+
+	return 0, false
+}
+
+// sum = product {("+" | "-") product}
+func (p *transformParser) sum() (transform, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.next("+-")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		if op == '+' {
+			left = func(x float64) float64 { return l(x) + right(x) }
+		} else {
+			left = func(x float64) float64 { return l(x) - right(x) }
+		}
+	}
+}
+
+// product = unary {("*" | "/") unary}
+func (p *transformParser) product() (transform, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.next("*/")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		if op == '*' {
+			left = func(x float64) float64 { return l(x) * right(x) }
+		} else {
+			left = func(x float64) float64 { return l(x) / right(x) }
+		}
+	}
+}
+
+// unary = ("-" | "+") unary | power
+func (p *transformParser) unary() (transform, error) {
+	op, ok := p.next("+-")
+	if !ok {
+		return p.power()
+	}
+
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	if op == '-' {
+		return func(x float64) float64 { return -operand(x) }, nil
+	}
+
+	return operand, nil
+}
+
+// power = primary ["^" unary] (right associative)
+func (p *transformParser) power() (transform, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.next("^"); !ok {
+		return base, nil
+	}
+
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(x float64) float64 { return math.Pow(base(x), exp(x)) }, nil
+}
+
+// primary = number | "raw" | "(" sum ")"
+func (p *transformParser) primary() (transform, error) {
+	p.skipSpaces()
+
+	if p.pos >= len(p.expr) {
+		return nil, p.errorf("unexpected end")
+	}
+
+	if _, ok := p.next("("); ok {
+		t, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := p.next(")"); !ok {
+			return nil, p.errorf("missing )")
+		}
+
+		return t, nil
+	}
+
+	if strings.HasPrefix(p.expr[p.pos:], "raw") {
+		p.pos += len("raw")
+		return func(x float64) float64 { return x }, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.expr) && strings.IndexByte("0123456789.", p.expr[p.pos]) >= 0 {
+		p.pos++
+	}
+
+	v, err := strconv.ParseFloat(p.expr[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("expected number, raw or (")
+	}
+
+	return func(float64) float64 { return v }, nil
+}
+
+// apply transforms decoded value, results which aren't finite numbers are null
+func (t transform) apply(v interface{}) interface{} {
+	f, ok := toFloat64(v)
+	if t == nil || !ok {
+		return v
+	}
+
+	res := t(f)
+	if math.IsNaN(res) || math.IsInf(res, 0) {
+		return nil
+	}
+
+	return res
+}
+
+// getTransform returns transform from params (nil if not passed)
+func getTransform(params objx.Map, encoding string) (transform, error) {
+	if params.Get("transform").IsNil() {
+		return nil, nil
+	}
+
+	switch encoding {
+	case encRaw, encBitmask, encTimestamp:
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "transform can't be used with "+encoding+" encoding")
+	}
+
+	t, err := parseTransform(params.Get("transform").Str())
+	if err != nil {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", err.Error()).AddData("v", params.Get("transform").Data())
+	}
+
+	return t, nil
+}
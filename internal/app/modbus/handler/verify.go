@@ -0,0 +1,162 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+func getFloat64(params objx.Map, k string, def float64) (float64, error) {
+	val := params.Get(k)
+	if val.IsNil() {
+		return def, nil
+	}
+
+	var (
+		value float64
+		err   error
+	)
+
+	switch v := val.Data().(type) {
+	case json.Number:
+		value, err = v.Float64()
+	case string:
+		value, err = strconv.ParseFloat(v, 64)
+	default:
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be number")
+	}
+
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, jsonrpc.ErrInvalidParams.AddData("msg", k+" should be number")
+	}
+
+	return value, nil
+}
+
+// writeCheck reads written values back and compares them with request
+type writeCheck struct {
+	// max allowed difference of decoded register values
+	tolerance float64
+}
+
+// getWriteCheck returns write check if verify param set (nil otherwise)
+func getWriteCheck(params objx.Map) (*writeCheck, error) {
+	if !params.Get("verify").Bool() {
+		return nil, nil
+	}
+
+	tolerance, err := getFloat64(params, "verify_tolerance", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tolerance < 0 {
+		return nil, jsonrpc.ErrInvalidParams.AddData("msg", "verify_tolerance should not be negative")
+	}
+
+	return &writeCheck{tolerance: tolerance}, nil
+}
+
+func verifyErr(expected, actual interface{}) error {
+	return jsonrpc.ErrServer.AddData("msg", "write verification failed").
+		AddData("expected", expected).AddData("actual", actual).SetCode(-32098)
+}
+
+// coils compares coils with written ones (tolerance is not applicable to coils)
+func (w *writeCheck) coils(s Service, slaveID byte, addr, quantity uint16, written []byte) error {
+	if w == nil {
+		return nil
+	}
+
+	res, err := s.readBlock(slaveID, modbus.FuncCodeReadCoils, addr, quantity)
+	if err != nil {
+		return err
+	}
+
+	expected := parseResultByteToBits(written, quantity)
+	actual := parseResultByteToBits(res, quantity)
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			return verifyErr(expected, actual)
+		}
+	}
+
+	return nil
+}
+
+// registers reads holding registers back and compares them with written ones
+func (w *writeCheck) registers(s Service, params objx.Map, slaveID byte, addr, quantity uint16,
+	c codec, written []byte) error {
+	if w == nil {
+		return nil
+	}
+
+	var (
+		res []byte
+		err error
+	)
+
+	if params.Get("chunked").Bool() {
+		res, err = s.readRegistersChunked(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, quantity)
+	} else {
+		res, err = s.readBlock(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, quantity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return w.compare(c, written, res)
+}
+
+// compare compares read back registers with written ones
+// values are compared after decoding so tolerance works for any encoding
+func (w *writeCheck) compare(c codec, written, res []byte) error {
+	if w.tolerance == 0 && bytes.Equal(res, written) {
+		return nil
+	}
+
+	expected, err := c.decode(written)
+	if err != nil {
+		return err
+	}
+
+	actual, err := c.decode(res)
+	if err != nil {
+		return err
+	}
+
+	for i := range expected {
+		e, _ := toFloat64(expected[i])
+		a, _ := toFloat64(actual[i])
+
+		if math.Abs(e-a) > w.tolerance {
+			return verifyErr(expected, actual)
+		}
+	}
+
+	return nil
+}
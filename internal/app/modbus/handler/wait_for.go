@@ -0,0 +1,121 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/stretchr/objx"
+)
+
+const (
+	waitForInterval = 100 * time.Millisecond
+	waitForTimeout  = 10 * time.Second
+)
+
+// Context sets context of service
+// long running methods stop when it's done
+func Context(ctx context.Context) Option {
+	return func(s *Service) {
+		s.ctx = ctx
+	}
+}
+
+type waitForResult struct {
+	Value   uint16 `json:"value"`
+	Matched bool   `json:"matched"`
+	// elapsed time in milliseconds
+	Elapsed int64 `json:"elapsed_ms"`
+}
+
+// waitFor polls register until (register & mask) == (value & mask)
+// or timeout expired
+// it returns last read value and elapsed time
+func (s Service) waitFor(params objx.Map) (interface{}, error) {
+	addr, value, err := getAddrAndValue(params)
+	if err != nil {
+		return nil, err
+	}
+
+	mask, err := getUint16(params, "mask", int64(maxUint16))
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := getDuration(params, "interval", waitForInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := getDuration(params, "timeout", waitForTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	read := s.getClient(slaveID).ReadHoldingRegisters
+	if params.Get("input").Bool() {
+		read = s.getClient(slaveID).ReadInputRegisters
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var res waitForResult
+
+	for {
+		// cache is not used because value should be fresh
+		b, err := read(addr, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(b) != 2 {
+			return nil, truncatedErr(2, len(b))
+		}
+
+		res.Value = binary.BigEndian.Uint16(b)
+		res.Elapsed = time.Since(start).Milliseconds()
+		res.Matched = res.Value&mask == value&mask
+
+		if res.Matched {
+			return res, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			// timeout is normal result, but not service shutdown
+			if s.ctx.Err() != nil {
+				return nil, s.ctx.Err()
+			}
+
+			return res, nil
+		case <-ticker.C:
+		}
+	}
+}
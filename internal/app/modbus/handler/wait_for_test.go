@@ -0,0 +1,72 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// countingSlave increments holding register before each read of it
+type countingSlave struct {
+	*mockSlave
+}
+
+func (m countingSlave) Send(adu []byte) ([]byte, error) {
+	if adu[7] == modbus.FuncCodeReadHoldingRegisters {
+		m.holding[binary.BigEndian.Uint16(adu[8:])]++
+	}
+
+	return m.mockSlave.Send(adu)
+}
+
+func TestWaitFor(t *testing.T) {
+	m := countingSlave{&mockSlave{}}
+	m.holding[7] = 0x0100
+	srv := New(m, func(s byte) modbus.Packager { return modbus.NewTCPPackager(s) })
+
+	// masked bits of value are compared only
+	res, err := srv.Call(jsonrpc.Request{
+		Method: "modbus-wait-for",
+		Params: objx.Map{"address": num("7"), "value": num("3"), "mask": num("255"), "interval": "1ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := res.(waitForResult); !r.Matched || r.Value != 0x0103 || len(m.pdus) != 3 {
+		t.Errorf("unexpected result %+v after %d reads", r, len(m.pdus))
+	}
+
+	// timeout isn't error
+	res, err = srv.Call(jsonrpc.Request{
+		Method: "modbus-wait-for",
+		Params: objx.Map{"address": num("7"), "value": num("0"), "interval": "5ms", "timeout": "30ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := res.(waitForResult); r.Matched || r.Elapsed < 10 {
+		t.Errorf("expected not matched result after timeout but got %+v", r)
+	}
+}
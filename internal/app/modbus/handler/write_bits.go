@@ -0,0 +1,160 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/stretchr/objx"
+
+	"github.com/Rightech/ric-edge/pkg/jsonrpc"
+	"github.com/Rightech/ric-edge/third_party/goburrow/modbus"
+)
+
+// mechanisms of modbus-write-bits
+const (
+	mechanismMaskWrite       = "mask_write"
+	mechanismReadModifyWrite = "read_modify_write"
+)
+
+type writeBitsResult struct {
+	// mask_write (FC22) or read_modify_write (FC3 + FC6 if slave doesn't support FC22)
+	Mechanism string `json:"mechanism"`
+	AndMask   uint16 `json:"and_mask"`
+	OrMask    uint16 `json:"or_mask"`
+	// register value before and after write (read_modify_write only)
+	Previous *uint16 `json:"previous,omitempty"`
+	Value    *uint16 `json:"value,omitempty"`
+}
+
+// getBitMasks returns masks of bits param (object of bit index to 0 or 1)
+// and mask has cleared bits to change, or mask has set bits to set
+func getBitMasks(params objx.Map) (uint16, uint16, error) {
+	v := params.Get("bits")
+	if !v.IsMSI() && !v.IsObjxMap() {
+		return 0, 0, jsonrpc.ErrInvalidParams.AddData("msg", "bits should be object of bit index to 0 or 1")
+	}
+
+	bits := v.ObjxMap()
+	if len(bits) == 0 {
+		return 0, 0, emptyErr("bits")
+	}
+
+	keys := make([]string, 0, len(bits))
+	for k := range bits {
+		keys = append(keys, k)
+	}
+
+	// first invalid bit is reported the same way each time
+	sort.Strings(keys)
+
+	andMask, orMask := uint16(0xFFFF), uint16(0)
+
+	for _, k := range keys {
+		bit, err := strconv.Atoi(k)
+		if err != nil || bit < 0 || bit > 15 {
+			return 0, 0, jsonrpc.ErrInvalidParams.AddData("msg", "bits keys should be bit numbers 0-15").AddData("v", k)
+		}
+
+		value, err := getUint16(objx.Map{"value": bits[k]}, "value")
+		if err != nil || value > 1 {
+			return 0, 0, jsonrpc.ErrInvalidParams.AddData("msg", "bits values should be 0 or 1").AddData("v", k)
+		}
+
+		andMask &^= 1 << uint(bit)
+		orMask |= value << uint(bit)
+	}
+
+	return andMask, orMask, nil
+}
+
+// writeBits changes given bits of holding register leaving other bits untouched
+// it uses mask write (FC22) which is atomic on slave, slaves which answer it by
+// illegal function exception get read-modify-write (see setBit for its atomicity)
+func (s Service) writeBits(params objx.Map) (interface{}, error) {
+	addr, err := getUint16(params, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	andMask, orMask, err := getBitMasks(params)
+	if err != nil {
+		return nil, err
+	}
+
+	slaveID, err := getSlaveID(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// fallback goes under the same locks as mask write attempt
+	unlock := s.registerLocks.lock(slaveID, addr)
+	defer unlock()
+
+	if _, bus := s.connection(slaveID); bus != nil {
+		if err := bus.acquire(); err != nil {
+			return nil, err
+		}
+		defer bus.release()
+
+		s = s.withBusHeld(slaveID)
+	}
+
+	cli := s.getClient(slaveID)
+	result := writeBitsResult{Mechanism: mechanismMaskWrite, AndMask: andMask, OrMask: orMask}
+
+	_, err = cli.MaskWriteRegister(addr, andMask, orMask)
+
+	var mbErr *modbus.ModbusError
+	if errors.As(err, &mbErr) && mbErr.ExceptionCode == modbus.ExceptionCodeIllegalFunction {
+		result.Mechanism = mechanismReadModifyWrite
+		result.Previous, result.Value, err = readModifyWrite(cli, addr, andMask, orMask)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.invalidate(slaveID, modbus.FuncCodeReadHoldingRegisters, addr, 1)
+
+	return result, nil
+}
+
+// readModifyWrite applies masks to holding register as mask write does
+// (value = previous AND and_mask OR (or_mask AND NOT and_mask))
+func readModifyWrite(cli modbus.Client, addr, andMask, orMask uint16) (*uint16, *uint16, error) {
+	// cache is bypassed, register should be fresh
+	res, err := cli.ReadHoldingRegisters(addr, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(res) != 2 {
+		return nil, nil, truncatedErr(2, len(res))
+	}
+
+	previous := parseResult(res)[0]
+	value := previous&andMask | orMask&^andMask
+
+	if _, err := cli.WriteSingleRegister(addr, value); err != nil {
+		return nil, nil, err
+	}
+
+	return &previous, &value, nil
+}
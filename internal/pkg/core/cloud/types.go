@@ -100,6 +100,7 @@ func (m *Model) prepare() error {
 
 type params struct {
 	JSONRPC string                 `json:"jsonrpc"`
+	ID      *string                `json:"id"` // null id (not notification) so core waits response
 	Method  string                 `json:"method"`
 	Params  map[string]interface{} `json:"params"`
 }
@@ -160,6 +160,10 @@ func (s Service) rpcCallback(_ paho.Client, msg paho.Message) {
 	connectorID := strings.Split(msg.Topic(), "/")[1]
 
 	resp := s.rpc.Call(connectorID, msg.Payload())
+	if resp == nil {
+		// nothing to respond (notification or batch of notifications)
+		return
+	}
 
 	err := s.publish(fmt.Sprintf(responseTopic, connectorID), resp)
 	if err != nil {
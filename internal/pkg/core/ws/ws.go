@@ -316,6 +316,13 @@ func (s *Service) listen(conn conn) {
 			continue
 		}
 
+		if jsonrpc.IsBatch(msg) {
+			// this msg is batch of responses
+			conn.routeBatch(msg)
+
+			continue
+		}
+
 		// check this message request or response
 		methodVal := jsoniter.ConfigFastest.Get(msg, "method")
 		if methodVal.ValueType() != jsoniter.InvalidValue {
@@ -332,13 +339,7 @@ func (s *Service) listen(conn conn) {
 			continue
 		}
 
-		id := idVal.ToString()
-
-		conn.rmx.Lock()
-		ch, ok := conn.req[id]
-		delete(conn.req, id)
-		conn.rmx.Unlock()
-
+		ch, ok := conn.takeReq(idVal.ToString())
 		if !ok {
 			panic("resp chan not found")
 		}
@@ -347,16 +348,68 @@ func (s *Service) listen(conn conn) {
 	}
 }
 
+// takeReq returns response chan of request with id and forgets it
+func (c conn) takeReq(id string) (chan<- []byte, bool) {
+	c.rmx.Lock()
+	defer c.rmx.Unlock()
+
+	ch, ok := c.req[id]
+	delete(c.req, id)
+
+	return ch, ok
+}
+
+// routeBatch passes each response of batch to chan of its request
+func (c conn) routeBatch(msg []byte) {
+	var items []jsoniter.RawMessage
+
+	err := jsoniter.ConfigFastest.Unmarshal(msg, &items)
+	if err != nil {
+		c.l.WithError(err).Error("unmarshal batch")
+
+		return
+	}
+
+	for _, item := range items {
+		idVal := jsoniter.ConfigFastest.Get(item, "id")
+
+		ch, ok := c.takeReq(idVal.ToString())
+		if !ok {
+			// errors of malformed requests have null id
+			c.l.WithField("m", string(item)).Error("resp chan of batch item not found")
+
+			continue
+		}
+
+		ch <- item
+	}
+}
+
 func (s *Service) Call(name, id string, payload []byte) <-chan []byte {
-	resp := make(chan []byte, 1)
+	return s.CallBatch(name, []string{id}, payload)[0]
+}
+
+// CallBatch sends batch of requests to connector in one message
+// and returns response chans of requests in order of ids
+func (s *Service) CallBatch(name string, ids []string, payload []byte) []<-chan []byte {
+	resp := make([]chan []byte, len(ids))
+	res := make([]<-chan []byte, len(ids))
+
+	for i := range ids {
+		resp[i] = make(chan []byte, 1)
+		res[i] = resp[i]
+	}
 
 	s.mx.RLock()
 	conn, ok := s.conns[name]
 	s.mx.RUnlock()
 
 	if !ok {
-		resp <- jsonrpc.BuildErrResp(id, errNotFound)
-		return resp
+		for i, id := range ids {
+			resp[i] <- jsonrpc.BuildErrResp(id, errNotFound)
+		}
+
+		return res
 	}
 
 	conn.cmx.Lock()
@@ -368,14 +421,23 @@ func (s *Service) Call(name, id string, payload []byte) <-chan []byte {
 
 		conn.l.WithError(err).Error("ws.conn.write")
 
-		resp <- jsonrpc.BuildErrResp(id, errNotAvailable.AddData("sid", conn.sid))
+		for i, id := range ids {
+			resp[i] <- jsonrpc.BuildErrResp(id, errNotAvailable.AddData("sid", conn.sid))
+		}
 
-		return resp
+		return res
 	}
 
 	conn.rmx.Lock()
-	conn.req[id] = resp
+	for i, id := range ids {
+		// there is no response to notification (request without id)
+		if id == "" {
+			continue
+		}
+
+		conn.req[id] = resp[i]
+	}
 	conn.rmx.Unlock()
 
-	return resp
+	return res
 }
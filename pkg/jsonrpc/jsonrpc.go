@@ -38,6 +38,12 @@ type Caller interface {
 	Call(Request) (interface{}, error)
 }
 
+// BatchCaller is implemented by callers which share state by requests of one batch
+// (e.g. limits of whole batch), Batch returns caller of requests of new batch
+type BatchCaller interface {
+	Batch() Caller
+}
+
 type RPC interface {
 	NewNotification(params objx.Map) NotificationService
 }
@@ -119,8 +125,8 @@ func (s Service) Serve(ctx context.Context) error {
 			return err
 		}
 
-		var req Request
-		err = decoder.Decode(&req)
+		var raw jsoniter.RawMessage
+		err = decoder.Decode(&raw)
 
 		if errors.Is(err, io.EOF) {
 			// if error is a EOF we should return error
@@ -130,33 +136,207 @@ func (s Service) Serve(ctx context.Context) error {
 			return err
 		}
 
-		res := s.handleMessage(req, err)
-		s.mx.Lock()
-		encoder, closer, err := newEncoder(s.tr) // json encoder
+		res := s.handle(raw, err)
+		if res == nil {
+			// nothing to respond (notification or batch of notifications)
+			continue
+		}
 
+		err = s.write(res)
 		if err != nil {
-			s.mx.Unlock()
 			return err
 		}
+	}
 
-		encoder.WriteVal(res)
-		encoder.Flush()
-		closer.Close()
-		s.mx.Unlock()
+	return nil
+}
 
-		if encoder.Error != nil {
-			panic(encoder.Error)
-		}
+func (s Service) write(res interface{}) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	encoder, closer, err := newEncoder(s.tr) // json encoder
+	if err != nil {
+		return err
+	}
+
+	encoder.WriteVal(res)
+	encoder.Flush()
+	closer.Close()
+
+	if encoder.Error != nil {
+		panic(encoder.Error)
 	}
 
 	return nil
 }
 
+// decodeConfig used to decode raw messages (it keeps numbers as json.Number
+// like decoder returned by newDecoder)
+var decodeConfig = jsoniter.Config{ // nolint: gochecknoglobals
+	EscapeHTML:                    false,
+	MarshalFloatWith6Digits:       true,
+	ObjectFieldMustBeSimpleString: true,
+	UseNumber:                     true,
+}.Froze()
+
+// IsBatch reports whether raw message is batch (json array)
+func IsBatch(raw []byte) bool {
+	for _, c := range raw {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// handle returns response for single request or array of responses for batch
+func (s Service) handle(raw jsoniter.RawMessage, err error) interface{} {
+	if err != nil {
+		return s.handleMessage(Request{}, err)
+	}
+
+	if IsBatch(raw) {
+		return s.handleBatch(raw)
+	}
+
+	req, err := decodeRequest(raw)
+
+	res := s.handleMessage(req, err)
+
+	if err == nil && req.isNotification() {
+		logNotificationErr(req, res)
+		return nil
+	}
+
+	return res
+}
+
+// notification is a request without id
+// it executes as usual but server must not reply to it
+func (r Request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// logNotificationErr logs error of notification call
+// because there is no way to return it to client
+func logNotificationErr(req Request, res response) {
+	if res.Error != nil {
+		log.WithError(res.Error).WithField("method", req.Method).
+			Error("notification call")
+	}
+}
+
+var errEmptyBatch = ErrInvalidRequest.AddData("msg", "empty batch")
+
+// handleBatch calls each request from batch and returns responses in the same order
+// notifications (requests without id) has no responses
+// so if batch contains only notifications handleBatch returns nil
+func (s Service) handleBatch(raw jsoniter.RawMessage) interface{} {
+	var items []jsoniter.RawMessage
+
+	err := decodeConfig.Unmarshal(raw, &items)
+	if err != nil {
+		return s.handleMessage(Request{}, err)
+	}
+
+	if len(items) == 0 {
+		return buildResult(nil, nil, errEmptyBatch)
+	}
+
+	if bc, ok := s.c.(BatchCaller); ok {
+		s.c = bc.Batch()
+	}
+
+	res := make([]response, 0, len(items))
+
+	for _, item := range items {
+		req, err := decodeRequest(item)
+		if err != nil {
+			// malformed element shouldn't fail whole batch
+			res = append(res, buildResult(nil, nil, ErrInvalidRequest.AddData("msg", err.Error())))
+			continue
+		}
+
+		resp := s.handleMessage(req, nil)
+
+		if req.isNotification() {
+			logNotificationErr(req, resp)
+			continue
+		}
+
+		res = append(res, resp)
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
 type Request struct {
 	JSONRPC string              `json:"jsonrpc"`
 	Method  string              `json:"method"`
 	ID      jsoniter.RawMessage `json:"id,omitempty"`
 	Params  objx.Map            `json:"params"`
+
+	// set if params is not an object (params are not decoded then)
+	paramsErr error
+}
+
+var errParamsNotObject = ErrInvalidParams.AddData("msg", "params must be an object")
+
+// isObjectOrNull reports whether raw json value is object, null or missing
+func isObjectOrNull(raw jsoniter.RawMessage) bool {
+	for _, c := range raw {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', 'n':
+			return true
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeRequest decodes request
+// request with params of other type than object keeps its id and method
+// so the error can be returned as response to it
+func decodeRequest(raw jsoniter.RawMessage) (Request, error) {
+	var req struct {
+		JSONRPC string              `json:"jsonrpc"`
+		Method  string              `json:"method"`
+		ID      jsoniter.RawMessage `json:"id,omitempty"`
+		Params  jsoniter.RawMessage `json:"params"`
+	}
+
+	err := decodeConfig.Unmarshal(raw, &req)
+	if err != nil {
+		return Request{}, err
+	}
+
+	res := Request{JSONRPC: req.JSONRPC, Method: req.Method, ID: req.ID}
+
+	if !isObjectOrNull(req.Params) {
+		res.paramsErr = errParamsNotObject
+		return res, nil
+	}
+
+	if len(req.Params) != 0 {
+		err = decodeConfig.Unmarshal(req.Params, &res.Params)
+	}
+
+	return res, err
 }
 
 type response struct {
@@ -270,6 +450,10 @@ func (s Service) handleMessage(req Request, err error) response {
 		return buildResult(req.ID, nil, errBadMethod)
 	}
 
+	if req.paramsErr != nil {
+		return buildResult(req.ID, nil, req.paramsErr)
+	}
+
 	res, err := s.call(req)
 
 	if v, ok := res.(interface {
@@ -0,0 +1,214 @@
+/**
+ * Copyright 2019 Rightech IoT. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonrpc
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// methodCaller returns method of request as result, method "fail" returns error
+type methodCaller struct{}
+
+func (methodCaller) Call(req Request) (interface{}, error) {
+	if req.Method == "fail" {
+		return nil, ErrInvalidParams.AddData("msg", "fail")
+	}
+
+	return req.Method, nil
+}
+
+func TestBatch(t *testing.T) {
+	s := New(nil, methodCaller{})
+
+	res, ok := s.handle(jsoniter.RawMessage(` [
+		{"jsonrpc":"2.0","id":3,"method":"c"},
+		1,
+		{"jsonrpc":"2.0","id":"a","method":"a"},
+		{"jsonrpc":"2.0","id":2,"method":"fail"}
+	]`), nil).([]response)
+	if !ok || len(res) != 4 {
+		t.Fatalf("unexpected batch response %+v", res)
+	}
+
+	// responses are in order of requests
+	if string(res[0].ID) != "3" || res[0].Result != "c" || res[0].Error != nil {
+		t.Errorf("unexpected first response %+v", res[0])
+	}
+
+	// malformed element gets its own error without id
+	if string(res[1].ID) != "null" || res[1].Error == nil || res[1].Error.code != ErrInvalidRequest.code {
+		t.Errorf("unexpected response of malformed element %+v", res[1])
+	}
+
+	if string(res[2].ID) != `"a"` || res[2].Result != "a" || res[2].Error != nil {
+		t.Errorf("unexpected third response %+v", res[2])
+	}
+
+	if string(res[3].ID) != "2" || res[3].Error == nil || res[3].Error.code != ErrInvalidParams.code {
+		t.Errorf("unexpected response of failed call %+v", res[3])
+	}
+
+	// empty batch is invalid request answered by single response
+	empty, ok := s.handle(jsoniter.RawMessage(`[]`), nil).(response)
+	if !ok || empty.Error == nil || empty.Error.code != ErrInvalidRequest.code || string(empty.ID) != "null" {
+		t.Errorf("unexpected response of empty batch %+v", empty)
+	}
+}
+
+// budgetCaller fails calls after given count of calls of one batch
+type budgetCaller struct {
+	left *int
+}
+
+func (c budgetCaller) Call(req Request) (interface{}, error) {
+	if c.left == nil {
+		return req.Method, nil
+	}
+
+	if *c.left == 0 {
+		return nil, ErrInternal
+	}
+
+	*c.left--
+
+	return req.Method, nil
+}
+
+func (budgetCaller) Batch() Caller {
+	left := 2
+	return budgetCaller{&left}
+}
+
+func TestBatchCaller(t *testing.T) {
+	s := New(nil, budgetCaller{})
+
+	batch := jsoniter.RawMessage(`[
+		{"jsonrpc":"2.0","id":1,"method":"a"},
+		{"jsonrpc":"2.0","id":2,"method":"b"},
+		{"jsonrpc":"2.0","id":3,"method":"c"}
+	]`)
+
+	// requests of batch share state of caller
+	res, ok := s.handle(batch, nil).([]response)
+	if !ok || len(res) != 3 || res[0].Error != nil || res[1].Error != nil || res[2].Error == nil {
+		t.Errorf("expected third request to fail but got %+v", res)
+	}
+
+	// each batch gets own state
+	res, ok = s.handle(batch, nil).([]response)
+	if !ok || len(res) != 3 || res[0].Error != nil || res[2].Error == nil {
+		t.Errorf("expected new state of next batch but got %+v", res)
+	}
+
+	// single request isn't limited
+	for i := 0; i < 3; i++ {
+		single, ok := s.handle(jsoniter.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"a"}`), nil).(response)
+		if !ok || single.Error != nil {
+			t.Errorf("unexpected response of single request %+v", single)
+		}
+	}
+}
+
+func TestNotification(t *testing.T) {
+	s := New(nil, methodCaller{})
+	hook := test.NewGlobal()
+
+	if res := s.handle(jsoniter.RawMessage(`{"jsonrpc":"2.0","method":"a"}`), nil); res != nil {
+		t.Errorf("notification should have no response but got %+v", res)
+	}
+
+	if len(hook.AllEntries()) != 0 {
+		t.Errorf("successful notification shouldn't be logged but got %v", hook.AllEntries())
+	}
+
+	// failed notification is logged because there is no response to it
+	if res := s.handle(jsoniter.RawMessage(`{"jsonrpc":"2.0","method":"fail"}`), nil); res != nil {
+		t.Errorf("failed notification should have no response but got %+v", res)
+	}
+
+	if e := hook.LastEntry(); e == nil || e.Level != log.ErrorLevel || e.Data["method"] != "fail" {
+		t.Errorf("expected error log of failed notification but got %+v", e)
+	}
+
+	// batch of notifications only has no response at all
+	if res := s.handle(jsoniter.RawMessage(`[
+		{"jsonrpc":"2.0","method":"a"},
+		{"jsonrpc":"2.0","method":"fail"}
+	]`), nil); res != nil {
+		t.Errorf("batch of notifications should have no response but got %+v", res)
+	}
+
+	// notifications of mixed batch are skipped
+	res, ok := s.handle(jsoniter.RawMessage(`[
+		{"jsonrpc":"2.0","method":"a"},
+		{"jsonrpc":"2.0","id":1,"method":"b"}
+	]`), nil).([]response)
+	if !ok || len(res) != 1 || string(res[0].ID) != "1" || res[0].Result != "b" {
+		t.Errorf("unexpected response of mixed batch %+v", res)
+	}
+
+	// request with null id isn't notification
+	single, ok := s.handle(jsoniter.RawMessage(`{"jsonrpc":"2.0","id":null,"method":"a"}`), nil).(response)
+	if !ok || single.Result != "a" {
+		t.Errorf("request with null id should have response but got %+v", single)
+	}
+}
+
+// paramsCaller returns params of request as result
+type paramsCaller struct{}
+
+func (paramsCaller) Call(req Request) (interface{}, error) {
+	return req.Params, nil
+}
+
+func TestParamsNotObject(t *testing.T) {
+	s := New(nil, paramsCaller{})
+
+	for _, raw := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"m","params":[1,2]}`,
+		`{"jsonrpc":"2.0","id":1,"method":"m","params":"a"}`,
+	} {
+		res, ok := s.handle(jsoniter.RawMessage(raw), nil).(response)
+		if !ok || res.Error == nil || res.Error.code != ErrInvalidParams.code || string(res.ID) != "1" {
+			t.Errorf("%s: unexpected response %+v", raw, res)
+		}
+	}
+
+	for _, raw := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"m","params":{"a":1}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"m","params":null}`,
+		`{"jsonrpc":"2.0","id":1,"method":"m"}`,
+	} {
+		res, ok := s.handle(jsoniter.RawMessage(raw), nil).(response)
+		if !ok || res.Error != nil {
+			t.Errorf("%s: unexpected response %+v", raw, res)
+		}
+	}
+
+	// batch item with wrong params doesn't fail other items
+	res, ok := s.handle(jsoniter.RawMessage(`[
+		{"jsonrpc":"2.0","id":1,"method":"m","params":[1]},
+		{"jsonrpc":"2.0","id":2,"method":"m","params":{}}
+	]`), nil).([]response)
+	if !ok || len(res) != 2 || res[0].Error == nil || res[1].Error != nil {
+		t.Errorf("unexpected batch response %+v", res)
+	}
+}
@@ -57,6 +57,9 @@ func ASCIIClient(address string) Client {
 // ASCIIPackager implements Packager interface.
 type ASCIIPackager struct {
 	SlaveId byte
+	// UNSAFE: SkipChecksum disables response LRC verification.
+	// Corrupted frames will be accepted, use it only for broken slaves.
+	SkipChecksum bool
 }
 
 // Encode encodes PDU in a ASCII frame:
@@ -161,7 +164,7 @@ func (mb *ASCIIPackager) Decode(adu []byte) (pdu *ProtocolDataUnit, err error) {
 	var lrc lrc
 	lrc.reset()
 	lrc.pushByte(address).pushByte(pdu.FunctionCode).pushBytes(pdu.Data)
-	if lrcVal != lrc.value() {
+	if lrcVal != lrc.value() && !mb.SkipChecksum {
 		err = fmt.Errorf("modbus: response lrc '%v' does not match expected '%v'", lrcVal, lrc.value())
 		return
 	}
@@ -174,6 +177,11 @@ type ASCIISerialTransporter struct {
 }
 
 func (mb *ASCIISerialTransporter) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return mb.SendTimeout(aduRequest, mb.Timeout)
+}
+
+// SendTimeout is like Send but uses given read timeout instead of Timeout.
+func (mb *ASCIISerialTransporter) SendTimeout(aduRequest []byte, timeout time.Duration) (aduResponse []byte, err error) {
 	mb.serialPort.mu.Lock()
 	defer mb.serialPort.mu.Unlock()
 
@@ -181,6 +189,12 @@ func (mb *ASCIISerialTransporter) Send(aduRequest []byte) (aduResponse []byte, e
 	if err = mb.serialPort.connect(); err != nil {
 		return
 	}
+	if timeout != mb.Timeout {
+		if err = mb.serialPort.setReadTimeout(timeout); err != nil {
+			return
+		}
+		defer mb.serialPort.setReadTimeout(mb.Timeout)
+	}
 	// Start the timer to close when idle
 	mb.serialPort.lastActivity = time.Now()
 	mb.serialPort.startCloseTimer()
@@ -26,6 +26,18 @@ const (
 	FuncCodeReadWriteMultipleRegisters = 23
 	FuncCodeMaskWriteRegister          = 22
 	FuncCodeReadFIFOQueue              = 24
+
+	// File record access
+	FuncCodeReadFileRecord  = 20
+	FuncCodeWriteFileRecord = 21
+
+	// Diagnostics
+	FuncCodeDiagnostics         = 8
+	FuncCodeGetCommEventCounter = 11
+	FuncCodeGetCommEventLog     = 12
+
+	// Encapsulated interface (read device identification)
+	FuncCodeEncapsulatedInterface = 43
 )
 
 const (
@@ -16,6 +16,8 @@ const (
 	rtuMaxSize = 256
 
 	rtuExceptionSize = 5
+
+	meiReadDeviceIdentification = 0x0E
 )
 
 // RTUClientHandler implements Packager and Transporter interface.
@@ -55,6 +57,9 @@ func RTUClient(address string) Client {
 // RTUPackager implements Packager interface.
 type RTUPackager struct {
 	SlaveId byte
+	// UNSAFE: SkipChecksum disables response CRC verification.
+	// Corrupted frames will be accepted, use it only for broken slaves.
+	SkipChecksum bool
 }
 
 // Encode encodes PDU in a RTU frame:
@@ -107,7 +112,7 @@ func (mb *RTUPackager) Decode(adu []byte) (pdu *ProtocolDataUnit, err error) {
 	var crc crc
 	crc.reset().pushBytes(adu[0 : length-2])
 	checksum := uint16(adu[length-1])<<8 | uint16(adu[length-2])
-	if checksum != crc.value() {
+	if checksum != crc.value() && !mb.SkipChecksum {
 		err = fmt.Errorf("modbus: response crc '%v' does not match expected '%v'", checksum, crc.value())
 		return
 	}
@@ -124,6 +129,26 @@ type RTUSerialTransporter struct {
 }
 
 func (mb *RTUSerialTransporter) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return mb.SendExpect(aduRequest, calculateResponseLength(aduRequest))
+}
+
+// SendTimeout is like Send but uses given read timeout instead of Timeout.
+func (mb *RTUSerialTransporter) SendTimeout(aduRequest []byte, timeout time.Duration) (aduResponse []byte, err error) {
+	if err = mb.serialPort.connect(); err != nil {
+		return
+	}
+	if timeout != mb.Timeout {
+		if err = mb.serialPort.setReadTimeout(timeout); err != nil {
+			return
+		}
+		defer mb.serialPort.setReadTimeout(mb.Timeout)
+	}
+	return mb.Send(aduRequest)
+}
+
+// SendExpect is like Send but reads response of given length
+// (for non-standard responses which length can't be calculated from request).
+func (mb *RTUSerialTransporter) SendExpect(aduRequest []byte, bytesToRead int) (aduResponse []byte, err error) {
 	// Make sure port is connected
 	if err = mb.serialPort.connect(); err != nil {
 		return
@@ -139,7 +164,6 @@ func (mb *RTUSerialTransporter) Send(aduRequest []byte) (aduResponse []byte, err
 	}
 	function := aduRequest[1]
 	functionFail := aduRequest[1] & 0x80
-	bytesToRead := calculateResponseLength(aduRequest)
 	time.Sleep(mb.calculateDelay(len(aduRequest) + bytesToRead))
 
 	var n int
@@ -153,6 +177,16 @@ func (mb *RTUSerialTransporter) Send(aduRequest []byte) (aduResponse []byte, err
 	}
 	//if the function is correct
 	if data[1] == function {
+		//length of variable responses is read from the frame itself
+		if bytesToRead <= rtuMinSize {
+			for length := calculateFrameLength(data[:n]); n < length && length <= rtuMaxSize; length = calculateFrameLength(data[:n]) {
+				n1, err = io.ReadFull(mb.port, data[n:length])
+				n += n1
+				if err != nil {
+					break
+				}
+			}
+		}
 		//we read the rest of the bytes
 		if n < bytesToRead {
 			if bytesToRead > rtuMinSize && bytesToRead <= rtuMaxSize {
@@ -215,9 +249,51 @@ func calculateResponseLength(adu []byte) int {
 		length += 4
 	case FuncCodeMaskWriteRegister:
 		length += 6
-	case FuncCodeReadFIFOQueue:
+	case FuncCodeDiagnostics,
+		FuncCodeGetCommEventCounter:
+		length += 4
+	case FuncCodeReadFIFOQueue,
+		FuncCodeGetCommEventLog,
+		FuncCodeReadFileRecord,
+		FuncCodeWriteFileRecord:
 		// undetermined
 	default:
 	}
 	return length
 }
+
+// calculateFrameLength calculates length of response which can't be calculated
+// from request using its received part (at least rtuMinSize bytes),
+// it returns 0 if the length is unknown
+func calculateFrameLength(adu []byte) int {
+	switch adu[1] {
+	case FuncCodeGetCommEventLog,
+		FuncCodeReadFileRecord,
+		FuncCodeWriteFileRecord:
+		// byte count follows function code
+		return 3 + int(adu[2]) + 2
+	case FuncCodeEncapsulatedInterface:
+		if adu[2] == meiReadDeviceIdentification {
+			return deviceIdentificationLength(adu)
+		}
+	}
+	return 0
+}
+
+// deviceIdentificationLength calculates length of read device identification response
+// from its received objects (each object is id, length and value)
+func deviceIdentificationLength(adu []byte) int {
+	// slave id, function, MEI type, read code, conformity level,
+	// more follows, next object id and number of objects
+	length := 8
+	if len(adu) < length {
+		return length
+	}
+	for i := 0; i < int(adu[7]); i++ {
+		if len(adu) < length+2 {
+			return length + 2
+		}
+		length += 2 + int(adu[length+1])
+	}
+	return length + 2
+}
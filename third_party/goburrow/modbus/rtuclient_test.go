@@ -0,0 +1,150 @@
+// Copyright 2014 Quoc-Viet Nguyen. All rights reserved.
+// This software may be modified and distributed under the terms
+// of the BSD license. See the LICENSE file for details.
+
+package modbus
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// bytePort is serial port which returns response by one byte per read
+type bytePort struct {
+	response []byte
+}
+
+func (p *bytePort) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (p *bytePort) Read(b []byte) (int, error) {
+	if len(p.response) == 0 {
+		return 0, io.EOF
+	}
+
+	b[0] = p.response[0]
+	p.response = p.response[1:]
+
+	return 1, nil
+}
+
+func (p *bytePort) Close() error {
+	return nil
+}
+
+// timeoutBytePort is bytePort which records read timeouts set on it
+type timeoutBytePort struct {
+	bytePort
+	timeouts []time.Duration
+}
+
+func (p *timeoutBytePort) SetReadTimeout(timeout time.Duration) error {
+	p.timeouts = append(p.timeouts, timeout)
+	return nil
+}
+
+func rtuFrame(t *testing.T, functionCode byte, data ...byte) []byte {
+	t.Helper()
+
+	packager := RTUPackager{SlaveId: 1}
+
+	adu, err := packager.Encode(&ProtocolDataUnit{FunctionCode: functionCode, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return adu
+}
+
+func testVariableResponse(t *testing.T, request, response []byte) {
+	t.Helper()
+
+	// trailing byte belongs to the next frame and shouldn't be read
+	port := &bytePort{response: append(append([]byte{}, response...), 0xff)}
+
+	mb := &RTUSerialTransporter{}
+	mb.port = port
+
+	res, err := mb.Send(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(res, response) {
+		t.Errorf("expected response % x but got % x", response, res)
+	}
+
+	if len(port.response) != 1 {
+		t.Errorf("expected 1 unread byte but got %d", len(port.response))
+	}
+}
+
+func TestRTUCommEventLog(t *testing.T) {
+	testVariableResponse(t,
+		rtuFrame(t, FuncCodeGetCommEventLog),
+		rtuFrame(t, FuncCodeGetCommEventLog, 8, 0, 0, 0x01, 0x08, 0x01, 0x21, 0x20, 0x00),
+	)
+}
+
+func TestRTUReadFileRecord(t *testing.T) {
+	testVariableResponse(t,
+		rtuFrame(t, FuncCodeReadFileRecord, 7, 6, 0, 4, 0, 1, 0, 2),
+		rtuFrame(t, FuncCodeReadFileRecord, 6, 5, 6, 0x0d, 0xfe, 0x00, 0x20),
+	)
+}
+
+func TestRTUWriteFileRecord(t *testing.T) {
+	// response is echo of request
+	request := rtuFrame(t, FuncCodeWriteFileRecord, 11, 6, 0, 4, 0, 7, 0, 2, 0x06, 0xaf, 0x04, 0xbe)
+	testVariableResponse(t, request, request)
+}
+
+func TestRTUReadDeviceIdentification(t *testing.T) {
+	testVariableResponse(t,
+		rtuFrame(t, FuncCodeEncapsulatedInterface, meiReadDeviceIdentification, 1, 0),
+		rtuFrame(t, FuncCodeEncapsulatedInterface, meiReadDeviceIdentification, 1, 1, 0, 0, 3,
+			0, 3, 'A', 'B', 'C',
+			1, 0,
+			2, 4, 'v', '1', '.', '0'),
+	)
+
+	// response without objects
+	testVariableResponse(t,
+		rtuFrame(t, FuncCodeEncapsulatedInterface, meiReadDeviceIdentification, 4, 5),
+		rtuFrame(t, FuncCodeEncapsulatedInterface, meiReadDeviceIdentification, 4, 1, 0, 0, 0),
+	)
+}
+
+func TestRTUSendTimeout(t *testing.T) {
+	request := rtuFrame(t, FuncCodeReadHoldingRegisters, 0, 0, 0, 1)
+	response := rtuFrame(t, FuncCodeReadHoldingRegisters, 2, 0, 42)
+
+	port := &timeoutBytePort{bytePort: bytePort{response: response}}
+
+	mb := &RTUSerialTransporter{}
+	mb.Timeout = serialTimeout
+	mb.port = port
+
+	res, err := mb.SendTimeout(request, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(res, response) {
+		t.Errorf("expected response % x but got % x", response, res)
+	}
+
+	// timeout of port is restored after transaction
+	if len(port.timeouts) != 2 || port.timeouts[0] != 100*time.Millisecond || port.timeouts[1] != serialTimeout {
+		t.Errorf("unexpected read timeouts %v", port.timeouts)
+	}
+
+	// port without changeable timeout can't send with other timeout
+	mb.port = &bytePort{response: response}
+	if _, err := mb.SendTimeout(request, 100*time.Millisecond); err == nil {
+		t.Error("expected error of read timeout")
+	}
+}
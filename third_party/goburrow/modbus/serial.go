@@ -5,6 +5,7 @@
 package modbus
 
 import (
+	"errors"
 	"io"
 	"sync"
 	"time"
@@ -52,6 +53,29 @@ func (mb *serialPort) connect() error {
 	return nil
 }
 
+// timeoutPort is implemented by ports which read timeout can be changed after open.
+type timeoutPort interface {
+	SetReadTimeout(timeout time.Duration) error
+}
+
+// setReadTimeout changes read timeout of connected port.
+func (mb *serialPort) setReadTimeout(timeout time.Duration) error {
+	p, ok := mb.port.(timeoutPort)
+	if !ok {
+		return errors.New("modbus: read timeout of serial port can't be changed")
+	}
+	return p.SetReadTimeout(timeout)
+}
+
+// Connected reports whether serial port is open
+// (it's opened on demand and closed after IdleTimeout).
+func (mb *serialPort) Connected() bool {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	return mb.port != nil
+}
+
 func (mb *serialPort) Close() (err error) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
@@ -68,6 +92,39 @@ func (mb *serialPort) close() (err error) {
 	return
 }
 
+// LineConfig returns serial port configuration.
+func (mb *serialPort) LineConfig() serial.Config {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	return mb.Config
+}
+
+// SetLineConfig changes baud rate, data bits, stop bits and parity and reopens the port.
+// Previous parameters are restored if the port can't be opened with new ones.
+// Caller must make sure there is no transaction in progress.
+func (mb *serialPort) SetLineConfig(c serial.Config) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	prev := mb.Config
+	if err := mb.close(); err != nil {
+		mb.logf("modbus: closing serial port: %v", err)
+	}
+
+	mb.BaudRate, mb.DataBits, mb.StopBits, mb.Parity = c.BaudRate, c.DataBits, c.StopBits, c.Parity
+
+	if err := mb.connect(); err != nil {
+		mb.Config = prev
+		return err
+	}
+
+	mb.lastActivity = time.Now()
+	mb.startCloseTimer()
+
+	return nil
+}
+
 func (mb *serialPort) logf(format string, v ...interface{}) {
 	if mb.Logger != nil {
 		mb.Logger.Printf(format, v...)
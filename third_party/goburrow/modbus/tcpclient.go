@@ -59,12 +59,57 @@ func TCPClient(address string) Client {
 	return NewClient(handler)
 }
 
+// TransactionIdSource generates transaction identifiers.
+// It's shared between packagers so it must be safe for concurrent use.
+type TransactionIdSource interface {
+	NextTransactionId() uint16
+}
+
+// TransactionIdRange generates monotonically increasing transaction identifiers
+// in [First, Last] range, it wraps around to First after Last.
+type TransactionIdRange struct {
+	First, Last uint16
+
+	mu   sync.Mutex
+	next uint32
+}
+
+// NewTransactionIdRange allocates a new TransactionIdRange.
+func NewTransactionIdRange(first, last uint16) *TransactionIdRange {
+	return &TransactionIdRange{First: first, Last: last, next: uint32(first)}
+}
+
+// NextTransactionId returns next identifier of the range.
+func (r *TransactionIdRange) NextTransactionId() uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next < uint32(r.First) || r.next > uint32(r.Last) {
+		r.next = uint32(r.First)
+	}
+	id := uint16(r.next)
+	r.next++
+	return id
+}
+
 // TCPPackager implements Packager interface.
 type TCPPackager struct {
 	// For synchronization between messages of server & client
 	transactionId uint32
+	// Set when transaction id is set explicitly
+	transactionIdSet uint32
 	// Broadcast address is 0
 	SlaveId byte
+	// Transaction identifiers source (optional), it's not used
+	// when transaction id is set explicitly
+	IdSource TransactionIdSource
+}
+
+// SetTransactionId sets transaction identifier used by next Encode.
+func (mb *TCPPackager) SetTransactionId(id uint16) {
+	// Encode increments identifier before use
+	atomic.StoreUint32(&mb.transactionId, uint32(id)-1)
+	atomic.StoreUint32(&mb.transactionIdSet, 1)
 }
 
 // Encode adds modbus application protocol header:
@@ -78,8 +123,12 @@ func (mb *TCPPackager) Encode(pdu *ProtocolDataUnit) (adu []byte, err error) {
 	adu = make([]byte, tcpHeaderSize+1+len(pdu.Data))
 
 	// Transaction identifier
-	transactionId := atomic.AddUint32(&mb.transactionId, 1)
-	binary.BigEndian.PutUint16(adu, uint16(transactionId))
+	if mb.IdSource != nil && atomic.LoadUint32(&mb.transactionIdSet) == 0 {
+		binary.BigEndian.PutUint16(adu, mb.IdSource.NextTransactionId())
+	} else {
+		transactionId := atomic.AddUint32(&mb.transactionId, 1)
+		binary.BigEndian.PutUint16(adu, uint16(transactionId))
+	}
 	// Protocol identifier
 	binary.BigEndian.PutUint16(adu[2:], tcpProtocolIdentifier)
 	// Length = sizeof(SlaveId) + sizeof(FunctionCode) + Data
@@ -144,6 +193,8 @@ type TCPTransporter struct {
 	Address string
 	// Connect & Read timeout
 	Timeout time.Duration
+	// Connect timeout (Timeout is used if it's not set)
+	ConnectTimeout time.Duration
 	// Idle timeout to close the connection
 	IdleTimeout time.Duration
 	// Transmission logger
@@ -158,11 +209,32 @@ type TCPTransporter struct {
 
 // Send sends data to server and ensures response length is greater than header length.
 func (mb *TCPTransporter) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return mb.SendTimeout(aduRequest, mb.Timeout)
+}
+
+// SendTimeout is like Send but uses given write and read timeout instead of Timeout.
+func (mb *TCPTransporter) SendTimeout(aduRequest []byte, readTimeout time.Duration) (aduResponse []byte, err error) {
+	return mb.send(aduRequest, mb.connectTimeout(), readTimeout)
+}
+
+// SendTimeouts is like Send but uses given connect and read timeouts,
+// zero timeouts mean ConnectTimeout and Timeout.
+func (mb *TCPTransporter) SendTimeouts(aduRequest []byte, connectTimeout, readTimeout time.Duration) (aduResponse []byte, err error) {
+	if connectTimeout <= 0 {
+		connectTimeout = mb.connectTimeout()
+	}
+	if readTimeout <= 0 {
+		readTimeout = mb.Timeout
+	}
+	return mb.send(aduRequest, connectTimeout, readTimeout)
+}
+
+func (mb *TCPTransporter) send(aduRequest []byte, connectTimeout, readTimeout time.Duration) (aduResponse []byte, err error) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
 	// Establish a new connection if not connected
-	if err = mb.connect(); err != nil {
+	if err = mb.dial(connectTimeout); err != nil {
 		return
 	}
 	// Set timer to close when idle
@@ -170,8 +242,8 @@ func (mb *TCPTransporter) Send(aduRequest []byte) (aduResponse []byte, err error
 	mb.startCloseTimer()
 	// Set write and read timeout
 	var timeout time.Time
-	if mb.Timeout > 0 {
-		timeout = mb.lastActivity.Add(mb.Timeout)
+	if readTimeout > 0 {
+		timeout = mb.lastActivity.Add(readTimeout)
 	}
 	if err = mb.conn.SetDeadline(timeout); err != nil {
 		return
@@ -214,12 +286,19 @@ func (mb *TCPTransporter) Connect() error {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
-	return mb.connect()
+	return mb.dial(mb.connectTimeout())
 }
 
-func (mb *TCPTransporter) connect() error {
+func (mb *TCPTransporter) connectTimeout() time.Duration {
+	if mb.ConnectTimeout > 0 {
+		return mb.ConnectTimeout
+	}
+	return mb.Timeout
+}
+
+func (mb *TCPTransporter) dial(timeout time.Duration) error {
 	if mb.conn == nil {
-		dialer := net.Dialer{Timeout: mb.Timeout}
+		dialer := net.Dialer{Timeout: timeout}
 		conn, err := dialer.Dial("tcp", mb.Address)
 		if err != nil {
 			return err
@@ -240,6 +319,15 @@ func (mb *TCPTransporter) startCloseTimer() {
 	}
 }
 
+// Connected reports whether connection is open
+// (it's opened on demand and closed after IdleTimeout).
+func (mb *TCPTransporter) Connected() bool {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	return mb.conn != nil
+}
+
 // Close closes current connection.
 func (mb *TCPTransporter) Close() error {
 	mb.mu.Lock()
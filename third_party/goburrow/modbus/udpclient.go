@@ -0,0 +1,133 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// Default UDP timeout is not set
+	udpTimeout = 10 * time.Second
+)
+
+// NewUDPTransporter allocates a new UDPTransporter.
+// It sends Modbus TCP frames (use TCPPackager) in datagrams.
+func NewUDPTransporter(address string) *UDPTransporter {
+	t := &UDPTransporter{}
+	t.Address = address
+	t.Timeout = udpTimeout
+
+	return t
+}
+
+// UDPTransporter implements Transporter interface.
+// Each frame is sent in one datagram, responses with other transaction id
+// (late responses of timed out requests and duplicates) are dropped.
+type UDPTransporter struct {
+	// Connect string
+	Address string
+	// Read timeout
+	Timeout time.Duration
+	// Transmission logger
+	Logger Logger
+
+	// UDP socket
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Send sends datagram to server and waits response with the same transaction id.
+func (mb *UDPTransporter) Send(aduRequest []byte) (aduResponse []byte, err error) {
+	return mb.SendTimeout(aduRequest, mb.Timeout)
+}
+
+// SendTimeout is like Send but uses given read timeout instead of Timeout.
+func (mb *UDPTransporter) SendTimeout(aduRequest []byte, readTimeout time.Duration) (aduResponse []byte, err error) {
+	if len(aduRequest) < tcpHeaderSize {
+		err = fmt.Errorf("modbus: request length '%v' must not be less than '%v'", len(aduRequest), tcpHeaderSize)
+		return
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if err = mb.connect(); err != nil {
+		return
+	}
+	var timeout time.Time
+	if readTimeout > 0 {
+		timeout = time.Now().Add(readTimeout)
+	}
+	if err = mb.conn.SetDeadline(timeout); err != nil {
+		return
+	}
+	mb.logf("modbus: sending % x", aduRequest)
+	if _, err = mb.conn.Write(aduRequest); err != nil {
+		return
+	}
+	var data [tcpMaxLength]byte
+	for {
+		var n int
+		if n, err = mb.conn.Read(data[:]); err != nil {
+			return
+		}
+		if n < tcpHeaderSize {
+			mb.logf("modbus: dropping short datagram % x", data[:n])
+			continue
+		}
+		// Transaction id
+		if binary.BigEndian.Uint16(data[:]) != binary.BigEndian.Uint16(aduRequest) {
+			mb.logf("modbus: dropping datagram of other transaction % x", data[:n])
+			continue
+		}
+		// Datagram boundary is the frame boundary so length must match it
+		length := int(binary.BigEndian.Uint16(data[4:]))
+		if length+tcpHeaderSize-1 != n {
+			err = fmt.Errorf("modbus: length in response header '%v' does not match datagram length '%v'", length, n)
+			return
+		}
+		aduResponse = append([]byte(nil), data[:n]...)
+		mb.logf("modbus: received % x\n", aduResponse)
+		return
+	}
+}
+
+func (mb *UDPTransporter) connect() error {
+	if mb.conn == nil {
+		conn, err := net.Dial("udp", mb.Address)
+		if err != nil {
+			return err
+		}
+		mb.conn = conn
+	}
+	return nil
+}
+
+// Connected reports whether socket is open (it's opened on first send).
+func (mb *UDPTransporter) Connected() bool {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	return mb.conn != nil
+}
+
+// Close closes current socket.
+func (mb *UDPTransporter) Close() (err error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.conn != nil {
+		err = mb.conn.Close()
+		mb.conn = nil
+	}
+	return
+}
+
+func (mb *UDPTransporter) logf(format string, v ...interface{}) {
+	if mb.Logger != nil {
+		mb.Logger.Printf(format, v...)
+	}
+}
@@ -82,6 +82,12 @@ func (p *port) Close() (err error) {
 	return
 }
 
+// SetReadTimeout changes read timeout of opened port.
+func (p *port) SetReadTimeout(timeout time.Duration) error {
+	p.timeout = timeout
+	return nil
+}
+
 // Read reads from serial port. Port must be opened before calling this method.
 // It is blocked until all data received or timeout after p.timeout.
 func (p *port) Read(b []byte) (n int, err error) {
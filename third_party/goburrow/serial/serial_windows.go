@@ -3,6 +3,7 @@ package serial
 import (
 	"fmt"
 	"syscall"
+	"time"
 )
 
 type port struct {
@@ -82,17 +83,28 @@ func (p *port) Write(b []byte) (n int, err error) {
 	return
 }
 
-func (p *port) setTimeouts(c *Config) error {
+// SetReadTimeout changes read (and write) timeout of opened port.
+func (p *port) SetReadTimeout(timeout time.Duration) error {
+	timeouts := commTimeouts(timeout)
+	return SetCommTimeouts(p.handle, &timeouts)
+}
+
+func commTimeouts(t time.Duration) c_COMMTIMEOUTS {
 	var timeouts c_COMMTIMEOUTS
 	// Read and write timeout
-	if c.Timeout > 0 {
-		timeout := toDWORD(int(c.Timeout.Nanoseconds() / 1E6))
+	if t > 0 {
+		timeout := toDWORD(int(t.Nanoseconds() / 1E6))
 		// wait until a byte arrived or time out
 		timeouts.ReadIntervalTimeout = c_MAXDWORD
 		timeouts.ReadTotalTimeoutMultiplier = c_MAXDWORD
 		timeouts.ReadTotalTimeoutConstant = timeout
 		timeouts.WriteTotalTimeoutConstant = timeout
 	}
+	return timeouts
+}
+
+func (p *port) setTimeouts(c *Config) error {
+	timeouts := commTimeouts(c.Timeout)
 	err := GetCommTimeouts(p.handle, &p.oldTimeouts)
 	if err != nil {
 		return err